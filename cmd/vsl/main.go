@@ -6,9 +6,40 @@ import (
 	"os"
 	"os/signal"
 	"sort"
+	"time"
 
+	"github.com/gloo-foo/vsl/internal/app"
+	buildcmd "github.com/gloo-foo/vsl/internal/app/commands/build"
+	cleancmd "github.com/gloo-foo/vsl/internal/app/commands/clean"
+	configcmd "github.com/gloo-foo/vsl/internal/app/commands/config"
+	debugcmd "github.com/gloo-foo/vsl/internal/app/commands/debug"
+	"github.com/gloo-foo/vsl/internal/app/commands/down"
+	execcmd "github.com/gloo-foo/vsl/internal/app/commands/exec"
+	"github.com/gloo-foo/vsl/internal/app/commands/gitcredential"
+	"github.com/gloo-foo/vsl/internal/app/commands/initcmd"
+	"github.com/gloo-foo/vsl/internal/app/commands/inspect"
+	"github.com/gloo-foo/vsl/internal/app/commands/kill"
+	"github.com/gloo-foo/vsl/internal/app/commands/lock"
+	"github.com/gloo-foo/vsl/internal/app/commands/logs"
+	"github.com/gloo-foo/vsl/internal/app/commands/prewarm"
+	"github.com/gloo-foo/vsl/internal/app/commands/ps"
+	"github.com/gloo-foo/vsl/internal/app/commands/replayfixture"
+	"github.com/gloo-foo/vsl/internal/app/commands/restart"
+	"github.com/gloo-foo/vsl/internal/app/commands/restore"
 	"github.com/gloo-foo/vsl/internal/app/commands/run"
+	"github.com/gloo-foo/vsl/internal/app/commands/scriptcmd"
+	"github.com/gloo-foo/vsl/internal/app/commands/selftest"
+	"github.com/gloo-foo/vsl/internal/app/commands/stop"
+	"github.com/gloo-foo/vsl/internal/app/commands/tasks"
+	testcmd "github.com/gloo-foo/vsl/internal/app/commands/test"
+	"github.com/gloo-foo/vsl/internal/app/commands/up"
+	"github.com/gloo-foo/vsl/internal/app/commands/validate"
+	"github.com/gloo-foo/vsl/internal/app/commands/worktree"
 	"github.com/gloo-foo/vsl/internal/app/log"
+	"github.com/gloo-foo/vsl/internal/container/clean"
+	"github.com/gloo-foo/vsl/internal/docker"
+	"github.com/gloo-foo/vsl/internal/git"
+	"github.com/gloo-foo/vsl/internal/script"
 	"github.com/urfave/cli/v2"
 )
 
@@ -23,11 +54,14 @@ var appVersion string
 
 var loggerConfig log.Config
 
+var staleCheckConfig clean.StaleCheckConfig
+
 func main() { runApp() }
 
 var (
 	appCreator    = createApp
 	loggerCreator = log.GetLogger
+	staleChecker  = clean.CheckStale
 )
 
 func runApp() {
@@ -36,7 +70,10 @@ func runApp() {
 
 	c := appCreator(loggerCreator)
 
-	if err := c.RunContext(ctx, os.Args); err != nil {
+	// Installed scripts run as commands of their own, and scripts given
+	// instead of a command, as by a shebang line, run as vsl run of them
+	args := run.ExpandShebang(c, scriptcmd.ExpandAlias(c, os.Args))
+	if err := c.RunContext(ctx, args); err != nil {
 		slog.Error("Application error", "error", err)
 		cancel()
 		os.Exit(1)
@@ -44,15 +81,45 @@ func runApp() {
 }
 
 func createApp(getLogger log.GetLoggerFunc) *cli.App {
+	script.Version = appVersion
 	c := &cli.App{
 		Name:    appName,
 		Usage:   appUsage,
 		Version: appVersion,
+		// Volume options and --mount specs use commas, so repeated flags are the only list separator
+		DisableSliceFlagSeparator: true,
 		Commands: []*cli.Command{
+			buildcmd.Command(appEnvPrefix),
+			cleancmd.Command(appEnvPrefix),
+			configcmd.Command(appEnvPrefix),
+			debugcmd.Command(appEnvPrefix),
+			down.Command(appEnvPrefix),
+			execcmd.Command(appEnvPrefix),
+			gitcredential.Command(),
+			initcmd.Command(appEnvPrefix),
+			inspect.Command(appEnvPrefix),
+			kill.Command(appEnvPrefix),
+			lock.Command(appEnvPrefix),
+			logs.Command(appEnvPrefix),
+			prewarm.Command(appEnvPrefix),
+			ps.Command(appEnvPrefix),
+			replayfixture.Command(appEnvPrefix),
+			restart.Command(appEnvPrefix),
+			restore.Command(appEnvPrefix),
 			run.Command(appEnvPrefix),
+			scriptcmd.Command(appEnvPrefix),
+			selftest.Command(appEnvPrefix),
+			stop.Command(appEnvPrefix),
+			tasks.Command(appEnvPrefix),
+			testcmd.Command(appEnvPrefix),
+			up.Command(appEnvPrefix),
+			validate.Command(appEnvPrefix),
+			worktree.Command(appEnvPrefix),
 		},
 		Before: func(c *cli.Context) error {
-			c.App.Metadata[log.LoggerMetadataKey] = getLogger(c, loggerConfig)
+			logger := getLogger(c, loggerConfig)
+			c.App.Metadata[log.LoggerMetadataKey] = logger
+			staleChecker(c.Context, logger, staleCheckConfig)
 			return nil
 		},
 		Flags: []cli.Flag{
@@ -70,6 +137,57 @@ func createApp(getLogger log.GetLoggerFunc) *cli.App {
 				Usage:       "Set the log output format (text, json)",
 				Destination: (*string)(&loggerConfig.Format),
 			},
+			&cli.BoolFlag{
+				Name:    app.FlagStableOutput,
+				EnvVars: []string{appEnvPrefix + "STABLE_OUTPUT"},
+				Usage:   "Write deterministic JSON results (sorted keys and lists, no IDs, durations or timestamps) for snapshots and diffs",
+			},
+			&cli.IntFlag{
+				Name:        "retries",
+				EnvVars:     []string{appEnvPrefix + "RETRIES"},
+				Value:       docker.Retries.Attempts,
+				Usage:       "Attempts for daemon API calls that fail transiently and are safe to repeat (1 disables retries)",
+				Destination: &docker.Retries.Attempts,
+			},
+			&cli.DurationFlag{
+				Name:        "retry-backoff",
+				EnvVars:     []string{appEnvPrefix + "RETRY_BACKOFF"},
+				Value:       docker.Retries.Backoff,
+				Usage:       "Delay before the first retry of a daemon API call, doubled for each further retry",
+				Destination: &docker.Retries.Backoff,
+			},
+			&cli.DurationFlag{
+				Name:        "stale-check-interval",
+				EnvVars:     []string{appEnvPrefix + "STALE_CHECK_INTERVAL"},
+				Value:       time.Hour,
+				Usage:       "Minimum time between checks for resources left by crashed runs (0 disables)",
+				Destination: &staleCheckConfig.Interval,
+			},
+			&cli.BoolFlag{
+				Name:        "git-discovery-cache",
+				EnvVars:     []string{appEnvPrefix + "GIT_DISCOVERY_CACHE"},
+				Usage:       "Keep git repository discovery results on disk between runs, invalidated when the directories or .git entries involved change",
+				Destination: &git.DiskCache,
+			},
+			&cli.BoolFlag{
+				Name:        "script-cache",
+				EnvVars:     []string{appEnvPrefix + "SCRIPT_CACHE"},
+				Value:       true,
+				Usage:       "Keep parsed scripts on disk between runs, invalidated when the content of a file they were read from changes",
+				Destination: &script.DiskCache,
+			},
+			&cli.StringFlag{
+				Name:        "profile",
+				EnvVars:     []string{appEnvPrefix + "PROFILE"},
+				Usage:       "Apply the named profile of the scripts run, overriding their image, environment, resources or command",
+				Destination: &script.Profile,
+			},
+			&cli.BoolFlag{
+				Name:        "auto-clean",
+				EnvVars:     []string{appEnvPrefix + "AUTO_CLEAN"},
+				Usage:       "Remove resources left by crashed runs instead of warning about them",
+				Destination: &staleCheckConfig.AutoClean,
+			},
 		},
 	}
 
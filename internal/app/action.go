@@ -5,6 +5,7 @@ package app
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"log/slog"
 
 	"github.com/gloo-foo/vsl/internal/app/log"
@@ -27,6 +28,19 @@ type HasOutput interface {
 	OutputFilePath() FilePath
 }
 
+// Failable is implemented by results that can describe a failed run.
+// Runners return a partially populated result alongside their error so
+// automation still receives machine-readable output on failure.
+type Failable interface {
+	Failed(err error) json.Marshaler
+}
+
+// Quietable is implemented by configs that can suppress the JSON result on
+// stdout, e.g. when container output is piped. A result file is still written.
+type Quietable interface {
+	QuietOutput() bool
+}
+
 // Runner is a generic function type for command runners
 type Runner[CONFIG Configurable, RESULT json.Marshaler] func(context.Context, *slog.Logger, CONFIG) (RESULT, error)
 
@@ -36,12 +50,42 @@ var getLogger = log.GetLogger
 func Action[C Configurable, R json.Marshaler](c *cli.Context, cfg C, runner Runner[C, R]) error {
 	logger := getLogger(c, cfg.LoggerConfig())
 
+	quiet := false
+	if q, ok := any(cfg).(Quietable); ok {
+		quiet = q.QuietOutput() && cfg.OutputFilePath() == ""
+	}
+
 	result, err := runner(c.Context, logger, cfg)
 	if err != nil {
+		if failable, ok := any(result).(Failable); ok && !quiet {
+			if outErr := output(c, logger, cfg.OutputFilePath(), failable.Failed(err)); outErr != nil {
+				logger.Error("Failed to write error result", "error", outErr)
+			}
+		}
+		// Preserve exit codes wrapped in domain errors, e.g. a container's own exit status
+		var exitCoder cli.ExitCoder
+		if errors.As(err, &exitCoder) {
+			return cli.Exit(err.Error(), exitCoder.ExitCode())
+		}
 		return err
 	}
 
-	return Output(logger, cfg.OutputFilePath(), result)
+	if quiet {
+		return nil
+	}
+	return output(c, logger, cfg.OutputFilePath(), result)
+}
+
+// output writes the result, made deterministic when --stable-output is set.
+func output(c *cli.Context, logger *slog.Logger, filePath FilePath, result json.Marshaler) error {
+	if c.Bool(FlagStableOutput) {
+		stable, err := Stable(result)
+		if err != nil {
+			return err
+		}
+		result = stable
+	}
+	return Output(logger, filePath, result)
 }
 
 // Default creates a default action function that combines configuration and runner
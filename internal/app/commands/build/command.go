@@ -0,0 +1,87 @@
+// Package build implements the "build" command.
+package build
+
+import (
+	"github.com/gloo-foo/vsl/internal/app"
+	"github.com/gloo-foo/vsl/internal/container"
+	"github.com/gloo-foo/vsl/internal/container/build"
+	"github.com/urfave/cli/v2"
+)
+
+// Command metadata
+const (
+	Name        = "build"
+	usage       = "Build the images of UP scripts without running them"
+	argsUsage   = "<script> [script...]"
+	description = `Build and tag the image of each script's build block without running
+anything, for CI pipelines that warm image caches separately from execution.
+
+Images get the same tag and labels a run of the script would give them (the
+script's image, or a tag derived from the build context), so later runs start
+from the built image and reuse its layer cache. Scripts without a build block
+are skipped. The command fails if any script is invalid or fails to build.
+
+Examples:
+  # Pre-build the images of all scripts
+  vsl build scripts/*.up
+
+  # Rebuild from scratch with fresh base images
+  vsl build --no-cache --pull tools/lint.up
+`
+)
+
+// Flag names
+const (
+	flagNoCache = "no-cache"
+	flagPull    = "pull"
+)
+
+// Package-level config populated by urfave/cli via Destination
+var cfg build.Config
+
+var buildAction = build.Build
+
+// Command returns the CLI command for building script images
+func Command(prefix app.AppEnvPrefix) *cli.Command {
+	return &cli.Command{
+		Name:        Name,
+		Usage:       usage,
+		ArgsUsage:   argsUsage,
+		Description: description,
+		Flags:       flags(prefix),
+		Action:      action,
+	}
+}
+
+// action handles the build command
+func action(c *cli.Context) error {
+	if c.NArg() == 0 {
+		return cli.Exit("expected at least one script", 1)
+	}
+	for _, arg := range c.Args().Slice() {
+		cfg.Scripts = append(cfg.Scripts, container.ScriptPath(arg))
+	}
+	return app.Action(c, cfg, buildAction)
+}
+
+// flags defines all command flags
+func flags(prefix app.AppEnvPrefix) []cli.Flag {
+	envPrefix := string(prefix) + "BUILD_"
+
+	baseFlags := []cli.Flag{
+		&cli.BoolFlag{
+			Name:        flagNoCache,
+			Usage:       "Do not use the build cache",
+			EnvVars:     []string{envPrefix + "NO_CACHE"},
+			Destination: &cfg.NoCache,
+		},
+		&cli.BoolFlag{
+			Name:        flagPull,
+			Usage:       "Always pull newer versions of base images",
+			EnvVars:     []string{envPrefix + "PULL"},
+			Destination: &cfg.Pull,
+		},
+	}
+
+	return app.WithOutputFlags(prefix, &cfg.Output, baseFlags)
+}
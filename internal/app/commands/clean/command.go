@@ -0,0 +1,118 @@
+// Package clean implements the "clean" command.
+package clean
+
+import (
+	"github.com/gloo-foo/vsl/internal/app"
+	"github.com/gloo-foo/vsl/internal/container/clean"
+	"github.com/gloo-foo/vsl/internal/container/resolve"
+	"github.com/urfave/cli/v2"
+)
+
+// Command metadata
+const (
+	Name        = "clean"
+	usage       = "Remove containers, networks, and volumes created by vsl"
+	argsUsage   = "[container...]"
+	description = `Remove resources labeled as managed by vsl.
+
+Containers may be selected by name, ID prefix, script path, session name, or
+"last" for the most recently created one. Without arguments every matching
+vsl resource is considered.
+
+Stopped containers, networks, and volumes are removed. Running containers are
+left alone unless --force is given. With --stale, only resources whose owning
+vsl process is no longer alive (for example after a crash) are removed.
+With --cache-limit, only unused cache volumes are removed, oldest first, until
+the cache volumes fit the given size.
+
+Examples:
+  # Remove everything created during a session
+  vsl clean --session feature-x
+
+  # Remove the most recent container
+  vsl clean last
+
+  # Remove leftovers from crashed runs
+  vsl clean --stale --force
+
+  # Keep dependency caches under 10 GiB
+  vsl clean --cache-limit 10gb
+
+  # Remove every cache volume not in use
+  vsl clean --cache-limit 0
+
+  # Remove all vsl resources, including running containers
+  vsl clean --force
+`
+)
+
+// Flag names
+const (
+	flagSession = "session"
+	flagForce   = "force"
+	flagStale   = "stale"
+	flagCaches  = "cache-limit"
+)
+
+// Package-level config populated by urfave/cli via Destination
+var cfg clean.Config
+
+var cleanAction = clean.Clean
+
+// Command returns the CLI command for cleaning up resources
+func Command(prefix app.AppEnvPrefix) *cli.Command {
+	return &cli.Command{
+		Name:        Name,
+		Usage:       usage,
+		ArgsUsage:   argsUsage,
+		Description: description,
+		Flags:       flags(prefix),
+		Action:      action,
+	}
+}
+
+// action handles the clean command
+func action(c *cli.Context) error {
+	for _, arg := range c.Args().Slice() {
+		cfg.Containers = append(cfg.Containers, resolve.Reference(arg))
+	}
+	// A limit of zero removes every unused cache volume
+	cfg.TrimCaches = c.IsSet(flagCaches)
+	return app.Action(c, cfg, cleanAction)
+}
+
+// flags defines all command flags
+func flags(prefix app.AppEnvPrefix) []cli.Flag {
+	envPrefix := string(prefix) + "CLEAN_"
+
+	baseFlags := []cli.Flag{
+		&cli.StringFlag{
+			Name:        flagSession,
+			Usage:       "Only remove resources labeled with this session",
+			EnvVars:     []string{string(prefix) + "SESSION"},
+			Destination: (*string)(&cfg.Session),
+		},
+		&cli.BoolFlag{
+			Name:        flagForce,
+			Aliases:     []string{"f"},
+			Usage:       "Also remove running containers",
+			EnvVars:     []string{envPrefix + "FORCE"},
+			Destination: &cfg.Force,
+		},
+		&cli.BoolFlag{
+			Name:        flagStale,
+			Usage:       "Only remove resources left behind by vsl processes that exited abnormally",
+			EnvVars:     []string{envPrefix + "STALE"},
+			Destination: &cfg.Stale,
+		},
+		&cli.GenericFlag{
+			Name:        flagCaches,
+			Usage:       "Only trim unused cache volumes, oldest first, to this total size (e.g. 10gb)",
+			EnvVars:     []string{envPrefix + "CACHE_LIMIT"},
+			Value:       &cfg.CacheLimit,
+			DefaultText: "none",
+		},
+	}
+
+	return app.WithOutputFlags(prefix, &cfg.Output, baseFlags)
+}
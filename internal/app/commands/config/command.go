@@ -0,0 +1,106 @@
+// Package config implements the "config" command.
+package config
+
+import (
+	"github.com/gloo-foo/vsl/internal/app"
+	"github.com/gloo-foo/vsl/internal/credentials"
+	"github.com/urfave/cli/v2"
+)
+
+// Command metadata
+const (
+	Name        = "config"
+	usage       = "Manage vsl configuration and stored secrets"
+	description = `Manage vsl configuration.
+
+Secrets stored with set-secret are encrypted at rest in the vsl config
+directory, and scripts read them with the store secret backend
+(from store:NAME). The key is kept in the OS keychain (macOS Keychain, or
+libsecret via secret-tool on Linux) when available, and otherwise in a key
+file readable only by the user.
+
+Examples:
+  # Store a token read from stdin, so it never appears in the process list
+  gh auth token | vsl config set-secret gh_token
+
+  # Re-encrypt stored secrets with a new key
+  vsl config rotate-key
+`
+)
+
+// Subcommand metadata
+const (
+	rotateKeyName     = "rotate-key"
+	rotateKeyUsage    = "Re-encrypt stored secrets with a newly generated key"
+	setSecretName     = "set-secret"
+	setSecretUsage    = "Store the secret read from stdin under a name, encrypted"
+	deleteSecretName  = "delete-secret"
+	deleteSecretUsage = "Remove a stored secret"
+	secretArgsUsage   = "<name>"
+)
+
+// Package-level config populated by urfave/cli via Destination
+var (
+	rotateCfg credentials.RotateConfig
+	secretCfg credentials.SecretConfig
+)
+
+var (
+	rotateKeyAction    = credentials.RotateKey
+	setSecretAction    = credentials.SetSecret
+	deleteSecretAction = credentials.DeleteSecret
+)
+
+// Command returns the CLI command for managing configuration
+func Command(prefix app.AppEnvPrefix) *cli.Command {
+	return &cli.Command{
+		Name:        Name,
+		Usage:       usage,
+		Description: description,
+		Subcommands: []*cli.Command{
+			{
+				Name:   rotateKeyName,
+				Usage:  rotateKeyUsage,
+				Flags:  app.OutputFlags(prefix, &rotateCfg.Output),
+				Action: rotateKey,
+			},
+			{
+				Name:      setSecretName,
+				Usage:     setSecretUsage,
+				ArgsUsage: secretArgsUsage,
+				Flags:     app.OutputFlags(prefix, &secretCfg.Output),
+				Action:    setSecret,
+			},
+			{
+				Name:      deleteSecretName,
+				Usage:     deleteSecretUsage,
+				ArgsUsage: secretArgsUsage,
+				Flags:     app.OutputFlags(prefix, &secretCfg.Output),
+				Action:    deleteSecret,
+			},
+		},
+	}
+}
+
+// rotateKey handles the rotate-key subcommand
+func rotateKey(c *cli.Context) error {
+	return app.Action(c, rotateCfg, rotateKeyAction)
+}
+
+// setSecret handles the set-secret subcommand
+func setSecret(c *cli.Context) error {
+	if c.NArg() != 1 {
+		return cli.Exit("expected the name of the secret, with its value on stdin", 1)
+	}
+	secretCfg.Name = c.Args().First()
+	return app.Action(c, secretCfg, setSecretAction)
+}
+
+// deleteSecret handles the delete-secret subcommand
+func deleteSecret(c *cli.Context) error {
+	if c.NArg() != 1 {
+		return cli.Exit("expected the name of the secret", 1)
+	}
+	secretCfg.Name = c.Args().First()
+	return app.Action(c, secretCfg, deleteSecretAction)
+}
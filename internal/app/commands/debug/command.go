@@ -0,0 +1,84 @@
+// Package debug implements the "debug" command.
+package debug
+
+import (
+	"github.com/gloo-foo/vsl/internal/app"
+	"github.com/gloo-foo/vsl/internal/container/debug"
+	"github.com/urfave/cli/v2"
+)
+
+// Command metadata
+const (
+	Name        = "debug"
+	usage       = "Open a shell in a re-created failed run"
+	argsUsage   = "[last|container-id]"
+	description = `Re-create a run from its recorded configuration with an interactive shell as
+the entrypoint, mounting everything identically, so the failure can be
+inspected in the context it happened in.
+
+Without an argument (or with "last") the most recent failed run is used.
+With --restore the session starts from the failed container's filesystem,
+including scratch files and build artifacts, when the container still exists.
+
+Examples:
+  # Debug the last failed run
+  vsl debug last
+
+  # Debug a specific run with bash
+  vsl debug --shell /bin/bash 3f2a9c
+`
+)
+
+// Flag names
+const (
+	flagShell   = "shell"
+	flagRestore = "restore"
+)
+
+// Package-level config populated by urfave/cli via Destination
+var cfg debug.Config
+
+var debugAction = debug.Debug
+
+// Command returns the CLI command for debugging failed runs
+func Command(prefix app.AppEnvPrefix) *cli.Command {
+	return &cli.Command{
+		Name:        Name,
+		Usage:       usage,
+		ArgsUsage:   argsUsage,
+		Description: description,
+		Flags:       flags(prefix),
+		Action:      action,
+	}
+}
+
+// action handles the debug command
+func action(c *cli.Context) error {
+	cfg.Run = debug.Last
+	if c.NArg() > 0 {
+		cfg.Run = c.Args().First()
+	}
+	return app.Action(c, cfg, debugAction)
+}
+
+// flags defines all command flags
+func flags(prefix app.AppEnvPrefix) []cli.Flag {
+	envPrefix := string(prefix) + "DEBUG_"
+
+	baseFlags := []cli.Flag{
+		&cli.StringFlag{
+			Name:        flagShell,
+			Usage:       "Shell to start instead of the run's command",
+			EnvVars:     []string{envPrefix + "SHELL"},
+			Value:       "/bin/sh",
+			Destination: &cfg.Shell,
+		},
+		&cli.BoolFlag{
+			Name:        flagRestore,
+			Usage:       "Start from the failed container's filesystem when it still exists",
+			Destination: &cfg.Restore,
+		},
+	}
+
+	return app.WithOutputFlags(prefix, &cfg.Output, baseFlags)
+}
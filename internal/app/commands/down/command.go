@@ -0,0 +1,48 @@
+// Package down implements the "down" command.
+package down
+
+import (
+	"github.com/gloo-foo/vsl/internal/app"
+	"github.com/gloo-foo/vsl/internal/container"
+	"github.com/gloo-foo/vsl/internal/container/services"
+	"github.com/urfave/cli/v2"
+)
+
+// Command metadata
+const (
+	Name        = "down"
+	usage       = "Remove the services vsl up started for a script"
+	argsUsage   = "<script>"
+	description = `Remove the containers of the script's services, running or not, and the
+network they shared. Cache and named volumes are kept.
+
+Examples:
+  vsl down stack.up
+`
+)
+
+// Package-level config populated by urfave/cli via Destination
+var cfg services.Config
+
+var downAction = services.Down
+
+// Command returns the CLI command for removing services
+func Command(prefix app.AppEnvPrefix) *cli.Command {
+	return &cli.Command{
+		Name:        Name,
+		Usage:       usage,
+		ArgsUsage:   argsUsage,
+		Description: description,
+		Flags:       app.OutputFlags(prefix, &cfg.Output),
+		Action:      action,
+	}
+}
+
+// action handles the down command
+func action(c *cli.Context) error {
+	if c.NArg() != 1 {
+		return cli.Exit("expected the script declaring the services", 1)
+	}
+	cfg.Script = container.ScriptPath(c.Args().First())
+	return app.Action(c, cfg, downAction)
+}
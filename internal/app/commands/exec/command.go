@@ -0,0 +1,127 @@
+// Package exec implements the "exec" command.
+package exec
+
+import (
+	"github.com/gloo-foo/vsl/internal/app"
+	"github.com/gloo-foo/vsl/internal/container"
+	"github.com/gloo-foo/vsl/internal/container/exec"
+	"github.com/gloo-foo/vsl/internal/container/resolve"
+	"github.com/urfave/cli/v2"
+)
+
+// Command metadata
+const (
+	Name        = "exec"
+	usage       = "Run a command in a running vsl container"
+	argsUsage   = "<container> [--] <command> [args...]"
+	description = `Run a command inside a running vsl-managed container.
+
+The container may be given by name, ID prefix, script path, session name, or
+"last" for the most recently created one.
+
+Like run, exec reports a JSON result with the exit code and duration, records
+a history entry, and exits with the command's exit code. Output can be
+captured into the result with --capture, streamed with --pipe, or the result
+suppressed with --quiet.
+
+Examples:
+  # Run tests in the most recent container, streaming their output
+  vsl exec --pipe last -- go test ./...
+
+  # Capture a command's output in the JSON result
+  vsl exec --capture dev-shell -- cat /etc/os-release
+`
+)
+
+// Flag names
+const (
+	flagEnv        = "env"
+	flagUser       = "user"
+	flagWorkingDir = "working-dir"
+	flagTTY        = "tty"
+	flagCapture    = "capture"
+	flagPipe       = "pipe"
+	flagQuiet      = "quiet"
+)
+
+// Package-level config populated by urfave/cli via Destination
+var cfg exec.Config
+
+var execAction = exec.Exec
+
+// Command returns the CLI command for running commands in containers
+func Command(prefix app.AppEnvPrefix) *cli.Command {
+	return &cli.Command{
+		Name:        Name,
+		Usage:       usage,
+		ArgsUsage:   argsUsage,
+		Description: description,
+		Flags:       flags(prefix),
+		Action:      action,
+	}
+}
+
+// action handles the exec command
+func action(c *cli.Context) error {
+	if c.NArg() < 2 {
+		return cli.Exit("expected a container and a command", 1)
+	}
+	cfg.Container = resolve.Reference(c.Args().First())
+	cfg.Command = c.Args().Tail()
+	for _, env := range c.StringSlice(flagEnv) {
+		cfg.Environment = append(cfg.Environment, container.Environment(env))
+	}
+	return app.Action(c, cfg, execAction)
+}
+
+// flags defines all command flags
+func flags(prefix app.AppEnvPrefix) []cli.Flag {
+	envPrefix := string(prefix) + "EXEC_"
+
+	baseFlags := []cli.Flag{
+		&cli.StringSliceFlag{
+			Name:    flagEnv,
+			Aliases: []string{"e"},
+			Usage:   "Set environment variables (KEY=value)",
+		},
+		&cli.StringFlag{
+			Name:        flagUser,
+			Aliases:     []string{"u"},
+			Usage:       "User to run as (uid:gid or username)",
+			Destination: (*string)(&cfg.User),
+		},
+		&cli.StringFlag{
+			Name:        flagWorkingDir,
+			Aliases:     []string{"w"},
+			Usage:       "Working directory inside the container",
+			Destination: (*string)(&cfg.WorkingDir),
+		},
+		&cli.BoolFlag{
+			Name:        flagTTY,
+			Aliases:     []string{"t"},
+			Usage:       "Allocate a pseudo-TTY",
+			Destination: &cfg.TTY,
+		},
+		&cli.BoolFlag{
+			Name:        flagCapture,
+			Usage:       "Include the command's stdout and stderr in the JSON result",
+			EnvVars:     []string{envPrefix + "CAPTURE"},
+			Destination: &cfg.Capture,
+		},
+		&cli.BoolFlag{
+			Name:        flagPipe,
+			Usage:       "Stream the command's output to stdout/stderr and omit the JSON result unless --output is set",
+			EnvVars:     []string{envPrefix + "PIPE"},
+			Destination: &cfg.Pipe,
+		},
+		&cli.BoolFlag{
+			Name:        flagQuiet,
+			Aliases:     []string{"q"},
+			Usage:       "Do not print the JSON result; the exit code reflects the command's",
+			EnvVars:     []string{envPrefix + "QUIET"},
+			Destination: &cfg.Quiet,
+		},
+	}
+
+	return app.WithOutputFlags(prefix, &cfg.Output, baseFlags)
+}
@@ -0,0 +1,58 @@
+// Package gitcredential implements the hidden "git-credential-helper" command.
+package gitcredential
+
+import (
+	"os"
+
+	"github.com/gloo-foo/vsl/internal/container/run"
+	"github.com/gloo-foo/vsl/internal/git"
+	"github.com/urfave/cli/v2"
+)
+
+// Command metadata
+const (
+	Name        = run.CredentialHelperCommand
+	usage       = "Forward git credential requests to the host (run by git in containers)"
+	argsUsage   = "get|store|erase"
+	description = `The credential.helper git runs in containers started with
+--git-credential-bridge. The request git writes on stdin is forwarded to the
+host's credential helpers through the bridge socket and their answer is
+written to stdout, following the git credential helper protocol. The bridge
+only answers get, for the hosts of the mounted repositories' remotes.`
+)
+
+// Flag names
+const (
+	flagSocket = "socket"
+)
+
+// Command returns the hidden CLI command git runs as its credential helper
+func Command() *cli.Command {
+	return &cli.Command{
+		Name:        Name,
+		Usage:       usage,
+		ArgsUsage:   argsUsage,
+		Description: description,
+		Hidden:      true,
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  flagSocket,
+				Usage: "Path of the credential bridge socket",
+				Value: run.CredentialSocketPath,
+			},
+		},
+		Action: action,
+	}
+}
+
+// action handles the helper command, which speaks the git credential
+// protocol on stdin and stdout rather than writing a JSON result
+func action(c *cli.Context) error {
+	if c.NArg() != 1 {
+		return cli.Exit("expected one action: get, store or erase", 1)
+	}
+	if err := git.CredentialHelper(c.String(flagSocket), c.Args().First(), os.Stdin, os.Stdout); err != nil {
+		return cli.Exit(err.Error(), 1)
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+// Package initcmd implements the "init" command.
+package initcmd
+
+import (
+	"github.com/gloo-foo/vsl/internal/app"
+	"github.com/gloo-foo/vsl/internal/script/scaffold"
+	"github.com/urfave/cli/v2"
+)
+
+// Command metadata
+const (
+	Name        = "init"
+	usage       = "Write a starter UP script for the project"
+	argsUsage   = "[go|node|python|rust]"
+	description = `Write an executable starter script into the current directory, with a
+shebang line, an image, dependency caches and environment for the stack.
+
+Without a template, it is detected from the files of the current directory,
+then of the repository root: go.mod or go.work for go, package.json for node,
+pyproject.toml, requirements.txt, setup.py or Pipfile for python, and
+Cargo.toml for rust.
+
+The script is named after the template, such as go.up, unless --file is set,
+and an existing script is only replaced with --force.
+
+Examples:
+  # Detect the stack and write its script
+  vsl init
+
+  # Write the node template as test.up
+  vsl init --file test.up node
+`
+)
+
+// Flag names
+const (
+	flagFile  = "file"
+	flagForce = "force"
+)
+
+// Package-level config populated by urfave/cli via Destination
+var cfg scaffold.Config
+
+var initAction = scaffold.Init
+
+// Command returns the CLI command for writing a starter script
+func Command(prefix app.AppEnvPrefix) *cli.Command {
+	return &cli.Command{
+		Name:        Name,
+		Usage:       usage,
+		ArgsUsage:   argsUsage,
+		Description: description,
+		Flags:       flags(prefix),
+		Action:      action,
+	}
+}
+
+// action handles the init command
+func action(c *cli.Context) error {
+	if c.NArg() > 1 {
+		return cli.Exit("expected at most one template", 1)
+	}
+	cfg.Template = c.Args().First()
+	return app.Action(c, cfg, initAction)
+}
+
+// flags defines all command flags
+func flags(prefix app.AppEnvPrefix) []cli.Flag {
+	envPrefix := string(prefix) + "INIT_"
+
+	baseFlags := []cli.Flag{
+		&cli.StringFlag{
+			Name:        flagFile,
+			Aliases:     []string{"f"},
+			Usage:       "Script to write (default <template>.up)",
+			EnvVars:     []string{envPrefix + "FILE"},
+			Destination: &cfg.File,
+		},
+		&cli.BoolFlag{
+			Name:        flagForce,
+			Usage:       "Overwrite an existing script",
+			EnvVars:     []string{envPrefix + "FORCE"},
+			Destination: &cfg.Force,
+		},
+	}
+
+	return app.WithOutputFlags(prefix, &cfg.Output, baseFlags)
+}
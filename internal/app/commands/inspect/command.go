@@ -0,0 +1,74 @@
+// Package inspect implements the "inspect" command.
+package inspect
+
+import (
+	"github.com/gloo-foo/vsl/internal/app"
+	"github.com/gloo-foo/vsl/internal/app/commands/run"
+	runpkg "github.com/gloo-foo/vsl/internal/container/run"
+	"github.com/urfave/cli/v2"
+)
+
+// Command metadata
+const (
+	Name        = "inspect"
+	usage       = "Print the resolved configuration of a run as docker run, compose or settings"
+	argsUsage   = "[run flags] [script [args...] | command...]"
+	description = `Resolve a script or vsl run invocation as vsl run would, with its mounts of
+the current directory and git repository, caches, environment and resources,
+and print it as a docker run command line or a compose file instead of running
+it, for environments where vsl is not installed. Runs that build their image
+are printed after the docker build command, or with a build section. The
+settings format lists each setting of the run with where its value comes
+from: vsl's default, a configuration file, the script, or the command line.
+
+Secrets are passed from the environment by name rather than written out, and
+capabilities the run requires are assumed available. vsl's own labels are
+left out, so the exported container is not treated as a vsl run.
+
+Flags are those of vsl run.
+
+Examples:
+  # Print a script as a docker run command line
+  vsl inspect ./test.up
+
+  # Hand a CLI invocation over as a compose service
+  vsl inspect --format compose --image node:20 --cache /root/.npm -- npm test > compose.yaml
+
+  # See which settings of a script the command line overrides
+  vsl inspect --format settings --memory 4gb ./test.up
+`
+)
+
+// Flag names
+const (
+	flagFormat = "format"
+)
+
+// format is the form the run is printed in
+var format string
+
+// Command returns the CLI command for inspecting runs
+func Command(prefix app.AppEnvPrefix) *cli.Command {
+	envPrefix := string(prefix) + "INSPECT_"
+	return &cli.Command{
+		Name:        Name,
+		Usage:       usage,
+		ArgsUsage:   argsUsage,
+		Description: description,
+		Flags: append([]cli.Flag{
+			&cli.StringFlag{
+				Name:        flagFormat,
+				Usage:       "Format to print the run in: docker-run, compose or settings",
+				Value:       string(runpkg.ExportDockerRun),
+				EnvVars:     []string{envPrefix + "FORMAT"},
+				Destination: &format,
+			},
+		}, run.Command(prefix).Flags...),
+		Action: action,
+	}
+}
+
+// action handles the inspect command
+func action(c *cli.Context) error {
+	return run.InspectAction(c, runpkg.ExportFormat(format))
+}
@@ -0,0 +1,93 @@
+// Package kill implements the "kill" command.
+package kill
+
+import (
+	"github.com/gloo-foo/vsl/internal/app"
+	"github.com/gloo-foo/vsl/internal/container/resolve"
+	"github.com/gloo-foo/vsl/internal/container/stop"
+	"github.com/urfave/cli/v2"
+)
+
+// Command metadata
+const (
+	Name        = "kill"
+	usage       = "Kill vsl containers"
+	argsUsage   = "[container...]"
+	description = `Send a signal, SIGKILL by default, to running vsl containers without waiting
+for them to exit. Prefer vsl stop, which lets containers shut down cleanly.
+
+Containers may be selected by name, ID prefix, script path or session name,
+every container of a project with --project, or every vsl container with
+--all. A name or ID prefix matching several containers is refused with the
+list of candidates; only script paths and sessions select several.
+
+Examples:
+  # Kill a container that does not respond to vsl stop
+  vsl kill web-1
+
+  # Ask every container of the current project to reload
+  vsl kill --project . --signal HUP
+`
+)
+
+// Flag names
+const (
+	flagProject = "project"
+	flagAll     = "all"
+	flagSignal  = "signal"
+)
+
+// Package-level config populated by urfave/cli via Destination
+var cfg stop.Config
+
+var killAction = stop.Kill
+
+// Command returns the CLI command for killing containers
+func Command(prefix app.AppEnvPrefix) *cli.Command {
+	return &cli.Command{
+		Name:        Name,
+		Usage:       usage,
+		ArgsUsage:   argsUsage,
+		Description: description,
+		Flags:       flags(prefix),
+		Action:      action,
+	}
+}
+
+// action handles the kill command
+func action(c *cli.Context) error {
+	for _, arg := range c.Args().Slice() {
+		cfg.Containers = append(cfg.Containers, resolve.Reference(arg))
+	}
+	return app.Action(c, cfg, killAction)
+}
+
+// flags defines all command flags
+func flags(prefix app.AppEnvPrefix) []cli.Flag {
+	envPrefix := string(prefix) + "KILL_"
+
+	baseFlags := []cli.Flag{
+		&cli.StringFlag{
+			Name:        flagProject,
+			Usage:       "Kill the containers run for the project in this directory (its git root, or the directory outside git)",
+			EnvVars:     []string{envPrefix + "PROJECT"},
+			Destination: (*string)(&cfg.Project),
+		},
+		&cli.BoolFlag{
+			Name:        flagAll,
+			Usage:       "Kill every vsl container",
+			EnvVars:     []string{envPrefix + "ALL"},
+			Destination: &cfg.All,
+		},
+		&cli.StringFlag{
+			Name:        flagSignal,
+			Aliases:     []string{"s"},
+			Usage:       "Signal to send, by name or number (e.g. TERM, HUP, 9)",
+			Value:       "KILL",
+			EnvVars:     []string{envPrefix + "SIGNAL"},
+			Destination: &cfg.Signal,
+		},
+	}
+
+	return app.WithOutputFlags(prefix, &cfg.Output, baseFlags)
+}
@@ -0,0 +1,83 @@
+// Package lock implements the "lock" command.
+package lock
+
+import (
+	"github.com/gloo-foo/vsl/internal/app"
+	"github.com/gloo-foo/vsl/internal/container"
+	"github.com/gloo-foo/vsl/internal/image/lock"
+	"github.com/urfave/cli/v2"
+)
+
+// Command metadata
+const (
+	Name        = "lock"
+	usage       = "Pin the images of scripts to digests in vsl.lock"
+	argsUsage   = "[script...]"
+	description = `Resolve the image tags of the workspace's scripts to the digests of their
+manifests and record them in vsl.lock at the workspace root. Runs of scripts
+in the workspace then use the pinned digest instead of the tag, so everyone
+committing the lock file runs the same images.
+
+Images already in the lock file keep their digest; --update resolves them
+again. Without scripts every UP script of the workspace is locked, including
+the images of steps and services, and pins of images no script uses are
+dropped. Built images and images given by variables are not locked.
+
+Examples:
+  # Lock every script of the workspace
+  vsl lock
+
+  # Refresh the pins to the current tags
+  vsl lock --update
+
+  # Lock the images of one script
+  vsl lock ./scripts/test.up
+`
+)
+
+// Flag names
+const (
+	flagUpdate = "update"
+)
+
+// Package-level config populated by urfave/cli via Destination
+var cfg lock.Config
+
+var lockAction = lock.Lock
+
+// Command returns the CLI command for locking image digests
+func Command(prefix app.AppEnvPrefix) *cli.Command {
+	return &cli.Command{
+		Name:        Name,
+		Usage:       usage,
+		ArgsUsage:   argsUsage,
+		Description: description,
+		Flags:       flags(prefix),
+		Action:      action,
+	}
+}
+
+// action handles the lock command
+func action(c *cli.Context) error {
+	for _, arg := range c.Args().Slice() {
+		cfg.Scripts = append(cfg.Scripts, container.ScriptPath(arg))
+	}
+	return app.Action(c, cfg, lockAction)
+}
+
+// flags defines all command flags
+func flags(prefix app.AppEnvPrefix) []cli.Flag {
+	envPrefix := string(prefix) + "LOCK_"
+
+	baseFlags := []cli.Flag{
+		&cli.BoolFlag{
+			Name:        flagUpdate,
+			Aliases:     []string{"u"},
+			Usage:       "Resolve images the lock file already pins again",
+			EnvVars:     []string{envPrefix + "UPDATE"},
+			Destination: &cfg.Update,
+		},
+	}
+
+	return app.WithOutputFlags(prefix, &cfg.Output, baseFlags)
+}
@@ -0,0 +1,145 @@
+// Package logs implements the "logs" command.
+package logs
+
+import (
+	"os"
+
+	"github.com/gloo-foo/vsl/internal/app"
+	"github.com/gloo-foo/vsl/internal/container/logs"
+	"github.com/gloo-foo/vsl/internal/container/resolve"
+	lg "github.com/gloo-foo/vsl/internal/logs"
+	"github.com/urfave/cli/v2"
+)
+
+// Command metadata
+const (
+	Name        = "logs"
+	usage       = "Show the output of vsl containers"
+	argsUsage   = "[container...]"
+	description = `Show the logs of one or more vsl-managed containers.
+
+Containers may be selected by name, ID prefix, script path, session name, or
+"last" for the container of the most recent run recorded in the history that
+still exists (the most recently created container when none does). Output of
+several containers is interleaved with each line prefixed by the container
+name.
+
+Lines are colored by detected log level (logfmt, JSON, and bracketed formats)
+when writing to a terminal.
+
+Only the log lines are written to stdout; the JSON result, listing the
+containers shown, is written to the file given with --output.
+
+Examples:
+  # Follow the most recent container
+  vsl logs --follow last
+
+  # The last 100 lines of a detached service, then new output
+  vsl logs --tail 100 --follow ./services/db.up
+
+  # Errors from every container of a session in the last ten minutes
+  vsl logs --session feature-x --since 10m --grep 'level=error'
+`
+)
+
+// Flag names
+const (
+	flagSession    = "session"
+	flagFollow     = "follow"
+	flagTimestamps = "timestamps"
+	flagSince      = "since"
+	flagUntil      = "until"
+	flagTail       = "tail"
+	flagGrep       = "grep"
+	flagNoColor    = "no-color"
+)
+
+// Package-level config populated by urfave/cli via Destination
+var cfg logs.Config
+
+var noColor bool
+
+var logsAction = logs.Logs
+
+// Command returns the CLI command for showing logs
+func Command(prefix app.AppEnvPrefix) *cli.Command {
+	return &cli.Command{
+		Name:        Name,
+		Usage:       usage,
+		ArgsUsage:   argsUsage,
+		Description: description,
+		Flags:       flags(prefix),
+		Action:      action,
+	}
+}
+
+// action handles the logs command
+func action(c *cli.Context) error {
+	for _, arg := range c.Args().Slice() {
+		cfg.Containers = append(cfg.Containers, resolve.Reference(arg))
+	}
+	cfg.Color = !noColor && lg.IsTerminal(os.Stdout)
+	return app.Action(c, cfg, logsAction)
+}
+
+// flags defines all command flags
+func flags(prefix app.AppEnvPrefix) []cli.Flag {
+	envPrefix := string(prefix) + "LOGS_"
+
+	baseFlags := []cli.Flag{
+		&cli.StringFlag{
+			Name:        flagSession,
+			Usage:       "Show logs of every container in this session",
+			EnvVars:     []string{string(prefix) + "SESSION"},
+			Destination: (*string)(&cfg.Session),
+		},
+		&cli.BoolFlag{
+			Name:        flagFollow,
+			Aliases:     []string{"f"},
+			Usage:       "Keep streaming new output",
+			EnvVars:     []string{envPrefix + "FOLLOW"},
+			Destination: &cfg.Follow,
+		},
+		&cli.BoolFlag{
+			Name:        flagTimestamps,
+			Aliases:     []string{"t"},
+			Usage:       "Prefix lines with their timestamps",
+			EnvVars:     []string{envPrefix + "TIMESTAMPS"},
+			Destination: &cfg.Timestamps,
+		},
+		&cli.StringFlag{
+			Name:        flagSince,
+			Usage:       "Show logs since a timestamp or relative duration (e.g. 10m)",
+			EnvVars:     []string{envPrefix + "SINCE"},
+			Destination: &cfg.Since,
+		},
+		&cli.StringFlag{
+			Name:        flagUntil,
+			Usage:       "Show logs before a timestamp or relative duration (e.g. 1m)",
+			EnvVars:     []string{envPrefix + "UNTIL"},
+			Destination: &cfg.Until,
+		},
+		&cli.StringFlag{
+			Name:        flagTail,
+			Aliases:     []string{"n"},
+			Usage:       "Number of lines to show from the end of each container's logs, or all",
+			Value:       "all",
+			EnvVars:     []string{envPrefix + "TAIL"},
+			Destination: &cfg.Tail,
+		},
+		&cli.StringFlag{
+			Name:        flagGrep,
+			Usage:       "Only show lines matching this regular expression",
+			EnvVars:     []string{envPrefix + "GREP"},
+			Destination: &cfg.Grep,
+		},
+		&cli.BoolFlag{
+			Name:        flagNoColor,
+			Usage:       "Disable level-based coloring",
+			EnvVars:     []string{envPrefix + "NO_COLOR", "NO_COLOR"},
+			Destination: &noColor,
+		},
+	}
+
+	return app.WithOutputFlags(prefix, &cfg.Output, baseFlags)
+}
@@ -0,0 +1,90 @@
+// Package prewarm implements the "prewarm" command.
+package prewarm
+
+import (
+	"github.com/gloo-foo/vsl/internal/app"
+	"github.com/gloo-foo/vsl/internal/image/prewarm"
+	"github.com/urfave/cli/v2"
+)
+
+// Command metadata
+const (
+	Name        = "prewarm"
+	usage       = "Pull the images listed in a shared manifest"
+	argsUsage   = "[manifest]"
+	description = `Pull or update every image listed in a team-distributed manifest, so a new
+machine gets all project tool images in one command.
+
+The manifest is an UP file (local path or http(s) URL) with an images list:
+
+  images [
+    golang:1.22
+    node:20-alpine
+  ]
+
+With --every the manifest is re-read and images are pulled on that interval
+until interrupted; a manifest that cannot be read is retried at the next
+interval instead of stopping.
+
+Examples:
+  # Pull everything once
+  vsl prewarm ./tools/prewarm.up
+
+  # Keep images warm from a shared URL
+  vsl prewarm --every 6h https://example.com/team/prewarm.up
+`
+)
+
+// Flag names
+const (
+	flagManifest = "manifest"
+	flagEvery    = "every"
+)
+
+// Package-level config populated by urfave/cli via Destination
+var cfg prewarm.Config
+
+var prewarmAction = prewarm.Prewarm
+
+// Command returns the CLI command for pre-warming images
+func Command(prefix app.AppEnvPrefix) *cli.Command {
+	return &cli.Command{
+		Name:        Name,
+		Usage:       usage,
+		ArgsUsage:   argsUsage,
+		Description: description,
+		Flags:       flags(prefix),
+		Action:      action,
+	}
+}
+
+// action handles the prewarm command
+func action(c *cli.Context) error {
+	if c.NArg() > 0 {
+		cfg.Manifest = prewarm.ManifestSource(c.Args().First())
+	}
+	return app.Action(c, cfg, prewarmAction)
+}
+
+// flags defines all command flags
+func flags(prefix app.AppEnvPrefix) []cli.Flag {
+	envPrefix := string(prefix) + "PREWARM_"
+
+	baseFlags := []cli.Flag{
+		&cli.StringFlag{
+			Name:        flagManifest,
+			Aliases:     []string{"m"},
+			Usage:       "Path or URL of the manifest listing images",
+			EnvVars:     []string{envPrefix + "MANIFEST"},
+			Destination: (*string)(&cfg.Manifest),
+		},
+		&cli.DurationFlag{
+			Name:        flagEvery,
+			Usage:       "Repeat on this interval until interrupted (daemon mode)",
+			EnvVars:     []string{envPrefix + "EVERY"},
+			Destination: &cfg.Every,
+		},
+	}
+
+	return app.WithOutputFlags(prefix, &cfg.Output, baseFlags)
+}
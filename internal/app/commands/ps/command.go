@@ -0,0 +1,97 @@
+// Package ps implements the "ps" command.
+package ps
+
+import (
+	"github.com/gloo-foo/vsl/internal/app"
+	"github.com/gloo-foo/vsl/internal/container/ps"
+	"github.com/urfave/cli/v2"
+)
+
+// Command metadata
+const (
+	Name        = "ps"
+	usage       = "List the containers created by vsl"
+	description = `List the running and exited containers vsl created, newest first, with the
+script and project they were run for, their image, how long they have been
+running and the ports they publish.
+
+Containers are found by the labels vsl puts on them, so containers started
+with docker directly are left out. The table shows projects by directory name
+and scripts relative to their project; --format json prints the full paths,
+the start time and uptime in the JSON result instead.
+
+Examples:
+  # List every vsl container
+  vsl ps
+
+  # Running containers of the current project
+  vsl ps --running --project .
+
+  # Containers of a session, for scripts
+  vsl ps --session feature-x --format json
+`
+)
+
+// Flag names
+const (
+	flagSession = "session"
+	flagProject = "project"
+	flagRunning = "running"
+	flagFormat  = "format"
+)
+
+// Package-level config populated by urfave/cli via Destination
+var cfg ps.Config
+
+var psAction = ps.Ps
+
+// Command returns the CLI command for listing containers
+func Command(prefix app.AppEnvPrefix) *cli.Command {
+	return &cli.Command{
+		Name:        Name,
+		Usage:       usage,
+		Description: description,
+		Flags:       flags(prefix),
+		Action:      action,
+	}
+}
+
+// action handles the ps command
+func action(c *cli.Context) error {
+	return app.Action(c, cfg, psAction)
+}
+
+// flags defines all command flags
+func flags(prefix app.AppEnvPrefix) []cli.Flag {
+	envPrefix := string(prefix) + "PS_"
+
+	baseFlags := []cli.Flag{
+		&cli.StringFlag{
+			Name:        flagSession,
+			Usage:       "Only list the containers of this session",
+			EnvVars:     []string{string(prefix) + "SESSION"},
+			Destination: (*string)(&cfg.Session),
+		},
+		&cli.StringFlag{
+			Name:        flagProject,
+			Usage:       "Only list the containers run for the project in this directory (its git root, or the directory outside git)",
+			EnvVars:     []string{envPrefix + "PROJECT"},
+			Destination: (*string)(&cfg.Project),
+		},
+		&cli.BoolFlag{
+			Name:        flagRunning,
+			Usage:       "Only list running containers",
+			EnvVars:     []string{envPrefix + "RUNNING"},
+			Destination: &cfg.Running,
+		},
+		&cli.StringFlag{
+			Name:        flagFormat,
+			Usage:       "Format of the listing: table or json",
+			Value:       string(ps.FormatTable),
+			EnvVars:     []string{envPrefix + "FORMAT"},
+			Destination: (*string)(&cfg.Format),
+		},
+	}
+
+	return app.WithOutputFlags(prefix, &cfg.Output, baseFlags)
+}
@@ -0,0 +1,56 @@
+// Package replayfixture implements the "replay-fixture" command.
+package replayfixture
+
+import (
+	"github.com/gloo-foo/vsl/internal/app"
+	"github.com/gloo-foo/vsl/internal/container/replay"
+	"github.com/gloo-foo/vsl/internal/fixture"
+	"github.com/urfave/cli/v2"
+)
+
+// Command metadata
+const (
+	Name        = "replay-fixture"
+	usage       = "Replay a run recorded with --record-fixture (for maintainers)"
+	argsUsage   = "<dir>"
+	description = `Re-run a fixture recorded by "vsl run --record-fixture <dir>" without Docker.
+
+The recorded configuration runs from the recorded working directory and
+environment, and every daemon API request is answered from the recording in
+order. The command fails if a request diverges from the recording or the
+run result differs, so user bug reports can be reproduced as regression cases.
+
+Examples:
+  # Capture a failing scenario
+  vsl run --record-fixture ./fixtures/issue-42 --image alpine -- false
+
+  # Reproduce it without a daemon
+  vsl replay-fixture ./fixtures/issue-42
+`
+)
+
+// Package-level config populated by urfave/cli via Destination
+var cfg replay.Config
+
+var replayAction = replay.Replay
+
+// Command returns the CLI command for replaying fixtures
+func Command(prefix app.AppEnvPrefix) *cli.Command {
+	return &cli.Command{
+		Name:        Name,
+		Usage:       usage,
+		ArgsUsage:   argsUsage,
+		Description: description,
+		Flags:       app.OutputFlags(prefix, &cfg.Output),
+		Action:      action,
+	}
+}
+
+// action handles the replay-fixture command
+func action(c *cli.Context) error {
+	if c.NArg() != 1 {
+		return cli.Exit("expected exactly one fixture directory", 1)
+	}
+	cfg.Fixture = fixture.Dir(c.Args().First())
+	return app.Action(c, cfg, replayAction)
+}
@@ -0,0 +1,65 @@
+// Package restart implements the "restart" command.
+package restart
+
+import (
+	"github.com/gloo-foo/vsl/internal/app"
+	"github.com/gloo-foo/vsl/internal/container/resolve"
+	"github.com/gloo-foo/vsl/internal/container/restart"
+	"github.com/urfave/cli/v2"
+)
+
+// Command metadata
+const (
+	Name        = "restart"
+	usage       = "Restart a detached container, recreating it when its configuration changed"
+	argsUsage   = "[last|container|script|session]"
+	description = `Bring a container started with vsl run --detach in line with its configuration.
+
+The configuration is resolved again from the recorded run, re-reading the
+run's script, and compared with the one the container was created with: its
+image (by ID, so rebuilt and re-pulled tags count), mounts, environment,
+command and settings. When anything changed, the container is replaced by one
+with the same name and the changes are listed; otherwise it is just restarted.
+
+Without an argument (or with "last") the most recently created container is
+restarted.
+
+Examples:
+  # Restart the most recently created container
+  vsl restart last
+
+  # Pick up edits to a service script
+  vsl restart ./services/db.up
+`
+)
+
+// Package-level config populated by urfave/cli via Destination
+var cfg restart.Config
+
+var restartAction = restart.Restart
+
+// Command returns the CLI command for restarting detached containers
+func Command(prefix app.AppEnvPrefix) *cli.Command {
+	return &cli.Command{
+		Name:        Name,
+		Usage:       usage,
+		ArgsUsage:   argsUsage,
+		Description: description,
+		Flags:       flags(prefix),
+		Action:      action,
+	}
+}
+
+// action handles the restart command
+func action(c *cli.Context) error {
+	cfg.Container = resolve.Last
+	if c.NArg() > 0 {
+		cfg.Container = c.Args().First()
+	}
+	return app.Action(c, cfg, restartAction)
+}
+
+// flags defines all command flags
+func flags(prefix app.AppEnvPrefix) []cli.Flag {
+	return app.WithOutputFlags(prefix, &cfg.Output, nil)
+}
@@ -0,0 +1,88 @@
+// Package restore implements the "restore" command.
+package restore
+
+import (
+	"github.com/gloo-foo/vsl/internal/app"
+	"github.com/gloo-foo/vsl/internal/snapshot"
+	"github.com/urfave/cli/v2"
+)
+
+// Command metadata
+const (
+	Name        = "restore"
+	usage       = "Revert the worktree to a snapshot taken by vsl run --snapshot"
+	argsUsage   = "[snapshot]"
+	description = `Put the files of the current git repository back as they were when
+vsl run --snapshot recorded them, before the container could change them.
+Files the run modified or deleted are rewritten and files it created are
+removed; ignored files, the index and commits made since are kept. The files
+to be removed are listed and confirmation is asked for on the terminal, or
+--force is needed.
+
+Without an argument, the newest snapshot is restored. Snapshots are kept as
+refs under refs/vsl/snapshots, the newest 20 per repository.
+
+Examples:
+  # Revert what the last snapshotted run did
+  vsl restore
+
+  # List the snapshots, then restore one
+  vsl restore --list
+  vsl restore 1a2b3c4d5e6f
+
+  # Restore without asking, e.g. in a script
+  vsl restore --force
+`
+)
+
+// Flag names
+const (
+	flagList  = "list"
+	flagForce = "force"
+)
+
+// Package-level config populated by urfave/cli via Destination
+var cfg snapshot.Config
+
+var restoreAction = snapshot.Restore
+
+// Command returns the CLI command for restoring snapshots
+func Command(prefix app.AppEnvPrefix) *cli.Command {
+	return &cli.Command{
+		Name:        Name,
+		Usage:       usage,
+		ArgsUsage:   argsUsage,
+		Description: description,
+		Flags:       flags(prefix),
+		Action:      action,
+	}
+}
+
+// action handles the restore command
+func action(c *cli.Context) error {
+	if c.NArg() > 1 {
+		return cli.Exit("expected at most one snapshot", 1)
+	}
+	cfg.ID = c.Args().First()
+	return app.Action(c, cfg, restoreAction)
+}
+
+// flags defines all command flags
+func flags(prefix app.AppEnvPrefix) []cli.Flag {
+	baseFlags := []cli.Flag{
+		&cli.BoolFlag{
+			Name:        flagList,
+			Aliases:     []string{"l"},
+			Usage:       "List the snapshots of the repository instead of restoring one",
+			Destination: &cfg.List,
+		},
+		&cli.BoolFlag{
+			Name:        flagForce,
+			Aliases:     []string{"f"},
+			Usage:       "Delete the files created since the snapshot without asking",
+			Destination: &cfg.Force,
+		},
+	}
+
+	return app.WithOutputFlags(prefix, &cfg.Output, baseFlags)
+}
@@ -2,7 +2,12 @@
 package run
 
 import (
+	"errors"
+	"fmt"
 	"os"
+	"reflect"
+	"strconv"
+	"strings"
 
 	"github.com/gloo-foo/vsl/internal/app"
 	"github.com/gloo-foo/vsl/internal/container"
@@ -28,6 +33,7 @@ Features:
   - Interactive mode with TTY support
   - Custom volume mounts
   - Network mode configuration
+  - Session labels for grouping related containers
 
 Examples:
   # Run a command in an Ubuntu container
@@ -39,31 +45,177 @@ Examples:
   # Disable git repository discovery
   vsl run --image node:latest --no-git -- npm test
 
+  # Run in a clean environment without access to local files
+  vsl run --image golang:latest --no-auto-mounts -- go version
+
+  # Try a project without cloning it yourself
+  vsl run --image golang:latest --repo https://github.com/org/proj --ref v1.2.3 -- go test ./...
+
+  # Reuse dependencies between runs without touching the host checkout
+  vsl run --image node:latest --cache node_modules -- npm ci
+
+  # Hide secrets and host dependencies from the container
+  vsl run --image node:latest --mask secrets --mask node_modules --mask-with volume -- npm ci
+
   # Run with custom volumes
   vsl run --image postgres:latest --volume /data:/var/lib/postgresql/data
 
+  # Mount every matching file into a directory
+  vsl run --image alpine:latest --volume './configs/*.yaml:/etc/app/:ro' -- ls /etc/app
+
+  # Interpolate ${GIT_ROOT}, ${PWD}, ${HOME} and environment variables ($$ for a literal $)
+  vsl run --image alpine:latest --volume '${GIT_ROOT}/.cache:${HOME}/.cache' --working-dir '${GIT_ROOT}/app'
+
+  # Precise mounts, including paths with colons and tmpfs
+  vsl run --image alpine:latest --mount 'type=bind,"src=/data/a:b",dst=/data,ro' --mount type=tmpfs,dst=/scratch,tmpfs-size=64m
+
+  # Stream output like a local command, exiting with the container's exit code
+  vsl run --image golang:1.22 --pipe -- go test ./...
+
+  # Share nested mounts with the host (e.g. buildkit)
+  vsl run --image moby/buildkit --privileged --volume /var/lib/buildkit:/var/lib/buildkit:rw,rshared
+
+  # Commit and fetch inside the container with the host's git identity
+  vsl run --image alpine/git --git-identity --git-credentials -- git pull
+
+  # Reclaim files a root container created in the source tree
+  vsl run --image node:20 --fix-ownership -- npm ci
+
+  # Limit resources with human-friendly sizes and durations
+  vsl run --image node:20 --memory 2gb --shm-size 512m --timeout 10m -- npm test
+
+  # Pass variables between pipeline steps
+  vsl run --image alpine --env-from-output .vsl/env -- sh -c 'echo VERSION=1.2.3 >> "$VSL_ENV_OUTPUT"'
+  vsl run --image alpine --env-from-output .vsl/env -- sh -c 'echo "$VERSION"'
+
+  # Run a host step (e.g. a registry login) without asking for confirmation
+  vsl run --host-commands allow ./login.up
+
+  # Diagnose quoting: show each argument exactly as the container receives it
+  vsl run --image alpine --print-argv -- sh -c 'echo "$HOME"'
+
+  # Review what a script you did not write would mount and pass before running it
+  vsl run --explain ./downloaded.up
+
+  # Keep a service running in the background, then restart it after edits
+  vsl run --image redis:latest --detach
+  vsl restart last
+
+  # Group containers of a working session
+  vsl run --image redis:latest --session feature-x
+
   # Execute an UP script file (shebang mode)
   vsl my-script.up arg1 arg2
+
+  # Run a script published by URL or in a repository, checking its sha256;
+  # its plan is shown and confirmed first unless --trust-remote is given
+  vsl run --sha256 9f86d08... https://example.com/tools/lint.up ./...
+  vsl run github.com/org/tools//lint.up@v1.2.0
+
+  # Run a service of a compose file with vsl's mounts, overriding its command
+  vsl run --from-compose ./compose.yaml web -- npm test
+
+  # In a repository with a vsl.up or .vsl/default.up, run its image
+  vsl run -- make test
+
+  # Run the project's Dev Container definition, or a shell in it
+  vsl run --devcontainer -- make test
+  vsl run .devcontainer/devcontainer.json
 `
 )
 
 // Flag names
 const (
-	flagImage       = "image"
-	flagNoGit       = "no-git"
-	flagInteractive = "interactive"
-	flagWorkingDir  = "working-dir"
-	flagUser        = "user"
-	flagEnv         = "env"
-	flagVolume      = "volume"
-	flagEntrypoint  = "entrypoint"
-	flagNetworkMode = "network-mode"
-	flagPrivileged  = "privileged"
+	flagImage        = "image"
+	flagNoGit        = "no-git"
+	flagInteractive  = "interactive"
+	flagNoMountCwd   = "no-mount-cwd"
+	flagNoAutoMount  = "no-auto-mounts"
+	flagNoInterp     = "no-interpolate"
+	flagWorkingDir   = "working-dir"
+	flagUser         = "user"
+	flagEnv          = "env"
+	flagEnvOutput    = "env-from-output"
+	flagVolume       = "volume"
+	flagMount        = "mount"
+	flagCache        = "cache"
+	flagVolumesFrom  = "volumes-from"
+	flagLenient      = "lenient-mounts"
+	flagGitIdentity  = "git-identity"
+	flagGitCreds     = "git-credentials"
+	flagGitBridge    = "git-credential-bridge"
+	flagGitLFS       = "git-lfs"
+	flagGitEnv       = "git-env"
+	flagGitHooks     = "allow-git-hooks"
+	flagMask         = "mask"
+	flagMaskWith     = "mask-with"
+	flagMaskIgnored  = "mask-ignored"
+	flagEntrypoint   = "entrypoint"
+	flagNetworkMode  = "network-mode"
+	flagMemory       = "memory"
+	flagShmSize      = "shm-size"
+	flagCPUs         = "cpus"
+	flagPublish      = "publish"
+	flagDevice       = "device"
+	flagCapAdd       = "cap-add"
+	flagTimeout      = "timeout"
+	flagPrivileged   = "privileged"
+	flagHostCmds     = "host-commands"
+	flagSession      = "session"
+	flagRelabel      = "selinux-relabel"
+	flagCapFallback  = "capability-fallback"
+	flagConsistency  = "consistency"
+	flagProbe        = "probe"
+	flagRecord       = "record-fixture"
+	flagCapture      = "capture"
+	flagPipe         = "pipe"
+	flagQuiet        = "quiet"
+	flagPrintArgv    = "print-argv"
+	flagExplain      = "explain"
+	flagDetach       = "detach"
+	flagAuditOwner   = "audit-ownership"
+	flagGitDepth     = "git-depth"
+	flagGitRoot      = "git-root"
+	flagRepo         = "repo"
+	flagSnapshot     = "snapshot"
+	flagRef          = "ref"
+	flagGitCeiling   = "git-ceiling"
+	flagGitMaxFiles  = "git-root-max-files"
+	flagForceRoot    = "force-git-root-mount"
+	flagGitRO        = "git-readonly"
+	flagGitRootRO    = "git-readonly-root"
+	flagGitTracked   = "git-tracked-only"
+	flagFixOwner     = "fix-ownership"
+	flagCompose      = "from-compose"
+	flagDevcontainer = "devcontainer"
+	flagNoProject    = "no-project-config"
+	flagSHA256       = "sha256"
+	flagTrustRemote  = "trust-remote"
 )
 
 // Package-level config populated by urfave/cli via Destination
 var cfg run.Config
 
+// fromCompose is the compose file whose service is run instead of a script
+var fromCompose string
+
+// devcontainer runs the Dev Container definition of the workspace
+var devcontainer bool
+
+// noProjectConfig keeps runs without an image from using the project's
+// default script
+var noProjectConfig bool
+
+// scriptSHA256 is the sha256 a remote script must have
+var scriptSHA256 string
+
+// trustRemote runs remote scripts without explaining them and asking first
+var trustRemote bool
+
+// fetchedRemote is set when the script run was fetched from a remote, so
+// its plan is explained and confirmed before it runs
+var fetchedRemote bool
+
 var runAction = run.Run
 
 // Command returns the CLI command for running containers
@@ -78,29 +230,133 @@ func Command(prefix app.AppEnvPrefix) *cli.Command {
 	}
 }
 
-// action handles the run command, including script file detection
+// action handles the run command
 func action(c *cli.Context) error {
+	return runArgs(c, c.Args().Slice())
+}
+
+// WorktreeAction runs in a temporary worktree of the ref given as the first
+// argument, with the run command's flags and the remaining arguments
+func WorktreeAction(c *cli.Context) error {
+	if c.NArg() == 0 {
+		return cli.Exit("a branch, tag or commit to check out is required", 1)
+	}
+	cfg.Worktree = c.Args().First()
+	// Flag parsing ends at the ref, leaving the separator before the command
+	args := c.Args().Slice()[1:]
+	if len(args) > 0 && args[0] == "--" {
+		args = args[1:]
+	}
+	return runArgs(c, args)
+}
+
+// InspectAction renders the run the arguments describe in format instead of
+// running it
+func InspectAction(c *cli.Context, format run.ExportFormat) error {
+	cfg.Inspect = format
+	return runArgs(c, c.Args().Slice())
+}
+
+// runArgs runs with the given arguments, including script file detection
+func runArgs(c *cli.Context, args []string) error {
+	if fromCompose != "" {
+		return runCompose(c, args)
+	}
+	if devcontainer {
+		pwd, err := os.Getwd()
+		if err != nil {
+			return err
+		}
+		path, ok := script.FindDevcontainer(pwd)
+		if !ok {
+			return cli.Exit("no .devcontainer/devcontainer.json or .devcontainer.json found", 1)
+		}
+		return runDevcontainer(c, path, args)
+	}
+
+	// Fetch a script given by URL or repository path, unless a local file
+	// has the same name. Remote scripts can ask for host secrets, volumes
+	// and privileges, so they run once their plan is confirmed
+	if len(args) > 0 {
+		if remote, ok := script.ParseRemote(args[0]); ok {
+			if _, err := os.Stat(args[0]); err != nil {
+				path, err := remote.Fetch(c.Context, scriptSHA256)
+				if err != nil {
+					return cli.Exit(err.Error(), 1)
+				}
+				args = append([]string{path}, args[1:]...)
+				fetchedRemote = true
+			}
+		}
+	}
+
 	// Check if we're being used as a shebang interpreter
 	// If first arg is a file, try to parse it as an UP script
-	if c.NArg() > 0 {
-		firstArg := c.Args().Get(0)
+	if len(args) > 0 {
+		firstArg := args[0]
 		if info, err := os.Stat(firstArg); err == nil && !info.IsDir() {
+			if script.IsDevcontainer(firstArg) {
+				args = args[1:]
+				if len(args) > 0 && args[0] == "--" {
+					args = args[1:]
+				}
+				return runDevcontainer(c, firstArg, args)
+			}
 			// First argument is a file - try to parse as UP script
 			scriptCfg, err := script.ParseFile(firstArg)
+			var valueErr *script.ValueError
+			if errors.Is(err, script.ErrUnknownProfile) || errors.Is(err, script.ErrUnsupported) {
+				return cli.Exit(err.Error(), 1)
+			}
+			if errors.As(err, &valueErr) {
+				return cli.Exit(fmt.Sprintf("%s: %v", firstArg, err), 1)
+			}
 			if err == nil && scriptCfg != nil {
+				if script.WantsHelp(scriptCfg, args[1:]) {
+					usage, err := script.Usage(firstArg, scriptCfg)
+					if err != nil {
+						return err
+					}
+					_, err = fmt.Fprint(c.App.Writer, usage)
+					return err
+				}
 				scriptCfg.ScriptPath = container.ScriptPath(firstArg)
-				scriptCfg.ScriptArgs = c.Args().Slice()[1:]
+				scriptCfg.ScriptArgs = args[1:]
+				applyFlags(c, scriptCfg)
 				return app.Action(c, *scriptCfg, runAction)
 			}
 			// If parsing failed, fall through to normal CLI mode
 		}
 	}
 
+	// Without an image, run the project's default script if it has one
+	if !c.IsSet(flagImage) && !noProjectConfig {
+		pwd, err := os.Getwd()
+		if err != nil {
+			return err
+		}
+		if path, ok := script.FindProjectScript(pwd); ok {
+			return runProjectScript(c, path, args)
+		}
+	}
+
 	// Normal CLI mode - collect command arguments
-	args := c.Args().Slice()
 	for _, arg := range args {
 		cfg.Command = append(cfg.Command, container.Command(arg))
 	}
+	cfg.AddOrigins("command", run.OriginFlag, len(args))
+
+	// Fill what the flags leave unset from the configuration files
+	pwd, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	defaults, keys, err := script.Defaults(pwd)
+	if err != nil {
+		return cli.Exit(err.Error(), 1)
+	}
+	underlay(c, *defaults, keys)
+	applyFlags(c, &cfg)
 
 	// If no image specified and no script, error
 	if cfg.Image == "" {
@@ -110,6 +366,225 @@ func action(c *cli.Context) error {
 	return app.Action(c, cfg, runAction)
 }
 
+// runCompose runs the compose service named by the first argument, or the
+// only service of the compose file, with the remaining arguments as its command
+func runCompose(c *cli.Context, args []string) error {
+	var service string
+	if len(args) > 0 {
+		service, args = args[0], args[1:]
+		if len(args) > 0 && args[0] == "--" {
+			args = args[1:]
+		}
+	} else {
+		services, err := script.ComposeServices(fromCompose)
+		if err != nil {
+			return cli.Exit(err.Error(), 1)
+		}
+		if len(services) != 1 {
+			return cli.Exit(fmt.Sprintf("name the service to run: %s", strings.Join(services, ", ")), 1)
+		}
+		service = services[0]
+	}
+
+	composeCfg, err := script.ParseCompose(fromCompose, service)
+	if err != nil {
+		return cli.Exit(err.Error(), 1)
+	}
+	if len(args) > 0 {
+		composeCfg.Command = nil
+		for _, arg := range args {
+			composeCfg.Command = append(composeCfg.Command, container.Command(arg))
+		}
+		composeCfg.SetOrigin("command")
+		composeCfg.AddOrigins("command", run.OriginFlag, len(args))
+	}
+	applyFlags(c, composeCfg)
+	return app.Action(c, *composeCfg, runAction)
+}
+
+// runProjectScript runs the project's default script at path, with the
+// arguments replacing its command
+func runProjectScript(c *cli.Context, path string, args []string) error {
+	scriptCfg, err := script.ParseFile(path)
+	if err != nil {
+		return cli.Exit(fmt.Sprintf("%s: %v", path, err), 1)
+	}
+	scriptCfg.ScriptPath = container.ScriptPath(path)
+	if len(args) > 0 {
+		scriptCfg.Command = nil
+		for _, arg := range args {
+			scriptCfg.Command = append(scriptCfg.Command, container.Command(arg))
+		}
+		scriptCfg.SetOrigin("command")
+		scriptCfg.AddOrigins("command", run.OriginFlag, len(args))
+	}
+	applyFlags(c, scriptCfg)
+	return app.Action(c, *scriptCfg, runAction)
+}
+
+// runDevcontainer runs the Dev Container definition at path with the
+// arguments as its command
+func runDevcontainer(c *cli.Context, path string, args []string) error {
+	devCfg, err := script.ParseDevcontainer(path, args)
+	if err != nil {
+		return cli.Exit(err.Error(), 1)
+	}
+	applyFlags(c, devCfg)
+	return app.Action(c, *devCfg, runAction)
+}
+
+// listFlags are the flags adding to the lists of a run, by the script key
+// of the list.
+var listFlags = []struct {
+	flag, key string
+}{
+	{flagVolume, "volume"},
+	{flagMount, "mounts"},
+	{flagCache, "caches"},
+	{flagVolumesFrom, "volumes_from"},
+	{flagMask, "exclude"},
+	{flagPublish, "ports"},
+	{flagDevice, "devices"},
+	{flagCapAdd, "cap_add"},
+	{flagGitCeiling, "git_ceiling"},
+}
+
+// flagFields returns the addresses of the fields of cfg the flags given on
+// the command line set: flags write to the fields their Destination or
+// Value points to.
+func flagFields(c *cli.Context) map[uintptr]bool {
+	set := map[uintptr]bool{}
+	for _, flag := range c.Command.Flags {
+		if !c.IsSet(flag.Names()[0]) {
+			continue
+		}
+		v := reflect.ValueOf(flag).Elem()
+		for _, name := range []string{"Destination", "Value"} {
+			if f := v.FieldByName(name); f.IsValid() && f.Kind() == reflect.Pointer && !f.IsNil() {
+				set[f.Pointer()] = true
+			} else if f.IsValid() && f.Kind() == reflect.Interface && !f.IsNil() && f.Elem().Kind() == reflect.Pointer {
+				set[f.Elem().Pointer()] = true
+			}
+		}
+	}
+	return set
+}
+
+// underlay sets the fields of cfg whose keys the configuration files set,
+// unless a flag given on the command line sets them: lists are extended,
+// the defaults first, and other values replaced.
+func underlay(c *cli.Context, defaults run.Config, keys map[string]bool) {
+	set := flagFields(c)
+	target := reflect.ValueOf(&cfg).Elem()
+	source := reflect.ValueOf(defaults)
+	for i := 0; i < target.NumField(); i++ {
+		field := target.Field(i)
+		key := target.Type().Field(i).Tag.Get("up")
+		if !keys[key] || set[field.Addr().Pointer()] {
+			continue
+		}
+		if field.Kind() == reflect.Slice {
+			given := cfg.Origins[key]
+			cfg.SetOrigin(key)
+			cfg.AddOrigins(key, run.OriginConfig, source.Field(i).Len())
+			cfg.Origins[key] = append(cfg.Origins[key], given...)
+			field.Set(reflect.AppendSlice(reflect.AppendSlice(reflect.MakeSlice(field.Type(), 0, source.Field(i).Len()+field.Len()), source.Field(i)), field))
+			continue
+		}
+		cfg.SetOrigin(key, run.OriginConfig)
+		field.Set(source.Field(i))
+	}
+}
+
+// applyFlags applies the flags given on the command line over the
+// configuration of a run, from a script, a compose service or the
+// configuration files: values the flags set replace the configuration's,
+// list flags add to its lists, --env replaces the variables of the same
+// name and --entrypoint the whole entrypoint.
+func applyFlags(c *cli.Context, scriptCfg *run.Config) {
+	// Settings of the invocation rather than of the run
+	scriptCfg.Session = cfg.Session
+	scriptCfg.RecordFixture = cfg.RecordFixture
+	scriptCfg.Capture = cfg.Capture
+	scriptCfg.Pipe = cfg.Pipe
+	scriptCfg.Quiet = cfg.Quiet
+	scriptCfg.PrintArgv = cfg.PrintArgv
+	scriptCfg.Explain = cfg.Explain || (fetchedRemote && !trustRemote)
+	scriptCfg.Inspect = cfg.Inspect
+	scriptCfg.HostCommands = cfg.HostCommands
+	scriptCfg.NoInterpolate = cfg.NoInterpolate
+
+	set := flagFields(c)
+	target := reflect.ValueOf(scriptCfg).Elem()
+	source := reflect.ValueOf(&cfg).Elem()
+	for i := 0; i < source.NumField(); i++ {
+		if !set[source.Field(i).Addr().Pointer()] {
+			continue
+		}
+		target.Field(i).Set(source.Field(i))
+		if key := source.Type().Field(i).Tag.Get("up"); key != "" && key != "-" {
+			scriptCfg.SetOrigin(key, run.OriginFlag)
+		}
+	}
+	// A repository without a ref is checked out at its default branch
+	if c.IsSet(flagRepo) {
+		scriptCfg.Ref = cfg.Ref
+	}
+
+	for _, list := range listFlags {
+		values := c.StringSlice(list.flag)
+		if len(values) == 0 {
+			continue
+		}
+		field := fieldByKey(target, list.key)
+		for _, value := range values {
+			field.Set(reflect.Append(field, reflect.ValueOf(value).Convert(field.Type().Elem())))
+		}
+		scriptCfg.AddOrigins(list.key, run.OriginFlag, len(values))
+	}
+	if envs := c.StringSlice(flagEnv); len(envs) > 0 {
+		names := map[string]bool{}
+		for _, env := range envs {
+			name, _, _ := strings.Cut(env, "=")
+			names[name] = true
+		}
+		var (
+			kept    []container.Environment
+			origins []run.Origin
+		)
+		for i, env := range scriptCfg.Environment {
+			if name, _, _ := strings.Cut(string(env), "="); !names[name] {
+				kept = append(kept, env)
+				origins = append(origins, scriptCfg.Origin("env", i))
+			}
+		}
+		for _, env := range envs {
+			kept = append(kept, container.Environment(env))
+			origins = append(origins, run.OriginFlag)
+		}
+		scriptCfg.Environment = kept
+		scriptCfg.SetOrigin("env", origins...)
+	}
+	if entrypoint := c.StringSlice(flagEntrypoint); len(entrypoint) > 0 {
+		scriptCfg.Entrypoint = nil
+		for _, part := range entrypoint {
+			scriptCfg.Entrypoint = append(scriptCfg.Entrypoint, container.Entrypoint(part))
+		}
+		scriptCfg.SetOrigin("entrypoint")
+		scriptCfg.AddOrigins("entrypoint", run.OriginFlag, len(entrypoint))
+	}
+}
+
+// fieldByKey returns the field of a run.Config value its script key sets.
+func fieldByKey(v reflect.Value, key string) reflect.Value {
+	for i := 0; i < v.NumField(); i++ {
+		if v.Type().Field(i).Tag.Get("up") == key {
+			return v.Field(i)
+		}
+	}
+	panic("no field for key " + key)
+}
+
 // flags defines all command flags
 func flags(prefix app.AppEnvPrefix) []cli.Flag {
 	envPrefix := string(prefix) + "RUN_"
@@ -130,6 +605,121 @@ func flags(prefix app.AppEnvPrefix) []cli.Flag {
 			Value:       false,
 			Destination: &cfg.NoGit,
 		},
+		&cli.BoolFlag{
+			Name:        flagSnapshot,
+			Usage:       "Record the worktree of the git root before the run, so vsl restore can revert what the container changes",
+			EnvVars:     []string{envPrefix + "SNAPSHOT"},
+			Destination: &cfg.Snapshot,
+		},
+		&cli.StringFlag{
+			Name:        flagRepo,
+			Usage:       "Run in a shallow checkout of this remote repository, kept in the vsl cache, instead of the current directory",
+			EnvVars:     []string{envPrefix + "REPO"},
+			Destination: &cfg.Repo,
+		},
+		&cli.StringFlag{
+			Name:        flagRef,
+			Usage:       "Branch, tag or commit of --repo to check out (default: the remote HEAD)",
+			EnvVars:     []string{envPrefix + "REF"},
+			Destination: &cfg.Ref,
+		},
+		&cli.StringFlag{
+			Name:        flagGitRoot,
+			Usage:       "Repository root to mount instead of the one discovered above the working directory",
+			EnvVars:     []string{envPrefix + "GIT_ROOT"},
+			Destination: (*string)(&cfg.GitRoot),
+		},
+		&cli.StringSliceFlag{
+			Name:    flagGitCeiling,
+			Usage:   "Directory upward repository discovery does not enter, like GIT_CEILING_DIRECTORIES (repeatable)",
+			EnvVars: []string{envPrefix + "GIT_CEILING"},
+		},
+		&cli.IntFlag{
+			Name:        flagGitDepth,
+			Usage:       "Directory levels below the working directory searched for nested repositories to mount (0 for the default, negative to disable)",
+			EnvVars:     []string{envPrefix + "GIT_DEPTH"},
+			DefaultText: strconv.Itoa(run.DefaultGitDepth),
+			Destination: &cfg.GitDepth,
+		},
+		&cli.IntFlag{
+			Name:        flagGitMaxFiles,
+			Usage:       "Tracked files above which only the working directory and git directory are mounted instead of the git root (0 for the default, negative for no limit)",
+			EnvVars:     []string{envPrefix + "GIT_ROOT_MAX_FILES"},
+			DefaultText: strconv.Itoa(run.DefaultGitRootMaxFiles),
+			Destination: &cfg.GitRootMaxFiles,
+		},
+		&cli.BoolFlag{
+			Name:        flagForceRoot,
+			Usage:       "Mount the git root regardless of its size",
+			EnvVars:     []string{envPrefix + "FORCE_GIT_ROOT_MOUNT"},
+			Destination: &cfg.ForceGitRootMount,
+		},
+		&cli.BoolFlag{
+			Name:        flagGitRO,
+			Usage:       "Mount git directories read-only, so the container cannot rewrite refs, commit or install hooks",
+			EnvVars:     []string{envPrefix + "GIT_READONLY"},
+			Destination: &cfg.GitReadonly,
+		},
+		&cli.BoolFlag{
+			Name:        flagGitRootRO,
+			Usage:       "Mount the whole git root read-only, including the working directory (implies --git-readonly)",
+			EnvVars:     []string{envPrefix + "GIT_READONLY_ROOT"},
+			Destination: &cfg.GitReadonlyRoot,
+		},
+		&cli.BoolFlag{
+			Name:        flagGitTracked,
+			Usage:       "Hide the files git does not track in the mounted worktrees, such as .env files, from the container",
+			EnvVars:     []string{envPrefix + "GIT_TRACKED_ONLY"},
+			Destination: &cfg.GitTrackedOnly,
+		},
+		&cli.BoolFlag{
+			Name:        flagNoMountCwd,
+			Usage:       "Do not mount the current directory",
+			EnvVars:     []string{envPrefix + "NO_MOUNT_CWD"},
+			Destination: &cfg.NoMountCwd,
+		},
+		&cli.BoolFlag{
+			Name:        flagNoAutoMount,
+			Usage:       "Do not mount the current directory or git repository",
+			EnvVars:     []string{envPrefix + "NO_AUTO_MOUNTS"},
+			Destination: &cfg.NoAutoMounts,
+		},
+		&cli.StringFlag{
+			Name:        flagCompose,
+			Usage:       "Run a service of a docker compose file, given as the first argument, with vsl's mounts",
+			EnvVars:     []string{envPrefix + "FROM_COMPOSE"},
+			Destination: &fromCompose,
+		},
+		&cli.BoolFlag{
+			Name:        flagDevcontainer,
+			Usage:       "Run the Dev Container definition of the current directory or repository",
+			EnvVars:     []string{envPrefix + "DEVCONTAINER"},
+			Destination: &devcontainer,
+		},
+		&cli.BoolFlag{
+			Name:        flagNoProject,
+			Usage:       "Without --image, do not run the project's default script (vsl.up or .vsl/default.up)",
+			EnvVars:     []string{envPrefix + "NO_PROJECT_CONFIG"},
+			Destination: &noProjectConfig,
+		},
+		&cli.StringFlag{
+			Name:        flagSHA256,
+			Usage:       "sha256 the remote script given as the first argument must have; a cached copy that does is run without fetching it again",
+			EnvVars:     []string{envPrefix + "SHA256"},
+			Destination: &scriptSHA256,
+		},
+		&cli.BoolFlag{
+			Name:        flagTrustRemote,
+			Usage:       "Run a remote script without printing its plan and asking first, as in scripts and CI without a terminal",
+			EnvVars:     []string{envPrefix + "TRUST_REMOTE"},
+			Destination: &trustRemote,
+		},
+		&cli.BoolFlag{
+			Name:        flagNoInterp,
+			Usage:       "Use volumes, mounts and script values literally, without expanding ${VAR} references",
+			EnvVars:     []string{envPrefix + "NO_INTERPOLATE"},
+			Destination: &cfg.NoInterpolate,
+		},
 		&cli.BoolFlag{
 			Name:        flagInteractive,
 			Aliases:     []string{"it"},
@@ -141,7 +731,7 @@ func flags(prefix app.AppEnvPrefix) []cli.Flag {
 		&cli.StringFlag{
 			Name:        flagWorkingDir,
 			Aliases:     []string{"w"},
-			Usage:       "Working directory inside the container",
+			Usage:       "Working directory inside the container (supports ${PWD}, ${GIT_ROOT}, ${HOME})",
 			EnvVars:     []string{envPrefix + "WORKING_DIR"},
 			Destination: (*string)(&cfg.WorkingDir),
 		},
@@ -158,12 +748,93 @@ func flags(prefix app.AppEnvPrefix) []cli.Flag {
 			Usage:   "Set environment variables (KEY=value)",
 			EnvVars: []string{envPrefix + "ENV"},
 		},
+		&cli.StringFlag{
+			Name:        flagEnvOutput,
+			Usage:       "Import KEY=VALUE lines from this file, which the container can append to via $VSL_ENV_OUTPUT for the next step",
+			EnvVars:     []string{envPrefix + "ENV_FROM_OUTPUT"},
+			Destination: &cfg.EnvFromOutput,
+		},
 		&cli.StringSliceFlag{
 			Name:    flagVolume,
 			Aliases: []string{"v"},
-			Usage:   "Bind mount a volume (source:target[:ro])",
+			Usage:   "Bind mount a volume (source:target[:ro|rw][,propagation][,z|Z])",
 			EnvVars: []string{envPrefix + "VOLUME"},
 		},
+		&cli.StringSliceFlag{
+			Name:    flagMount,
+			Usage:   "Attach a mount (type=bind|volume|tmpfs,src=...,dst=...[,ro])",
+			EnvVars: []string{envPrefix + "MOUNT"},
+		},
+		&cli.StringSliceFlag{
+			Name:    flagCache,
+			Usage:   "Back a container directory with a per-project cache volume (e.g. node_modules)",
+			EnvVars: []string{envPrefix + "CACHE"},
+		},
+		&cli.BoolFlag{
+			Name:        flagLenient,
+			Usage:       "Skip invalid volumes with a warning instead of failing",
+			EnvVars:     []string{envPrefix + "LENIENT_MOUNTS"},
+			Destination: &cfg.LenientMounts,
+		},
+		&cli.BoolFlag{
+			Name:        flagGitIdentity,
+			Usage:       "Mount the host's git config (~/.gitconfig, $XDG_CONFIG_HOME/git/config) read-only into the container's home",
+			EnvVars:     []string{envPrefix + "GIT_IDENTITY"},
+			Destination: &cfg.GitIdentity,
+		},
+		&cli.BoolFlag{
+			Name:        flagGitCreds,
+			Usage:       "Also mount the git credential store (~/.git-credentials) read-only; implies --git-identity",
+			EnvVars:     []string{envPrefix + "GIT_CREDENTIALS"},
+			Destination: &cfg.GitCredentials,
+		},
+		&cli.BoolFlag{
+			Name:        flagGitBridge,
+			Usage:       "Answer git credential lookups from the container for the hosts of the mounted repositories' remotes with the host's credential helpers over a socket, without copying credentials (Linux hosts)",
+			EnvVars:     []string{envPrefix + "GIT_CREDENTIAL_BRIDGE"},
+			Destination: &cfg.GitCredentialBridge,
+		},
+		&cli.BoolFlag{
+			Name:        flagGitLFS,
+			Usage:       "Mount the host's git-lfs binary read-only at " + run.LFSBinaryPath + " (needs a Linux host on the daemon's architecture)",
+			EnvVars:     []string{envPrefix + "GIT_LFS"},
+			Destination: &cfg.GitLFS,
+		},
+		&cli.BoolFlag{
+			Name:        flagGitEnv,
+			Usage:       "Set VSL_GIT_BRANCH, VSL_GIT_COMMIT, VSL_GIT_DIRTY and VSL_GIT_REMOTE_URL in the container",
+			EnvVars:     []string{envPrefix + "GIT_ENV"},
+			Destination: &cfg.GitEnv,
+		},
+		&cli.BoolFlag{
+			Name:        flagGitHooks,
+			Usage:       "Let git in the container run the repository's hooks, disabled by default as they are written for the host",
+			EnvVars:     []string{envPrefix + "ALLOW_GIT_HOOKS"},
+			Destination: &cfg.AllowGitHooks,
+		},
+		&cli.StringSliceFlag{
+			Name:    flagVolumesFrom,
+			Usage:   "Share the volumes of another container, by name or ID (container[:ro|rw])",
+			EnvVars: []string{envPrefix + "VOLUMES_FROM"},
+		},
+		&cli.StringSliceFlag{
+			Name:    flagMask,
+			Usage:   "Hide a subpath of the mounted directories behind an empty mount (e.g. secrets)",
+			EnvVars: []string{envPrefix + "MASK"},
+		},
+		&cli.StringFlag{
+			Name:        flagMaskWith,
+			Usage:       "Mount type used to hide masked paths (tmpfs, volume)",
+			EnvVars:     []string{envPrefix + "MASK_WITH"},
+			Value:       "tmpfs",
+			Destination: (*string)(&cfg.MaskWith),
+		},
+		&cli.BoolFlag{
+			Name:        flagMaskIgnored,
+			Usage:       "Mask the directories .gitignore excludes entirely, such as build output and node_modules",
+			EnvVars:     []string{envPrefix + "MASK_IGNORED"},
+			Destination: &cfg.MaskIgnored,
+		},
 		&cli.StringSliceFlag{
 			Name:    flagEntrypoint,
 			Usage:   "Override the default entrypoint",
@@ -175,6 +846,58 @@ func flags(prefix app.AppEnvPrefix) []cli.Flag {
 			EnvVars:     []string{envPrefix + "NETWORK_MODE"},
 			Destination: (*string)(&cfg.NetworkMode),
 		},
+		&cli.GenericFlag{
+			Name:        flagMemory,
+			Aliases:     []string{"m"},
+			Usage:       "Memory limit (e.g. 512m, 2gb)",
+			EnvVars:     []string{envPrefix + "MEMORY"},
+			Value:       &cfg.Memory,
+			DefaultText: "unlimited",
+		},
+		&cli.GenericFlag{
+			Name:        flagShmSize,
+			Usage:       "Size of /dev/shm (e.g. 256m)",
+			EnvVars:     []string{envPrefix + "SHM_SIZE"},
+			Value:       &cfg.ShmSize,
+			DefaultText: "64MiB",
+		},
+		&cli.Float64Flag{
+			Name:        flagCPUs,
+			Usage:       "CPU limit in cores (e.g. 1.5)",
+			EnvVars:     []string{envPrefix + "CPUS"},
+			Destination: &cfg.CPUs,
+			DefaultText: "unlimited",
+		},
+		&cli.StringSliceFlag{
+			Name:    flagPublish,
+			Aliases: []string{"p"},
+			Usage:   "Publish a container port on the host (e.g. 8080:80, 127.0.0.1:5432:5432, 53:53/udp)",
+			EnvVars: []string{envPrefix + "PUBLISH"},
+		},
+		&cli.StringSliceFlag{
+			Name:    flagDevice,
+			Usage:   "Make a host device available in the container (host[:container][:permissions])",
+			EnvVars: []string{envPrefix + "DEVICE"},
+		},
+		&cli.StringSliceFlag{
+			Name:    flagCapAdd,
+			Usage:   "Add a Linux capability to the container (e.g. NET_ADMIN, SYS_PTRACE)",
+			EnvVars: []string{envPrefix + "CAP_ADD"},
+		},
+		&cli.GenericFlag{
+			Name:        flagTimeout,
+			Usage:       "Stop the container when it runs longer than this (e.g. 90s, 1h30m)",
+			EnvVars:     []string{envPrefix + "TIMEOUT"},
+			Value:       &cfg.Timeout,
+			DefaultText: "none",
+		},
+		&cli.StringFlag{
+			Name:        flagHostCmds,
+			Usage:       "Policy for scripts with host: true that run on the host: allow, confirm, or deny",
+			EnvVars:     []string{envPrefix + "HOST_COMMANDS"},
+			Value:       string(run.HostConfirm),
+			Destination: (*string)(&cfg.HostCommands),
+		},
 		&cli.BoolFlag{
 			Name:        flagPrivileged,
 			Usage:       "Give extended privileges to this container",
@@ -182,6 +905,91 @@ func flags(prefix app.AppEnvPrefix) []cli.Flag {
 			Value:       false,
 			Destination: &cfg.Privileged,
 		},
+		&cli.BoolFlag{
+			Name:        flagAuditOwner,
+			Usage:       "After the run, report files of the mounted directory owned by another user than yours",
+			EnvVars:     []string{envPrefix + "AUDIT_OWNERSHIP"},
+			Destination: &cfg.AuditOwnership,
+		},
+		&cli.BoolFlag{
+			Name:        flagFixOwner,
+			Usage:       "After the run, give such files back to you with a short-lived root container from the run's image",
+			EnvVars:     []string{envPrefix + "FIX_OWNERSHIP"},
+			Destination: &cfg.FixOwnership,
+		},
+		&cli.StringFlag{
+			Name:        flagRelabel,
+			Usage:       "SELinux relabel mode for the automatic pwd and git mounts (z or Z)",
+			EnvVars:     []string{envPrefix + "SELINUX_RELABEL"},
+			Destination: (*string)(&cfg.SELinuxRelabel),
+		},
+		&cli.StringFlag{
+			Name:        flagProbe,
+			Usage:       "Shell command run after start whose output is recorded as the toolchain version (e.g. \"go version\")",
+			EnvVars:     []string{envPrefix + "PROBE"},
+			Destination: &cfg.Probe,
+		},
+		&cli.StringFlag{
+			Name:        flagConsistency,
+			Usage:       "Consistency of the automatic pwd/git mounts on Docker Desktop for Mac (consistent, cached, delegated)",
+			EnvVars:     []string{envPrefix + "CONSISTENCY"},
+			Destination: (*string)(&cfg.Consistency),
+		},
+		&cli.StringFlag{
+			Name:        flagCapFallback,
+			Usage:       "How to handle capabilities required by a script but missing on the host (fail, fallback)",
+			EnvVars:     []string{envPrefix + "CAPABILITY_FALLBACK"},
+			Destination: (*string)(&cfg.CapabilityFallback),
+		},
+		&cli.StringFlag{
+			Name:        flagSession,
+			Usage:       "Label the container as part of a named working session",
+			EnvVars:     []string{string(prefix) + "SESSION"},
+			Destination: (*string)(&cfg.Session),
+		},
+		&cli.BoolFlag{
+			Name:        flagDetach,
+			Aliases:     []string{"d"},
+			Usage:       "Start the container in the background and keep it after it exits; see vsl restart",
+			EnvVars:     []string{envPrefix + "DETACH"},
+			Destination: &cfg.Detach,
+		},
+		&cli.BoolFlag{
+			Name:        flagCapture,
+			Usage:       "Include the container's stdout and stderr in the JSON result",
+			EnvVars:     []string{envPrefix + "CAPTURE"},
+			Destination: &cfg.Capture,
+		},
+		&cli.BoolFlag{
+			Name:        flagPipe,
+			Usage:       "Stream the container's output to stdout/stderr and omit the JSON result unless --output is set",
+			EnvVars:     []string{envPrefix + "PIPE"},
+			Destination: &cfg.Pipe,
+		},
+		&cli.BoolFlag{
+			Name:        flagQuiet,
+			Aliases:     []string{"q"},
+			Usage:       "Do not print the JSON result; the exit code reflects the container's",
+			EnvVars:     []string{envPrefix + "QUIET"},
+			Destination: &cfg.Quiet,
+		},
+		&cli.BoolFlag{
+			Name:        flagPrintArgv,
+			Usage:       "Print the exact argv, env and working directory the container receives to stderr, escaping invisible characters",
+			EnvVars:     []string{envPrefix + "PRINT_ARGV"},
+			Destination: &cfg.PrintArgv,
+		},
+		&cli.BoolFlag{
+			Name:        flagExplain,
+			Usage:       "Print the host commands, image, mounts, environment and network of the run, with secrets masked, and ask before running anything",
+			EnvVars:     []string{envPrefix + "EXPLAIN"},
+			Destination: &cfg.Explain,
+		},
+		&cli.StringFlag{
+			Name:        flagRecord,
+			Usage:       "Record daemon API interactions, with environment values masked, into a fixture directory for replay-fixture",
+			Destination: (*string)(&cfg.RecordFixture),
+		},
 	}
 
 	return app.WithOutputFlags(prefix, &cfg.Output, baseFlags)
@@ -0,0 +1,65 @@
+package run
+
+import (
+	"os"
+	"strings"
+
+	"github.com/gloo-foo/vsl/internal/app"
+	"github.com/urfave/cli/v2"
+)
+
+// ExpandShebang rewrites the command line args of the app when vsl runs as
+// the interpreter of a script, as vsl run of the script. The shebang line
+// may give global flags and flags of vsl run before the script's path:
+//
+//	#!/usr/bin/env -S vsl --profile ci --no-git
+//
+// runs as vsl --profile ci run --no-git script args... Flags the kernel
+// passes as one argument, as it does for #!/usr/local/bin/vsl --no-git, are
+// split first.
+func ExpandShebang(a *cli.App, args []string) []string {
+	cmd := a.Command(Name)
+	if cmd == nil {
+		return args
+	}
+	if len(args) > 2 && strings.ContainsAny(args[1], " \t") && !isFile(args[1]) {
+		args = append(append([]string{args[0]}, strings.Fields(args[1])...), args[2:]...)
+	}
+
+	globals, local := []string{args[0]}, []string{}
+	for i := 1; i < len(args); i++ {
+		arg := args[i]
+		if !strings.HasPrefix(arg, "-") {
+			if a.Command(arg) != nil || !isFile(arg) {
+				return args
+			}
+			expanded := append(append(globals, Name), local...)
+			return append(expanded, args[i:]...)
+		}
+		if arg == "--" {
+			return args
+		}
+		// Global flags stay before run, and flags of run follow it
+		name, _, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		takesValue, found := app.FlagTakesValue(a.Flags, name)
+		target := &globals
+		if !found {
+			if takesValue, found = app.FlagTakesValue(cmd.Flags, name); !found {
+				return args
+			}
+			target = &local
+		}
+		*target = append(*target, arg)
+		if takesValue && !hasValue && i+1 < len(args) {
+			i++
+			*target = append(*target, args[i])
+		}
+	}
+	return args
+}
+
+// isFile reports whether path names a regular file.
+func isFile(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && !info.IsDir()
+}
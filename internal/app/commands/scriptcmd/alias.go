@@ -0,0 +1,44 @@
+package scriptcmd
+
+import (
+	"strings"
+
+	"github.com/gloo-foo/vsl/internal/app"
+	"github.com/gloo-foo/vsl/internal/script/registry"
+	"github.com/urfave/cli/v2"
+)
+
+// ExpandAlias rewrites the command line args of the app that names an
+// installed script instead of a command as vsl run of the script, keeping
+// the global flags before it and the arguments after it.
+func ExpandAlias(a *cli.App, args []string) []string {
+	for i := 1; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			return args
+		}
+		if strings.HasPrefix(arg, "-") {
+			// The value of a global flag given as a separate argument is skipped
+			name, _, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+			if takesValue, _ := app.FlagTakesValue(a.Flags, name); takesValue && !hasValue {
+				i++
+			}
+			continue
+		}
+		if a.Command(arg) != nil {
+			return args
+		}
+		scripts, err := registry.Load()
+		if err != nil {
+			return args
+		}
+		entry, ok := scripts.Lookup(arg)
+		if !ok {
+			return args
+		}
+		expanded := append([]string{}, args[:i]...)
+		expanded = append(expanded, entry.RunArgs()...)
+		return append(expanded, args[i+1:]...)
+	}
+	return args
+}
@@ -0,0 +1,156 @@
+// Package scriptcmd implements the "script" command.
+package scriptcmd
+
+import (
+	"github.com/gloo-foo/vsl/internal/app"
+	"github.com/gloo-foo/vsl/internal/script/registry"
+	"github.com/urfave/cli/v2"
+)
+
+// Command metadata
+const (
+	Name        = "script"
+	usage       = "Install scripts as vsl commands"
+	description = `Keep a personal toolbox of containerized commands: a script installed under
+a name runs as vsl NAME, with the arguments that follow, like vsl run of the
+script. Installed scripts are recorded in scripts.json in the vsl config
+directory. Local scripts are run from where they are; remote scripts, given
+by URL or as host/org/repo//path@ref, are pinned to the sha256 of the script
+fetched when installing, or to the one given with --sha256, and run only
+with that content. --unpinned fetches them again each time they run instead.
+
+Examples:
+  # Run ./tools/lint.up as vsl lint
+  vsl script install ./tools/lint.up
+  vsl lint ./...
+
+  # Install a shared script under another name, pinned to its sha256
+  vsl script install --name fmt --sha256 2c26b46b... https://example.com/tools/format.up
+
+  # Follow a script as it is updated, fetching it on each run
+  vsl script install --unpinned https://example.com/tools/nightly.up
+
+  # Show and remove installed scripts
+  vsl script list
+  vsl script remove fmt
+`
+)
+
+// Subcommand metadata
+const (
+	installName  = "install"
+	installUsage = "Install a script as a vsl command"
+	installArgs  = "<path|url>"
+	listName     = "list"
+	listUsage    = "List installed scripts"
+	removeName   = "remove"
+	removeUsage  = "Remove installed scripts, leaving the scripts themselves in place"
+	removeArgs   = "<name...>"
+	flagName     = "name"
+	flagSHA256   = "sha256"
+	flagForce    = "force"
+	flagUnpinned = "unpinned"
+)
+
+// Package-level config populated by urfave/cli via Destination
+var (
+	installCfg registry.InstallConfig
+	listCfg    registry.ListConfig
+	removeCfg  registry.RemoveConfig
+)
+
+var (
+	installAction = registry.Install
+	listAction    = registry.List
+	removeAction  = registry.Remove
+)
+
+// Command returns the CLI command for managing installed scripts
+func Command(prefix app.AppEnvPrefix) *cli.Command {
+	return &cli.Command{
+		Name:        Name,
+		Usage:       usage,
+		Description: description,
+		Subcommands: []*cli.Command{
+			{
+				Name:      installName,
+				Usage:     installUsage,
+				ArgsUsage: installArgs,
+				Flags:     installFlags(prefix),
+				Action:    install,
+			},
+			{
+				Name:   listName,
+				Usage:  listUsage,
+				Flags:  app.OutputFlags(prefix, &listCfg.Output),
+				Action: list,
+			},
+			{
+				Name:      removeName,
+				Usage:     removeUsage,
+				ArgsUsage: removeArgs,
+				Flags:     app.OutputFlags(prefix, &removeCfg.Output),
+				Action:    remove,
+			},
+		},
+	}
+}
+
+// install handles the install subcommand
+func install(c *cli.Context) error {
+	if c.NArg() != 1 {
+		return cli.Exit("the path or URL of one script is required", 1)
+	}
+	installCfg.Source = c.Args().First()
+	for _, cmd := range c.App.Commands {
+		installCfg.Reserved = append(installCfg.Reserved, cmd.Names()...)
+	}
+	return app.Action(c, installCfg, installAction)
+}
+
+// list handles the list subcommand
+func list(c *cli.Context) error {
+	return app.Action(c, listCfg, listAction)
+}
+
+// remove handles the remove subcommand
+func remove(c *cli.Context) error {
+	removeCfg.Names = c.Args().Slice()
+	return app.Action(c, removeCfg, removeAction)
+}
+
+// installFlags defines the flags of the install subcommand
+func installFlags(prefix app.AppEnvPrefix) []cli.Flag {
+	envPrefix := string(prefix) + "SCRIPT_INSTALL_"
+
+	baseFlags := []cli.Flag{
+		&cli.StringFlag{
+			Name:        flagName,
+			Aliases:     []string{"n"},
+			Usage:       "Name the script runs by (the script's file name without extension by default)",
+			EnvVars:     []string{envPrefix + "NAME"},
+			Destination: &installCfg.Name,
+		},
+		&cli.StringFlag{
+			Name:        flagSHA256,
+			Usage:       "sha256 the remote script must have, checked each time it runs (default: the sha256 of the script fetched now)",
+			EnvVars:     []string{envPrefix + "SHA256"},
+			Destination: &installCfg.SHA256,
+		},
+		&cli.BoolFlag{
+			Name:        flagUnpinned,
+			Usage:       "Fetch the remote script again each time it runs, accepting whatever its source then serves",
+			EnvVars:     []string{envPrefix + "UNPINNED"},
+			Destination: &installCfg.Unpinned,
+		},
+		&cli.BoolFlag{
+			Name:        flagForce,
+			Aliases:     []string{"f"},
+			Usage:       "Replace a script installed under the same name from another source",
+			EnvVars:     []string{envPrefix + "FORCE"},
+			Destination: &installCfg.Force,
+		},
+	}
+
+	return app.WithOutputFlags(prefix, &installCfg.Output, baseFlags)
+}
@@ -0,0 +1,81 @@
+// Package selftest implements the "selftest" command.
+package selftest
+
+import (
+	"github.com/gloo-foo/vsl/internal/app"
+	"github.com/gloo-foo/vsl/internal/container/selftest"
+	"github.com/urfave/cli/v2"
+)
+
+// Command metadata
+const (
+	Name        = "selftest"
+	usage       = "Check that vsl works end to end on this host"
+	description = `Run containers from a small image through the same pipeline as vsl run and
+report which capabilities work on this host:
+
+  daemon       the Docker daemon answers
+  pull         the image can be pulled
+  mount        the container reads and writes the mounted working directory
+  map_user     a run as the invoking user creates files it owns on the host
+  interactive  only interactive runs get a TTY
+  exit_code    the exit code of the container is reported
+  cleanup      finished containers are removed
+
+The outcome of each check is written to stderr as a table and to the JSON
+result. Checks that do not apply, or that an earlier failure blocks, are
+skipped. The command fails when any check fails, so it doubles as a smoke
+test in CI.
+
+Examples:
+  # Check this host
+  vsl selftest
+
+  # Check with an image from an internal registry
+  vsl selftest --image registry.example.com/mirror/busybox:latest
+`
+)
+
+// Flag names
+const (
+	flagImage = "image"
+)
+
+// Package-level config populated by urfave/cli via Destination
+var cfg selftest.Config
+
+var selftestAction = selftest.SelfTest
+
+// Command returns the CLI command for the self test
+func Command(prefix app.AppEnvPrefix) *cli.Command {
+	return &cli.Command{
+		Name:        Name,
+		Usage:       usage,
+		Description: description,
+		Flags:       flags(prefix),
+		Action:      action,
+	}
+}
+
+// action handles the selftest command
+func action(c *cli.Context) error {
+	return app.Action(c, cfg, selftestAction)
+}
+
+// flags defines all command flags
+func flags(prefix app.AppEnvPrefix) []cli.Flag {
+	envPrefix := string(prefix) + "SELFTEST_"
+
+	baseFlags := []cli.Flag{
+		&cli.StringFlag{
+			Name:        flagImage,
+			Aliases:     []string{"i"},
+			Usage:       "Image the checks run, which needs sh, cat, id and touch",
+			EnvVars:     []string{envPrefix + "IMAGE"},
+			Value:       string(selftest.DefaultImage),
+			Destination: (*string)(&cfg.Image),
+		},
+	}
+
+	return app.WithOutputFlags(prefix, &cfg.Output, baseFlags)
+}
@@ -0,0 +1,102 @@
+// Package stop implements the "stop" command.
+package stop
+
+import (
+	"time"
+
+	"github.com/gloo-foo/vsl/internal/app"
+	"github.com/gloo-foo/vsl/internal/container/resolve"
+	"github.com/gloo-foo/vsl/internal/container/stop"
+	"github.com/gloo-foo/vsl/internal/units"
+	"github.com/urfave/cli/v2"
+)
+
+// Command metadata
+const (
+	Name        = "stop"
+	usage       = "Gracefully stop vsl containers"
+	argsUsage   = "[container...]"
+	description = `Stop running vsl containers: each is sent its stop signal, SIGTERM unless the
+image sets another, and killed when it has not exited after --timeout.
+
+Containers may be selected by name, ID prefix, script path or session name,
+every container of a project with --project, or every vsl container with
+--all. A name or ID prefix matching several containers is refused with the
+list of candidates; only script paths and sessions select several.
+
+Stopped containers of detached runs are kept, so vsl restart can start them
+again; use vsl clean to remove them.
+
+Examples:
+  # Stop a detached service
+  vsl stop ./services/db.up
+
+  # Stop everything run for the current project, waiting up to a minute
+  vsl stop --project . --timeout 1m
+`
+)
+
+// Flag names
+const (
+	flagProject = "project"
+	flagAll     = "all"
+	flagTimeout = "timeout"
+)
+
+// defaultTimeout is how long containers have to stop, like docker stop.
+const defaultTimeout = 10 * time.Second
+
+// Package-level config populated by urfave/cli via Destination
+var cfg = stop.Config{Timeout: units.Duration(defaultTimeout)}
+
+var stopAction = stop.Stop
+
+// Command returns the CLI command for stopping containers
+func Command(prefix app.AppEnvPrefix) *cli.Command {
+	return &cli.Command{
+		Name:        Name,
+		Usage:       usage,
+		ArgsUsage:   argsUsage,
+		Description: description,
+		Flags:       flags(prefix),
+		Action:      action,
+	}
+}
+
+// action handles the stop command
+func action(c *cli.Context) error {
+	for _, arg := range c.Args().Slice() {
+		cfg.Containers = append(cfg.Containers, resolve.Reference(arg))
+	}
+	return app.Action(c, cfg, stopAction)
+}
+
+// flags defines all command flags
+func flags(prefix app.AppEnvPrefix) []cli.Flag {
+	envPrefix := string(prefix) + "STOP_"
+
+	baseFlags := []cli.Flag{
+		&cli.StringFlag{
+			Name:        flagProject,
+			Usage:       "Stop the containers run for the project in this directory (its git root, or the directory outside git)",
+			EnvVars:     []string{envPrefix + "PROJECT"},
+			Destination: (*string)(&cfg.Project),
+		},
+		&cli.BoolFlag{
+			Name:        flagAll,
+			Usage:       "Stop every vsl container",
+			EnvVars:     []string{envPrefix + "ALL"},
+			Destination: &cfg.All,
+		},
+		&cli.GenericFlag{
+			Name:        flagTimeout,
+			Aliases:     []string{"t"},
+			Usage:       "Time to wait for containers to exit before killing them (e.g. 30s, 2m)",
+			EnvVars:     []string{envPrefix + "TIMEOUT"},
+			Value:       &cfg.Timeout,
+			DefaultText: defaultTimeout.String(),
+		},
+	}
+
+	return app.WithOutputFlags(prefix, &cfg.Output, baseFlags)
+}
@@ -0,0 +1,124 @@
+// Package tasks implements the "tasks" command.
+package tasks
+
+import (
+	"github.com/gloo-foo/vsl/internal/app"
+	"github.com/gloo-foo/vsl/internal/task"
+	"github.com/urfave/cli/v2"
+)
+
+// Command metadata
+const (
+	Name        = "tasks"
+	usage       = "Find and run the UP scripts of the workspace"
+	argsUsage   = "[query] [-- task args...]"
+	description = `Index every UP script (*.up) under the git root, or the current directory
+outside repositories, as a task named by its path without the extension, with
+the description, image and build declared in the script. Files excluded by
+.gitignore and the other git ignore files are skipped.
+
+Scripts describe themselves for the index with a description key:
+
+  description Lint Go code with golangci-lint
+  image golangci/golangci-lint:latest
+
+On a terminal, the tasks matching the fuzzy query are offered in a picker;
+the selected task runs with the arguments after --, streaming its output.
+A query naming a task exactly runs it directly. A task run writes only its
+output to stdout, and its JSON result to the file given with --output. With
+--all, or without a terminal, the matching tasks are listed as JSON.
+
+The project configuration .vsl.up at the workspace root sets defaults per
+task, listed with each task: a profile applied unless --profile selects one,
+args used when none are given after --, and env variables replacing those of
+the script. With --explain, the plan of the run lists the defaults applied.
+
+  tasks {
+    go/test {
+      profile ci
+      args [
+        -run
+        TestFast
+      ]
+      env {
+        DATABASE_URL postgres://localhost/test
+      }
+    }
+  }
+
+Examples:
+  # Pick a task interactively
+  vsl tasks
+
+  # Narrow the picker down and pass arguments to the task
+  vsl tasks lint -- --fix
+
+  # Run a task by name with its project defaults
+  vsl tasks go/test
+
+  # Review what a task would run, with its project defaults, before running it
+  vsl tasks --explain go/test
+
+  # Index all tasks of a monorepo
+  vsl tasks --all
+`
+)
+
+// Flag names
+const (
+	flagAll     = "all"
+	flagExplain = "explain"
+)
+
+// Package-level config populated by urfave/cli via Destination
+var cfg task.Config
+
+var tasksAction = task.Tasks
+
+// Command returns the CLI command for finding and running tasks
+func Command(prefix app.AppEnvPrefix) *cli.Command {
+	return &cli.Command{
+		Name:        Name,
+		Usage:       usage,
+		ArgsUsage:   argsUsage,
+		Description: description,
+		Flags:       flags(prefix),
+		Action:      action,
+	}
+}
+
+// action handles the tasks command
+func action(c *cli.Context) error {
+	args := c.Args().Slice()
+	if len(args) > 0 {
+		cfg.Query, cfg.Args = args[0], args[1:]
+	}
+	// The separator is kept in the arguments when it follows the query
+	if len(cfg.Args) > 0 && cfg.Args[0] == "--" {
+		cfg.Args = cfg.Args[1:]
+	}
+	return app.Action(c, cfg, tasksAction)
+}
+
+// flags defines all command flags
+func flags(prefix app.AppEnvPrefix) []cli.Flag {
+	envPrefix := string(prefix) + "TASKS_"
+
+	baseFlags := []cli.Flag{
+		&cli.BoolFlag{
+			Name:        flagAll,
+			Aliases:     []string{"a"},
+			Usage:       "List every task instead of picking one",
+			EnvVars:     []string{envPrefix + "ALL"},
+			Destination: &cfg.All,
+		},
+		&cli.BoolFlag{
+			Name:        flagExplain,
+			Usage:       "Print the plan of the task's run, with the project defaults applied, and ask before running anything",
+			EnvVars:     []string{envPrefix + "EXPLAIN"},
+			Destination: &cfg.Explain,
+		},
+	}
+
+	return app.WithOutputFlags(prefix, &cfg.Output, baseFlags)
+}
@@ -0,0 +1,94 @@
+// Package test implements the "test" command.
+package test
+
+import (
+	"github.com/gloo-foo/vsl/internal/app"
+	"github.com/gloo-foo/vsl/internal/container"
+	"github.com/gloo-foo/vsl/internal/container/scripttest"
+	"github.com/urfave/cli/v2"
+)
+
+// Command metadata
+const (
+	Name        = "test"
+	usage       = "Run the test cases declared in UP scripts"
+	argsUsage   = "<script> [script...]"
+	description = `Run each test case in the tests block of the given scripts and check its
+assertions, so scripts can be tested in CI like code.
+
+Every case runs the script in an ephemeral container with the case's
+arguments and output captured, then checks the expected exit code (default 0),
+regular expressions the stdout and stderr must match, and files the run must
+produce (relative to the current directory, which is mounted as usual):
+
+  tests {
+    greets {
+      args [world]
+      exit_code 0
+      stdout ^hello world
+      files [out/greeting.txt]
+    }
+  }
+
+With --dry-run the scripts and their test cases are only validated, without
+a Docker daemon. When no daemon is reachable, the cases are skipped the same
+way with a warning, and no_daemon is set in the result. The command fails if
+any script is invalid or any case fails.
+
+Examples:
+  # Test all scripts of a repository
+  vsl test scripts/*.up
+
+  # Validate test declarations in CI without Docker
+  vsl test --dry-run scripts/*.up
+`
+)
+
+// Flag names
+const (
+	flagDryRun = "dry-run"
+)
+
+// Package-level config populated by urfave/cli via Destination
+var cfg scripttest.Config
+
+var testAction = scripttest.Test
+
+// Command returns the CLI command for testing scripts
+func Command(prefix app.AppEnvPrefix) *cli.Command {
+	return &cli.Command{
+		Name:        Name,
+		Usage:       usage,
+		ArgsUsage:   argsUsage,
+		Description: description,
+		Flags:       flags(prefix),
+		Action:      action,
+	}
+}
+
+// action handles the test command
+func action(c *cli.Context) error {
+	if c.NArg() == 0 {
+		return cli.Exit("expected at least one script", 1)
+	}
+	for _, arg := range c.Args().Slice() {
+		cfg.Scripts = append(cfg.Scripts, container.ScriptPath(arg))
+	}
+	return app.Action(c, cfg, testAction)
+}
+
+// flags defines all command flags
+func flags(prefix app.AppEnvPrefix) []cli.Flag {
+	envPrefix := string(prefix) + "TEST_"
+
+	baseFlags := []cli.Flag{
+		&cli.BoolFlag{
+			Name:        flagDryRun,
+			Usage:       "Validate the scripts and their test cases without running containers",
+			EnvVars:     []string{envPrefix + "DRY_RUN"},
+			Destination: &cfg.DryRun,
+		},
+	}
+
+	return app.WithOutputFlags(prefix, &cfg.Output, baseFlags)
+}
@@ -0,0 +1,86 @@
+// Package up implements the "up" command.
+package up
+
+import (
+	"github.com/gloo-foo/vsl/internal/app"
+	"github.com/gloo-foo/vsl/internal/container"
+	"github.com/gloo-foo/vsl/internal/container/services"
+	"github.com/urfave/cli/v2"
+)
+
+// Command metadata
+const (
+	Name        = "up"
+	usage       = "Start the services a script declares"
+	argsUsage   = "<script>"
+	description = `Start each service of the script's services block in a detached container
+on a network shared by the script's services, where they reach each other
+by service name. Services start after the services they list in depends_on
+are healthy: their healthcheck passes or, without one, they are running.
+
+Running up again converges the services: unchanged containers are
+restarted and those whose configuration changed are recreated.
+vsl down removes them.
+
+Examples:
+  # Start the app and its database
+  vsl up stack.up
+
+  # Also wait for every service to be healthy
+  vsl up --wait --wait-timeout 5m stack.up
+`
+)
+
+// Flag names
+const (
+	flagWait        = "wait"
+	flagWaitTimeout = "wait-timeout"
+)
+
+// Package-level config populated by urfave/cli via Destination
+var cfg = services.Config{WaitTimeout: services.DefaultWaitTimeout}
+
+var upAction = services.Up
+
+// Command returns the CLI command for starting services
+func Command(prefix app.AppEnvPrefix) *cli.Command {
+	return &cli.Command{
+		Name:        Name,
+		Usage:       usage,
+		ArgsUsage:   argsUsage,
+		Description: description,
+		Flags:       flags(prefix),
+		Action:      action,
+	}
+}
+
+// action handles the up command
+func action(c *cli.Context) error {
+	if c.NArg() != 1 {
+		return cli.Exit("expected the script declaring the services", 1)
+	}
+	cfg.Script = container.ScriptPath(c.Args().First())
+	return app.Action(c, cfg, upAction)
+}
+
+// flags defines all command flags
+func flags(prefix app.AppEnvPrefix) []cli.Flag {
+	envPrefix := string(prefix) + "UP_"
+
+	baseFlags := []cli.Flag{
+		&cli.BoolFlag{
+			Name:        flagWait,
+			Usage:       "Wait for every service to be healthy, not only those others depend on",
+			EnvVars:     []string{envPrefix + "WAIT"},
+			Destination: &cfg.Wait,
+		},
+		&cli.GenericFlag{
+			Name:    flagWaitTimeout,
+			Usage:   "How long a service may take to become healthy (e.g. 90s, 5m)",
+			EnvVars: []string{envPrefix + "WAIT_TIMEOUT"},
+			Value:   &cfg.WaitTimeout,
+		},
+	}
+
+	return app.WithOutputFlags(prefix, &cfg.Output, baseFlags)
+}
@@ -0,0 +1,88 @@
+// Package validate implements the "validate" command.
+package validate
+
+import (
+	"github.com/gloo-foo/vsl/internal/app"
+	"github.com/gloo-foo/vsl/internal/container"
+	"github.com/gloo-foo/vsl/internal/script/validate"
+	"github.com/urfave/cli/v2"
+)
+
+// Command metadata
+const (
+	Name        = "validate"
+	usage       = "Check UP scripts against the script schema"
+	argsUsage   = "<script> [script...]"
+	description = `Parse each script, with the fragments it includes, and check it against the
+script schema without running it: unknown keys, values of the wrong type,
+invalid volumes, mounts and ports, blocks left open, and scripts that set
+neither image, build nor host. Profiles, services, steps and tests are
+checked too.
+
+Problems are printed to stderr like compiler errors, one per line:
+
+  scripts/lint.up:4: memory: invalid size "lots"
+  scripts/lint.up:9: volume: invalid volume "src": missing target; ...
+
+The command exits with 0 when every script is valid, 1 when any script has
+problems or cannot be read, and 2 when no script is given, so it can guard
+commits and CI.
+
+Examples:
+  # Validate the scripts of a repository
+  vsl validate scripts/*.up
+
+  # As a pre-commit hook
+  git diff --cached --name-only -- '*.up' | xargs -r vsl validate --quiet
+`
+)
+
+// Flag names
+const (
+	flagQuiet = "quiet"
+)
+
+// Package-level config populated by urfave/cli via Destination
+var cfg validate.Config
+
+var validateAction = validate.Validate
+
+// Command returns the CLI command for validating scripts
+func Command(prefix app.AppEnvPrefix) *cli.Command {
+	return &cli.Command{
+		Name:        Name,
+		Usage:       usage,
+		ArgsUsage:   argsUsage,
+		Description: description,
+		Flags:       flags(prefix),
+		Action:      action,
+	}
+}
+
+// action handles the validate command
+func action(c *cli.Context) error {
+	if c.NArg() == 0 {
+		return cli.Exit("expected at least one script", 2)
+	}
+	for _, arg := range c.Args().Slice() {
+		cfg.Scripts = append(cfg.Scripts, container.ScriptPath(arg))
+	}
+	return app.Action(c, cfg, validateAction)
+}
+
+// flags defines all command flags
+func flags(prefix app.AppEnvPrefix) []cli.Flag {
+	envPrefix := string(prefix) + "VALIDATE_"
+
+	baseFlags := []cli.Flag{
+		&cli.BoolFlag{
+			Name:        flagQuiet,
+			Aliases:     []string{"q"},
+			Usage:       "Only print the problems found, not the JSON result",
+			EnvVars:     []string{envPrefix + "QUIET"},
+			Destination: &cfg.Quiet,
+		},
+	}
+
+	return app.WithOutputFlags(prefix, &cfg.Output, baseFlags)
+}
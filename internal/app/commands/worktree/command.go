@@ -0,0 +1,49 @@
+// Package worktree implements the "worktree" command.
+package worktree
+
+import (
+	"github.com/gloo-foo/vsl/internal/app"
+	"github.com/gloo-foo/vsl/internal/app/commands/run"
+	"github.com/urfave/cli/v2"
+)
+
+// Command metadata
+const (
+	Name        = "worktree"
+	usage       = "Run containers against other refs of the repository"
+	runName     = "run"
+	runUsage    = "Run a container in a temporary worktree of a branch, tag or commit"
+	runArgs     = "[run flags] <ref> [command...]"
+	description = `Check the ref out, detached, in a temporary linked worktree in the vsl
+cache directory, run there as vsl run would from the same subdirectory, and
+remove the worktree afterwards, changes included. The current checkout, its
+index and its branches are left untouched.
+
+Flags are those of vsl run and come before the ref.
+
+Examples:
+  # Test a branch without switching to it
+  vsl worktree run --image golang:1.22 feature-x -- go test ./...
+
+  # Compare the output of a release with the current checkout
+  vsl worktree run --image node:20 --capture v1.2.3 -- npm run build
+`
+)
+
+// Command returns the CLI command for worktree runs
+func Command(prefix app.AppEnvPrefix) *cli.Command {
+	return &cli.Command{
+		Name:  Name,
+		Usage: usage,
+		Subcommands: []*cli.Command{
+			{
+				Name:        runName,
+				Usage:       runUsage,
+				ArgsUsage:   runArgs,
+				Description: description,
+				Flags:       run.Command(prefix).Flags,
+				Action:      run.WorktreeAction,
+			},
+		},
+	}
+}
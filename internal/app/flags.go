@@ -24,3 +24,17 @@ func OutputFlags(prefix AppEnvPrefix, output *FilePath) []cli.Flag {
 		},
 	}
 }
+
+// FlagTakesValue reports whether the flag of flags with the name or alias
+// name, given without dashes, takes a value, and whether flags has it.
+func FlagTakesValue(flags []cli.Flag, name string) (takesValue, found bool) {
+	for _, f := range flags {
+		for _, n := range f.Names() {
+			if n == name {
+				v, ok := f.(cli.DocGenerationFlag)
+				return ok && v.TakesValue(), true
+			}
+		}
+	}
+	return false, false
+}
@@ -0,0 +1,138 @@
+// Package hook runs the host commands scripts declare around their
+// container, such as generating certificates before a run or fixing file
+// permissions after it.
+package hook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/gloo-foo/vsl/internal/units"
+)
+
+// Stage is when a hook runs.
+type Stage string
+
+// Hook stages.
+const (
+	Before Stage = "before" // Before the container starts; a failure stops the run
+	After  Stage = "after"  // After the container exits, whether it succeeded or not
+)
+
+// DefaultShell runs hooks given as a command line.
+const DefaultShell = "/bin/sh"
+
+// Hook is a host command run before or after a script's container.
+type Hook struct {
+	Command         []string       `json:"command"`                     // Command line run by the shell, or the argv when Exec
+	Exec            bool           `json:"exec,omitempty"`              // Run Command directly instead of through the shell
+	WorkingDir      string         `json:"workdir,omitempty"`           // Directory the hook runs in, the run's by default
+	Timeout         units.Duration `json:"timeout,omitempty"`           // Stop the hook when it runs longer
+	ContinueOnError bool           `json:"continue_on_error,omitempty"` // A failure is a warning instead of failing the run
+}
+
+// argv returns the command the hook runs.
+func (h Hook) argv() []string {
+	if h.Exec {
+		return h.Command
+	}
+	return []string{DefaultShell, "-c", strings.Join(h.Command, " ")}
+}
+
+// Result is the outcome of a hook.
+type Result struct {
+	Stage      Stage    `json:"stage"`
+	Command    []string `json:"command"`
+	ExitCode   *int     `json:"exit_code,omitempty"`
+	DurationMs int64    `json:"duration_ms"`
+	Error      string   `json:"error,omitempty"`
+}
+
+// Runner runs the hooks of a script.
+type Runner struct {
+	Logger *slog.Logger
+	Dir    string               // Working directory of hooks that set none
+	Env    []string             // Variables added to the host environment
+	Allow  func([]string) error // Host command policy applied to each hook
+	Output io.Writer            // Receives the hooks' stdout and stderr, keeping stdout for the result
+}
+
+// Run runs hooks in order, stopping at the first failure of a hook that
+// does not continue on error. Failures of hooks that do are returned as
+// warnings.
+func (r Runner) Run(ctx context.Context, stage Stage, hooks []Hook) ([]Result, []string, error) {
+	results := make([]Result, 0, len(hooks))
+	var warnings []string
+	for _, h := range hooks {
+		result, err := r.run(ctx, stage, h)
+		results = append(results, result)
+		if err == nil {
+			continue
+		}
+		if !h.ContinueOnError {
+			return results, warnings, err
+		}
+		r.Logger.Warn("Hook failed, continuing", "stage", stage, "command", result.Command, "error", err)
+		warnings = append(warnings, err.Error())
+	}
+	return results, warnings, nil
+}
+
+// run runs one hook.
+func (r Runner) run(ctx context.Context, stage Stage, h Hook) (Result, error) {
+	argv := h.argv()
+	result := Result{Stage: stage, Command: argv}
+	fail := func(err error) (Result, error) {
+		err = fmt.Errorf("%s hook %q: %w", stage, strings.Join(h.Command, " "), err)
+		result.Error = err.Error()
+		return result, err
+	}
+	if len(h.Command) == 0 {
+		return fail(errors.New("no command"))
+	}
+	if r.Allow != nil {
+		if err := r.Allow(argv); err != nil {
+			return fail(err)
+		}
+	}
+
+	if h.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(h.Timeout))
+		defer cancel()
+	}
+	cmd := exec.CommandContext(ctx, argv[0], argv[1:]...)
+	cmd.Dir = r.Dir
+	if h.WorkingDir != "" {
+		cmd.Dir = h.WorkingDir
+	}
+	cmd.Env = append(os.Environ(), r.Env...)
+	cmd.Stdout, cmd.Stderr = r.Output, r.Output
+
+	r.Logger.Info("Running hook", "stage", stage, "command", argv, "working_dir", cmd.Dir)
+	started := time.Now()
+	err := cmd.Run()
+	result.DurationMs = time.Since(started).Milliseconds()
+
+	var exitErr *exec.ExitError
+	switch {
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		return fail(fmt.Errorf("exceeded timeout of %s", h.Timeout))
+	case errors.As(err, &exitErr):
+		code := exitErr.ExitCode()
+		result.ExitCode = &code
+		return fail(fmt.Errorf("exited with code %d", code))
+	case err != nil:
+		return fail(err)
+	}
+	code := 0
+	result.ExitCode = &code
+	return result, nil
+}
@@ -0,0 +1,88 @@
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+)
+
+// FlagStableOutput is the global flag that makes JSON results deterministic.
+const FlagStableOutput = "stable-output"
+
+// volatileKeys name result fields that differ between otherwise identical
+// runs, such as container IDs, durations and timestamps.
+var volatileKeys = map[string]bool{
+	"container_id": true,
+	"id":           true,
+	"duration_ms":  true,
+	"time":         true,
+}
+
+// unorderedKeys name result lists whose order carries no meaning.
+var unorderedKeys = map[string]bool{
+	"containers":   true,
+	"environment":  true,
+	"imported_env": true,
+	"mounts":       true,
+	"volumes_from": true,
+	"warnings":     true,
+}
+
+// Stable re-encodes a result for snapshots and drift detection: object keys
+// are sorted, unordered lists such as mounts are sorted, and volatile fields
+// are omitted, so identical configurations produce identical output.
+func Stable(result json.Marshaler) (json.RawMessage, error) {
+	data, err := json.Marshal(result)
+	if err != nil {
+		return nil, err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var v any
+	if err := decoder.Decode(&v); err != nil {
+		return nil, err
+	}
+	// Maps are encoded with sorted keys
+	return json.Marshal(stabilize(v))
+}
+
+// stabilize drops volatile fields and sorts unordered lists, recursively.
+func stabilize(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for key, value := range v {
+			if volatileKeys[key] {
+				delete(v, key)
+				continue
+			}
+			value = stabilize(value)
+			if list, ok := value.([]any); ok && unorderedKeys[key] {
+				sortValues(list)
+			}
+			v[key] = value
+		}
+	case []any:
+		for i := range v {
+			v[i] = stabilize(v[i])
+		}
+	}
+	return v
+}
+
+// sortValues orders list elements by their JSON encoding.
+func sortValues(list []any) {
+	type element struct {
+		key   string
+		value any
+	}
+	elements := make([]element, len(list))
+	for i, value := range list {
+		data, _ := json.Marshal(value)
+		elements[i] = element{key: string(data), value: value}
+	}
+	sort.SliceStable(elements, func(i, j int) bool { return elements[i].key < elements[j].key })
+	for i, e := range elements {
+		list[i] = e.value
+	}
+}
@@ -0,0 +1,131 @@
+// Package capability detects optional host capabilities that scripts may require.
+package capability
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/docker/docker/api/types/build"
+	"github.com/docker/docker/client"
+	"github.com/gloo-foo/vsl/internal/container"
+)
+
+// Known capabilities.
+const (
+	GPU      container.Capability = "gpu"
+	Kata     container.Capability = "kata"
+	BuildKit container.Capability = "buildkit"
+)
+
+// Strategies for handling a missing capability.
+const (
+	StrategyFail     container.CapabilityStrategy = "fail"
+	StrategyFallback container.CapabilityStrategy = "fallback"
+)
+
+// guidance explains how to make each capability available.
+var guidance = map[container.Capability]string{
+	GPU:      "install the NVIDIA Container Toolkit (https://docs.nvidia.com/datacenter/cloud-native/container-toolkit/) and restart the Docker daemon",
+	Kata:     "install Kata Containers (https://katacontainers.io) and register its runtime in /etc/docker/daemon.json",
+	BuildKit: "upgrade Docker to 18.09 or newer, or enable BuildKit with {\"features\": {\"buildkit\": true}} in /etc/docker/daemon.json",
+}
+
+// fallbacks describes what happens when a missing capability is skipped.
+var fallbacks = map[container.Capability]string{
+	GPU:      "running without GPU access",
+	Kata:     "running with the default runtime",
+	BuildKit: "using the classic builder",
+}
+
+// Host describes the capabilities of the Docker host.
+type Host struct {
+	GPU         bool   // An NVIDIA runtime or CDI GPU devices are available
+	KataRuntime string // Name of the registered Kata runtime, if any
+	BuildKit    bool   // The daemon builds with BuildKit
+}
+
+// Detect queries the Docker daemon for its capabilities.
+func Detect(ctx context.Context, dockerCli client.SystemAPIClient) (Host, error) {
+	info, err := dockerCli.Info(ctx)
+	if err != nil {
+		return Host{}, fmt.Errorf("failed to query docker info: %w", err)
+	}
+	ping, err := dockerCli.Ping(ctx)
+	if err != nil {
+		return Host{}, fmt.Errorf("failed to ping docker daemon: %w", err)
+	}
+
+	host := Host{BuildKit: ping.BuilderVersion == build.BuilderBuildKit}
+	for name := range info.Runtimes {
+		switch {
+		case strings.Contains(name, "nvidia"):
+			host.GPU = true
+		case strings.Contains(name, "kata"):
+			host.KataRuntime = name
+		}
+	}
+	for _, device := range info.DiscoveredDevices {
+		if strings.Contains(device.ID, "gpu") {
+			host.GPU = true
+		}
+	}
+	return host, nil
+}
+
+// Has reports whether the host provides a capability.
+func (h Host) Has(c container.Capability) bool {
+	switch c {
+	case GPU:
+		return h.GPU
+	case Kata:
+		return h.KataRuntime != ""
+	case BuildKit:
+		return h.BuildKit
+	}
+	return false
+}
+
+// Decision records how a required capability was handled.
+type Decision struct {
+	Capability container.Capability
+	Granted    bool   // The capability is available and will be used
+	Runtime    string // Container runtime providing the capability, if any
+	Warning    string // Set when the capability was skipped
+}
+
+// Resolve decides how to handle each required capability. With the fail
+// strategy a missing capability is an error carrying installation guidance;
+// with the fallback strategy it is skipped and a warning is recorded.
+func Resolve(host Host, required []container.Capability, strategy container.CapabilityStrategy) ([]Decision, error) {
+	if strategy == "" {
+		strategy = StrategyFail
+	}
+	if strategy != StrategyFail && strategy != StrategyFallback {
+		return nil, fmt.Errorf("unknown capability strategy %q, expected fail or fallback", strategy)
+	}
+
+	decisions := make([]Decision, 0, len(required))
+	for _, c := range required {
+		hint, known := guidance[c]
+		if !known {
+			return nil, fmt.Errorf("unknown capability %q, expected gpu, kata, or buildkit", c)
+		}
+		if host.Has(c) {
+			d := Decision{Capability: c, Granted: true}
+			if c == Kata {
+				d.Runtime = host.KataRuntime
+			}
+			decisions = append(decisions, d)
+			continue
+		}
+		if strategy == StrategyFail {
+			return nil, fmt.Errorf("required capability %q is not available: %s", c, hint)
+		}
+		decisions = append(decisions, Decision{
+			Capability: c,
+			Warning:    fmt.Sprintf("capability %q is not available, %s; to enable it, %s", c, fallbacks[c], hint),
+		})
+	}
+	return decisions, nil
+}
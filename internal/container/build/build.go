@@ -0,0 +1,101 @@
+// Package build pre-builds the images of UP scripts with build blocks, so CI
+// can warm image caches separately from running the scripts.
+package build
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/gloo-foo/vsl/internal/container"
+	"github.com/gloo-foo/vsl/internal/container/run"
+	"github.com/gloo-foo/vsl/internal/docker"
+	"github.com/gloo-foo/vsl/internal/image"
+	"github.com/gloo-foo/vsl/internal/script"
+)
+
+// ErrFailed is returned when a script is invalid or its image fails to build.
+var ErrFailed = errors.New("script image builds failed")
+
+// Result holds the outcome of building script images.
+type Result struct {
+	Success bool           `json:"success"`
+	Scripts []ScriptResult `json:"scripts"`
+	Message string         `json:"message"`
+	Error   string         `json:"error,omitempty"`
+}
+
+// ScriptResult holds the outcome of building one script's image.
+type ScriptResult struct {
+	Script  container.ScriptPath `json:"script"`
+	Build   *image.BuildResult   `json:"build,omitempty"`
+	Skipped bool                 `json:"skipped,omitempty"` // The script has no build block
+	Error   string               `json:"error,omitempty"`
+}
+
+// MarshalJSON implements json.Marshaler
+func (r Result) MarshalJSON() ([]byte, error) {
+	type Alias Result
+	return json.Marshal((Alias)(r))
+}
+
+// Failed implements app.Failable
+func (r Result) Failed(err error) json.Marshaler {
+	r.Success = false
+	r.Error = err.Error()
+	return r
+}
+
+// Build builds and tags the image of each script's build block without
+// running anything. Images get the tag and labels a run would give them, so
+// later runs of the scripts start from the built image and its cache. Scripts
+// without a build block are skipped.
+func Build(ctx context.Context, logger *slog.Logger, cfg Config) (Result, error) {
+	result := Result{Scripts: make([]ScriptResult, 0, len(cfg.Scripts))}
+
+	dockerCli, err := docker.NewClient(docker.WithRetry(logger))
+	if err != nil {
+		return result, err
+	}
+	defer docker.Close(dockerCli)
+
+	built, failed := 0, 0
+	for _, path := range cfg.Scripts {
+		sr := ScriptResult{Script: path}
+		scriptCfg, err := script.ParseFile(string(path))
+		switch {
+		case err != nil:
+			sr.Error = err.Error()
+		case scriptCfg.Build == nil:
+			logger.Warn("Script has no build block", "script", path)
+			sr.Skipped = true
+		default:
+			scriptCfg.ScriptPath = path
+			scriptCfg.Build.NoCache = scriptCfg.Build.NoCache || cfg.NoCache
+			scriptCfg.Build.Pull = scriptCfg.Build.Pull || cfg.Pull
+			b, err := run.BuildScript(ctx, logger, dockerCli, *scriptCfg)
+			sr.Build = &b
+			if err != nil {
+				sr.Error = err.Error()
+			}
+		}
+
+		switch {
+		case sr.Error != "":
+			logger.Warn("Failed to build script image", "script", path, "error", sr.Error)
+			failed++
+		case !sr.Skipped:
+			built++
+		}
+		result.Scripts = append(result.Scripts, sr)
+	}
+
+	result.Message = fmt.Sprintf("Built %d images, %d failed, %d scripts skipped", built, failed, len(cfg.Scripts)-built-failed)
+	if failed > 0 {
+		return result, ErrFailed
+	}
+	result.Success = true
+	return result, nil
+}
@@ -0,0 +1,21 @@
+package build
+
+import (
+	"github.com/gloo-foo/vsl/internal/app"
+	"github.com/gloo-foo/vsl/internal/app/log"
+	"github.com/gloo-foo/vsl/internal/container"
+)
+
+// Config holds configuration for pre-building the images of UP scripts.
+type Config struct {
+	Scripts []container.ScriptPath // Scripts whose build block is built
+	NoCache bool                   // Do not use the build cache, overriding the scripts
+	Pull    bool                   // Always pull newer base images, overriding the scripts
+
+	// Output and logging
+	Output  app.FilePath
+	Logging log.Config
+}
+
+func (c Config) OutputFilePath() app.FilePath { return c.Output }
+func (c Config) LoggerConfig() log.Config     { return c.Logging }
@@ -0,0 +1,66 @@
+package clean
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/volume"
+	"github.com/docker/docker/client"
+	cont "github.com/gloo-foo/vsl/internal/container"
+	"github.com/gloo-foo/vsl/internal/mount"
+	"github.com/gloo-foo/vsl/internal/units"
+)
+
+// trimCaches removes unused cache volumes, oldest first, until the cache
+// volumes together use at most limit bytes. Volumes in use, and volumes
+// named like caches that vsl did not create, are kept.
+func trimCaches(ctx context.Context, logger *slog.Logger, dockerCli *client.Client, limit units.Bytes) (Result, error) {
+	result := Result{
+		Containers: []cont.ContainerID{},
+		Networks:   []string{},
+		Volumes:    []string{},
+	}
+
+	usage, err := dockerCli.DiskUsage(ctx, types.DiskUsageOptions{Types: []types.DiskUsageObject{types.VolumeObject}})
+	if err != nil {
+		return result, fmt.Errorf("failed to read volume usage: %w", err)
+	}
+
+	var caches []*volume.Volume
+	var total units.Bytes
+	for _, v := range usage.Volumes {
+		if !mount.IsCacheVolume(v.Name) || v.Labels[cont.LabelManaged] != "true" || v.UsageData == nil || v.UsageData.Size < 0 {
+			continue
+		}
+		caches = append(caches, v)
+		total += units.Bytes(v.UsageData.Size)
+	}
+	sort.Slice(caches, func(i, j int) bool { return caches[i].CreatedAt < caches[j].CreatedAt })
+
+	logger.Info("Cache volume usage", "size", total, "limit", limit)
+	for _, v := range caches {
+		if total <= limit {
+			break
+		}
+		if v.UsageData.RefCount > 0 {
+			continue
+		}
+		logger.Debug("Removing cache volume", "name", v.Name, "size", units.Bytes(v.UsageData.Size))
+		if err := dockerCli.VolumeRemove(ctx, v.Name, false); err != nil {
+			return result, fmt.Errorf("failed to remove volume %s: %w", v.Name, err)
+		}
+		total -= units.Bytes(v.UsageData.Size)
+		result.Volumes = append(result.Volumes, v.Name)
+	}
+
+	result.CacheSize = total
+	if total > limit {
+		logger.Warn("Cache volumes in use exceed the limit", "size", total, "limit", limit)
+	}
+	result.Success = true
+	result.Message = fmt.Sprintf("Removed %d cache volumes, %s of caches remain (limit %s)", len(result.Volumes), total, limit)
+	return result, nil
+}
@@ -0,0 +1,180 @@
+// Package clean contains the logic for removing vsl-managed resources.
+package clean
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/api/types/volume"
+	"github.com/docker/docker/client"
+	cont "github.com/gloo-foo/vsl/internal/container"
+	"github.com/gloo-foo/vsl/internal/container/resolve"
+	"github.com/gloo-foo/vsl/internal/docker"
+	"github.com/gloo-foo/vsl/internal/units"
+)
+
+// Result holds the result of a cleanup.
+type Result struct {
+	Success    bool               `json:"success"`
+	Session    cont.Session       `json:"session,omitempty"`
+	Containers []cont.ContainerID `json:"containers"`
+	Networks   []string           `json:"networks"`
+	Volumes    []string           `json:"volumes"`
+	CacheSize  units.Bytes        `json:"cache_size,omitempty"` // Bytes used by cache volumes after trimming
+	Message    string             `json:"message"`
+}
+
+// MarshalJSON implements json.Marshaler
+func (r Result) MarshalJSON() ([]byte, error) {
+	type Alias Result
+	return json.Marshal((Alias)(r))
+}
+
+// Clean removes the containers, networks, and volumes created by vsl.
+func Clean(ctx context.Context, logger *slog.Logger, cfg Config) (Result, error) {
+	logger.Info("Cleaning vsl resources", "session", cfg.Session, "force", cfg.Force, "stale", cfg.Stale)
+
+	dockerCli, err := docker.NewClient(docker.WithRetry(logger))
+	if err != nil {
+		return Result{}, err
+	}
+	defer docker.Close(dockerCli)
+
+	args := filters.NewArgs()
+	for _, label := range cont.LabelFilter(cfg.Session) {
+		args.Add("label", label)
+	}
+
+	if len(cfg.Containers) > 0 {
+		return removeReferenced(ctx, logger, dockerCli, cfg)
+	}
+	if cfg.TrimCaches {
+		return trimCaches(ctx, logger, dockerCli, cfg.CacheLimit)
+	}
+
+	result := Result{
+		Session:    cfg.Session,
+		Containers: []cont.ContainerID{},
+		Networks:   []string{},
+		Volumes:    []string{},
+	}
+
+	if result.Containers, err = removeContainers(ctx, logger, dockerCli, args, cfg); err != nil {
+		return Result{}, err
+	}
+	if result.Networks, err = removeNetworks(ctx, logger, dockerCli, args, cfg); err != nil {
+		return Result{}, err
+	}
+	if result.Volumes, err = removeVolumes(ctx, logger, dockerCli, args, cfg); err != nil {
+		return Result{}, err
+	}
+
+	result.Success = true
+	result.Message = fmt.Sprintf("Removed %d containers, %d networks, %d volumes",
+		len(result.Containers), len(result.Networks), len(result.Volumes))
+	return result, nil
+}
+
+// removeReferenced removes only the containers named by the configured references.
+func removeReferenced(ctx context.Context, logger *slog.Logger, dockerCli *client.Client, cfg Config) (Result, error) {
+	result := Result{
+		Containers: []cont.ContainerID{},
+		Networks:   []string{},
+		Volumes:    []string{},
+	}
+
+	for _, ref := range cfg.Containers {
+		found, err := resolve.Resolve(ctx, dockerCli, ref)
+		if err != nil {
+			return result, err
+		}
+		for _, c := range found {
+			if c.State == container.StateRunning && !cfg.Force {
+				return result, fmt.Errorf("container %s is running, use --force to remove it", resolve.ShortID(c.ID))
+			}
+			logger.Debug("Removing container", "id", c.ID, "reference", ref)
+			if err := dockerCli.ContainerRemove(ctx, c.ID, container.RemoveOptions{Force: cfg.Force, RemoveVolumes: true}); err != nil {
+				return result, fmt.Errorf("failed to remove container %s: %w", c.ID, err)
+			}
+			result.Containers = append(result.Containers, cont.ContainerID(c.ID))
+		}
+	}
+
+	result.Success = true
+	result.Message = fmt.Sprintf("Removed %d containers", len(result.Containers))
+	return result, nil
+}
+
+// selected reports whether a resource with the given labels should be removed.
+func selected(cfg Config, labels map[string]string) bool {
+	return !cfg.Stale || cont.Stale(labels)
+}
+
+func removeContainers(ctx context.Context, logger *slog.Logger, dockerCli *client.Client, args filters.Args, cfg Config) ([]cont.ContainerID, error) {
+	containers, err := dockerCli.ContainerList(ctx, container.ListOptions{All: true, Filters: args})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list containers: %w", err)
+	}
+
+	removed := []cont.ContainerID{}
+	for _, c := range containers {
+		if !selected(cfg, c.Labels) {
+			continue
+		}
+		if c.State == container.StateRunning && !cfg.Force {
+			logger.Info("Skipping running container", "id", c.ID)
+			continue
+		}
+		logger.Debug("Removing container", "id", c.ID)
+		if err := dockerCli.ContainerRemove(ctx, c.ID, container.RemoveOptions{Force: cfg.Force, RemoveVolumes: true}); err != nil {
+			return removed, fmt.Errorf("failed to remove container %s: %w", c.ID, err)
+		}
+		removed = append(removed, cont.ContainerID(c.ID))
+	}
+	return removed, nil
+}
+
+func removeNetworks(ctx context.Context, logger *slog.Logger, dockerCli *client.Client, args filters.Args, cfg Config) ([]string, error) {
+	networks, err := dockerCli.NetworkList(ctx, network.ListOptions{Filters: args})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list networks: %w", err)
+	}
+
+	removed := []string{}
+	for _, n := range networks {
+		if !selected(cfg, n.Labels) {
+			continue
+		}
+		logger.Debug("Removing network", "name", n.Name)
+		if err := dockerCli.NetworkRemove(ctx, n.ID); err != nil {
+			return removed, fmt.Errorf("failed to remove network %s: %w", n.Name, err)
+		}
+		removed = append(removed, n.Name)
+	}
+	return removed, nil
+}
+
+func removeVolumes(ctx context.Context, logger *slog.Logger, dockerCli *client.Client, args filters.Args, cfg Config) ([]string, error) {
+	volumes, err := dockerCli.VolumeList(ctx, volume.ListOptions{Filters: args})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list volumes: %w", err)
+	}
+
+	removed := []string{}
+	for _, v := range volumes.Volumes {
+		if !selected(cfg, v.Labels) {
+			continue
+		}
+		logger.Debug("Removing volume", "name", v.Name)
+		if err := dockerCli.VolumeRemove(ctx, v.Name, false); err != nil {
+			return removed, fmt.Errorf("failed to remove volume %s: %w", v.Name, err)
+		}
+		removed = append(removed, v.Name)
+	}
+	return removed, nil
+}
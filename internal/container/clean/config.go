@@ -0,0 +1,28 @@
+package clean
+
+import (
+	"github.com/gloo-foo/vsl/internal/app"
+	"github.com/gloo-foo/vsl/internal/app/log"
+	"github.com/gloo-foo/vsl/internal/container"
+	"github.com/gloo-foo/vsl/internal/container/resolve"
+	"github.com/gloo-foo/vsl/internal/units"
+)
+
+// Config holds configuration for cleaning up vsl-managed resources.
+type Config struct {
+	Containers []resolve.Reference // Specific containers to remove instead of everything
+
+	Session container.Session // Restrict cleanup to a single session
+	Force   bool              // Also remove running containers
+	Stale   bool              // Only remove resources whose owning vsl process has exited
+
+	TrimCaches bool        // Only trim unused cache volumes, oldest first, until they fit CacheLimit
+	CacheLimit units.Bytes // Total size the cache volumes are trimmed to; zero removes every unused one
+
+	// Output and logging
+	Output  app.FilePath
+	Logging log.Config
+}
+
+func (c Config) OutputFilePath() app.FilePath { return c.Output }
+func (c Config) LoggerConfig() log.Config     { return c.Logging }
@@ -0,0 +1,128 @@
+package clean
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/api/types/volume"
+	cont "github.com/gloo-foo/vsl/internal/container"
+	"github.com/gloo-foo/vsl/internal/docker"
+	"github.com/gloo-foo/vsl/internal/state"
+)
+
+// staleCheckFile records when the last stale resource check ran.
+const staleCheckFile = "stale-check"
+
+// StaleCheckConfig controls the startup check for leftover resources.
+type StaleCheckConfig struct {
+	Interval  time.Duration // Minimum time between checks; zero disables the check
+	AutoClean bool          // Remove stale resources instead of warning about them
+}
+
+// CheckStale looks for resources left behind by vsl processes that exited abnormally.
+// The check is rate-limited and never fails the invocation: problems are only logged.
+func CheckStale(ctx context.Context, logger *slog.Logger, cfg StaleCheckConfig) {
+	if cfg.Interval <= 0 || !due(logger, cfg.Interval) {
+		return
+	}
+
+	count, err := countStale(ctx)
+	if err != nil {
+		logger.Debug("Skipping stale resource check", "error", err)
+		return
+	}
+	if count == 0 {
+		return
+	}
+
+	if !cfg.AutoClean {
+		logger.Warn("Found resources left by crashed vsl runs; run `vsl clean --stale` to remove them", "count", count)
+		return
+	}
+
+	result, err := Clean(ctx, logger, Config{Stale: true, Force: true})
+	if err != nil {
+		logger.Warn("Failed to remove stale resources", "error", err)
+		return
+	}
+	logger.Info("Removed stale resources", "message", result.Message)
+}
+
+// due reports whether enough time has passed since the last check and records this one.
+func due(logger *slog.Logger, interval time.Duration) bool {
+	dir, err := state.CacheDir()
+	if err != nil {
+		logger.Debug("Skipping stale resource check", "error", err)
+		return false
+	}
+	path := filepath.Join(dir, staleCheckFile)
+
+	info, err := os.Stat(path)
+	if err == nil && time.Since(info.ModTime()) < interval {
+		return false
+	}
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		logger.Debug("Skipping stale resource check", "error", err)
+		return false
+	}
+
+	if err := os.WriteFile(path, []byte(time.Now().Format(time.RFC3339)+"\n"), 0o600); err != nil {
+		logger.Debug("Failed to record stale resource check", "error", err)
+	}
+	return true
+}
+
+// countStale counts vsl-managed resources whose owning process has exited.
+func countStale(ctx context.Context) (int, error) {
+	dockerCli, err := docker.NewClient()
+	if err != nil {
+		return 0, err
+	}
+	defer docker.Close(dockerCli)
+
+	args := filters.NewArgs()
+	for _, label := range cont.LabelFilter("") {
+		args.Add("label", label)
+	}
+
+	count := 0
+	containers, err := dockerCli.ContainerList(ctx, container.ListOptions{All: true, Filters: args})
+	if err != nil {
+		return 0, err
+	}
+	for _, c := range containers {
+		if cont.Stale(c.Labels) {
+			count++
+		}
+	}
+
+	networks, err := dockerCli.NetworkList(ctx, network.ListOptions{Filters: args})
+	if err != nil {
+		return 0, err
+	}
+	for _, n := range networks {
+		if cont.Stale(n.Labels) {
+			count++
+		}
+	}
+
+	volumes, err := dockerCli.VolumeList(ctx, volume.ListOptions{Filters: args})
+	if err != nil {
+		return 0, err
+	}
+	for _, v := range volumes.Volumes {
+		if cont.Stale(v.Labels) {
+			count++
+		}
+	}
+
+	return count, nil
+}
@@ -0,0 +1,20 @@
+package debug
+
+import (
+	"github.com/gloo-foo/vsl/internal/app"
+	"github.com/gloo-foo/vsl/internal/app/log"
+)
+
+// Config holds configuration for debugging a failed run.
+type Config struct {
+	Run     string // "last" for the last failed run, or a container ID prefix from the history
+	Shell   string // Shell used as the entrypoint
+	Restore bool   // Start from the failed container's filesystem when it still exists
+
+	// Output and logging
+	Output  app.FilePath
+	Logging log.Config
+}
+
+func (c Config) OutputFilePath() app.FilePath { return c.Output }
+func (c Config) LoggerConfig() log.Config     { return c.Logging }
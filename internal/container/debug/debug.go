@@ -0,0 +1,146 @@
+// Package debug re-creates failed runs with a shell for inspecting the failure.
+package debug
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/docker/docker/api/types/container"
+
+	cont "github.com/gloo-foo/vsl/internal/container"
+	"github.com/gloo-foo/vsl/internal/container/resolve"
+	"github.com/gloo-foo/vsl/internal/container/run"
+	"github.com/gloo-foo/vsl/internal/docker"
+	"github.com/gloo-foo/vsl/internal/history"
+)
+
+// Last selects the most recent failed run.
+const Last = "last"
+
+// Result holds the result of a debug session.
+type Result struct {
+	Success  bool          `json:"success"`
+	Original history.Entry `json:"original"`
+	Restored cont.Image    `json:"restored_image,omitempty"`
+	Session  *run.Result   `json:"session,omitempty"`
+	Message  string        `json:"message"`
+	Error    string        `json:"error,omitempty"`
+}
+
+// MarshalJSON implements json.Marshaler
+func (r Result) MarshalJSON() ([]byte, error) {
+	type Alias Result
+	return json.Marshal((Alias)(r))
+}
+
+// Failed implements app.Failable
+func (r Result) Failed(err error) json.Marshaler {
+	r.Success = false
+	r.Error = err.Error()
+	r.Message = "Debug session failed"
+	return r
+}
+
+// Debug re-creates a recorded run from its host directory with identical
+// mounts, replacing its command with an interactive shell.
+func Debug(ctx context.Context, logger *slog.Logger, cfg Config) (Result, error) {
+	var result Result
+
+	entry, err := selectEntry(cfg.Run)
+	if err != nil {
+		return result, err
+	}
+	result.Original = entry
+	if len(entry.Config) == 0 {
+		return result, fmt.Errorf("run %s has no recorded configuration", resolve.ShortID(string(entry.ContainerID)))
+	}
+
+	var runCfg run.Config
+	if err := json.Unmarshal(entry.Config, &runCfg); err != nil {
+		return result, fmt.Errorf("failed to decode recorded configuration: %w", err)
+	}
+	runCfg.Entrypoint = []cont.Entrypoint{cont.Entrypoint(cfg.Shell)}
+	runCfg.Command = nil
+	runCfg.ScriptArgs = nil
+	runCfg.Interactive = true
+	runCfg.Attach = true
+	runCfg.Probe = ""
+	runCfg.Capture, runCfg.Pipe, runCfg.Quiet = false, false, false
+	runCfg.RecordFixture = ""
+	runCfg.Output = ""
+
+	if cfg.Restore {
+		if result.Restored, err = restore(ctx, logger, entry); err != nil {
+			return result, err
+		}
+		if result.Restored != "" {
+			runCfg.Image = result.Restored
+		}
+	}
+
+	// Mounts are resolved relative to the directory the run started from
+	if entry.HostDir != "" {
+		if err := os.Chdir(entry.HostDir); err != nil {
+			return result, fmt.Errorf("failed to enter the run's directory: %w", err)
+		}
+	}
+
+	logger.Info("Debugging run", "container", resolve.ShortID(string(entry.ContainerID)), "error", entry.Error, "shell", cfg.Shell)
+	session, err := run.Run(ctx, logger, runCfg)
+	result.Session = &session
+	if err != nil {
+		return result, err
+	}
+
+	result.Success = true
+	result.Message = "Debug session ended"
+	return result, nil
+}
+
+// selectEntry finds the run to debug in the history.
+func selectEntry(ref string) (history.Entry, error) {
+	if ref == "" || ref == Last {
+		return history.LastFailed(history.KindRun)
+	}
+
+	entries, err := history.Load()
+	if err != nil {
+		return history.Entry{}, err
+	}
+	for i := len(entries) - 1; i >= 0; i-- {
+		if entries[i].Kind == history.KindRun && strings.HasPrefix(string(entries[i].ContainerID), ref) {
+			return entries[i], nil
+		}
+	}
+	return history.Entry{}, fmt.Errorf("no run matching %q in history", ref)
+}
+
+// restore commits the failed container, if it still exists, so the debug
+// session starts from the files it left behind.
+func restore(ctx context.Context, logger *slog.Logger, entry history.Entry) (cont.Image, error) {
+	dockerCli, err := docker.NewClient(docker.WithRetry(logger))
+	if err != nil {
+		return "", err
+	}
+	defer docker.Close(dockerCli)
+
+	id := string(entry.ContainerID)
+	if _, err := dockerCli.ContainerInspect(ctx, id); err != nil {
+		logger.Warn("Failed container no longer exists, starting from its image", "container", resolve.ShortID(id))
+		return "", nil
+	}
+
+	reference := "vsl-debug:" + resolve.ShortID(id)
+	if _, err := dockerCli.ContainerCommit(ctx, id, container.CommitOptions{
+		Reference: reference,
+		Comment:   "vsl debug snapshot of " + id,
+	}); err != nil {
+		return "", fmt.Errorf("failed to snapshot container: %w", err)
+	}
+	logger.Info("Restored failed container filesystem", "image", reference)
+	return cont.Image(reference), nil
+}
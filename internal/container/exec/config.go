@@ -0,0 +1,31 @@
+package exec
+
+import (
+	"github.com/gloo-foo/vsl/internal/app"
+	"github.com/gloo-foo/vsl/internal/app/log"
+	"github.com/gloo-foo/vsl/internal/container"
+	"github.com/gloo-foo/vsl/internal/container/resolve"
+)
+
+// Config holds configuration for running a command in a running container.
+type Config struct {
+	Container   resolve.Reference       // Container to run the command in
+	Command     []string                // Command and arguments
+	Environment []container.Environment // Additional environment variables
+	User        container.User          // User to run as
+	WorkingDir  container.WorkingDir    // Working directory for the command
+	TTY         bool                    // Allocate a pseudo-TTY
+
+	// Output handling, shared with run
+	Capture bool // Include command output in the JSON result
+	Pipe    bool // Stream command output to stdout/stderr (implies Quiet)
+	Quiet   bool // Do not write the JSON result to stdout
+
+	// Output and logging
+	Output  app.FilePath
+	Logging log.Config
+}
+
+func (c Config) OutputFilePath() app.FilePath { return c.Output }
+func (c Config) LoggerConfig() log.Config     { return c.Logging }
+func (c Config) QuietOutput() bool            { return c.Quiet || c.Pipe }
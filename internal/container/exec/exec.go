@@ -0,0 +1,146 @@
+// Package exec contains the logic for running commands in vsl containers.
+package exec
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/client"
+	cont "github.com/gloo-foo/vsl/internal/container"
+	"github.com/gloo-foo/vsl/internal/container/resolve"
+	"github.com/gloo-foo/vsl/internal/container/run"
+	"github.com/gloo-foo/vsl/internal/container/stream"
+	"github.com/gloo-foo/vsl/internal/docker"
+	"github.com/gloo-foo/vsl/internal/history"
+)
+
+// Result holds the result of an exec, mirroring the run result fields.
+type Result struct {
+	Success     bool             `json:"success"`
+	ContainerID cont.ContainerID `json:"container_id"`
+	Command     []string         `json:"command"`
+	ExitCode    *int             `json:"exit_code,omitempty"`
+	DurationMs  int64            `json:"duration_ms,omitempty"`
+	Output      *stream.Output   `json:"output,omitempty"`
+	Message     string           `json:"message"`
+	Error       *run.ErrorInfo   `json:"error,omitempty"`
+}
+
+// MarshalJSON implements json.Marshaler
+func (r Result) MarshalJSON() ([]byte, error) {
+	type Alias Result
+	return json.Marshal((Alias)(r))
+}
+
+// Failed implements app.Failable
+func (r Result) Failed(err error) json.Marshaler {
+	r.Success = false
+	r.Error = run.NewErrorInfo(err)
+	r.Message = "Command failed"
+	return r
+}
+
+// Exec runs a command in a running container and waits for it to exit.
+func Exec(ctx context.Context, logger *slog.Logger, cfg Config) (Result, error) {
+	result := Result{Command: cfg.Command}
+	if len(cfg.Command) == 0 {
+		return result, run.Fail(run.ErrorInvalidConfig, fmt.Errorf("no command given"))
+	}
+
+	dockerCli, err := docker.NewClient(docker.WithRetry(logger))
+	if err != nil {
+		return result, run.Fail(run.ErrorDaemonUnreachable, err)
+	}
+	defer docker.Close(dockerCli)
+
+	target, err := resolve.ResolveOne(ctx, dockerCli, cfg.Container)
+	if err != nil {
+		return result, run.Fail(run.ErrorInvalidConfig, err)
+	}
+	result.ContainerID = cont.ContainerID(target.ID)
+
+	env := make([]string, len(cfg.Environment))
+	for i, e := range cfg.Environment {
+		env[i] = string(e)
+	}
+
+	logger.Info("Running command in container", "container", resolve.Name(target), "command", cfg.Command)
+	created, err := dockerCli.ContainerExecCreate(ctx, target.ID, container.ExecOptions{
+		User:         string(cfg.User),
+		Tty:          cfg.TTY,
+		AttachStdout: true,
+		AttachStderr: true,
+		Env:          env,
+		WorkingDir:   string(cfg.WorkingDir),
+		Cmd:          cfg.Command,
+	})
+	if err != nil {
+		return result, run.Fail(run.ErrorStartFailed, fmt.Errorf("failed to create exec: %w", err))
+	}
+
+	started := time.Now()
+	err = execute(ctx, dockerCli, created.ID, cfg, &result)
+	result.DurationMs = time.Since(started).Milliseconds()
+	addHistory(logger, target, cfg, result, err)
+	if err != nil {
+		return result, err
+	}
+
+	result.Success = true
+	result.Message = "Command executed successfully"
+	return result, nil
+}
+
+// execute starts the exec, streams its output according to the configured
+// mode and records its exit code.
+func execute(ctx context.Context, dockerCli client.ContainerAPIClient, execID string, cfg Config, result *Result) error {
+	attach, err := dockerCli.ContainerExecAttach(ctx, execID, container.ExecAttachOptions{Tty: cfg.TTY})
+	if err != nil {
+		return run.Fail(run.ErrorStartFailed, fmt.Errorf("failed to start exec: %w", err))
+	}
+	defer attach.Close()
+
+	// Without pipe or capture the output is drained and discarded
+	output, err := stream.Copy(attach.Reader, cfg.TTY, stream.Mode{Pipe: cfg.Pipe, Capture: cfg.Capture})
+	if err != nil {
+		return run.Fail(run.ErrorWaitFailed, err)
+	}
+	result.Output = output
+
+	inspect, err := dockerCli.ContainerExecInspect(ctx, execID)
+	if err != nil {
+		return run.Fail(run.ErrorWaitFailed, fmt.Errorf("failed to inspect exec: %w", err))
+	}
+	exitCode := inspect.ExitCode
+	result.ExitCode = &exitCode
+	if exitCode != 0 {
+		return run.Fail(run.ErrorExited, &stream.ExitError{Code: exitCode})
+	}
+	return nil
+}
+
+// addHistory records the finished exec, logging rather than failing on errors.
+func addHistory(logger *slog.Logger, target container.Summary, cfg Config, result Result, err error) {
+	entry := history.Entry{
+		Time:        time.Now(),
+		Kind:        history.KindExec,
+		ContainerID: result.ContainerID,
+		Image:       cont.Image(target.Image),
+		Command:     cfg.Command,
+		WorkingDir:  string(cfg.WorkingDir),
+		Session:     cont.Session(target.Labels[cont.LabelSession]),
+		ExitCode:    result.ExitCode,
+		DurationMs:  result.DurationMs,
+		Success:     err == nil,
+	}
+	if err != nil {
+		entry.Error = err.Error()
+	}
+	if err := history.Append(entry); err != nil {
+		logger.Warn("Failed to record history", "error", err)
+	}
+}
@@ -0,0 +1,79 @@
+package container
+
+import (
+	"os"
+	"strconv"
+
+	"github.com/gloo-foo/vsl/internal/process"
+)
+
+// Label keys applied to every resource vsl creates.
+const (
+	LabelPrefix    = "foo.gloo.vsl."
+	LabelManaged   = LabelPrefix + "managed"
+	LabelSession   = LabelPrefix + "session"
+	LabelScript    = LabelPrefix + "script"
+	LabelProject   = LabelPrefix + "project"
+	LabelOwnerPID  = LabelPrefix + "owner.pid"
+	LabelOwnerHost = LabelPrefix + "owner.host"
+	LabelSpec      = LabelPrefix + "spec"    // Resolved configuration of a detached container
+	LabelGroup     = LabelPrefix + "group"   // Services started together by vsl up
+	LabelService   = LabelPrefix + "service" // Name of the service a container runs
+)
+
+// Provenance describes where a vsl-created resource came from.
+type Provenance struct {
+	Session    Session    // Working session the resource belongs to
+	Script     ScriptPath // Absolute path of the script that created the resource
+	Project    Project    // Project the resource was created for
+	Persistent bool       // Resource outlives its creator, so no owner process is recorded
+}
+
+// Labels returns the labels to apply to a container, network, or volume created by vsl.
+// Non-persistent resources are tagged with the creating process so leftovers
+// from crashed runs can be detected.
+func Labels(p Provenance) map[string]string {
+	labels := map[string]string{
+		LabelManaged: "true",
+	}
+	if !p.Persistent {
+		labels[LabelOwnerPID] = strconv.Itoa(os.Getpid())
+		if host, err := os.Hostname(); err == nil {
+			labels[LabelOwnerHost] = host
+		}
+	}
+	if p.Session != "" {
+		labels[LabelSession] = string(p.Session)
+	}
+	if p.Script != "" {
+		labels[LabelScript] = string(p.Script)
+	}
+	if p.Project != "" {
+		labels[LabelProject] = string(p.Project)
+	}
+	return labels
+}
+
+// LabelFilter returns label filter expressions ("key" or "key=value") that
+// select vsl-managed resources, optionally restricted to a session.
+func LabelFilter(session Session) []string {
+	filters := []string{LabelManaged + "=true"}
+	if session != "" {
+		filters = append(filters, LabelSession+"="+string(session))
+	}
+	return filters
+}
+
+// Stale reports whether a resource's owning vsl process has exited.
+// Only resources created on this host with an owner label are considered.
+func Stale(labels map[string]string) bool {
+	pid, err := strconv.Atoi(labels[LabelOwnerPID])
+	if err != nil {
+		return false
+	}
+	host, err := os.Hostname()
+	if err != nil || labels[LabelOwnerHost] != host {
+		return false
+	}
+	return !process.Alive(pid)
+}
@@ -0,0 +1,30 @@
+package logs
+
+import (
+	"github.com/gloo-foo/vsl/internal/app"
+	"github.com/gloo-foo/vsl/internal/app/log"
+	"github.com/gloo-foo/vsl/internal/container"
+	"github.com/gloo-foo/vsl/internal/container/resolve"
+)
+
+// Config holds configuration for showing container logs.
+type Config struct {
+	Containers []resolve.Reference // Containers to show logs for
+	Session    container.Session   // Show logs for every container in a session
+
+	Follow     bool   // Keep streaming new output
+	Timestamps bool   // Prefix lines with their timestamps
+	Since      string // Only show logs since a timestamp or relative duration (e.g. 10m)
+	Until      string // Only show logs before a timestamp or relative duration
+	Tail       string // Only show this many lines from the end of each container's logs, or "all"
+	Grep       string // Only show lines matching this regular expression
+	Color      bool   // Color lines by detected log level
+
+	// Output and logging
+	Output  app.FilePath
+	Logging log.Config
+}
+
+func (c Config) OutputFilePath() app.FilePath { return c.Output }
+func (c Config) LoggerConfig() log.Config     { return c.Logging }
+func (c Config) QuietOutput() bool            { return true }
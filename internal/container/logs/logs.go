@@ -0,0 +1,218 @@
+// Package logs contains the logic for showing the output of vsl containers.
+package logs
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"regexp"
+	"strconv"
+	"sync"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/client"
+	"github.com/docker/docker/pkg/stdcopy"
+	cont "github.com/gloo-foo/vsl/internal/container"
+	"github.com/gloo-foo/vsl/internal/container/resolve"
+	"github.com/gloo-foo/vsl/internal/docker"
+	"github.com/gloo-foo/vsl/internal/history"
+	lg "github.com/gloo-foo/vsl/internal/logs"
+)
+
+// Result holds the result of showing logs.
+type Result struct {
+	Success    bool               `json:"success"`
+	Containers []cont.ContainerID `json:"containers"`
+	Lines      int                `json:"lines"`
+	Message    string             `json:"message"`
+}
+
+// MarshalJSON implements json.Marshaler
+func (r Result) MarshalJSON() ([]byte, error) {
+	type Alias Result
+	return json.Marshal((Alias)(r))
+}
+
+// stdout is where log lines are written.
+var stdout io.Writer = os.Stdout
+
+// Logs streams the logs of the selected containers to stdout.
+// When several containers are selected, their output is multiplexed and each
+// line is prefixed with the container name.
+func Logs(ctx context.Context, logger *slog.Logger, cfg Config) (Result, error) {
+	var grep *regexp.Regexp
+	if cfg.Grep != "" {
+		var err error
+		if grep, err = regexp.Compile(cfg.Grep); err != nil {
+			return Result{}, fmt.Errorf("invalid --grep expression: %w", err)
+		}
+	}
+	if cfg.Tail != "" && cfg.Tail != "all" {
+		if n, err := strconv.Atoi(cfg.Tail); err != nil || n < 0 {
+			return Result{}, fmt.Errorf("invalid --tail %q, expected a number of lines or all", cfg.Tail)
+		}
+	}
+
+	dockerCli, err := docker.NewClient(docker.WithRetry(logger))
+	if err != nil {
+		return Result{}, err
+	}
+	defer docker.Close(dockerCli)
+
+	targets, err := selectContainers(ctx, dockerCli, cfg)
+	if err != nil {
+		return Result{}, err
+	}
+
+	result := Result{Containers: make([]cont.ContainerID, len(targets))}
+	writers := make([]*lg.LineWriter, len(targets))
+	mu := &sync.Mutex{}
+	for i, c := range targets {
+		result.Containers[i] = cont.ContainerID(c.ID)
+		filter := lg.Filter{Grep: grep, Color: cfg.Color}
+		if len(targets) > 1 {
+			filter.Prefix = resolve.Name(c) + " | "
+		}
+		writers[i] = lg.NewLineWriter(stdout, mu, filter)
+	}
+
+	errs := make([]error, len(targets))
+	var wg sync.WaitGroup
+	for i, c := range targets {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			logger.Debug("Streaming logs", "id", c.ID)
+			errs[i] = stream(ctx, dockerCli, c.ID, cfg, writers[i])
+		}()
+	}
+	wg.Wait()
+
+	for i, w := range writers {
+		if err := w.Flush(); err != nil && errs[i] == nil {
+			errs[i] = err
+		}
+		result.Lines += w.Lines()
+	}
+	for i, err := range errs {
+		if err != nil {
+			return result, fmt.Errorf("failed to read logs of %s: %w", resolve.ShortID(targets[i].ID), err)
+		}
+	}
+
+	result.Success = true
+	result.Message = fmt.Sprintf("Showed %d lines from %d containers", result.Lines, len(targets))
+	return result, nil
+}
+
+// selectContainers resolves the configured references and session to containers.
+func selectContainers(ctx context.Context, dockerCli *client.Client, cfg Config) ([]container.Summary, error) {
+	var targets []container.Summary
+	seen := map[string]bool{}
+	add := func(found []container.Summary) {
+		for _, c := range found {
+			if !seen[c.ID] {
+				seen[c.ID] = true
+				targets = append(targets, c)
+			}
+		}
+	}
+
+	if cfg.Session != "" {
+		args := filters.NewArgs()
+		for _, label := range cont.LabelFilter(cfg.Session) {
+			args.Add("label", label)
+		}
+		found, err := dockerCli.ContainerList(ctx, container.ListOptions{All: true, Filters: args})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list containers: %w", err)
+		}
+		add(found)
+	}
+
+	for _, ref := range cfg.Containers {
+		resolveRef := resolve.Resolve
+		if ref == resolve.Last {
+			resolveRef = lastRun
+		}
+		found, err := resolveRef(ctx, dockerCli, ref)
+		if err != nil {
+			return nil, err
+		}
+		add(found)
+	}
+
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("no containers selected, pass a container reference or --session")
+	}
+	return targets, nil
+}
+
+// lastRun resolves the container of the most recent run recorded in the
+// history that still exists. Runs not recorded, such as those made with
+// --no-history, fall back to the newest vsl container.
+func lastRun(ctx context.Context, dockerCli client.ContainerAPIClient, ref resolve.Reference) ([]container.Summary, error) {
+	entries, err := history.Load()
+	if err != nil {
+		return nil, err
+	}
+	args := filters.NewArgs()
+	for _, label := range cont.LabelFilter("") {
+		args.Add("label", label)
+	}
+	containers, err := dockerCli.ContainerList(ctx, container.ListOptions{All: true, Filters: args})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list containers: %w", err)
+	}
+	// Containers of runs that were not detached are removed when they exit
+	for i := len(entries) - 1; i >= 0; i-- {
+		if entries[i].Kind != history.KindRun || entries[i].ContainerID == "" {
+			continue
+		}
+		for _, c := range containers {
+			if c.ID == string(entries[i].ContainerID) {
+				return []container.Summary{c}, nil
+			}
+		}
+	}
+	return resolve.Resolve(ctx, dockerCli, ref)
+}
+
+// stream copies one container's logs into w, demultiplexing stdout and stderr
+// unless the container uses a TTY.
+func stream(ctx context.Context, dockerCli *client.Client, id string, cfg Config, w io.Writer) error {
+	info, err := dockerCli.ContainerInspect(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	reader, err := dockerCli.ContainerLogs(ctx, id, container.LogsOptions{
+		ShowStdout: true,
+		ShowStderr: true,
+		Follow:     cfg.Follow,
+		Timestamps: cfg.Timestamps,
+		Since:      cfg.Since,
+		Until:      cfg.Until,
+		Tail:       cfg.Tail,
+	})
+	if err != nil {
+		return err
+	}
+	defer func(reader io.ReadCloser) {
+		err := reader.Close()
+		if err != nil {
+			panic(err)
+		}
+	}(reader)
+
+	if info.Config != nil && info.Config.Tty {
+		_, err = io.Copy(w, reader)
+		return err
+	}
+	_, err = stdcopy.StdCopy(w, w, reader)
+	return err
+}
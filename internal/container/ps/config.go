@@ -0,0 +1,32 @@
+package ps
+
+import (
+	"github.com/gloo-foo/vsl/internal/app"
+	"github.com/gloo-foo/vsl/internal/app/log"
+	"github.com/gloo-foo/vsl/internal/container"
+)
+
+// Format is the form containers are listed in.
+type Format string
+
+// List formats.
+const (
+	FormatTable Format = "table" // Table on stdout, without the JSON result
+	FormatJSON  Format = "json"  // JSON result only
+)
+
+// Config holds configuration for listing vsl containers.
+type Config struct {
+	Session container.Session // Only list the containers of this session
+	Project container.Project // Only list the containers of this project
+	Running bool              // Only list running containers
+	Format  Format            // Form of the listing
+
+	// Output and logging
+	Output  app.FilePath
+	Logging log.Config
+}
+
+func (c Config) OutputFilePath() app.FilePath { return c.Output }
+func (c Config) LoggerConfig() log.Config     { return c.Logging }
+func (c Config) QuietOutput() bool            { return c.Format != FormatJSON }
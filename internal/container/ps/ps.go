@@ -0,0 +1,197 @@
+// Package ps contains the logic for listing vsl containers.
+package ps
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/client"
+	cont "github.com/gloo-foo/vsl/internal/container"
+	"github.com/gloo-foo/vsl/internal/container/resolve"
+	"github.com/gloo-foo/vsl/internal/docker"
+	"github.com/gloo-foo/vsl/internal/paths"
+)
+
+// Container describes a vsl container.
+type Container struct {
+	ID        cont.ContainerID `json:"id"`
+	Name      string           `json:"name"`
+	Image     cont.Image       `json:"image"`
+	State     string           `json:"state"`  // running, exited, ...
+	Status    string           `json:"status"` // Docker's description, such as "Exited (1) 2 minutes ago"
+	Script    cont.ScriptPath  `json:"script,omitempty"`
+	Project   cont.Project     `json:"project,omitempty"`
+	Session   cont.Session     `json:"session,omitempty"`
+	Service   string           `json:"service,omitempty"`
+	Created   time.Time        `json:"created"`
+	StartedAt *time.Time       `json:"started_at,omitempty"` // Set for running containers
+	UptimeMs  int64            `json:"uptime_ms,omitempty"`
+	Ports     []string         `json:"ports,omitempty"` // Published ports as HOST_IP:HOST_PORT->PORT/PROTO
+}
+
+// Result holds the vsl containers found.
+type Result struct {
+	Success    bool        `json:"success"`
+	Containers []Container `json:"containers"`
+	Message    string      `json:"message"`
+}
+
+// MarshalJSON implements json.Marshaler
+func (r Result) MarshalJSON() ([]byte, error) {
+	type Alias Result
+	return json.Marshal((Alias)(r))
+}
+
+// stdout is where the table is written.
+var stdout io.Writer = os.Stdout
+
+// Ps lists the containers vsl created, newest first, as a table on stdout
+// unless the JSON format is requested.
+func Ps(ctx context.Context, logger *slog.Logger, cfg Config) (Result, error) {
+	switch cfg.Format {
+	case FormatTable, FormatJSON, "":
+	default:
+		return Result{}, fmt.Errorf("invalid format %q, expected %s or %s", cfg.Format, FormatTable, FormatJSON)
+	}
+
+	dockerCli, err := docker.NewClient(docker.WithRetry(logger))
+	if err != nil {
+		return Result{}, err
+	}
+	defer docker.Close(dockerCli)
+
+	containers, err := list(ctx, dockerCli, cfg)
+	if err != nil {
+		return Result{}, err
+	}
+	result := Result{Success: true, Containers: containers, Message: fmt.Sprintf("Found %d containers", len(containers))}
+	if cfg.Format != FormatJSON {
+		writeTable(stdout, containers)
+	}
+	return result, nil
+}
+
+// list returns the vsl containers matching cfg, newest first.
+func list(ctx context.Context, dockerCli client.ContainerAPIClient, cfg Config) ([]Container, error) {
+	args := filters.NewArgs()
+	for _, label := range cont.LabelFilter(cfg.Session) {
+		args.Add("label", label)
+	}
+	if cfg.Running {
+		args.Add("status", "running")
+	}
+	summaries, err := dockerCli.ContainerList(ctx, container.ListOptions{All: true, Filters: args})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list containers: %w", err)
+	}
+	sort.Slice(summaries, func(i, j int) bool { return summaries[i].Created > summaries[j].Created })
+
+	project := resolve.ProjectDir(cfg.Project)
+	now := time.Now()
+	containers := []Container{}
+	for _, s := range summaries {
+		if project != "" && !paths.Equal(s.Labels[cont.LabelProject], project) {
+			continue
+		}
+		c := Container{
+			ID:      cont.ContainerID(s.ID),
+			Name:    resolve.Name(s),
+			Image:   cont.Image(s.Image),
+			State:   s.State,
+			Status:  s.Status,
+			Script:  cont.ScriptPath(s.Labels[cont.LabelScript]),
+			Project: cont.Project(s.Labels[cont.LabelProject]),
+			Session: cont.Session(s.Labels[cont.LabelSession]),
+			Service: s.Labels[cont.LabelService],
+			Created: time.Unix(s.Created, 0),
+			Ports:   ports(s.Ports),
+		}
+		// Uptime is counted from the last start, which the list does not report
+		if s.State == "running" {
+			if inspect, err := dockerCli.ContainerInspect(ctx, s.ID); err == nil && inspect.State != nil {
+				if started, err := time.Parse(time.RFC3339Nano, inspect.State.StartedAt); err == nil {
+					c.StartedAt = &started
+					c.UptimeMs = now.Sub(started).Milliseconds()
+				}
+			}
+		}
+		containers = append(containers, c)
+	}
+	return containers, nil
+}
+
+// ports formats the published ports of a container, sorted.
+func ports(list []container.Port) []string {
+	var formatted []string
+	for _, p := range list {
+		if p.PublicPort == 0 {
+			continue
+		}
+		host := p.IP
+		if strings.Contains(host, ":") {
+			host = "[" + host + "]"
+		}
+		formatted = append(formatted, fmt.Sprintf("%s:%d->%d/%s", host, p.PublicPort, p.PrivatePort, p.Type))
+	}
+	sort.Strings(formatted)
+	return formatted
+}
+
+// writeTable writes containers as a table, with projects by directory name
+// and scripts relative to their project.
+func writeTable(w io.Writer, containers []Container) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "CONTAINER ID\tNAME\tIMAGE\tSTATE\tUPTIME\tPORTS\tPROJECT\tSCRIPT")
+	for _, c := range containers {
+		uptime := "-"
+		if c.StartedAt != nil {
+			uptime = formatDuration(time.Duration(c.UptimeMs) * time.Millisecond)
+		}
+		project, script := "-", "-"
+		if c.Project != "" {
+			project = filepath.Base(string(c.Project))
+		}
+		if c.Script != "" {
+			script = string(c.Script)
+			if rel, err := filepath.Rel(string(c.Project), script); err == nil && c.Project != "" && !strings.HasPrefix(rel, "..") {
+				script = rel
+			}
+		}
+		portList := "-"
+		if len(c.Ports) > 0 {
+			portList = strings.Join(c.Ports, ", ")
+		}
+		_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			resolve.ShortID(string(c.ID)), c.Name, c.Image, c.State, uptime, portList, project, script)
+	}
+	_ = tw.Flush()
+}
+
+// formatDuration formats d in its two largest units, such as 3h12m or 45s.
+func formatDuration(d time.Duration) string {
+	d = d.Round(time.Second)
+	days := d / (24 * time.Hour)
+	hours := (d % (24 * time.Hour)) / time.Hour
+	minutes := (d % time.Hour) / time.Minute
+	seconds := (d % time.Minute) / time.Second
+	switch {
+	case days > 0:
+		return fmt.Sprintf("%dd%dh", days, hours)
+	case hours > 0:
+		return fmt.Sprintf("%dh%dm", hours, minutes)
+	case minutes > 0:
+		return fmt.Sprintf("%dm%ds", minutes, seconds)
+	}
+	return fmt.Sprintf("%ds", seconds)
+}
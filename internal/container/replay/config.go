@@ -0,0 +1,19 @@
+package replay
+
+import (
+	"github.com/gloo-foo/vsl/internal/app"
+	"github.com/gloo-foo/vsl/internal/app/log"
+	"github.com/gloo-foo/vsl/internal/fixture"
+)
+
+// Config holds configuration for replaying a recorded fixture.
+type Config struct {
+	Fixture fixture.Dir // Directory the fixture was recorded into
+
+	// Output and logging
+	Output  app.FilePath
+	Logging log.Config
+}
+
+func (c Config) OutputFilePath() app.FilePath { return c.Output }
+func (c Config) LoggerConfig() log.Config     { return c.Logging }
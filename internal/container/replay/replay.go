@@ -0,0 +1,147 @@
+// Package replay re-runs a recorded fixture against its recorded daemon
+// responses, reproducing user scenarios without Docker.
+package replay
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"reflect"
+
+	"github.com/gloo-foo/vsl/internal/container/run"
+	"github.com/gloo-foo/vsl/internal/fixture"
+)
+
+// ErrDiverged is returned when a replay does not reproduce the recording.
+var ErrDiverged = errors.New("replay diverged from the recorded fixture")
+
+// Result holds the outcome of a replay.
+type Result struct {
+	Success      bool            `json:"success"`
+	Fixture      fixture.Dir     `json:"fixture"`
+	Interactions int             `json:"interactions"`
+	Replayed     int             `json:"replayed"`
+	Mismatches   []string        `json:"mismatches,omitempty"`
+	Expected     json.RawMessage `json:"expected,omitempty"`
+	Actual       json.RawMessage `json:"actual,omitempty"`
+	Error        string          `json:"error,omitempty"`
+}
+
+// MarshalJSON implements json.Marshaler
+func (r Result) MarshalJSON() ([]byte, error) {
+	type Alias Result
+	return json.Marshal((Alias)(r))
+}
+
+// Failed returns the result annotated with err.
+func (r Result) Failed(err error) json.Marshaler {
+	r.Success = false
+	r.Error = err.Error()
+	return r
+}
+
+// Replay loads the fixture in cfg.Fixture, restores its working directory and
+// environment, and runs the recorded configuration with every daemon request
+// answered from the recording. The run outcome must match the recorded one.
+func Replay(ctx context.Context, logger *slog.Logger, cfg Config) (Result, error) {
+	result := Result{Fixture: cfg.Fixture}
+
+	f, err := fixture.Load(cfg.Fixture)
+	if err != nil {
+		return result, err
+	}
+	result.Interactions = len(f.Interactions)
+	result.Expected = f.Result
+
+	var runCfg run.Config
+	if err := json.Unmarshal(f.Config, &runCfg); err != nil {
+		return result, fmt.Errorf("failed to decode recorded config: %w", err)
+	}
+
+	restore, err := enter(logger, f)
+	if err != nil {
+		return result, err
+	}
+	defer restore()
+
+	replayer := fixture.NewReplayer(f.Interactions)
+	runCfg.Transport = replayer.Wrap
+	runCfg.Output = ""
+
+	logger.Info("Replaying fixture", "dir", cfg.Fixture, "interactions", len(f.Interactions))
+	runResult, runErr := run.Run(ctx, logger, runCfg)
+	var outcome json.Marshaler = runResult
+	if runErr != nil {
+		outcome = runResult.Failed(runErr)
+	}
+	if result.Actual, err = json.Marshal(outcome); err != nil {
+		return result, fmt.Errorf("failed to encode replayed result: %w", err)
+	}
+
+	result.Replayed = replayer.Replayed()
+	result.Mismatches = replayer.Mismatches()
+	if result.Replayed < result.Interactions {
+		result.Mismatches = append(result.Mismatches,
+			fmt.Sprintf("%d recorded interactions were not replayed", result.Interactions-result.Replayed))
+	}
+	if !sameJSON(result.Expected, result.Actual) {
+		result.Mismatches = append(result.Mismatches, "result differs from the recording")
+	}
+	if len(result.Mismatches) > 0 {
+		return result, ErrDiverged
+	}
+
+	result.Success = true
+	return result, nil
+}
+
+// enter switches to the recorded working directory and environment,
+// returning a function that restores the current ones.
+func enter(logger *slog.Logger, f fixture.Fixture) (func(), error) {
+	pwd, err := os.Getwd()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get current directory: %w", err)
+	}
+
+	previous := map[string]*string{}
+	for name, value := range f.Environment {
+		if old, ok := os.LookupEnv(name); ok {
+			previous[name] = &old
+		} else {
+			previous[name] = nil
+		}
+		if err := os.Setenv(name, value); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := os.Chdir(f.WorkingDir); err != nil {
+		logger.Warn("Recorded working directory unavailable, replaying from the current one",
+			"dir", f.WorkingDir, "error", err)
+	}
+
+	return func() {
+		for name, value := range previous {
+			if value == nil {
+				_ = os.Unsetenv(name)
+			} else {
+				_ = os.Setenv(name, *value)
+			}
+		}
+		if err := os.Chdir(pwd); err != nil {
+			panic(err)
+		}
+	}, nil
+}
+
+// sameJSON reports whether two JSON documents are semantically equal.
+func sameJSON(a, b json.RawMessage) bool {
+	var x, y any
+	if json.Unmarshal(a, &x) != nil || json.Unmarshal(b, &y) != nil {
+		return false
+	}
+	return reflect.DeepEqual(x, y)
+}
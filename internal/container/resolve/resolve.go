@@ -0,0 +1,178 @@
+// Package resolve maps user-supplied container references to vsl-managed containers.
+package resolve
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/client"
+	cont "github.com/gloo-foo/vsl/internal/container"
+	"github.com/gloo-foo/vsl/internal/git"
+	"github.com/gloo-foo/vsl/internal/paths"
+)
+
+// Last is the reference that selects the most recently created container.
+const Last = "last"
+
+// Reference identifies one or more containers by name, ID prefix, script path,
+// session name, or the special value "last".
+type Reference string
+
+// Resolve returns every vsl-managed container matching ref, newest first.
+// Matches are tried in order of precision: exact ID, exact name, ID prefix,
+// script path, and finally session name; the first kind that matches wins.
+func Resolve(ctx context.Context, dockerCli client.ContainerAPIClient, ref Reference) ([]container.Summary, error) {
+	found, _, err := resolve(ctx, dockerCli, ref)
+	return found, err
+}
+
+// ResolveOne resolves ref to exactly one container, listing the candidates when it is ambiguous.
+func ResolveOne(ctx context.Context, dockerCli client.ContainerAPIClient, ref Reference) (container.Summary, error) {
+	found, err := Resolve(ctx, dockerCli, ref)
+	if err != nil {
+		return container.Summary{}, err
+	}
+	if len(found) > 1 {
+		return container.Summary{}, ambiguous(ref, found)
+	}
+	return found[0], nil
+}
+
+// Select resolves ref like Resolve for commands acting on what they select.
+// Only script paths and session names select several containers: an ID
+// prefix or name matching more than one is ambiguous, and the candidates are
+// listed instead.
+func Select(ctx context.Context, dockerCli client.ContainerAPIClient, ref Reference) ([]container.Summary, error) {
+	found, group, err := resolve(ctx, dockerCli, ref)
+	if err != nil {
+		return nil, err
+	}
+	if len(found) > 1 && !group {
+		return nil, ambiguous(ref, found)
+	}
+	return found, nil
+}
+
+// resolve returns the containers matching ref, newest first, reporting
+// whether they matched by a kind of reference naming a group of containers.
+func resolve(ctx context.Context, dockerCli client.ContainerAPIClient, ref Reference) ([]container.Summary, bool, error) {
+	if ref == "" {
+		return nil, false, fmt.Errorf("empty container reference")
+	}
+
+	args := filters.NewArgs()
+	for _, label := range cont.LabelFilter("") {
+		args.Add("label", label)
+	}
+	containers, err := dockerCli.ContainerList(ctx, container.ListOptions{All: true, Filters: args})
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to list containers: %w", err)
+	}
+	sort.Slice(containers, func(i, j int) bool { return containers[i].Created > containers[j].Created })
+
+	if ref == Last {
+		if len(containers) == 0 {
+			return nil, false, fmt.Errorf("no vsl containers found")
+		}
+		return containers[:1], false, nil
+	}
+
+	for _, m := range matchers(ref) {
+		if found := filter(containers, m.match); len(found) > 0 {
+			return found, m.group, nil
+		}
+	}
+
+	return nil, false, fmt.Errorf("no vsl container matches %q", ref)
+}
+
+// matcher matches containers by one kind of reference.
+type matcher struct {
+	match func(container.Summary) bool
+	group bool // The kind of reference names a group of containers
+}
+
+// matchers returns the matchers for ref, most precise first.
+func matchers(ref Reference) []matcher {
+	s := string(ref)
+	script := s
+	if abs, err := filepath.Abs(s); err == nil {
+		script = abs
+	}
+
+	return []matcher{
+		{match: func(c container.Summary) bool { return c.ID == s }},
+		{match: func(c container.Summary) bool { return hasName(c, s) }},
+		{match: func(c container.Summary) bool { return strings.HasPrefix(c.ID, s) }},
+		{match: func(c container.Summary) bool {
+			return c.Labels[cont.LabelScript] != "" && paths.Equal(c.Labels[cont.LabelScript], script)
+		}, group: true},
+		{match: func(c container.Summary) bool { return c.Labels[cont.LabelSession] == s }, group: true},
+	}
+}
+
+func filter(containers []container.Summary, match func(container.Summary) bool) []container.Summary {
+	var found []container.Summary
+	for _, c := range containers {
+		if match(c) {
+			found = append(found, c)
+		}
+	}
+	return found
+}
+
+func hasName(c container.Summary, name string) bool {
+	for _, n := range c.Names {
+		if strings.TrimPrefix(n, "/") == strings.TrimPrefix(name, "/") {
+			return true
+		}
+	}
+	return false
+}
+
+// ambiguous builds an error listing every container matching ref.
+func ambiguous(ref Reference, found []container.Summary) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "container reference %q is ambiguous, it matches:", ref)
+	for _, c := range found {
+		fmt.Fprintf(&b, "\n  %s  %s  %s  %s", ShortID(c.ID), Name(c), c.Image, c.State)
+	}
+	return fmt.Errorf("%s", b.String())
+}
+
+// ShortID returns the abbreviated form of a container ID.
+func ShortID(id string) string {
+	if len(id) > 12 {
+		return id[:12]
+	}
+	return id
+}
+
+// Name returns the primary name of a container without the leading slash.
+func Name(c container.Summary) string {
+	if len(c.Names) == 0 {
+		return ""
+	}
+	return strings.TrimPrefix(c.Names[0], "/")
+}
+
+// ProjectDir returns the project of the directory dir, as recorded in
+// container labels: its git root, or dir outside repositories.
+func ProjectDir(dir cont.Project) string {
+	if dir == "" {
+		return ""
+	}
+	abs, err := filepath.Abs(string(dir))
+	if err != nil {
+		return string(dir)
+	}
+	if root, err := git.FindRoot(abs); err == nil && root != "" {
+		return string(root)
+	}
+	return abs
+}
@@ -0,0 +1,18 @@
+package restart
+
+import (
+	"github.com/gloo-foo/vsl/internal/app"
+	"github.com/gloo-foo/vsl/internal/app/log"
+)
+
+// Config holds configuration for restarting a detached container.
+type Config struct {
+	Container string // Container reference: name, ID prefix, script path, session or "last"
+
+	// Output and logging
+	Output  app.FilePath
+	Logging log.Config
+}
+
+func (c Config) OutputFilePath() app.FilePath { return c.Output }
+func (c Config) LoggerConfig() log.Config     { return c.Logging }
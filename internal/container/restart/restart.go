@@ -0,0 +1,138 @@
+// Package restart converges detached containers with their configuration,
+// recreating them when it changed and restarting them otherwise.
+package restart
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+
+	cont "github.com/gloo-foo/vsl/internal/container"
+	"github.com/gloo-foo/vsl/internal/container/resolve"
+	"github.com/gloo-foo/vsl/internal/container/run"
+	"github.com/gloo-foo/vsl/internal/docker"
+	"github.com/gloo-foo/vsl/internal/history"
+	"github.com/gloo-foo/vsl/internal/script"
+)
+
+// Result holds the result of a restart.
+type Result struct {
+	Success     bool             `json:"success"`
+	Container   string           `json:"container"`
+	ContainerID cont.ContainerID `json:"container_id,omitempty"`
+	run.Convergence
+	Run     *run.Result `json:"run,omitempty"`
+	Message string      `json:"message"`
+	Error   string      `json:"error,omitempty"`
+}
+
+// MarshalJSON implements json.Marshaler
+func (r Result) MarshalJSON() ([]byte, error) {
+	type Alias Result
+	return json.Marshal((Alias)(r))
+}
+
+// Failed implements app.Failable
+func (r Result) Failed(err error) json.Marshaler {
+	r.Success = false
+	r.Error = err.Error()
+	r.Message = "Restart failed"
+	return r
+}
+
+// Restart re-resolves the configuration of a detached container from the
+// run that created it, re-reading its script, and converges the container
+// with it from the run's directory.
+func Restart(ctx context.Context, logger *slog.Logger, cfg Config) (Result, error) {
+	result := Result{Container: cfg.Container}
+
+	dockerCli, err := docker.NewClient(docker.WithRetry(logger))
+	if err != nil {
+		return result, err
+	}
+	found, err := resolve.ResolveOne(ctx, dockerCli, resolve.Reference(cfg.Container))
+	docker.Close(dockerCli)
+	if err != nil {
+		return result, err
+	}
+	result.ContainerID = cont.ContainerID(found.ID)
+
+	entry, err := lastRun(found.ID)
+	if err != nil {
+		return result, err
+	}
+
+	// Mounts and the script path are resolved relative to the run's directory
+	if entry.HostDir != "" {
+		if err := os.Chdir(entry.HostDir); err != nil {
+			return result, fmt.Errorf("failed to enter the run's directory: %w", err)
+		}
+	}
+	runCfg, err := configuration(entry)
+	if err != nil {
+		return result, err
+	}
+
+	logger.Info("Restarting container", "container", resolve.ShortID(found.ID), "script", runCfg.ScriptPath)
+	runResult, convergence, err := run.Converge(ctx, logger, runCfg, found.ID)
+	result.Run = &runResult
+	result.Convergence = convergence
+	if runResult.ContainerID != "" {
+		result.ContainerID = runResult.ContainerID
+	}
+	if err != nil {
+		return result, err
+	}
+
+	result.Success = true
+	result.Message = runResult.Message
+	return result, nil
+}
+
+// lastRun returns the history entry of the run that created the container.
+func lastRun(id string) (history.Entry, error) {
+	entries, err := history.Load()
+	if err != nil {
+		return history.Entry{}, err
+	}
+	for i := len(entries) - 1; i >= 0; i-- {
+		if entries[i].Kind == history.KindRun && string(entries[i].ContainerID) == id && len(entries[i].Config) > 0 {
+			return entries[i], nil
+		}
+	}
+	return history.Entry{}, fmt.Errorf("no recorded run for container %s", resolve.ShortID(id))
+}
+
+// configuration returns the run configuration of entry, re-reading its
+// script so edits to the script are picked up.
+func configuration(entry history.Entry) (run.Config, error) {
+	var recorded run.Config
+	if err := json.Unmarshal(entry.Config, &recorded); err != nil {
+		return recorded, fmt.Errorf("failed to decode recorded configuration: %w", err)
+	}
+	recorded.Pipe, recorded.Capture, recorded.Attach, recorded.Quiet = false, false, false, false
+	recorded.RecordFixture = ""
+	recorded.Output = ""
+	if recorded.ScriptPath == "" {
+		return recorded, nil
+	}
+
+	// The profile the run was started with is applied again
+	profile := script.Profile
+	if recorded.Profile != "" {
+		profile = recorded.Profile
+	}
+	scriptCfg, err := script.ParseFileProfile(string(recorded.ScriptPath), profile)
+	if err != nil {
+		return recorded, fmt.Errorf("script %s: %w", recorded.ScriptPath, err)
+	}
+	// Settings given on the command line rather than in the script are kept
+	scriptCfg.ScriptPath = recorded.ScriptPath
+	scriptCfg.ScriptArgs = recorded.ScriptArgs
+	scriptCfg.Session = recorded.Session
+	scriptCfg.NoGit = recorded.NoGit
+	scriptCfg.HostCommands = recorded.HostCommands
+	return *scriptCfg, nil
+}
@@ -0,0 +1,23 @@
+package run
+
+import (
+	"fmt"
+	"io"
+)
+
+// printArgv writes the exact entrypoint, command, environment and working
+// directory a process receives, one quoted value per line. Control bytes and
+// non-ASCII characters are escaped, so invisible or look-alike characters
+// that break quoting show up.
+func printArgv(w io.Writer, entrypoint, cmd, env []string, workingDir string) {
+	section := func(name string, values []string) {
+		_, _ = fmt.Fprintf(w, "%s (%d):\n", name, len(values))
+		for i, v := range values {
+			_, _ = fmt.Fprintf(w, "  [%d] %+q\n", i, v)
+		}
+	}
+	section("entrypoint", entrypoint)
+	section("cmd", cmd)
+	section("env", env)
+	_, _ = fmt.Fprintf(w, "workdir: %+q\n", workingDir)
+}
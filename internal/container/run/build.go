@@ -0,0 +1,63 @@
+package run
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+
+	"github.com/docker/docker/client"
+
+	cont "github.com/gloo-foo/vsl/internal/container"
+	"github.com/gloo-foo/vsl/internal/image"
+)
+
+// BuildScript builds the image of a script's build block without running it,
+// tagged and labelled as a run would, so later runs reuse it and its cache.
+func BuildScript(ctx context.Context, logger *slog.Logger, dockerCli client.APIClient, cfg Config) (image.BuildResult, error) {
+	if cfg.Build == nil {
+		return image.BuildResult{}, Fail(ErrorInvalidConfig, fmt.Errorf("script has no build block"))
+	}
+	pwd, err := os.Getwd()
+	if err != nil {
+		return image.BuildResult{}, Fail(ErrorInvalidConfig, fmt.Errorf("failed to get current directory: %w", err))
+	}
+	return buildImage(ctx, logger, dockerCli, cfg, pwd, project(cfg, pwd))
+}
+
+// buildImage builds the image described by cfg.Build, tagged with cfg.Image
+// or a tag derived from the build context. Relative contexts resolve against
+// the script's directory, or pwd outside scripts. Progress goes to stderr,
+// keeping stdout for the JSON result.
+func buildImage(ctx context.Context, logger *slog.Logger, dockerCli client.APIClient, cfg Config, pwd string, proj cont.Project) (image.BuildResult, error) {
+	spec, tag := buildSpec(cfg, pwd, proj)
+	p := provenance(cfg, proj)
+	p.Persistent = true
+	logger.Info("Building image", "tag", tag, "context", spec.Context, "dockerfile", spec.Dockerfile)
+	built, err := image.Build(ctx, dockerCli, spec, tag, cont.Labels(p), os.Stderr)
+	if err != nil {
+		return built, Fail(ErrorBuildFailed, err)
+	}
+	logger.Info("Image built", "tag", tag, "id", built.ID, "buildkit", built.BuildKit, "duration_ms", built.DurationMs)
+	return built, nil
+}
+
+// buildSpec returns the build of cfg with its context resolved, and the tag
+// of the image it builds.
+func buildSpec(cfg Config, pwd string, proj cont.Project) (image.BuildSpec, cont.Image) {
+	spec := *cfg.Build
+	if !filepath.IsAbs(spec.Context) {
+		base := pwd
+		if cfg.ScriptPath != "" {
+			base = filepath.Dir(string(provenance(cfg, proj).Script))
+		}
+		spec.Context = filepath.Join(base, spec.Context)
+	}
+
+	tag := cfg.Image
+	if tag == "" {
+		tag = image.BuildTag(spec)
+	}
+	return spec, tag
+}
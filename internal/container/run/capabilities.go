@@ -0,0 +1,45 @@
+package run
+
+import (
+	"context"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/client"
+	"github.com/gloo-foo/vsl/internal/capability"
+)
+
+// preflight checks the capabilities the configuration requires against the host.
+func preflight(ctx context.Context, dockerCli client.SystemAPIClient, cfg Config) ([]capability.Decision, error) {
+	if len(cfg.Capabilities) == 0 {
+		return nil, nil
+	}
+
+	host, err := capability.Detect(ctx, dockerCli)
+	if err != nil {
+		return nil, Fail(ErrorDaemonUnreachable, err)
+	}
+	decisions, err := capability.Resolve(host, cfg.Capabilities, cfg.CapabilityFallback)
+	if err != nil {
+		return nil, Fail(ErrorCapability, err)
+	}
+	return decisions, nil
+}
+
+// applyCapabilities configures the container to use the granted capabilities.
+func applyCapabilities(hostConfig *container.HostConfig, decisions []capability.Decision) {
+	for _, d := range decisions {
+		if !d.Granted {
+			continue
+		}
+		switch d.Capability {
+		case capability.GPU:
+			hostConfig.DeviceRequests = append(hostConfig.DeviceRequests, container.DeviceRequest{
+				Count:        -1,
+				Capabilities: [][]string{{"gpu"}},
+			})
+		}
+		if d.Runtime != "" {
+			hostConfig.Runtime = d.Runtime
+		}
+	}
+}
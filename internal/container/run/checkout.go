@@ -0,0 +1,51 @@
+package run
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+
+	"github.com/gloo-foo/vsl/internal/git"
+	"github.com/gloo-foo/vsl/internal/state"
+)
+
+// checkoutDir is the directory of the vsl cache holding --repo checkouts.
+const checkoutDir = "checkouts"
+
+// unsafeRefChars are replaced in the ref part of checkout directory names.
+var unsafeRefChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// checkoutRepo shallowly checks out cfg.Ref of cfg.Repo into the vsl cache
+// and returns its directory. Each repository and ref has a directory of its
+// own, reused by later runs so ignored files such as dependencies persist.
+func checkoutRepo(ctx context.Context, logger *slog.Logger, cfg Config) (string, error) {
+	if !git.Available() {
+		return "", git.ErrNotInstalled
+	}
+	cache, err := state.CacheDir()
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256([]byte(cfg.Repo + "\x00" + cfg.Ref))
+	name := hex.EncodeToString(sum[:])[:16]
+	if cfg.Ref != "" {
+		name += "-" + unsafeRefChars.ReplaceAllString(cfg.Ref, "_")
+	}
+	dir := filepath.Join(cache, checkoutDir, name)
+
+	_, statErr := os.Stat(dir)
+	logger.Info("Checking out repository", "repo", cfg.Repo, "ref", cfg.Ref, "path", dir)
+	if err := git.ShallowCheckout(ctx, dir, cfg.Repo, cfg.Ref); err != nil {
+		// Failed first checkouts, e.g. of a mistyped ref, leave nothing behind
+		if os.IsNotExist(statErr) {
+			_ = os.RemoveAll(dir)
+		}
+		return "", fmt.Errorf("failed to check out %s: %w", cfg.Repo, err)
+	}
+	return dir, nil
+}
@@ -2,30 +2,188 @@ package run
 
 import (
 	"github.com/gloo-foo/vsl/internal/app"
+	"github.com/gloo-foo/vsl/internal/app/hook"
 	"github.com/gloo-foo/vsl/internal/app/log"
 	"github.com/gloo-foo/vsl/internal/container"
+	"github.com/gloo-foo/vsl/internal/docker"
+	"github.com/gloo-foo/vsl/internal/fixture"
+	"github.com/gloo-foo/vsl/internal/image"
+	"github.com/gloo-foo/vsl/internal/secret"
+	"github.com/gloo-foo/vsl/internal/units"
 )
 
 // Config holds configuration for running a container.
 type Config struct {
 	// Container configuration
-	Image       container.Image         `up:"image"`        // Docker image to run
+	Image       container.Image         `up:"image"`        // Docker image to run, or the tag of the built image
+	Build       *image.BuildSpec        `up:"build"`        // Dockerfile the image is built from before running
 	Command     []container.Command     `up:"command"`      // Command to execute
 	Entrypoint  []container.Entrypoint  `up:"entrypoint"`   // Container entrypoint
 	WorkingDir  container.WorkingDir    `up:"workdir"`      // Working directory
 	Environment []container.Environment `up:"env"`          // Environment variables
 	Volumes     []container.Volume      `up:"volume"`       // Volume mounts
+	Mounts      []container.MountSpec   `up:"mounts"`       // Advanced mount specifications
+	Caches      []container.CachePath   `up:"caches"`       // Directories backed by per-project cache volumes
+	VolumesFrom []container.VolumesFrom `up:"volumes_from"` // Containers whose volumes are shared with this one
+	Masks       []container.MaskPath    `up:"exclude"`      // Subpaths of mounted directories hidden from the container
+	MaskWith    container.MaskMode      `up:"exclude_with"` // Mount type used to hide excluded paths (tmpfs or volume)
 	User        container.User          `up:"user"`         // User to run as
 	NetworkMode container.NetworkMode   `up:"network_mode"` // Network mode
+	Ports       []container.Port        `up:"ports"`        // Ports published on the host
+	Devices     []container.Device      `up:"devices"`      // Host devices made available to the container
+	CapAdd      []container.CapAdd      `up:"cap_add"`      // Linux capabilities added to the container
+
+	// Commands run in turn instead of the script's command, each in its own
+	// container with the script's mounts and environment
+	Steps []Step `up:"steps"`
+
+	// Record the worktree of the git root before the run, for `vsl restore`
+	Snapshot bool `up:"snapshot"`
+
+	// Remote repository checked out, shallowly at Ref, into the vsl cache
+	// and used as the working directory instead of the current directory
+	Repo string `up:"repo"`
+	Ref  string `up:"ref"`
+
+	// Ref checked out in a temporary worktree of the current repository,
+	// used as the working directory and removed after the run
+	Worktree string `up:"-"`
+
+	// Detached containers started together as the services of a script: the
+	// container name, its aliases on the NetworkMode network and the labels
+	// identifying the group
+	Name           string            `up:"-"`
+	NetworkAliases []string          `up:"-"`
+	Labels         map[string]string `up:"-"`
+
+	// Command the daemon runs in the container to tell whether it is healthy
+	Healthcheck *Healthcheck `up:"healthcheck"`
+
+	// Run the command on the host instead of in a container, subject to HostCommands
+	Host         bool       `up:"host"`
+	HostCommands HostPolicy `up:"-"` // Whether host commands run, need confirmation, or are refused
+
+	// Host commands run before the container starts and after it exits,
+	// subject to HostCommands
+	Before []hook.Hook `up:"before"`
+	After  []hook.Hook `up:"after"`
+
+	// KEY=VALUE file through which pipeline steps pass variables to the next step
+	EnvFromOutput string `up:"env_from_output"`
+
+	// Secrets resolved from their backends when the run starts and injected
+	// as environment variables, redacted from printed and recorded output
+	Secrets []secret.Ref `up:"secret"`
+
+	// Resource limits, accepting human values such as 512m, 2gb or 1h30m
+	Memory  units.Bytes    `up:"memory"`   // Memory limit
+	CPUs    float64        `up:"cpus"`     // CPU limit in cores, such as 1.5
+	ShmSize units.Bytes    `up:"shm_size"` // Size of /dev/shm
+	Timeout units.Duration `up:"timeout"`  // Stop the container when it runs longer
+
+	// Host capabilities required by the script and how to handle missing ones
+	Capabilities       []container.Capability       `up:"capabilities"`
+	CapabilityFallback container.CapabilityStrategy `up:"capability_fallback"`
+
+	// SELinux relabeling (z or Z) applied to the automatic pwd and git mounts
+	SELinuxRelabel container.Relabel `up:"selinux_relabel"`
+
+	// Docker Desktop consistency mode applied to the automatic pwd and git mounts
+	Consistency container.Consistency `up:"consistency"`
 
 	// Behavior flags
-	Interactive bool `up:"interactive"` // Run interactively with TTY
-	NoGit       bool `up:"-"`           // Disable git repository discovery
-	Privileged  bool `up:"privileged"`  // Run in privileged mode
+	Interactive    bool `up:"interactive"`     // Run interactively with TTY
+	NoGit          bool `up:"-"`               // Disable git repository discovery
+	NoMountCwd     bool `up:"no_mount_cwd"`    // Do not mount the current directory
+	NoAutoMounts   bool `up:"no_auto_mounts"`  // Do not mount the current directory or git repository
+	NoInterpolate  bool `up:"-"`               // Use volumes, mounts and script values literally, without expanding variables
+	LenientMounts  bool `up:"lenient_mounts"`  // Skip invalid volumes with a warning instead of failing
+	GitIdentity    bool `up:"git_identity"`    // Mount the host's global git configuration read-only
+	GitCredentials bool `up:"git_credentials"` // Also mount the git credential store (implies GitIdentity)
+	GitLFS         bool `up:"git_lfs"`         // Mount the host's git-lfs binary into the container
+	GitEnv         bool `up:"git_env"`         // Set VSL_GIT_* variables describing the host repository
+	AllowGitHooks  bool `up:"allow_git_hooks"` // Let git in the container run the repository's hooks
+	Privileged     bool `up:"privileged"`      // Run in privileged mode
+	AuditOwnership bool `up:"audit_ownership"` // Report files of the mounted directory left owned by another user
+	FixOwnership   bool `up:"fix_ownership"`   // Give such files back to the invoking user (implies AuditOwnership)
+
+	// Answer git credential requests from the container with the host's
+	// helpers over a socket, without copying credentials into it
+	GitCredentialBridge bool `up:"git_credential_bridge"`
+
+	// Start the container in the background and keep it after it exits
+	Detach bool `up:"detach"`
+
+	// Repository root mounted instead of the one discovered above the working
+	// directory, and directories that upward discovery does not enter in
+	// addition to GIT_CEILING_DIRECTORIES and the parent of the home directory
+	GitRoot     container.GitRoot `up:"git_root"`
+	GitCeilings []string          `up:"git_ceiling"`
+
+	// Directory levels below the working directory searched for nested
+	// repositories; 0 uses DefaultGitDepth and a negative depth disables the search
+	GitDepth int `up:"git_depth"`
+
+	// Tracked files above which only the working directory and git directory
+	// are mounted rather than the whole git root; 0 uses DefaultGitRootMaxFiles
+	// and a negative limit disables the check
+	GitRootMaxFiles   int  `up:"git_root_max_files"`
+	ForceGitRootMount bool `up:"force_git_root_mount"` // Mount the git root regardless of its size
+
+	// Mount git directories read-only, so the container can read history but
+	// not rewrite refs, commit or install hooks; GitReadonlyRoot also makes
+	// the whole git root read-only, including the working directory within it
+	GitReadonly     bool `up:"git_readonly"`
+	GitReadonlyRoot bool `up:"git_readonly_root"`
+
+	// Hide the files of the mounted worktrees that git does not track, such
+	// as .env files and build output, so only tracked files reach the
+	// container; MaskIgnored only hides the directories ignore files exclude
+	GitTrackedOnly bool `up:"git_tracked_only"`
+	MaskIgnored    bool `up:"mask_ignored"`
+
+	// Session grouping
+	Session container.Session `up:"-"` // Session label shared by resources created together
 
 	// Script handling
-	ScriptPath container.ScriptPath `up:"-"` // Path to UP script file (if running as interpreter)
-	ScriptArgs []string             `up:"-"` // Arguments passed to the script
+	ScriptPath container.ScriptPath `up:"-"`      // Path to UP script file (if running as interpreter)
+	ScriptArgs []string             `up:"-"`      // Arguments passed to the script
+	ArgNames   []string             `up:"args"`   // Names of the arguments in order, referenced as ${args.NAME}
+	Inputs     []Input              `up:"inputs"` // Parameters the script declares, set as --NAME value
+	Profile    string               `up:"-"`      // Profile of the script applied
+
+	// Project defaults overlaid on the script when it runs as a task, as
+	// listed in the explain plan
+	TaskDefaults string `up:"-"`
+
+	// Command run in the container after start to record toolchain versions
+	Probe string `up:"tool_version_cmd"`
+
+	// Container output handling
+	Attach  bool `up:"-"` // Connect the terminal's stdin and stdout to the container
+	Capture bool `up:"-"` // Include container output in the JSON result
+	Pipe    bool `up:"-"` // Stream container output to stdout/stderr (implies Quiet)
+	Quiet   bool `up:"-"` // Do not write the JSON result to stdout
+
+	// Print the exact argv, environment and working directory to stderr before running
+	PrintArgv bool `up:"-"`
+
+	// Print the plan of the run and ask for confirmation before running it
+	Explain bool `up:"-"`
+
+	// Render the resolved run in this format instead of running it
+	Inspect ExportFormat `up:"-"`
+
+	// Where the settings come from by script key, one origin per item for
+	// lists; settings without one have their default
+	Origins map[string][]Origin `up:"-" json:"-"`
+
+	// Do not record the run in the history, for runs vsl makes on its own behalf
+	NoHistory bool `up:"-"`
+
+	// Record/replay of daemon API interactions
+	RecordFixture fixture.Dir             `up:"-"`          // Directory to record a replayable fixture into
+	Transport     docker.TransportWrapper `up:"-" json:"-"` // Wraps the daemon transport (set by record and replay)
 
 	// Output and logging
 	Output  app.FilePath `up:"-"`
@@ -34,3 +192,4 @@ type Config struct {
 
 func (c Config) OutputFilePath() app.FilePath { return c.Output }
 func (c Config) LoggerConfig() log.Config     { return c.Logging }
+func (c Config) QuietOutput() bool            { return c.Quiet || c.Pipe || c.Inspect != "" }
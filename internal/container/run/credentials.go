@@ -0,0 +1,63 @@
+package run
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"runtime"
+
+	cont "github.com/gloo-foo/vsl/internal/container"
+	"github.com/gloo-foo/vsl/internal/git"
+	mnt "github.com/gloo-foo/vsl/internal/mount"
+)
+
+// Where the git credential bridge is exposed in the container.
+const (
+	CredentialSocketPath = "/run/vsl/git-credential.sock"
+	HelperBinaryPath     = "/usr/local/libexec/vsl/vsl"
+)
+
+// CredentialHelperCommand is the vsl command git in the container runs as
+// its credential helper.
+const CredentialHelperCommand = "git-credential-helper"
+
+// bridgeCredentials starts serving the host's git credential helpers for
+// the remotes of the repositories at roots and returns the bridge with the
+// mounts and git settings that make git in the container use it. The helper
+// git runs is the vsl binary itself, which only runs in the container when
+// the host is Linux on the daemon's architecture.
+func bridgeCredentials(ctx context.Context, logger *slog.Logger, roots []cont.GitRoot) (*git.CredentialBridge, []mnt.Mount, []gitSetting, error) {
+	if runtime.GOOS != "linux" {
+		return nil, nil, nil, fmt.Errorf("the helper run in the container is the vsl binary, which needs a Linux host")
+	}
+	binary, err := os.Executable()
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to locate the vsl binary: %w", err)
+	}
+	if binary, err = filepath.EvalSymlinks(binary); err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to locate the vsl binary: %w", err)
+	}
+
+	bridge, err := git.StartCredentialBridge(ctx, logger, git.RemoteTargets(ctx, roots))
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	helper := mnt.Bind(binary, HelperBinaryPath, "")
+	helper.ReadOnly = true
+	mounts := []mnt.Mount{mnt.Bind(bridge.Socket, CredentialSocketPath, ""), helper}
+	settings := []gitSetting{
+		// An empty helper drops those of mounted configuration files
+		{key: "credential.helper", value: ""},
+		{key: "credential.helper", value: fmt.Sprintf("%s %s --socket %s", HelperBinaryPath, CredentialHelperCommand, CredentialSocketPath)},
+	}
+	return bridge, mounts, settings, nil
+}
+
+// closeBridge stops the credential bridge once the container has exited.
+func closeBridge(logger *slog.Logger, bridge *git.CredentialBridge) {
+	if err := bridge.Close(); err != nil {
+		logger.Warn("Failed to close git credential bridge", "error", err)
+	}
+}
@@ -0,0 +1,66 @@
+package run
+
+import (
+	"context"
+	"runtime"
+
+	"github.com/docker/docker/api/types/mount"
+	"github.com/docker/docker/client"
+
+	mnt "github.com/gloo-foo/vsl/internal/mount"
+)
+
+// dockerDesktop is the operating system reported by Docker Desktop daemons.
+const dockerDesktop = "Docker Desktop"
+
+// Warnings about bind mount consistency.
+const (
+	warnConsistencyIgnored = "bind mount consistency options only take effect with Docker Desktop on macOS and are ignored here"
+	warnDesktopBinds       = "bind mounts on Docker Desktop for Mac can dominate build time in large repositories; enable VirtioFS file sharing or use --consistency cached"
+)
+
+// translator returns the path translator for the host and daemon. The daemon
+// is only queried on Windows, where Docker Desktop and native engines differ.
+func translator(ctx context.Context, dockerCli client.SystemAPIClient) mnt.Translator {
+	t := mnt.Translator{Platform: mnt.CurrentPlatform()}
+	if t.Platform == mnt.PlatformWindows {
+		if info, err := dockerCli.Info(ctx); err == nil {
+			t.DockerDesktop = info.OperatingSystem == dockerDesktop
+		}
+	}
+	return t
+}
+
+// consistencyWarnings checks bind mount consistency modes against the daemon.
+// Consistency only matters for Docker Desktop on macOS, so the daemon is only
+// queried when a mode is set or vsl itself runs on macOS.
+func consistencyWarnings(ctx context.Context, dockerCli client.SystemAPIClient, mounts []mnt.Mount) []string {
+	binds, consistent := false, false
+	for _, m := range mounts {
+		if m.Type != mount.TypeBind {
+			continue
+		}
+		binds = true
+		if m.Consistency != "" && m.Consistency != mount.ConsistencyDefault {
+			consistent = true
+		}
+	}
+	if !consistent && runtime.GOOS != "darwin" {
+		return nil
+	}
+
+	info, err := dockerCli.Info(ctx)
+	if err != nil {
+		// Daemon errors are reported by the calls that need it
+		return nil
+	}
+	desktopMac := info.OperatingSystem == dockerDesktop && runtime.GOOS == "darwin"
+
+	switch {
+	case consistent && !desktopMac:
+		return []string{warnConsistencyIgnored}
+	case !consistent && desktopMac && binds:
+		return []string{warnDesktopBinds}
+	}
+	return nil
+}
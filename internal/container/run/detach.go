@@ -0,0 +1,321 @@
+package run
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/client"
+	cont "github.com/gloo-foo/vsl/internal/container"
+	"github.com/gloo-foo/vsl/internal/docker"
+	mnt "github.com/gloo-foo/vsl/internal/mount"
+	"github.com/gloo-foo/vsl/internal/secret"
+)
+
+// Spec is the resolved configuration of a detached container, recorded in
+// its labels so restarts can tell what changed.
+type Spec struct {
+	Image       string   `json:"image"`
+	ImageID     string   `json:"image_id,omitempty"`
+	Entrypoint  []string `json:"entrypoint,omitempty"`
+	Command     []string `json:"command,omitempty"`
+	Env         []string `json:"env,omitempty"`
+	WorkingDir  string   `json:"working_dir,omitempty"`
+	User        string   `json:"user,omitempty"`
+	Mounts      []string `json:"mounts,omitempty"`
+	NetworkMode string   `json:"network_mode,omitempty"`
+	Privileged  bool     `json:"privileged,omitempty"`
+	Memory      int64    `json:"memory,omitempty"`
+	ShmSize     int64    `json:"shm_size,omitempty"`
+	NanoCPUs    int64    `json:"nano_cpus,omitempty"`
+	Ports       []string `json:"ports,omitempty"`
+	Devices     []string `json:"devices,omitempty"`
+	CapAdd      []string `json:"cap_add,omitempty"`
+	Tty         bool     `json:"tty,omitempty"`
+}
+
+// Change is a difference between the configuration a detached container was
+// created with and its current configuration.
+type Change struct {
+	Field   string   `json:"field"`
+	Removed []string `json:"removed,omitempty"`
+	Added   []string `json:"added,omitempty"`
+}
+
+// String describes the change on one line.
+func (c Change) String() string {
+	parts := []string{c.Field + ":"}
+	for _, r := range c.Removed {
+		parts = append(parts, "-"+r)
+	}
+	for _, a := range c.Added {
+		parts = append(parts, "+"+a)
+	}
+	return strings.Join(parts, " ")
+}
+
+// ConvergeAction is what Converge did to a detached container.
+type ConvergeAction string
+
+// Convergence actions.
+const (
+	ActionRestarted ConvergeAction = "restarted" // Configuration unchanged, the container was restarted
+	ActionRecreated ConvergeAction = "recreated" // Configuration changed, the container was replaced
+)
+
+// Convergence describes how a detached container was brought in line with
+// its configuration.
+type Convergence struct {
+	Action   ConvergeAction   `json:"action"`
+	Previous cont.ContainerID `json:"previous_id,omitempty"` // Replaced container
+	Changes  []Change         `json:"changes,omitempty"`
+}
+
+// Converge resolves cfg as a detached run and compares it with the existing
+// container: when the image, mounts, environment or other settings changed,
+// the container is replaced by one with the same name, otherwise it is just
+// restarted.
+func Converge(ctx context.Context, logger *slog.Logger, cfg Config, existing string) (Result, Convergence, error) {
+	var convergence Convergence
+	cfg.Detach = true
+	result := Result{
+		Image:      cfg.Image,
+		Mounts:     []MountInfo{},
+		ScriptPath: cfg.ScriptPath,
+		Session:    cfg.Session,
+	}
+
+	if err := validate(cfg); err != nil {
+		return result, convergence, err
+	}
+	if cfg.Host {
+		return result, convergence, Fail(ErrorInvalidConfig, fmt.Errorf("host steps run without a container to restart"))
+	}
+
+	pwd, err := os.Getwd()
+	if err != nil {
+		return result, convergence, Fail(ErrorInvalidConfig, fmt.Errorf("failed to get current directory: %w", err))
+	}
+	requested := cfg
+	system, discoveredRoot, err := findRoot(cfg, pwd)
+	if err != nil && cfg.GitRoot != "" {
+		return result, convergence, Fail(ErrorInvalidConfig, err)
+	}
+	cfg, err = expandConfig(cfg, mnt.NewVars(pwd, discoveredRoot))
+	if err != nil {
+		return result, convergence, Fail(ErrorInvalidConfig, err)
+	}
+	if err := pinImage(logger, &cfg, pwd); err != nil {
+		return result, convergence, err
+	}
+	result.Image = cfg.Image
+
+	describeGit(ctx, logger, cfg, system, discoveredRoot, &result)
+
+	p, err := newPlan(ctx, logger, cfg, pwd, &result)
+	if err != nil {
+		return result, convergence, err
+	}
+
+	dockerCli, err := docker.NewClient(docker.WithRetry(logger), docker.WithTransport(cfg.Transport))
+	if err != nil {
+		return result, convergence, Fail(ErrorDaemonUnreachable, err)
+	}
+	defer docker.Close(dockerCli)
+
+	containerConfig, hostConfig, err := p.containerConfig(ctx, logger, dockerCli, cfg, pwd, &result)
+	if err != nil {
+		return result, convergence, err
+	}
+
+	current, err := dockerCli.ContainerInspect(ctx, existing)
+	if err != nil {
+		return result, convergence, Fail(ErrorInvalidConfig, fmt.Errorf("failed to inspect container: %w", err))
+	}
+	desired, err := spec(ctx, dockerCli, containerConfig, hostConfig, p.secrets)
+	if err != nil {
+		return result, convergence, Fail(ErrorCreateFailed, err)
+	}
+	var recorded Spec
+	if label := current.Config.Labels[cont.LabelSpec]; label != "" {
+		if err := json.Unmarshal([]byte(label), &recorded); err != nil {
+			logger.Warn("Unreadable container configuration label", "error", err)
+		}
+	}
+	recorded.ImageID = current.Image
+
+	convergence.Changes = diff(recorded, desired)
+	if len(convergence.Changes) == 0 {
+		logger.Info("Configuration unchanged, restarting container", "id", current.ID)
+		if err := dockerCli.ContainerRestart(ctx, current.ID, container.StopOptions{}); err != nil {
+			return result, convergence, Fail(ErrorStartFailed, fmt.Errorf("failed to restart container: %w", err))
+		}
+		convergence.Action = ActionRestarted
+		result.ContainerID = cont.ContainerID(current.ID)
+		result.Success = true
+		result.Message = "Container restarted"
+		return result, convergence, nil
+	}
+
+	for _, c := range convergence.Changes {
+		logger.Info("Configuration changed", "change", c.String())
+	}
+	logger.Info("Recreating container", "id", current.ID)
+	if err := dockerCli.ContainerRemove(ctx, current.ID, container.RemoveOptions{Force: true}); err != nil {
+		return result, convergence, Fail(ErrorCreateFailed, fmt.Errorf("failed to remove container: %w", err))
+	}
+	convergence.Action = ActionRecreated
+	convergence.Previous = cont.ContainerID(current.ID)
+
+	err = detach(ctx, logger, dockerCli, containerConfig, hostConfig, networkingConfig(cfg), strings.TrimPrefix(current.Name, "/"), p.secrets, &result)
+	addHistory(logger, requested, pwd, p.cmd, result, err)
+	if err != nil {
+		return result, convergence, err
+	}
+	result.Success = true
+	result.Message = "Container recreated"
+	return result, convergence, nil
+}
+
+// detach creates the container, named name unless it is empty, and starts it
+// without waiting for it to exit. Its resolved configuration is recorded in a
+// label for Converge.
+func detach(ctx context.Context, logger *slog.Logger, dockerCli *client.Client, containerConfig *container.Config, hostConfig *container.HostConfig, networking *network.NetworkingConfig, name string, secrets secret.Values, result *Result) error {
+	s, err := spec(ctx, dockerCli, containerConfig, hostConfig, secrets)
+	if err != nil {
+		return Fail(ErrorCreateFailed, err)
+	}
+	label, err := json.Marshal(s)
+	if err != nil {
+		return Fail(ErrorCreateFailed, fmt.Errorf("failed to encode container configuration: %w", err))
+	}
+	containerConfig.Labels[cont.LabelSpec] = string(label)
+
+	logger.Info("Creating detached container", "name", name)
+	resp, err := dockerCli.ContainerCreate(ctx, containerConfig, hostConfig, networking, nil, name)
+	if err != nil {
+		return Fail(ErrorCreateFailed, fmt.Errorf("failed to create container: %w", err))
+	}
+	result.ContainerID = cont.ContainerID(resp.ID)
+
+	logger.Info("Starting container", "id", result.ContainerID)
+	if err := dockerCli.ContainerStart(ctx, resp.ID, container.StartOptions{}); err != nil {
+		return Fail(ErrorStartFailed, fmt.Errorf("failed to start container: %w", err))
+	}
+	return nil
+}
+
+// spec describes the container configuration, resolving the image to its ID
+// and replacing secret values by their fingerprints.
+func spec(ctx context.Context, dockerCli client.ImageAPIClient, containerConfig *container.Config, hostConfig *container.HostConfig, secrets secret.Values) (Spec, error) {
+	s := Spec{
+		Image:       containerConfig.Image,
+		Entrypoint:  containerConfig.Entrypoint,
+		Command:     containerConfig.Cmd,
+		Env:         secrets.FingerprintEnv(containerConfig.Env),
+		WorkingDir:  containerConfig.WorkingDir,
+		User:        containerConfig.User,
+		NetworkMode: string(hostConfig.NetworkMode),
+		Privileged:  hostConfig.Privileged,
+		Memory:      hostConfig.Memory,
+		ShmSize:     hostConfig.ShmSize,
+		NanoCPUs:    hostConfig.NanoCPUs,
+		CapAdd:      hostConfig.CapAdd,
+		Tty:         containerConfig.Tty,
+	}
+	inspect, err := dockerCli.ImageInspect(ctx, containerConfig.Image)
+	if err != nil {
+		return s, fmt.Errorf("failed to inspect image %s: %w", containerConfig.Image, err)
+	}
+	s.ImageID = inspect.ID
+
+	for _, m := range hostConfig.Mounts {
+		desc := fmt.Sprintf("%s:%s:%s", m.Type, m.Source, m.Target)
+		if m.ReadOnly {
+			desc += ":ro"
+		}
+		s.Mounts = append(s.Mounts, desc)
+	}
+	s.Mounts = append(s.Mounts, hostConfig.Binds...)
+	for port, bindings := range hostConfig.PortBindings {
+		for _, b := range bindings {
+			s.Ports = append(s.Ports, fmt.Sprintf("%s:%s:%s", b.HostIP, b.HostPort, port))
+		}
+	}
+	slices.Sort(s.Ports)
+	for _, d := range hostConfig.Devices {
+		s.Devices = append(s.Devices, fmt.Sprintf("%s:%s:%s", d.PathOnHost, d.PathInContainer, d.CgroupPermissions))
+	}
+	for _, from := range hostConfig.VolumesFrom {
+		s.Mounts = append(s.Mounts, "volumes-from:"+from)
+	}
+	return s, nil
+}
+
+// diff lists the differences between the recorded and desired configuration.
+func diff(recorded, desired Spec) []Change {
+	var changes []Change
+	scalar := func(field, old, current string) {
+		if old == current {
+			return
+		}
+		c := Change{Field: field}
+		if old != "" {
+			c.Removed = []string{old}
+		}
+		if current != "" {
+			c.Added = []string{current}
+		}
+		changes = append(changes, c)
+	}
+	set := func(field string, old, current []string) {
+		c := Change{Field: field, Removed: missing(old, current), Added: missing(current, old)}
+		if len(c.Removed) > 0 || len(c.Added) > 0 {
+			changes = append(changes, c)
+		}
+	}
+
+	scalar("image", recorded.Image, desired.Image)
+	scalar("image_id", recorded.ImageID, desired.ImageID)
+	scalar("entrypoint", argvString(recorded.Entrypoint), argvString(desired.Entrypoint))
+	scalar("command", argvString(recorded.Command), argvString(desired.Command))
+	set("env", recorded.Env, desired.Env)
+	scalar("working_dir", recorded.WorkingDir, desired.WorkingDir)
+	scalar("user", recorded.User, desired.User)
+	set("mounts", recorded.Mounts, desired.Mounts)
+	scalar("network_mode", recorded.NetworkMode, desired.NetworkMode)
+	scalar("privileged", fmt.Sprint(recorded.Privileged), fmt.Sprint(desired.Privileged))
+	scalar("memory", fmt.Sprint(recorded.Memory), fmt.Sprint(desired.Memory))
+	scalar("shm_size", fmt.Sprint(recorded.ShmSize), fmt.Sprint(desired.ShmSize))
+	scalar("nano_cpus", fmt.Sprint(recorded.NanoCPUs), fmt.Sprint(desired.NanoCPUs))
+	set("ports", recorded.Ports, desired.Ports)
+	set("devices", recorded.Devices, desired.Devices)
+	set("cap_add", recorded.CapAdd, desired.CapAdd)
+	scalar("tty", fmt.Sprint(recorded.Tty), fmt.Sprint(desired.Tty))
+	return changes
+}
+
+// missing returns the items of a that are not in b.
+func missing(a, b []string) []string {
+	var result []string
+	for _, item := range a {
+		if !slices.Contains(b, item) {
+			result = append(result, item)
+		}
+	}
+	return result
+}
+
+// argvString renders an argument list unambiguously, or "" when it is empty.
+func argvString(argv []string) string {
+	if len(argv) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%q", argv)
+}
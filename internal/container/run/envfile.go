@@ -0,0 +1,76 @@
+package run
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	mnt "github.com/gloo-foo/vsl/internal/mount"
+)
+
+// EnvOutputPath is where the --env-from-output file is mounted in the
+// container. Its path is also exported as EnvOutputVar, so a step can append
+// KEY=VALUE lines for the steps after it.
+const (
+	EnvOutputPath = "/run/vsl/env-output"
+	EnvOutputVar  = "VSL_ENV_OUTPUT"
+)
+
+// envOutput prepares the file steps pass variables through: it is created if
+// missing, its KEY=VALUE lines are returned for the container environment,
+// and it is mounted read-write at EnvOutputPath. Relative paths resolve against pwd.
+func envOutput(file, pwd string) ([]string, mnt.Mount, error) {
+	if !filepath.IsAbs(file) {
+		file = filepath.Join(pwd, file)
+	}
+	if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+		return nil, mnt.Mount{}, fmt.Errorf("env output %s: %w", file, err)
+	}
+	f, err := os.OpenFile(file, os.O_RDONLY|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, mnt.Mount{}, fmt.Errorf("env output %s: %w", file, err)
+	}
+	defer func(f *os.File) {
+		err := f.Close()
+		if err != nil {
+			panic(err)
+		}
+	}(f)
+
+	var env []string
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		key, _, ok := strings.Cut(text, "=")
+		if !ok || key == "" || strings.ContainsAny(key, " \t") {
+			return nil, mnt.Mount{}, fmt.Errorf("env output %s:%d: expected KEY=VALUE, got %q", file, line, text)
+		}
+		env = append(env, text)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, mnt.Mount{}, fmt.Errorf("env output %s: %w", file, err)
+	}
+
+	return env, mnt.Bind(file, EnvOutputPath, ""), nil
+}
+
+// resetEnvOutput empties the file steps pass variables through, so a run of
+// steps starts without the variables of earlier runs. Relative paths
+// resolve against pwd.
+func resetEnvOutput(file, pwd string) error {
+	if !filepath.IsAbs(file) {
+		file = filepath.Join(pwd, file)
+	}
+	if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+		return fmt.Errorf("env output %s: %w", file, err)
+	}
+	if err := os.WriteFile(file, nil, 0o600); err != nil {
+		return fmt.Errorf("env output %s: %w", file, err)
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package run
+
+import (
+	"errors"
+
+	cerrdefs "github.com/containerd/errdefs"
+	"github.com/docker/docker/client"
+)
+
+// ErrorCategory classifies a run failure for machine consumption.
+type ErrorCategory string
+
+// Run failure categories.
+const (
+	ErrorDaemonUnreachable ErrorCategory = "daemon_unreachable"
+	ErrorImageNotFound     ErrorCategory = "image_not_found"
+	ErrorInvalidConfig     ErrorCategory = "invalid_config"
+	ErrorCapability        ErrorCategory = "capability_missing"
+	ErrorSecretUnavailable ErrorCategory = "secret_unavailable"
+	ErrorCheckoutFailed    ErrorCategory = "checkout_failed"
+	ErrorSnapshotFailed    ErrorCategory = "snapshot_failed"
+	ErrorBuildFailed       ErrorCategory = "build_failed"
+	ErrorCreateFailed      ErrorCategory = "create_failed"
+	ErrorStartFailed       ErrorCategory = "start_failed"
+	ErrorWaitFailed        ErrorCategory = "wait_failed"
+	ErrorExited            ErrorCategory = "exited_nonzero"
+	ErrorTimeout           ErrorCategory = "timeout"
+	ErrorHookFailed        ErrorCategory = "hook_failed"
+	ErrorDeclined          ErrorCategory = "declined"
+	ErrorUnknown           ErrorCategory = "unknown"
+)
+
+// Error is a run failure tagged with the stage it occurred in.
+type Error struct {
+	Category ErrorCategory
+	Err      error
+}
+
+func (e *Error) Error() string { return e.Err.Error() }
+func (e *Error) Unwrap() error { return e.Err }
+
+// Fail tags err with a category, refined by the Docker error type when possible.
+func Fail(category ErrorCategory, err error) error {
+	switch {
+	case client.IsErrConnectionFailed(err):
+		category = ErrorDaemonUnreachable
+	case category == ErrorCreateFailed && cerrdefs.IsNotFound(err):
+		category = ErrorImageNotFound
+	}
+	return &Error{Category: category, Err: err}
+}
+
+// ErrorInfo describes a failure in the JSON result.
+type ErrorInfo struct {
+	Category ErrorCategory `json:"category"`
+	Message  string        `json:"message"`
+}
+
+// NewErrorInfo builds the JSON error description for err.
+func NewErrorInfo(err error) *ErrorInfo {
+	info := &ErrorInfo{Category: ErrorUnknown, Message: err.Error()}
+	var runErr *Error
+	if errors.As(err, &runErr) {
+		info.Category = runErr.Category
+	}
+	return info
+}
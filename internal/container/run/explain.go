@@ -0,0 +1,247 @@
+package run
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	cerrdefs "github.com/containerd/errdefs"
+	"github.com/docker/docker/client"
+	"github.com/gloo-foo/vsl/internal/app/hook"
+	cont "github.com/gloo-foo/vsl/internal/container"
+	"github.com/gloo-foo/vsl/internal/docker"
+	mnt "github.com/gloo-foo/vsl/internal/mount"
+	"golang.org/x/term"
+)
+
+// ErrDeclined is returned when the run is not confirmed after its plan is
+// explained.
+var ErrDeclined = errors.New("run not confirmed")
+
+// explainRun writes the plan of a whole run on stderr and asks to confirm
+// it: the project defaults of the task it runs, the commands its hooks and
+// host steps run on the host, the checkout or snapshot it makes, and the
+// container of the run or of each of its steps.
+// Containers are resolved as inspection does, without fetching secrets or
+// bridging credentials, so nothing runs before the run is confirmed.
+func explainRun(ctx context.Context, logger *slog.Logger, cfg Config) error {
+	if err := validate(cfg); err != nil {
+		return err
+	}
+	pwd, err := os.Getwd()
+	if err != nil {
+		return Fail(ErrorInvalidConfig, fmt.Errorf("failed to get current directory: %w", err))
+	}
+	// Without the daemon, images are reported as not inspected
+	var dockerCli client.ImageAPIClient
+	if c, err := docker.NewClient(docker.WithRetry(logger), docker.WithTransport(cfg.Transport)); err == nil {
+		defer docker.Close(c)
+		dockerCli = c
+	}
+
+	bw := bufio.NewWriter(os.Stderr)
+	if cfg.ScriptPath != "" {
+		_, _ = fmt.Fprintf(bw, "Script:   %s\n", cfg.ScriptPath)
+	}
+	if cfg.TaskDefaults != "" {
+		_, _ = fmt.Fprintf(bw, "Defaults: %s\n", cfg.TaskDefaults)
+	}
+	if cfg.Repo != "" {
+		ref := cfg.Ref
+		if ref == "" {
+			ref = "its default branch"
+		}
+		_, _ = fmt.Fprintf(bw, "Checkout: %s at %s, run in place of the current directory\n", cfg.Repo, ref)
+	}
+	if cfg.Worktree != "" {
+		_, _ = fmt.Fprintf(bw, "Worktree: %s, run in place of the current directory\n", cfg.Worktree)
+	}
+	if cfg.Snapshot {
+		_, _ = fmt.Fprintln(bw, "Snapshot: the worktree is recorded before the run")
+	}
+	explainHooks(bw, "Before hooks", cfg.Before)
+
+	if len(cfg.Steps) == 0 {
+		_, _ = fmt.Fprintln(bw)
+		err = explainContainer(ctx, logger, bw, dockerCli, cfg, pwd)
+	}
+	for i, step := range cfg.Steps {
+		if err != nil {
+			break
+		}
+		if step.Name == "" {
+			step.Name = fmt.Sprint(i + 1)
+		}
+		_, _ = fmt.Fprintf(bw, "\nStep %s:\n", step.Name)
+		err = explainContainer(ctx, logger, bw, dockerCli, stepConfig(cfg, step), pwd)
+	}
+	explainHooks(bw, "After hooks", cfg.After)
+	_ = bw.Flush()
+	if err != nil {
+		return err
+	}
+
+	if err := confirmRun(); err != nil {
+		return Fail(ErrorDeclined, err)
+	}
+	return nil
+}
+
+// explainHooks lists the host commands of hooks under title.
+func explainHooks(w io.Writer, title string, hooks []hook.Hook) {
+	if len(hooks) == 0 {
+		return
+	}
+	_, _ = fmt.Fprintf(w, "\n%s, run on the host (%d):\n", title, len(hooks))
+	for _, h := range hooks {
+		command := strings.Join(h.Command, " ")
+		if h.WorkingDir != "" {
+			command += " (in " + h.WorkingDir + ")"
+		}
+		_, _ = fmt.Fprintf(w, "  %s\n", command)
+	}
+}
+
+// explainContainer resolves the run of cfg and writes its plan, or the
+// command it runs on the host for host steps.
+func explainContainer(ctx context.Context, logger *slog.Logger, w io.Writer, dockerCli client.ImageAPIClient, cfg Config, pwd string) error {
+	system, root, err := findRoot(cfg, pwd)
+	if err != nil && cfg.GitRoot != "" {
+		return Fail(ErrorInvalidConfig, err)
+	}
+	cfg, err = expandConfig(cfg, mnt.NewVars(pwd, root))
+	if err != nil {
+		return Fail(ErrorInvalidConfig, err)
+	}
+	if cfg.Host {
+		_, _ = fmt.Fprintf(w, "Host:     runs on the host, without a container: %s\n", strings.Join(hostArgv(cfg), " "))
+		return nil
+	}
+	if err := pinImage(logger, &cfg, pwd); err != nil {
+		return err
+	}
+
+	var result Result
+	describeGit(ctx, logger, cfg, system, root, &result)
+	cfg.Inspect = ExportDockerRun
+	p, err := newPlan(ctx, logger, cfg, pwd, &result)
+	if err != nil {
+		return err
+	}
+	explain(ctx, w, dockerCli, cfg, p)
+	return nil
+}
+
+// explain writes the plan of a container for a reader deciding whether to
+// run a script they did not write: the image and whether it is built,
+// present or missing, what the container can reach, its mounts and its
+// environment, with the values of secrets masked.
+func explain(ctx context.Context, w io.Writer, dockerCli client.ImageAPIClient, cfg Config, p plan) {
+	bw := bufio.NewWriter(w)
+	defer func() { _ = bw.Flush() }()
+
+	_, _ = fmt.Fprintf(bw, "Image:    %s\n", imageDecision(ctx, dockerCli, cfg, p.image))
+	command := append(append([]string{}, p.entrypoint...), p.cmd...)
+	if len(command) == 0 {
+		command = []string{"(the image's default command)"}
+	}
+	_, _ = fmt.Fprintf(bw, "Command:  %s\n", strings.Join(command, " "))
+	if p.workingDir != "" {
+		_, _ = fmt.Fprintf(bw, "Workdir:  %s\n", p.workingDir)
+	}
+	user := string(cfg.User)
+	if user == "" {
+		user = "(the image's user)"
+	}
+	_, _ = fmt.Fprintf(bw, "User:     %s\n", user)
+	network := string(cfg.NetworkMode)
+	if network == "" {
+		network = "default (bridge, with internet access)"
+	}
+	_, _ = fmt.Fprintf(bw, "Network:  %s\n", network)
+	for _, port := range cfg.Ports {
+		_, _ = fmt.Fprintf(bw, "Port:     %s\n", port)
+	}
+	if cfg.Privileged {
+		_, _ = fmt.Fprintln(bw, "Access:   privileged, with full access to the host's devices")
+	}
+	for _, capability := range cfg.CapAdd {
+		_, _ = fmt.Fprintf(bw, "Access:   capability %s\n", capability)
+	}
+	for _, device := range cfg.Devices {
+		_, _ = fmt.Fprintf(bw, "Access:   device %s\n", device)
+	}
+
+	_, _ = fmt.Fprintf(bw, "\nMounts (%d):\n", len(p.mounts))
+	tw := tabwriter.NewWriter(bw, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "  TYPE\tSOURCE\tTARGET\tACCESS")
+	for _, m := range p.mounts {
+		access := "read-write"
+		if m.ReadOnly {
+			access = "read-only"
+		}
+		_, _ = fmt.Fprintf(tw, "  %s\t%s\t%s\t%s\n", m.Type, m.Source, m.Target, access)
+	}
+	_ = tw.Flush()
+
+	_, _ = fmt.Fprintf(bw, "\nEnvironment (%d):\n", len(p.env))
+	tw = tabwriter.NewWriter(bw, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "  NAME\tVALUE")
+	for _, env := range p.secrets.RedactEnv(p.env) {
+		name, value, _ := strings.Cut(env, "=")
+		_, _ = fmt.Fprintf(tw, "  %s\t%s\n", name, value)
+	}
+	_ = tw.Flush()
+	if len(p.secrets) > 0 {
+		names := make([]string, len(p.secrets))
+		for i, s := range p.secrets {
+			names[i] = s.Variable()
+		}
+		_, _ = fmt.Fprintf(bw, "  Values of secrets are masked: %s\n", strings.Join(names, ", "))
+	}
+}
+
+// imageDecision describes where the image of a run comes from: built from
+// its Dockerfile, or whether it is already present on the daemon.
+func imageDecision(ctx context.Context, dockerCli client.ImageAPIClient, cfg Config, ref cont.Image) string {
+	if cfg.Build != nil {
+		dockerfile := cfg.Build.Dockerfile
+		if dockerfile == "" {
+			dockerfile = "Dockerfile"
+		}
+		return fmt.Sprintf("built from %s in %s", dockerfile, filepath.Clean(cfg.Build.Context))
+	}
+	if dockerCli == nil {
+		return fmt.Sprintf("%s (could not be inspected: the daemon is unreachable)", ref)
+	}
+	inspect, err := dockerCli.ImageInspect(ctx, string(ref))
+	switch {
+	case err == nil:
+		return fmt.Sprintf("%s, present locally (%s)", ref, inspect.ID)
+	case cerrdefs.IsNotFound(err):
+		return fmt.Sprintf("%s, not present locally: pull it with docker pull %s", ref, ref)
+	}
+	return fmt.Sprintf("%s (could not be inspected: %v)", ref, err)
+}
+
+// confirmRun asks on the terminal whether to go ahead with the explained
+// run.
+func confirmRun() error {
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		return fmt.Errorf("%w: confirmation needs a terminal", ErrDeclined)
+	}
+	_, _ = fmt.Fprint(os.Stderr, "\nRun it? [y/N] ")
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return nil
+	}
+	return fmt.Errorf("%w: declined", ErrDeclined)
+}
@@ -0,0 +1,572 @@
+package run
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/mount"
+	"github.com/docker/docker/api/types/network"
+	"github.com/gloo-foo/vsl/internal/capability"
+	cont "github.com/gloo-foo/vsl/internal/container"
+	"github.com/gloo-foo/vsl/internal/image"
+	"gopkg.in/yaml.v3"
+)
+
+// ExportFormat is the form inspection renders a run in.
+type ExportFormat string
+
+// Export formats.
+const (
+	ExportDockerRun ExportFormat = "docker-run" // docker run command line, after docker build when the run builds its image
+	ExportCompose   ExportFormat = "compose"    // Compose file with the run as its only service
+	ExportSettings  ExportFormat = "settings"   // Settings of the run by script key, with where each comes from
+)
+
+// export is a run resolved for rendering.
+type export struct {
+	name       string
+	build      *image.BuildSpec
+	config     *container.Config
+	host       *container.HostConfig
+	networking *network.NetworkingConfig
+	secrets    map[string]bool // Variables left to the environment the export runs in
+}
+
+// inspect resolves the run as a run would, without the daemon, and writes it
+// to stdout in the format requested instead of running it. Capabilities the
+// run requires are assumed available, and secrets are passed from the
+// environment by name rather than written out.
+func inspect(ctx context.Context, logger *slog.Logger, cfg Config, pwd string, result Result) (Result, error) {
+	if cfg.Inspect == ExportSettings {
+		result.Export = settings(cfg)
+		_, _ = fmt.Fprint(os.Stdout, result.Export)
+		result.Success = true
+		result.Message = "Settings of the run with their origins"
+		return result, nil
+	}
+
+	p, err := newPlan(ctx, logger, cfg, pwd, &result)
+	if err != nil {
+		return result, err
+	}
+
+	e := export{name: exportName(cfg, p.proj), secrets: map[string]bool{}}
+	if cfg.Build != nil {
+		spec, tag := buildSpec(cfg, pwd, p.proj)
+		e.build = &spec
+		p.image = tag
+		result.Image = tag
+	}
+	decisions := make([]capability.Decision, len(cfg.Capabilities))
+	for i, c := range cfg.Capabilities {
+		decisions[i] = capability.Decision{Capability: c, Granted: true}
+	}
+	e.config, e.host, err = p.createConfig(cfg, &result, decisions)
+	if err != nil {
+		return result, err
+	}
+	e.networking = networkingConfig(cfg)
+	for _, s := range p.secrets {
+		e.secrets[s.Variable()] = true
+	}
+
+	switch cfg.Inspect {
+	case ExportCompose:
+		result.Export, err = e.compose()
+		if err != nil {
+			return result, Fail(ErrorInvalidConfig, err)
+		}
+	default:
+		result.Export = e.dockerRun()
+	}
+	_, _ = fmt.Fprint(os.Stdout, result.Export)
+
+	result.Success = true
+	result.Message = "Run exported as " + string(cfg.Inspect)
+	return result, nil
+}
+
+// exportName names the exported container: its name, or else the script or
+// project it runs for.
+func exportName(cfg Config, proj cont.Project) string {
+	switch {
+	case cfg.Name != "":
+		return cfg.Name
+	case cfg.ScriptPath != "":
+		base := filepath.Base(string(cfg.ScriptPath))
+		return strings.TrimSuffix(base, filepath.Ext(base))
+	case proj != "":
+		return filepath.Base(string(proj))
+	}
+	return "vsl"
+}
+
+// labels returns the labels of the container other than vsl's own, which
+// would have vsl treat the exported container as one of its runs.
+func (e export) labels() []string {
+	var labels []string
+	for key, value := range e.config.Labels {
+		if !strings.HasPrefix(key, cont.LabelPrefix) {
+			labels = append(labels, key+"="+value)
+		}
+	}
+	sort.Strings(labels)
+	return labels
+}
+
+// env returns the environment of the container, with secrets by name only.
+func (e export) env() []string {
+	env := make([]string, len(e.config.Env))
+	for i, entry := range e.config.Env {
+		name, _, _ := strings.Cut(entry, "=")
+		if e.secrets[name] {
+			entry = name
+		}
+		env[i] = entry
+	}
+	return env
+}
+
+// ports returns the published ports as docker run -p specs.
+func (e export) ports() []string {
+	var ports []string
+	for port, bindings := range e.host.PortBindings {
+		for _, b := range bindings {
+			spec := b.HostPort + ":" + string(port)
+			if b.HostIP != "" {
+				spec = b.HostIP + ":" + spec
+			}
+			ports = append(ports, strings.TrimSuffix(spec, "/tcp"))
+		}
+	}
+	sort.Strings(ports)
+	return ports
+}
+
+// devices returns the device mappings as host:container:permissions.
+func (e export) devices() []string {
+	devices := make([]string, len(e.host.Devices))
+	for i, d := range e.host.Devices {
+		devices[i] = d.PathOnHost + ":" + d.PathInContainer + ":" + d.CgroupPermissions
+	}
+	return devices
+}
+
+// gpus reports whether the container is given the GPUs of the host.
+func (e export) gpus() bool {
+	for _, r := range e.host.DeviceRequests {
+		for _, caps := range r.Capabilities {
+			for _, c := range caps {
+				if c == "gpu" {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// healthTest returns the health check as a shell command, the form docker
+// run accepts.
+func (e export) healthTest() string {
+	test := e.config.Healthcheck.Test
+	if len(test) > 1 && test[0] == "CMD-SHELL" {
+		return test[1]
+	}
+	if len(test) > 1 && test[0] == "CMD" {
+		return cont.ShellJoin(test[1:])
+	}
+	return ""
+}
+
+// dockerRun renders the export as a docker run command line, one option per
+// line, after the docker build of its image when it has one.
+func (e export) dockerRun() string {
+	var b strings.Builder
+	if e.build != nil {
+		args := []string{"docker", "build", "-t", e.config.Image}
+		if e.build.Dockerfile != "" {
+			args = append(args, "-f", filepath.Join(e.build.Context, e.build.Dockerfile))
+		}
+		if e.build.Target != "" {
+			args = append(args, "--target", e.build.Target)
+		}
+		keys := make([]string, 0, len(e.build.Args))
+		for key := range e.build.Args {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			args = append(args, "--build-arg", key+"="+e.build.Args[key])
+		}
+		for _, from := range e.build.CacheFrom {
+			args = append(args, "--cache-from", from)
+		}
+		if e.build.NoCache {
+			args = append(args, "--no-cache")
+		}
+		if e.build.Pull {
+			args = append(args, "--pull")
+		}
+		b.WriteString(cont.ShellJoin(append(args, e.build.Context)) + "\n")
+	}
+
+	lines := [][]string{{"docker", "run"}}
+	option := func(args ...string) { lines = append(lines, args) }
+	if e.host.AutoRemove {
+		option("--rm")
+	} else {
+		option("--detach")
+	}
+	if e.config.OpenStdin && e.config.Tty {
+		option("-it")
+	}
+	if e.name != "" {
+		option("--name", e.name)
+	}
+	if e.config.User != "" {
+		option("--user", e.config.User)
+	}
+	if e.config.WorkingDir != "" {
+		option("--workdir", e.config.WorkingDir)
+	}
+	for _, entry := range e.env() {
+		option("--env", entry)
+	}
+	for _, label := range e.labels() {
+		option("--label", label)
+	}
+	for _, bind := range e.host.Binds {
+		option("--volume", bind)
+	}
+	for _, m := range e.host.Mounts {
+		option("--mount", mountOption(m))
+	}
+	for _, from := range e.host.VolumesFrom {
+		option("--volumes-from", from)
+	}
+	if e.host.NetworkMode != "" {
+		option("--network", string(e.host.NetworkMode))
+	}
+	if e.networking != nil {
+		for _, endpoint := range e.networking.EndpointsConfig {
+			for _, alias := range endpoint.Aliases {
+				option("--network-alias", alias)
+			}
+		}
+	}
+	for _, port := range e.ports() {
+		option("--publish", port)
+	}
+	if e.host.Privileged {
+		option("--privileged")
+	}
+	for _, c := range e.host.CapAdd {
+		option("--cap-add", c)
+	}
+	for _, d := range e.devices() {
+		option("--device", d)
+	}
+	if e.gpus() {
+		option("--gpus", "all")
+	}
+	if e.host.Runtime != "" {
+		option("--runtime", e.host.Runtime)
+	}
+	if e.host.Memory > 0 {
+		option("--memory", strconv.FormatInt(e.host.Memory, 10))
+	}
+	if e.host.ShmSize > 0 {
+		option("--shm-size", strconv.FormatInt(e.host.ShmSize, 10))
+	}
+	if e.host.NanoCPUs > 0 {
+		option("--cpus", strconv.FormatFloat(float64(e.host.NanoCPUs)/1e9, 'f', -1, 64))
+	}
+	if h := e.config.Healthcheck; h != nil {
+		if test := e.healthTest(); test != "" {
+			option("--health-cmd", test)
+		}
+		if h.Interval > 0 {
+			option("--health-interval", h.Interval.String())
+		}
+		if h.Timeout > 0 {
+			option("--health-timeout", h.Timeout.String())
+		}
+		if h.StartPeriod > 0 {
+			option("--health-start-period", h.StartPeriod.String())
+		}
+		if h.Retries > 0 {
+			option("--health-retries", strconv.Itoa(h.Retries))
+		}
+	}
+
+	// docker run takes the entrypoint's executable only, its arguments
+	// lead the command
+	cmd := []string(e.config.Cmd)
+	if len(e.config.Entrypoint) > 0 {
+		option("--entrypoint", e.config.Entrypoint[0])
+		cmd = append(append([]string{}, e.config.Entrypoint[1:]...), cmd...)
+	}
+	option(append([]string{e.config.Image}, cmd...)...)
+
+	for i, line := range lines {
+		if i > 0 {
+			b.WriteString(" \\\n  ")
+		}
+		b.WriteString(cont.ShellJoin(line))
+	}
+	b.WriteString("\n")
+	return b.String()
+}
+
+// mountOption renders a mount in the docker run --mount syntax.
+func mountOption(m mount.Mount) string {
+	fields := []string{"type=" + string(m.Type)}
+	if m.Source != "" {
+		fields = append(fields, "source="+m.Source)
+	}
+	fields = append(fields, "target="+m.Target)
+	if m.ReadOnly {
+		fields = append(fields, "readonly")
+	}
+	if m.Consistency != "" {
+		fields = append(fields, "consistency="+string(m.Consistency))
+	}
+	if m.BindOptions != nil && m.BindOptions.Propagation != "" {
+		fields = append(fields, "bind-propagation="+string(m.BindOptions.Propagation))
+	}
+	if m.VolumeOptions != nil && m.VolumeOptions.NoCopy {
+		fields = append(fields, "volume-nocopy")
+	}
+	if m.TmpfsOptions != nil && m.TmpfsOptions.SizeBytes > 0 {
+		fields = append(fields, "tmpfs-size="+strconv.FormatInt(m.TmpfsOptions.SizeBytes, 10))
+	}
+	if m.TmpfsOptions != nil && m.TmpfsOptions.Mode != 0 {
+		fields = append(fields, "tmpfs-mode="+strconv.FormatUint(uint64(m.TmpfsOptions.Mode), 8))
+	}
+	return strings.Join(fields, ",")
+}
+
+// composeFile is the compose file an export renders as.
+type composeFile struct {
+	Services map[string]composeService `yaml:"services"`
+	Volumes  map[string]composeVolume  `yaml:"volumes,omitempty"`
+}
+
+// composeService is the compose service of an export.
+type composeService struct {
+	Image       string              `yaml:"image"`
+	Build       *composeBuild       `yaml:"build,omitempty"`
+	Entrypoint  []string            `yaml:"entrypoint,omitempty"`
+	Command     []string            `yaml:"command,omitempty"`
+	WorkingDir  string              `yaml:"working_dir,omitempty"`
+	User        string              `yaml:"user,omitempty"`
+	Environment []string            `yaml:"environment,omitempty"`
+	Labels      []string            `yaml:"labels,omitempty"`
+	Volumes     []composeMount      `yaml:"volumes,omitempty"`
+	VolumesFrom []string            `yaml:"volumes_from,omitempty"`
+	NetworkMode string              `yaml:"network_mode,omitempty"`
+	Ports       []string            `yaml:"ports,omitempty"`
+	Privileged  bool                `yaml:"privileged,omitempty"`
+	CapAdd      []string            `yaml:"cap_add,omitempty"`
+	Devices     []string            `yaml:"devices,omitempty"`
+	Runtime     string              `yaml:"runtime,omitempty"`
+	MemLimit    int64               `yaml:"mem_limit,omitempty"`
+	ShmSize     int64               `yaml:"shm_size,omitempty"`
+	CPUs        float64             `yaml:"cpus,omitempty"`
+	Tty         bool                `yaml:"tty,omitempty"`
+	StdinOpen   bool                `yaml:"stdin_open,omitempty"`
+	Healthcheck *composeHealthcheck `yaml:"healthcheck,omitempty"`
+	Deploy      *composeDeploy      `yaml:"deploy,omitempty"`
+}
+
+// composeBuild is the build section of a compose service.
+type composeBuild struct {
+	Context    string            `yaml:"context"`
+	Dockerfile string            `yaml:"dockerfile,omitempty"`
+	Target     string            `yaml:"target,omitempty"`
+	Args       map[string]string `yaml:"args,omitempty"`
+	CacheFrom  []string          `yaml:"cache_from,omitempty"`
+	NoCache    bool              `yaml:"no_cache,omitempty"`
+	Pull       bool              `yaml:"pull,omitempty"`
+}
+
+// composeMount is a mount in the long syntax of compose volumes.
+type composeMount struct {
+	Type        string               `yaml:"type"`
+	Source      string               `yaml:"source,omitempty"`
+	Target      string               `yaml:"target"`
+	ReadOnly    bool                 `yaml:"read_only,omitempty"`
+	Bind        *composeBindOptions  `yaml:"bind,omitempty"`
+	Tmpfs       *composeTmpfsOptions `yaml:"tmpfs,omitempty"`
+	Consistency string               `yaml:"consistency,omitempty"`
+}
+
+// composeBindOptions are the options of a compose bind mount.
+type composeBindOptions struct {
+	Propagation string `yaml:"propagation,omitempty"`
+	SELinux     string `yaml:"selinux,omitempty"`
+}
+
+// composeTmpfsOptions are the options of a compose tmpfs mount.
+type composeTmpfsOptions struct {
+	Size int64 `yaml:"size,omitempty"`
+}
+
+// composeVolume declares a named volume under its own name, so compose does
+// not prefix it with the project's.
+type composeVolume struct {
+	Name string `yaml:"name"`
+}
+
+// composeHealthcheck is the health check of a compose service.
+type composeHealthcheck struct {
+	Test        []string `yaml:"test"`
+	Interval    string   `yaml:"interval,omitempty"`
+	Timeout     string   `yaml:"timeout,omitempty"`
+	StartPeriod string   `yaml:"start_period,omitempty"`
+	Retries     int      `yaml:"retries,omitempty"`
+}
+
+// composeDeploy reserves devices of the host for a compose service.
+type composeDeploy struct {
+	Resources struct {
+		Reservations struct {
+			Devices []composeDevice `yaml:"devices"`
+		} `yaml:"reservations"`
+	} `yaml:"resources"`
+}
+
+// composeDevice is a device reservation of a compose service.
+type composeDevice struct {
+	Capabilities []string `yaml:"capabilities"`
+	Count        string   `yaml:"count"`
+}
+
+// compose renders the export as a compose file with a single service.
+func (e export) compose() (string, error) {
+	service := composeService{
+		Image:       e.config.Image,
+		Entrypoint:  e.config.Entrypoint,
+		Command:     e.config.Cmd,
+		WorkingDir:  e.config.WorkingDir,
+		User:        e.config.User,
+		Environment: e.env(),
+		Labels:      e.labels(),
+		VolumesFrom: e.host.VolumesFrom,
+		NetworkMode: string(e.host.NetworkMode),
+		Ports:       e.ports(),
+		Privileged:  e.host.Privileged,
+		CapAdd:      e.host.CapAdd,
+		Devices:     e.devices(),
+		Runtime:     e.host.Runtime,
+		MemLimit:    e.host.Memory,
+		ShmSize:     e.host.ShmSize,
+		CPUs:        float64(e.host.NanoCPUs) / 1e9,
+		Tty:         e.config.Tty,
+		StdinOpen:   e.config.OpenStdin,
+	}
+	if e.build != nil {
+		service.Build = &composeBuild{
+			Context:    e.build.Context,
+			Dockerfile: e.build.Dockerfile,
+			Target:     e.build.Target,
+			Args:       e.build.Args,
+			CacheFrom:  e.build.CacheFrom,
+			NoCache:    e.build.NoCache,
+			Pull:       e.build.Pull,
+		}
+	}
+
+	file := composeFile{Services: map[string]composeService{}}
+	for _, bind := range e.host.Binds {
+		// Binds carry SELinux relabeling, which the short syntax keeps
+		service.Volumes = append(service.Volumes, composeBind(bind))
+	}
+	for _, m := range e.host.Mounts {
+		cm := composeMount{Type: string(m.Type), Source: m.Source, Target: m.Target, ReadOnly: m.ReadOnly, Consistency: string(m.Consistency)}
+		if m.BindOptions != nil && m.BindOptions.Propagation != "" {
+			cm.Bind = &composeBindOptions{Propagation: string(m.BindOptions.Propagation)}
+		}
+		if m.TmpfsOptions != nil && m.TmpfsOptions.SizeBytes > 0 {
+			cm.Tmpfs = &composeTmpfsOptions{Size: m.TmpfsOptions.SizeBytes}
+		}
+		if m.Type == mount.TypeVolume && m.Source != "" {
+			if file.Volumes == nil {
+				file.Volumes = map[string]composeVolume{}
+			}
+			file.Volumes[m.Source] = composeVolume{Name: m.Source}
+		}
+		service.Volumes = append(service.Volumes, cm)
+	}
+	if h := e.config.Healthcheck; h != nil {
+		service.Healthcheck = &composeHealthcheck{
+			Test:        h.Test,
+			Interval:    durationString(h.Interval),
+			Timeout:     durationString(h.Timeout),
+			StartPeriod: durationString(h.StartPeriod),
+			Retries:     h.Retries,
+		}
+	}
+	if e.gpus() {
+		service.Deploy = &composeDeploy{}
+		service.Deploy.Resources.Reservations.Devices = []composeDevice{{Capabilities: []string{"gpu"}, Count: "all"}}
+	}
+	file.Services[e.name] = service
+
+	var b strings.Builder
+	encoder := yaml.NewEncoder(&b)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(file); err != nil {
+		return "", fmt.Errorf("failed to render compose file: %w", err)
+	}
+	return b.String(), nil
+}
+
+// composeBind converts a bind in the source:target:options syntax to the
+// long syntax.
+func composeBind(bind string) composeMount {
+	parts := strings.Split(bind, ":")
+	m := composeMount{Type: string(mount.TypeBind), Source: parts[0]}
+	if len(parts) > 1 {
+		m.Target = parts[1]
+	}
+	if len(parts) > 2 {
+		for _, option := range strings.Split(parts[2], ",") {
+			switch option {
+			case "ro":
+				m.ReadOnly = true
+			case "z", "Z":
+				if m.Bind == nil {
+					m.Bind = &composeBindOptions{}
+				}
+				m.Bind.SELinux = option
+			case "consistent", "cached", "delegated":
+				m.Consistency = option
+			case "rw", "":
+			default:
+				if m.Bind == nil {
+					m.Bind = &composeBindOptions{}
+				}
+				m.Bind.Propagation = option
+			}
+		}
+	}
+	return m
+}
+
+// durationString renders a duration for compose, empty when unset.
+func durationString(d time.Duration) string {
+	if d <= 0 {
+		return ""
+	}
+	return d.String()
+}
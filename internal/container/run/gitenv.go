@@ -0,0 +1,145 @@
+package run
+
+import (
+	"context"
+	"log/slog"
+	"strconv"
+	"strings"
+
+	cont "github.com/gloo-foo/vsl/internal/container"
+	"github.com/gloo-foo/vsl/internal/git"
+	mnt "github.com/gloo-foo/vsl/internal/mount"
+	"github.com/gloo-foo/vsl/internal/vcs"
+)
+
+// Variables describing the host repository, set in the container with GitEnv.
+const (
+	GitBranchVar    = "VSL_GIT_BRANCH"     // Checked-out branch, empty for a detached HEAD
+	GitCommitVar    = "VSL_GIT_COMMIT"     // Full SHA of HEAD
+	GitDirtyVar     = "VSL_GIT_DIRTY"      // true when the worktree has uncommitted changes
+	GitRemoteURLVar = "VSL_GIT_REMOTE_URL" // URL of the origin remote
+)
+
+// shortCommitLength is the length of abbreviated commit SHAs.
+const shortCommitLength = 7
+
+// GitInfo describes the host repository a run started in, as provenance for
+// tooling consuming the result.
+type GitInfo struct {
+	Root   cont.GitRoot `json:"root"`
+	Branch string       `json:"branch,omitempty"` // Empty for a detached HEAD
+	Commit string       `json:"commit,omitempty"` // Short SHA of HEAD
+	Dirty  bool         `json:"dirty"`            // The worktree has uncommitted changes
+	Remote string       `json:"remote,omitempty"` // URL of the origin remote
+
+	sha      string // Full SHA of HEAD
+	branched bool   // Branch was determined
+	checked  bool   // Dirty was determined
+}
+
+// describeGit records the repository at root, managed by system, in the
+// result. Values that cannot be determined are left out; they are reported
+// as warnings when GitEnv asks for them and only logged otherwise.
+func describeGit(ctx context.Context, logger *slog.Logger, cfg Config, system vcs.VCS, root cont.GitRoot, result *Result) {
+	if root == "" || cfg.NoGit {
+		return
+	}
+	result.VCS = system.Name()
+	info, warnings := gitInfo(ctx, system, root)
+	for _, w := range warnings {
+		if cfg.GitEnv {
+			logger.Warn("Incomplete git metadata", "detail", w)
+			result.Warnings = append(result.Warnings, w)
+		} else {
+			logger.Debug("Incomplete git metadata", "detail", w)
+		}
+	}
+	result.Git = &info
+}
+
+// gitInfo describes the repository at root, managed by system. A missing
+// default remote is not a problem; other values that cannot be determined
+// are described in the returned warnings.
+func gitInfo(ctx context.Context, system vcs.VCS, root cont.GitRoot) (GitInfo, []string) {
+	info := GitInfo{Root: root}
+	var warnings []string
+	prefix := system.Name() + " metadata: "
+
+	if branch, err := system.Branch(ctx, root); err != nil {
+		warnings = append(warnings, prefix+"branch: "+err.Error())
+	} else {
+		info.Branch, info.branched = branch, true
+	}
+	if commit, err := system.Commit(ctx, root); err != nil {
+		warnings = append(warnings, prefix+"commit: "+err.Error())
+	} else {
+		info.sha = commit
+		info.Commit = commit[:min(len(commit), shortCommitLength)]
+	}
+	if dirty, err := system.Dirty(ctx, root); err != nil {
+		warnings = append(warnings, prefix+"dirty: "+err.Error())
+	} else {
+		info.Dirty, info.checked = dirty, true
+	}
+	if url, err := system.RemoteURL(ctx, root); err == nil {
+		info.Remote = url
+	}
+	return info, warnings
+}
+
+// env returns the git metadata variables, so scripts can stamp artifacts
+// without git in the image. Values that could not be determined are left out.
+func (g GitInfo) env() []string {
+	var env []string
+	if g.branched {
+		env = append(env, GitBranchVar+"="+g.Branch)
+	}
+	if g.sha != "" {
+		env = append(env, GitCommitVar+"="+g.sha)
+	}
+	if g.checked {
+		env = append(env, GitDirtyVar+"="+strconv.FormatBool(g.Dirty))
+	}
+	if g.Remote != "" {
+		env = append(env, GitRemoteURLVar+"="+g.Remote)
+	}
+	return env
+}
+
+// forwardedGitEnv returns the GIT_DIR, GIT_WORK_TREE and GIT_COMMON_DIR
+// settings of the host as absolute container paths, so git in the container
+// uses the same split repository, which discovery mounts at its host paths.
+// They are only forwarded when they locate the repository at root.
+func forwardedGitEnv(root cont.GitRoot) []string {
+	overrides := git.EnvOverrides()
+	if !overrides.Applies(root) {
+		return nil
+	}
+	env := overrides.Env()
+	for i, e := range env {
+		name, value, _ := strings.Cut(e, "=")
+		env[i] = name + "=" + mnt.ContainerPath(value)
+	}
+	return env
+}
+
+// gitSetting is a git configuration entry set in the container.
+type gitSetting struct {
+	key   string
+	value string
+}
+
+// hooksDisabled points git's hooks at /dev/null, so the hooks in the mounted
+// repository, written for the host, do not run in the container.
+var hooksDisabled = gitSetting{key: "core.hooksPath", value: "/dev/null"}
+
+// gitConfigEnv returns variables applying settings the way git -c does,
+// after every configuration file (git 2.31 and newer).
+func gitConfigEnv(settings []gitSetting) []string {
+	env := []string{"GIT_CONFIG_COUNT=" + strconv.Itoa(len(settings))}
+	for i, s := range settings {
+		n := strconv.Itoa(i)
+		env = append(env, "GIT_CONFIG_KEY_"+n+"="+s.key, "GIT_CONFIG_VALUE_"+n+"="+s.value)
+	}
+	return env
+}
@@ -0,0 +1,52 @@
+package run
+
+import (
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/network"
+	"github.com/gloo-foo/vsl/internal/units"
+)
+
+// Healthcheck is the command the daemon runs in the container to tell
+// whether it is healthy, such as
+// healthcheck { test pg_isready -U postgres, interval 2s, retries 30 }.
+type Healthcheck struct {
+	Test        []string       `json:"test"`                   // Command run with the image's shell, or the argv when Exec
+	Exec        bool           `json:"exec,omitempty"`         // Run Test directly instead of through the shell
+	Interval    units.Duration `json:"interval,omitempty"`     // Time between checks (30s by default)
+	Timeout     units.Duration `json:"timeout,omitempty"`      // Time a check may take (30s by default)
+	StartPeriod units.Duration `json:"start_period,omitempty"` // Time failures do not count after start
+	Retries     int            `json:"retries,omitempty"`      // Failures in a row before the container is unhealthy (3 by default)
+}
+
+// config returns the daemon's health configuration, nil when h is nil to
+// keep the image's.
+func (h *Healthcheck) config() *container.HealthConfig {
+	if h == nil {
+		return nil
+	}
+	test := []string{"CMD-SHELL", strings.Join(h.Test, " ")}
+	if h.Exec {
+		test = append([]string{"CMD"}, h.Test...)
+	}
+	return &container.HealthConfig{
+		Test:        test,
+		Interval:    time.Duration(h.Interval),
+		Timeout:     time.Duration(h.Timeout),
+		StartPeriod: time.Duration(h.StartPeriod),
+		Retries:     h.Retries,
+	}
+}
+
+// networkingConfig gives the container its aliases on the network it joins,
+// nil when it has none.
+func networkingConfig(cfg Config) *network.NetworkingConfig {
+	if len(cfg.NetworkAliases) == 0 || cfg.NetworkMode == "" {
+		return nil
+	}
+	return &network.NetworkingConfig{EndpointsConfig: map[string]*network.EndpointSettings{
+		string(cfg.NetworkMode): {Aliases: cfg.NetworkAliases},
+	}}
+}
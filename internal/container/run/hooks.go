@@ -0,0 +1,65 @@
+package run
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+
+	"github.com/gloo-foo/vsl/internal/app/hook"
+)
+
+// runHooked runs the before hooks of cfg, then the run, then its after
+// hooks, which run whether the run succeeded or not, and also when it was
+// interrupted. A failing before hook stops the run and a failing after hook
+// fails it, unless they continue on error. After hooks see the outcome in
+// VSL_SUCCESS, VSL_EXIT_CODE and VSL_CONTAINER_ID.
+func runHooked(ctx context.Context, logger *slog.Logger, cfg Config) (Result, error) {
+	pwd, err := os.Getwd()
+	if err != nil {
+		return Result{}, Fail(ErrorInvalidConfig, fmt.Errorf("failed to get current directory: %w", err))
+	}
+	runner := hook.Runner{
+		Logger: logger,
+		Dir:    pwd,
+		Allow:  func(argv []string) error { return allowHost(cfg.HostCommands, argv) },
+		Output: os.Stderr,
+	}
+	before, after := cfg.Before, cfg.After
+	cfg.Before, cfg.After = nil, nil
+
+	hooks, warnings, err := runner.Run(ctx, hook.Before, before)
+	if err != nil {
+		result := Result{
+			Image:      cfg.Image,
+			Mounts:     []MountInfo{},
+			ScriptPath: cfg.ScriptPath,
+			Profile:    cfg.Profile,
+			Session:    cfg.Session,
+			Hooks:      hooks,
+			Warnings:   warnings,
+		}
+		return result, Fail(ErrorHookFailed, err)
+	}
+
+	result, err := Run(ctx, logger, cfg)
+	result.Hooks = append(hooks, result.Hooks...)
+	result.Warnings = append(warnings, result.Warnings...)
+
+	runner.Env = []string{"VSL_SUCCESS=" + strconv.FormatBool(err == nil)}
+	if result.ExitCode != nil {
+		runner.Env = append(runner.Env, "VSL_EXIT_CODE="+strconv.Itoa(*result.ExitCode))
+	}
+	if result.ContainerID != "" {
+		runner.Env = append(runner.Env, "VSL_CONTAINER_ID="+string(result.ContainerID))
+	}
+	hooks, warnings, afterErr := runner.Run(context.WithoutCancel(ctx), hook.After, after)
+	result.Hooks = append(result.Hooks, hooks...)
+	result.Warnings = append(result.Warnings, warnings...)
+	if afterErr != nil && err == nil {
+		result.Success = false
+		return result, Fail(ErrorHookFailed, afterErr)
+	}
+	return result, err
+}
@@ -0,0 +1,170 @@
+package run
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"golang.org/x/term"
+
+	"github.com/gloo-foo/vsl/internal/container/stream"
+	"github.com/gloo-foo/vsl/internal/secret"
+)
+
+// HostPolicy decides whether scripts may run commands on the host.
+type HostPolicy string
+
+// Host command policies.
+const (
+	HostAllow   HostPolicy = "allow"   // Run host commands without asking
+	HostConfirm HostPolicy = "confirm" // Ask on the terminal before each host command (default)
+	HostDeny    HostPolicy = "deny"    // Refuse host commands
+)
+
+// ErrHostDenied is returned when the host command policy refuses a command.
+var ErrHostDenied = errors.New("host command not allowed")
+
+// runHost runs the script command on the host, for steps such as a registry
+// login or opening a browser that cannot happen in a container. It shares
+// the logging, timing, output handling and history of container runs.
+func runHost(ctx context.Context, logger *slog.Logger, cfg Config, requested Config, pwd string, result Result) (Result, error) {
+	result.Host = true
+
+	argv := hostArgv(cfg)
+	if len(argv) == 0 {
+		return result, Fail(ErrorInvalidConfig, fmt.Errorf("host step has no command"))
+	}
+
+	if err := allowHost(cfg.HostCommands, argv); err != nil {
+		return result, Fail(ErrorInvalidConfig, err)
+	}
+
+	workingDir := string(cfg.WorkingDir)
+	if workingDir == "" {
+		workingDir = pwd
+	}
+	result.WorkingDir = cfg.WorkingDir
+	// Later entries win: the host environment, then imported variables, the
+	// script's environment and secrets
+	env := os.Environ()
+	inherited := len(env)
+	if cfg.EnvFromOutput != "" {
+		imported, m, err := envOutput(cfg.EnvFromOutput, pwd)
+		if err != nil {
+			return result, Fail(ErrorInvalidConfig, err)
+		}
+		for _, e := range imported {
+			key, _, _ := strings.Cut(e, "=")
+			result.ImportedEnv = append(result.ImportedEnv, key)
+		}
+		env = append(append(env, imported...), EnvOutputVar+"="+m.Source)
+	}
+	for _, e := range cfg.Environment {
+		env = append(env, string(e))
+	}
+	secrets, err := secret.Resolve(ctx, cfg.Secrets, func(argv []string) error { return allowHost(cfg.HostCommands, argv) })
+	if err != nil {
+		return result, Fail(ErrorSecretUnavailable, err)
+	}
+	if len(secrets) > 0 {
+		logger.Info("Injecting secrets", "secrets", secrets.Names())
+		result.Secrets = secrets.Names()
+		env = append(env, secrets.Env()...)
+	}
+
+	if cfg.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(cfg.Timeout))
+		defer cancel()
+	}
+	if cfg.PrintArgv {
+		// The host environment is inherited; only the variables added here are shown
+		printArgv(os.Stderr, nil, argv, secrets.RedactEnv(env[inherited:]), workingDir)
+	}
+	cmd := exec.CommandContext(ctx, argv[0], argv[1:]...)
+	cmd.Dir = workingDir
+	cmd.Env = env
+
+	// Interactive steps own the terminal, like an attached container
+	mode := stream.Mode{Pipe: cfg.Pipe, Capture: cfg.Capture}
+	captured := func() *stream.Output { return nil }
+	if cfg.Interactive && !mode.Enabled() {
+		cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	} else {
+		cmd.Stdout, cmd.Stderr, captured = stream.Writers(mode)
+	}
+
+	logger.Info("Running on the host", "command", argv, "working_dir", workingDir)
+	started := time.Now()
+	err = cmd.Run()
+	result.DurationMs = time.Since(started).Milliseconds()
+	result.Output = captured()
+	redactOutput(result.Output, secrets)
+
+	var exitErr *exec.ExitError
+	switch {
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		err = Fail(ErrorTimeout, fmt.Errorf("host command exceeded timeout of %s", cfg.Timeout))
+	case errors.As(err, &exitErr):
+		code := exitErr.ExitCode()
+		result.ExitCode = &code
+		err = Fail(ErrorExited, &stream.ExitError{Code: code})
+	case err != nil:
+		err = Fail(ErrorStartFailed, fmt.Errorf("failed to run host command: %w", err))
+	default:
+		code := 0
+		result.ExitCode = &code
+	}
+	addHistory(logger, requested, pwd, argv, result, err)
+	if err != nil {
+		return result, err
+	}
+
+	result.Success = true
+	result.Message = "Host command executed successfully"
+	return result, nil
+}
+
+// hostArgv returns the command a host step runs: its entrypoint, command
+// and script arguments.
+func hostArgv(cfg Config) []string {
+	argv := make([]string, 0, len(cfg.Entrypoint)+len(cfg.Command)+len(cfg.ScriptArgs))
+	for _, e := range cfg.Entrypoint {
+		argv = append(argv, string(e))
+	}
+	for _, c := range cfg.Command {
+		argv = append(argv, string(c))
+	}
+	return append(argv, cfg.ScriptArgs...)
+}
+
+// allowHost applies the host command policy, asking on the terminal when
+// confirmation is required. Without a terminal, confirmation fails closed.
+func allowHost(policy HostPolicy, argv []string) error {
+	switch policy {
+	case HostAllow:
+		return nil
+	case HostDeny:
+		return fmt.Errorf("%w by policy (--host-commands deny)", ErrHostDenied)
+	case HostConfirm, "":
+	default:
+		return fmt.Errorf("invalid host command policy %q, expected allow, confirm, or deny", policy)
+	}
+
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		return fmt.Errorf("%w: confirmation needs a terminal, use --host-commands allow to run it unattended", ErrHostDenied)
+	}
+	_, _ = fmt.Fprintf(os.Stderr, "Run on the host: %s\nContinue? [y/N] ", strings.Join(argv, " "))
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return nil
+	}
+	return fmt.Errorf("%w: declined", ErrHostDenied)
+}
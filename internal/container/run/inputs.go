@@ -0,0 +1,135 @@
+package run
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// InputType is the type of value a script input accepts.
+type InputType string
+
+// Input types.
+const (
+	InputString InputType = "string"
+	InputInt    InputType = "int"
+	InputNumber InputType = "number"
+	InputBool   InputType = "bool" // Given as --NAME alone, or --NAME=false
+)
+
+// Input is a parameter a script declares, given to the script as --NAME
+// value or, when args names it, as the argument in that position. Its value
+// is referenced as ${args.NAME}.
+type Input struct {
+	Name        string    `json:"name"`
+	Type        InputType `json:"type"`
+	Default     string    `json:"default,omitempty"`
+	Required    bool      `json:"required,omitempty"`
+	Description string    `json:"description,omitempty"`
+}
+
+// Check reports whether value is valid for the input's type.
+func (i Input) Check(value string) error {
+	var err error
+	switch i.Type {
+	case InputString:
+	case InputInt:
+		if _, e := strconv.Atoi(value); e != nil {
+			err = fmt.Errorf("expected an integer, got %q", value)
+		}
+	case InputNumber:
+		if _, e := strconv.ParseFloat(value, 64); e != nil {
+			err = fmt.Errorf("expected a number, got %q", value)
+		}
+	case InputBool:
+		if value != "true" && value != "false" {
+			err = fmt.Errorf("expected true or false, got %q", value)
+		}
+	default:
+		err = fmt.Errorf("unknown type %q (expected string, int, number or bool)", i.Type)
+	}
+	if err != nil {
+		return fmt.Errorf("input %s: %w", i.Name, err)
+	}
+	return nil
+}
+
+// bindInputs returns the values of the named arguments of a script and its
+// positional arguments. Without inputs, the names of args are given to the
+// arguments in order. With inputs, --NAME value and --NAME=value set them
+// and the arguments before "--" fill the names of args in order, and are
+// removed from the arguments; those after "--" are passed on untouched.
+// Values are checked against their types, missing inputs take their default,
+// and missing required inputs are an error, as are unknown --NAME options.
+func bindInputs(cfg Config) (map[string]string, []string, error) {
+	named := map[string]string{}
+	if len(cfg.Inputs) == 0 {
+		for i, arg := range cfg.ScriptArgs {
+			if i < len(cfg.ArgNames) {
+				named[cfg.ArgNames[i]] = arg
+			}
+		}
+		return named, cfg.ScriptArgs, nil
+	}
+
+	inputs := make(map[string]Input, len(cfg.Inputs))
+	for _, input := range cfg.Inputs {
+		inputs[input.Name] = input
+	}
+	var positional, passed []string
+	args := cfg.ScriptArgs
+	for len(args) > 0 {
+		arg := args[0]
+		args = args[1:]
+		if arg == "--" {
+			passed = args
+			break
+		}
+		if !strings.HasPrefix(arg, "--") {
+			positional = append(positional, arg)
+			continue
+		}
+		name, value, hasValue := strings.Cut(arg[2:], "=")
+		input, ok := inputs[name]
+		if !ok {
+			return nil, nil, fmt.Errorf("unknown input --%s; arguments for the command go after --", name)
+		}
+		switch {
+		case hasValue:
+		case input.Type == InputBool:
+			value = "true"
+		case len(args) == 0:
+			return nil, nil, fmt.Errorf("input %s: missing value", name)
+		default:
+			value, args = args[0], args[1:]
+		}
+		named[name] = value
+	}
+
+	// Arguments fill the names args gives them that options did not set
+	rest := positional
+	for _, name := range cfg.ArgNames {
+		if _, ok := named[name]; ok || len(rest) == 0 {
+			continue
+		}
+		named[name], rest = rest[0], rest[1:]
+	}
+
+	for _, input := range cfg.Inputs {
+		value, ok := named[input.Name]
+		switch {
+		case !ok && input.Required && input.Default == "":
+			return nil, nil, fmt.Errorf("input %s is required", input.Name)
+		case !ok && input.Default == "" && input.Type == InputBool:
+			named[input.Name] = "false"
+			continue
+		case !ok:
+			named[input.Name] = input.Default
+			continue
+		}
+		if err := input.Check(value); err != nil {
+			return nil, nil, err
+		}
+	}
+	return named, append(rest, passed...), nil
+}
@@ -0,0 +1,548 @@
+package run
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	cerrdefs "github.com/containerd/errdefs"
+	"github.com/docker/docker/api/types/mount"
+	"github.com/docker/docker/api/types/volume"
+	"github.com/docker/docker/client"
+
+	cont "github.com/gloo-foo/vsl/internal/container"
+	"github.com/gloo-foo/vsl/internal/git"
+	mnt "github.com/gloo-foo/vsl/internal/mount"
+	"github.com/gloo-foo/vsl/internal/paths"
+	"github.com/gloo-foo/vsl/internal/vcs"
+)
+
+// mountsCwd reports whether the current directory is mounted automatically.
+func mountsCwd(cfg Config) bool {
+	return !cfg.NoAutoMounts && !cfg.NoMountCwd
+}
+
+// DefaultGitDepth is how many directory levels below the working directory
+// are searched for nested repositories by default.
+const DefaultGitDepth = 3
+
+// DefaultGitRootMaxFiles is the number of tracked files above which the git
+// root is not mounted in addition to the working directory by default.
+const DefaultGitRootMaxFiles = 100_000
+
+// autoMounts builds the automatic mounts for the current directory, its git
+// repository and the repositories nested below it, recording every root found
+// in the result. It returns the mounted git root, empty when the working
+// directory covers it.
+func autoMounts(ctx context.Context, logger *slog.Logger, cfg Config, pwd string, result *Result) ([]mnt.Mount, cont.GitRoot) {
+	mounts := []mnt.Mount{}
+	if cfg.NoAutoMounts {
+		logger.Info("Automatic mounts disabled")
+		return mounts, ""
+	}
+
+	var covered []string
+	if mountsCwd(cfg) {
+		mounts = append(mounts, mnt.Bind(pwd, pwd, cfg.SELinuxRelabel))
+		covered = append(covered, pwd)
+	}
+
+	if cfg.NoGit {
+		return mounts, ""
+	}
+
+	logger.Debug("Discovering git repository")
+	mounted := cont.GitRoot("")
+	if cfg.GitRoot == "" {
+		if gitDir, err := git.FindBare(pwd, ceilings(cfg, pwd)); err == nil {
+			return bareMounts(logger, cfg, gitDir, result, mounts, &covered), ""
+		}
+	}
+	if _, gitRoot, err := findRoot(cfg, pwd); err == nil && gitRoot != "" {
+		result.GitRoots = append(result.GitRoots, gitRoot)
+		// The git root is already covered when it is the mounted working directory
+		mountRoot := !within(string(gitRoot), covered)
+		if mountRoot && mountsCwd(cfg) && !cfg.ForceGitRootMount {
+			if w, large := largeRoot(cfg, gitRoot); large {
+				logger.Warn("Not mounting large git root", "root", gitRoot, "detail", w)
+				result.Warnings = append(result.Warnings, w)
+				mountRoot = false
+			}
+		}
+		if mountRoot {
+			mounted = gitRoot
+		}
+		mounts = append(mounts, repoMounts(ctx, logger, cfg, gitRoot, mountRoot, &covered)...)
+	}
+
+	// Vendored repositories and the members of meta-repositories have git
+	// directories of their own, which may live outside the working directory
+	depth := cfg.GitDepth
+	if depth == 0 {
+		depth = DefaultGitDepth
+	}
+	for _, nested := range git.NestedRoots(pwd, depth) {
+		logger.Debug("Found nested git repository", "root", nested)
+		result.GitRoots = append(result.GitRoots, nested)
+		mounts = append(mounts, repoMounts(ctx, logger, cfg, nested, true, &covered)...)
+	}
+
+	// Read-only git roots take the working directory mounted within them along
+	if cfg.GitReadonlyRoot {
+		roots := rootPaths(result.GitRoots)
+		for i := range mounts {
+			if within(mounts[i].Source, roots) {
+				mounts[i].ReadOnly = true
+			}
+		}
+	}
+
+	return mounts, mounted
+}
+
+// findRoot returns the repository root of pwd and the version control
+// system managing it: the configured root, git unless another system
+// manages it, or the innermost repository discovered upward without entering
+// the ceilings. Relative paths are resolved against pwd.
+func findRoot(cfg Config, pwd string) (vcs.VCS, cont.GitRoot, error) {
+	if cfg.GitRoot != "" {
+		root := absFrom(pwd, string(cfg.GitRoot))
+		info, err := os.Stat(root)
+		if err != nil {
+			return nil, "", fmt.Errorf("git root: %w", err)
+		}
+		if !info.IsDir() {
+			return nil, "", fmt.Errorf("git root %s is not a directory", root)
+		}
+		if system, found, err := vcs.Detect(root, nil); err == nil && string(found) == root {
+			return system, found, nil
+		}
+		return vcs.Git{}, cont.GitRoot(root), nil
+	}
+	return vcs.Detect(pwd, ceilings(cfg, pwd))
+}
+
+// ceilings returns the directories upward discovery from pwd does not enter.
+func ceilings(cfg Config, pwd string) []string {
+	dirs := make([]string, len(cfg.GitCeilings))
+	for i, dir := range cfg.GitCeilings {
+		dirs[i] = absFrom(pwd, dir)
+	}
+	return git.Ceilings(dirs...)
+}
+
+// absFrom resolves p against dir unless it is absolute.
+func absFrom(dir, p string) string {
+	if !filepath.IsAbs(p) {
+		p = filepath.Join(dir, p)
+	}
+	return filepath.Clean(p)
+}
+
+// largeRoot reports whether the git root tracks more files than allowed, so
+// only the working directory and the git directory are mounted, keeping
+// startup fast on giant monorepos. The warning explains the decision.
+func largeRoot(cfg Config, root cont.GitRoot) (string, bool) {
+	limit := cfg.GitRootMaxFiles
+	if limit == 0 {
+		limit = DefaultGitRootMaxFiles
+	}
+	if limit < 0 {
+		return "", false
+	}
+	files, err := git.TrackedFiles(root)
+	if err != nil || files <= limit {
+		return "", false
+	}
+	return fmt.Sprintf("git root %s tracks %d files, more than %d: only the working directory and git directory are mounted; use --force-git-root-mount to mount it", root, files, limit), true
+}
+
+// repoMounts returns the mounts the repository at root needs beyond the
+// directories already covered, which it extends: the worktree itself unless
+// mountRoot is false, the git directories its .git file and submodules
+// reference elsewhere, and, read-only, the object stores it borrows from.
+// Read-only git directories are also laid over the writable mounts covering
+// them.
+func repoMounts(ctx context.Context, logger *slog.Logger, cfg Config, root cont.GitRoot, mountRoot bool, covered *[]string) []mnt.Mount {
+	var mounts []mnt.Mount
+	if mountRoot && !within(string(root), *covered) {
+		logger.Info("Found git repository", "root", root)
+		mounts = append(mounts, mnt.Bind(string(root), string(root), cfg.SELinuxRelabel))
+		*covered = append(*covered, string(root))
+	}
+
+	// Worktree and submodule .git files reference git directories elsewhere
+	// on the host: the worktree's own directory and the repository's common
+	// directory, or the module directory in the superproject's .git/modules.
+	// They are mounted at their host paths so those references resolve.
+	var gitDirs, alternates []cont.GitDir
+	if gitDir, commonDir, err := git.WorktreeGitDirs(root); err == nil {
+		gitDirs = append(gitDirs, commonDir, gitDir)
+		alternates = git.AlternateObjectDirs(commonDir)
+	}
+	gitDirs = append(gitDirs, git.SubmoduleGitDirs(root)...)
+
+	// The LFS object store may be moved out of the git directory by lfs.storage.
+	// It is created if missing so objects fetched in the container persist.
+	if storage, ok := git.LFSStorage(ctx, root); ok && !within(string(storage), *covered) {
+		if err := os.MkdirAll(string(storage), 0o755); err != nil {
+			logger.Warn("Failed to create LFS storage", "path", storage, "error", err)
+		} else {
+			gitDirs = append(gitDirs, storage)
+		}
+	}
+
+	readOnly := cfg.GitReadonly || cfg.GitReadonlyRoot
+	for _, dir := range gitDirs {
+		if within(string(dir), *covered) && !readOnly {
+			continue
+		}
+		logger.Info("Mounting git directory", "path", dir, "read_only", readOnly)
+		m := mnt.Bind(string(dir), string(dir), cfg.SELinuxRelabel)
+		m.ReadOnly = readOnly
+		mounts = append(mounts, m)
+		*covered = append(*covered, string(dir))
+	}
+
+	return append(mounts, alternateMounts(logger, cfg, alternates, covered)...)
+}
+
+// alternateMounts mounts the object stores a clone made with --reference or
+// --shared borrows objects from, which git in the container only reads.
+func alternateMounts(logger *slog.Logger, cfg Config, alternates []cont.GitDir, covered *[]string) []mnt.Mount {
+	var mounts []mnt.Mount
+	for _, dir := range alternates {
+		if within(string(dir), *covered) {
+			continue
+		}
+		logger.Info("Mounting alternate object store", "path", dir)
+		m := mnt.Bind(string(dir), string(dir), cfg.SELinuxRelabel)
+		m.ReadOnly = true
+		mounts = append(mounts, m)
+		*covered = append(*covered, string(dir))
+	}
+	return mounts
+}
+
+// bareMounts mounts the bare repository whose git directory contains the
+// working directory, or that its .git file references, read-only: there is
+// no working tree to mount, and its objects and refs are only read. Mounts
+// of the working directory inside it become read-only too, and GIT_DIR
+// points git in the container at it.
+func bareMounts(logger *slog.Logger, cfg Config, gitDir cont.GitDir, result *Result, mounts []mnt.Mount, covered *[]string) []mnt.Mount {
+	logger.Info("Found bare git repository", "git_dir", gitDir)
+	result.BareRepository = gitDir
+
+	for i := range mounts {
+		if paths.Within(mounts[i].Source, string(gitDir)) {
+			mounts[i].ReadOnly = true
+		}
+	}
+	if !within(string(gitDir), *covered) {
+		m := mnt.Bind(string(gitDir), string(gitDir), cfg.SELinuxRelabel)
+		m.ReadOnly = true
+		mounts = append(mounts, m)
+		*covered = append(*covered, string(gitDir))
+	}
+	return append(mounts, alternateMounts(logger, cfg, git.AlternateObjectDirs(gitDir), covered)...)
+}
+
+// within reports whether path is inside one of the mounted directories.
+func within(path string, mounted []string) bool {
+	for _, dir := range mounted {
+		if paths.Within(path, dir) {
+			return true
+		}
+	}
+	return false
+}
+
+// withConsistency applies the configured consistency mode to automatic bind mounts.
+func withConsistency(mounts []mnt.Mount, consistency cont.Consistency) []mnt.Mount {
+	for i := range mounts {
+		mounts[i].Consistency = mount.Consistency(consistency)
+	}
+	return mounts
+}
+
+// volumesFrom validates the containers whose volumes are shared, returning
+// them in the HostConfig.VolumesFrom form (container[:ro|rw]).
+func volumesFrom(cfg Config) ([]string, error) {
+	specs := make([]string, 0, len(cfg.VolumesFrom))
+	for _, from := range cfg.VolumesFrom {
+		name, mode, hasMode := strings.Cut(string(from), ":")
+		if name == "" {
+			return nil, fmt.Errorf("invalid volumes-from %q: container is required", from)
+		}
+		if hasMode && mode != "ro" && mode != "rw" {
+			return nil, fmt.Errorf("invalid volumes-from %q: mode must be ro or rw", from)
+		}
+		specs = append(specs, string(from))
+	}
+	return specs, nil
+}
+
+// ensureVolumes creates the named volumes referenced by mounts that do not exist yet,
+// labeling them so they can be found by `vsl clean`.
+func ensureVolumes(ctx context.Context, logger *slog.Logger, dockerCli client.VolumeAPIClient, mounts []mnt.Mount, p cont.Provenance) error {
+	p.Persistent = true
+	for _, m := range mounts {
+		if m.Type != mount.TypeVolume || m.Source == "" {
+			continue
+		}
+		if _, err := dockerCli.VolumeInspect(ctx, m.Source); err == nil {
+			continue
+		} else if !cerrdefs.IsNotFound(err) {
+			return fmt.Errorf("failed to inspect volume %s: %w", m.Source, err)
+		}
+
+		logger.Info("Creating volume", "name", m.Source)
+		if _, err := dockerCli.VolumeCreate(ctx, volume.CreateOptions{Name: m.Source, Labels: cont.Labels(p)}); err != nil {
+			return fmt.Errorf("failed to create volume %s: %w", m.Source, err)
+		}
+	}
+	return nil
+}
+
+// project identifies the project a run belongs to: the git root, or pwd outside git.
+func project(cfg Config, pwd string) cont.Project {
+	if !cfg.NoGit {
+		if _, root, err := findRoot(cfg, pwd); err == nil && root != "" {
+			return cont.Project(root)
+		}
+	}
+	return cont.Project(pwd)
+}
+
+// cacheMounts mounts a per-project cache volume over each configured cache directory.
+// Relative paths are resolved against the container working directory, and "~/"
+// against root's home directory.
+func cacheMounts(cfg Config, proj cont.Project, workingDir string) ([]mnt.Mount, error) {
+	mounts := make([]mnt.Mount, 0, len(cfg.Caches))
+	for _, c := range cfg.Caches {
+		target := string(c)
+		switch {
+		case strings.HasPrefix(target, "~/"):
+			home, ok := containerHome(cfg.User)
+			if !ok {
+				return nil, fmt.Errorf("cache %q: home directory of user %q is unknown, use an absolute path", c, cfg.User)
+			}
+			target = path.Join(home, target[2:])
+		case !path.IsAbs(target):
+			if workingDir == "" {
+				return nil, fmt.Errorf("cache %q: relative path requires a working directory", c)
+			}
+			target = path.Join(workingDir, target)
+		}
+		mounts = append(mounts, mnt.Cache(proj, path.Clean(target)))
+	}
+	return mounts, nil
+}
+
+// containerHome returns the home directory of user inside the container.
+// Only root's home is known without inspecting the image.
+func containerHome(user cont.User) (string, bool) {
+	if user != "" && user != "root" && user != "0" && !strings.HasPrefix(string(user), "0:") {
+		return "", false
+	}
+	return "/root", true
+}
+
+// identityMounts mounts the user's global git configuration, and with
+// GitCredentials the credential store, read-only into the container's home
+// directory, so commits and authenticated fetches use the host identity.
+func identityMounts(cfg Config) ([]mnt.Mount, error) {
+	if !cfg.GitIdentity && !cfg.GitCredentials {
+		return nil, nil
+	}
+	home, ok := containerHome(cfg.User)
+	if !ok {
+		return nil, fmt.Errorf("git identity: home directory of user %q is unknown", cfg.User)
+	}
+	files, err := git.IdentityFiles(cfg.GitCredentials)
+	if err != nil {
+		return nil, fmt.Errorf("git identity: %w", err)
+	}
+
+	mounts := make([]mnt.Mount, 0, len(files))
+	for _, f := range files {
+		m := mnt.Bind(f.Path, path.Join(home, f.Home), "")
+		m.ReadOnly = true
+		mounts = append(mounts, m)
+	}
+	return mounts, nil
+}
+
+// LFSBinaryPath is where the host's git-lfs binary is mounted in the container.
+const LFSBinaryPath = "/usr/local/bin/git-lfs"
+
+// lfsBinaryMount mounts the host's git-lfs binary read-only into the
+// container's PATH, for images that lack it. The binary only runs in the
+// container when the host is Linux on the daemon's architecture.
+func lfsBinaryMount(cfg Config) (mnt.Mount, bool, error) {
+	if !cfg.GitLFS {
+		return mnt.Mount{}, false, nil
+	}
+	binary, err := exec.LookPath("git-lfs")
+	if err != nil {
+		return mnt.Mount{}, false, nil
+	}
+	if binary, err = filepath.EvalSymlinks(binary); err != nil {
+		return mnt.Mount{}, false, fmt.Errorf("git-lfs: %w", err)
+	}
+	m := mnt.Bind(binary, LFSBinaryPath, "")
+	m.ReadOnly = true
+	return m, true, nil
+}
+
+// maskMounts hides the configured subpaths of the bind-mounted directories.
+// Relative paths are resolved against the current directory. The masks are
+// returned for appending after the bind mounts they cover.
+func maskMounts(cfg Config, pwd string, mounts []mnt.Mount) ([]mnt.Mount, error) {
+	masks := make([]mnt.Mount, 0, len(cfg.Masks))
+	for _, m := range cfg.Masks {
+		target := string(m)
+		if !filepath.IsAbs(target) {
+			target = filepath.Join(pwd, target)
+		}
+		target = filepath.Clean(target)
+
+		if !underBind(target, mounts) {
+			return nil, fmt.Errorf("mask %q is not inside a mounted directory", m)
+		}
+		mask, err := mnt.Mask(filepath.ToSlash(target), cfg.MaskWith)
+		if err != nil {
+			return nil, err
+		}
+		masks = append(masks, mask)
+	}
+	return masks, nil
+}
+
+// worktreeMasks hides paths of the working directory and the git roots
+// mounted automatically, nested repositories included. With GitTrackedOnly
+// it hides everything git does not track: untracked directories behind empty
+// mounts and untracked files behind /dev/null. With MaskIgnored it hides the
+// directories the ignore files exclude entirely, such as build output and
+// node_modules. Directories holding nested repositories are not masked
+// themselves but through the listing of those repositories.
+func worktreeMasks(ctx context.Context, logger *slog.Logger, cfg Config, pwd string, result *Result, mounts []mnt.Mount) ([]mnt.Mount, error) {
+	if !cfg.GitTrackedOnly && !cfg.MaskIgnored {
+		return nil, nil
+	}
+	roots := result.GitRoots
+	var worktrees []string
+	// Outside git the working directory has nothing to ignore, but nothing
+	// tracked either, which tracked-only mode reports
+	if cfg.GitTrackedOnly || within(pwd, rootPaths(roots)) {
+		worktrees = append(worktrees, pwd)
+	}
+	for _, root := range roots {
+		if !slices.Contains(worktrees, string(root)) {
+			worktrees = append(worktrees, string(root))
+		}
+	}
+
+	var masks []mnt.Mount
+	masked := map[string]bool{}
+	for _, dir := range worktrees {
+		target, ok := worktreeTarget(dir, worktrees, mounts)
+		if !ok {
+			continue
+		}
+		var listed []string
+		var err error
+		if cfg.GitTrackedOnly {
+			listed, err = git.UntrackedPaths(ctx, dir)
+			if err != nil {
+				return nil, fmt.Errorf("tracked files only: %s: %w", dir, err)
+			}
+			logger.Info("Hiding untracked files", "path", dir, "count", len(listed))
+		} else {
+			listed, err = git.IgnoredDirs(ctx, dir)
+			if err != nil {
+				logger.Warn("Failed to list ignored directories", "path", dir, "error", err)
+				result.Warnings = append(result.Warnings, fmt.Sprintf("ignored directories of %s not masked: %v", dir, err))
+				continue
+			}
+			logger.Info("Hiding ignored directories", "path", dir, "count", len(listed))
+		}
+		for _, rel := range listed {
+			isDir := strings.HasSuffix(rel, "/")
+			rel = strings.TrimSuffix(rel, "/")
+			t := path.Join(target, rel)
+			if masked[t] {
+				continue
+			}
+			masked[t] = true
+			if !isDir {
+				masks = append(masks, mnt.MaskFile(t))
+				continue
+			}
+			if holdsRoot(filepath.Join(dir, filepath.FromSlash(rel)), roots) {
+				continue
+			}
+			mask, err := mnt.Mask(t, cfg.MaskWith)
+			if err != nil {
+				return nil, err
+			}
+			masks = append(masks, mask)
+		}
+	}
+	return masks, nil
+}
+
+// rootPaths returns the git roots as paths.
+func rootPaths(roots []cont.GitRoot) []string {
+	dirs := make([]string, len(roots))
+	for i, root := range roots {
+		dirs[i] = string(root)
+	}
+	return dirs
+}
+
+// worktreeTarget returns where dir appears in the container through the
+// automatic bind mount of a worktree covering it, if any.
+func worktreeTarget(dir string, worktrees []string, mounts []mnt.Mount) (string, bool) {
+	for _, m := range mounts {
+		if m.Type != mount.TypeBind || !slices.Contains(worktrees, m.Source) || !paths.Within(dir, m.Source) {
+			continue
+		}
+		rel, err := filepath.Rel(m.Source, dir)
+		if err != nil {
+			continue
+		}
+		return path.Join(m.Target, filepath.ToSlash(rel)), true
+	}
+	return "", false
+}
+
+// holdsRoot reports whether one of the git roots lies within dir.
+func holdsRoot(dir string, roots []cont.GitRoot) bool {
+	for _, root := range roots {
+		if paths.Within(string(root), dir) {
+			return true
+		}
+	}
+	return false
+}
+
+// underBind reports whether target lies strictly below the target of a bind mount.
+func underBind(target string, mounts []mnt.Mount) bool {
+	for _, m := range mounts {
+		if m.Type != mount.TypeBind {
+			continue
+		}
+		rel, err := filepath.Rel(m.Target, target)
+		if err == nil && rel != "." && !strings.HasPrefix(rel, "..") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,92 @@
+package run
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"text/tabwriter"
+)
+
+// Origin is where the value of a setting comes from. Settings apply in the
+// order of the origins, each overriding the ones before it.
+type Origin string
+
+// Setting origins.
+const (
+	OriginDefault Origin = "default" // vsl's built-in default
+	OriginConfig  Origin = "config"  // Global or project configuration file
+	OriginScript  Origin = "script"  // Script, with the fragments it includes and the parent it extends
+	OriginFlag    Origin = "flag"    // Command-line flag, or the environment variable standing for it
+)
+
+// SetOrigin records where the value of key comes from, or each item of its
+// list.
+func (c *Config) SetOrigin(key string, origins ...Origin) {
+	if c.Origins == nil {
+		c.Origins = map[string][]Origin{}
+	}
+	c.Origins[key] = origins
+}
+
+// AddOrigins records where the n items last added to the list of key come
+// from.
+func (c *Config) AddOrigins(key string, origin Origin, n int) {
+	if c.Origins == nil {
+		c.Origins = map[string][]Origin{}
+	}
+	for ; n > 0; n-- {
+		c.Origins[key] = append(c.Origins[key], origin)
+	}
+}
+
+// Origin returns where item i of the list of key comes from, or its value
+// for i zero.
+func (c *Config) Origin(key string, i int) Origin {
+	if origins := c.Origins[key]; i < len(origins) {
+		return origins[i]
+	}
+	return OriginDefault
+}
+
+// settings renders the settings of cfg that are set by script key, one
+// value per line, with where each comes from.
+func settings(cfg Config) string {
+	var b strings.Builder
+	w := tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "KEY\tVALUE\tORIGIN")
+	t, v := reflect.TypeOf(cfg), reflect.ValueOf(cfg)
+	for i := 0; i < t.NumField(); i++ {
+		key := t.Field(i).Tag.Get("up")
+		field := v.Field(i)
+		if key == "" || key == "-" || field.IsZero() || (field.Kind() == reflect.Slice && field.Len() == 0) {
+			continue
+		}
+		values := []reflect.Value{field}
+		if field.Kind() == reflect.Slice {
+			values = values[:0]
+			for j := 0; j < field.Len(); j++ {
+				values = append(values, field.Index(j))
+			}
+		}
+		for j, value := range values {
+			_, _ = fmt.Fprintf(w, "%s\t%s\t%s\n", key, settingValue(value), cfg.Origin(key, j))
+		}
+	}
+	_ = w.Flush()
+	return b.String()
+}
+
+// settingValue formats a setting: values as they are written in scripts,
+// and blocks as JSON.
+func settingValue(v reflect.Value) string {
+	switch v.Kind() {
+	case reflect.Struct, reflect.Pointer, reflect.Map:
+		data, err := json.Marshal(v.Interface())
+		if err != nil {
+			return fmt.Sprint(v.Interface())
+		}
+		return string(data)
+	}
+	return fmt.Sprint(v.Interface())
+}
@@ -0,0 +1,150 @@
+package run
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"path/filepath"
+	"strconv"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/client"
+	cont "github.com/gloo-foo/vsl/internal/container"
+	"github.com/gloo-foo/vsl/internal/paths"
+)
+
+// Limits of the ownership audit.
+const (
+	ownershipReported = 20  // Files listed in the result
+	ownershipBatch    = 500 // Files chowned per helper container
+)
+
+// OwnershipReport lists the files of the mounted directory that a run left
+// owned by another user than the invoking one.
+type OwnershipReport struct {
+	Dir   string   `json:"dir"`
+	UID   int      `json:"uid"` // Invoking user
+	GID   int      `json:"gid"`
+	Count int      `json:"count"`
+	Files []string `json:"files,omitempty"` // First files found, relative to Dir
+	Fixed bool     `json:"fixed,omitempty"` // Ownership was given back to the invoking user
+
+	all []string // Every file found
+}
+
+// auditOwnership scans dir for files owned by another user than the invoking
+// one. It reports nothing on hosts without numeric owners.
+func auditOwnership(dir string) (OwnershipReport, bool, error) {
+	uid, gid, ok := paths.CurrentOwner()
+	if !ok {
+		return OwnershipReport{}, false, nil
+	}
+	report := OwnershipReport{Dir: dir, UID: uid, GID: gid}
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			// Unreadable directories, typically created by the container, were already checked
+			if p != dir && d != nil && d.IsDir() {
+				return nil
+			}
+			return err
+		}
+		if info, err := d.Info(); err == nil {
+			if owner, _, ok := paths.Owner(info); ok && owner != uid {
+				report.all = append(report.all, p)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return report, true, fmt.Errorf("failed to scan %s for file ownership: %w", dir, err)
+	}
+
+	report.Count = len(report.all)
+	for _, p := range report.all[:min(len(report.all), ownershipReported)] {
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			rel = p
+		}
+		report.Files = append(report.Files, filepath.ToSlash(rel))
+	}
+	return report, true, nil
+}
+
+// ownershipDir returns the bind-mounted directory a run can write to: the git
+// root when the repository is mounted, otherwise the working directory.
+func ownershipDir(cfg Config, pwd string, gitRoot cont.GitRoot) string {
+	if gitRoot != "" {
+		return string(gitRoot)
+	}
+	if mountsCwd(cfg) {
+		return pwd
+	}
+	return ""
+}
+
+// checkOwnership audits the mounted directory after a run and, with
+// FixOwnership, gives the files back to the invoking user. Problems are
+// reported as warnings without failing the run.
+func checkOwnership(ctx context.Context, logger *slog.Logger, dockerCli client.ContainerAPIClient, cfg Config, image cont.Image, dir string, result *Result) {
+	report, ok, err := auditOwnership(dir)
+	if err != nil {
+		logger.Warn("Ownership audit failed", "error", err)
+		result.Warnings = append(result.Warnings, err.Error())
+	}
+	if !ok || report.Count == 0 {
+		return
+	}
+	result.Ownership = &report
+
+	if !cfg.FixOwnership {
+		logger.Warn("Files owned by another user", "dir", dir, "count", report.Count, "first", report.Files[0])
+		result.Warnings = append(result.Warnings, fmt.Sprintf("%d files in %s are owned by another user; rerun with --fix-ownership to reclaim them", report.Count, dir))
+		return
+	}
+
+	logger.Info("Reclaiming file ownership", "dir", dir, "count", report.Count, "uid", report.UID, "gid", report.GID)
+	if err := chown(ctx, dockerCli, image, dir, report); err != nil {
+		logger.Warn("Failed to reclaim file ownership", "error", err)
+		result.Warnings = append(result.Warnings, err.Error())
+		return
+	}
+	result.Ownership.Fixed = true
+}
+
+// chown runs short-lived root containers from image that give the reported
+// files back to the invoking user.
+func chown(ctx context.Context, dockerCli client.ContainerAPIClient, image cont.Image, dir string, report OwnershipReport) error {
+	owner := strconv.Itoa(report.UID) + ":" + strconv.Itoa(report.GID)
+	for start := 0; start < len(report.all); start += ownershipBatch {
+		files := report.all[start:min(len(report.all), start+ownershipBatch)]
+		resp, err := dockerCli.ContainerCreate(ctx, &container.Config{
+			Image:      string(image),
+			Entrypoint: []string{"chown", "-h", owner, "--"},
+			Cmd:        files,
+			User:       "0:0",
+			Labels:     cont.Labels(cont.Provenance{}),
+		}, &container.HostConfig{
+			Binds:       []string{dir + ":" + dir},
+			AutoRemove:  true,
+			NetworkMode: "none",
+		}, nil, nil, "")
+		if err != nil {
+			return fmt.Errorf("failed to create ownership helper: %w", err)
+		}
+
+		statusCh, errCh := dockerCli.ContainerWait(ctx, resp.ID, container.WaitConditionNextExit)
+		if err := dockerCli.ContainerStart(ctx, resp.ID, container.StartOptions{}); err != nil {
+			return fmt.Errorf("failed to start ownership helper: %w", err)
+		}
+		select {
+		case err := <-errCh:
+			return fmt.Errorf("ownership helper failed: %w", err)
+		case status := <-statusCh:
+			if status.StatusCode != 0 {
+				return fmt.Errorf("ownership helper exited with code %d; does %s provide chown?", status.StatusCode, image)
+			}
+		}
+	}
+	return nil
+}
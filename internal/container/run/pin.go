@@ -0,0 +1,38 @@
+package run
+
+import (
+	"log/slog"
+	"path/filepath"
+
+	"github.com/gloo-foo/vsl/internal/image"
+)
+
+// pinImage replaces the image of cfg with the digest the workspace's lock
+// file pins it to, so runs use the image the lock was made with. The lock is
+// looked up from the script's directory, or pwd outside scripts. Built
+// images and host runs are not pinned.
+func pinImage(logger *slog.Logger, cfg *Config, pwd string) error {
+	if cfg.Image == "" || cfg.Build != nil || cfg.Host {
+		return nil
+	}
+	dir := pwd
+	if cfg.ScriptPath != "" {
+		dir = filepath.Dir(string(cfg.ScriptPath))
+		if !filepath.IsAbs(dir) {
+			dir = filepath.Join(pwd, dir)
+		}
+	}
+	path, ok := image.FindLock(dir)
+	if !ok {
+		return nil
+	}
+	lock, err := image.LoadLock(path)
+	if err != nil {
+		return Fail(ErrorInvalidConfig, err)
+	}
+	if pinned, ok := lock.Pin(cfg.Image); ok {
+		logger.Info("Using pinned image", "image", cfg.Image, "pinned", pinned, "lock", path)
+		cfg.Image = pinned
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+package run
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/client"
+
+	"github.com/gloo-foo/vsl/internal/container/stream"
+)
+
+// probe runs a toolchain version command in the started container and returns
+// its trimmed output. Tools that report versions on stderr (e.g. java -version)
+// are supported by falling back to stderr when stdout is empty.
+func probe(ctx context.Context, dockerCli client.ContainerAPIClient, id string, command string) (string, error) {
+	created, err := dockerCli.ContainerExecCreate(ctx, id, container.ExecOptions{
+		AttachStdout: true,
+		AttachStderr: true,
+		Cmd:          []string{"sh", "-c", command},
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to create version probe: %w", err)
+	}
+
+	attach, err := dockerCli.ContainerExecAttach(ctx, created.ID, container.ExecAttachOptions{})
+	if err != nil {
+		return "", fmt.Errorf("failed to start version probe: %w", err)
+	}
+	defer attach.Close()
+
+	output, err := stream.Copy(attach.Reader, false, stream.Mode{Capture: true})
+	if err != nil {
+		return "", err
+	}
+	inspect, err := dockerCli.ContainerExecInspect(ctx, created.ID)
+	if err != nil {
+		return "", fmt.Errorf("failed to inspect version probe: %w", err)
+	}
+	if inspect.ExitCode != 0 {
+		return "", fmt.Errorf("version probe %q exited with code %d: %s", command, inspect.ExitCode, strings.TrimSpace(output.Stderr))
+	}
+
+	if version := strings.TrimSpace(output.Stdout); version != "" {
+		return version, nil
+	}
+	return strings.TrimSpace(output.Stderr), nil
+}
@@ -0,0 +1,63 @@
+package run
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/gloo-foo/vsl/internal/fixture"
+)
+
+// record runs cfg while recording every daemon API interaction, then saves
+// the configuration, environment, interactions and outcome as a fixture.
+// Container environment values are masked in the recorded interactions.
+func record(ctx context.Context, logger *slog.Logger, cfg Config) (Result, error) {
+	// Attached streams bypass the recorded transport, so their runs could
+	// not be replayed
+	if cfg.Attach || cfg.Capture || cfg.Pipe || cfg.Probe != "" {
+		return Result{}, Fail(ErrorInvalidConfig, fmt.Errorf("runs streaming container output (--attach, --capture, --pipe or tool_version_cmd) cannot be recorded"))
+	}
+	dir := cfg.RecordFixture
+	recorder := &fixture.Recorder{}
+	cfg.RecordFixture = ""
+	cfg.Transport = recorder.Wrap
+
+	pwd, err := os.Getwd()
+	if err != nil {
+		return Result{}, Fail(ErrorInvalidConfig, fmt.Errorf("failed to get current directory: %w", err))
+	}
+	config, err := json.Marshal(cfg)
+	if err != nil {
+		return Result{}, Fail(ErrorInvalidConfig, fmt.Errorf("failed to encode config: %w", err))
+	}
+
+	result, runErr := Run(ctx, logger, cfg)
+	var outcome json.Marshaler = result
+	if runErr != nil {
+		outcome = result.Failed(runErr)
+	}
+	output, err := json.Marshal(outcome)
+	if err != nil {
+		return result, fmt.Errorf("failed to encode result: %w", err)
+	}
+
+	f := fixture.Fixture{
+		WorkingDir:   pwd,
+		Environment:  fixture.CaptureEnvironment(),
+		Config:       config,
+		Interactions: recorder.Interactions(),
+		Result:       output,
+	}
+	if runErr != nil {
+		f.Error = runErr.Error()
+	}
+	if err := f.Save(dir); err != nil {
+		logger.Warn("Failed to save fixture", "dir", dir, "error", err)
+		result.Warnings = append(result.Warnings, err.Error())
+	} else {
+		logger.Info("Recorded fixture", "dir", dir, "interactions", len(f.Interactions))
+	}
+	return result, runErr
+}
@@ -0,0 +1,85 @@
+package run
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/go-connections/nat"
+	cont "github.com/gloo-foo/vsl/internal/container"
+)
+
+// devicePermissions are the cgroup permissions a device mapping may grant.
+const devicePermissions = "rwm"
+
+// applyResources publishes the configured ports and sets the CPU limit,
+// devices and added Linux capabilities of the container.
+func applyResources(containerConfig *container.Config, hostConfig *container.HostConfig, cfg Config) error {
+	if len(cfg.Ports) > 0 {
+		specs := make([]string, len(cfg.Ports))
+		for i, p := range cfg.Ports {
+			specs[i] = string(p)
+		}
+		exposed, bindings, err := nat.ParsePortSpecs(specs)
+		if err != nil {
+			return fmt.Errorf("invalid port: %w", err)
+		}
+		containerConfig.ExposedPorts = exposed
+		hostConfig.PortBindings = bindings
+	}
+
+	if cfg.CPUs < 0 {
+		return fmt.Errorf("invalid cpus %v: must not be negative", cfg.CPUs)
+	}
+	hostConfig.NanoCPUs = int64(cfg.CPUs * 1e9)
+
+	for _, d := range cfg.Devices {
+		mapping, err := parseDevice(d)
+		if err != nil {
+			return err
+		}
+		hostConfig.Devices = append(hostConfig.Devices, mapping)
+	}
+
+	for _, c := range cfg.CapAdd {
+		name := strings.ToUpper(strings.TrimSpace(string(c)))
+		if name == "" {
+			return fmt.Errorf("invalid cap_add: empty capability")
+		}
+		hostConfig.CapAdd = append(hostConfig.CapAdd, name)
+	}
+	return nil
+}
+
+// parseDevice parses a device such as /dev/fuse, /dev/sda:/dev/xvda or
+// /dev/sda:/dev/xvda:r into a mapping. The container path defaults to the
+// host path and the permissions to rwm.
+func parseDevice(d cont.Device) (container.DeviceMapping, error) {
+	parts := strings.Split(string(d), ":")
+	mapping := container.DeviceMapping{PathOnHost: parts[0], PathInContainer: parts[0], CgroupPermissions: devicePermissions}
+	switch len(parts) {
+	case 1:
+	case 2:
+		if isPermissions(parts[1]) {
+			mapping.CgroupPermissions = parts[1]
+		} else {
+			mapping.PathInContainer = parts[1]
+		}
+	case 3:
+		if !isPermissions(parts[2]) {
+			return mapping, fmt.Errorf("invalid device %q: permissions must be a combination of r, w and m", d)
+		}
+		mapping.PathInContainer, mapping.CgroupPermissions = parts[1], parts[2]
+	default:
+		return mapping, fmt.Errorf("invalid device %q (expected host[:container][:permissions])", d)
+	}
+	if !strings.HasPrefix(mapping.PathOnHost, "/") || !strings.HasPrefix(mapping.PathInContainer, "/") {
+		return mapping, fmt.Errorf("invalid device %q: paths must be absolute", d)
+	}
+	return mapping, nil
+}
+
+// isPermissions reports whether s is a combination of cgroup device permissions.
+func isPermissions(s string) bool {
+	return s != "" && strings.Trim(s, devicePermissions) == ""
+}
@@ -4,34 +4,74 @@ package run
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
 	"os"
 	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/docker/docker/api/types/container"
-	"github.com/docker/docker/api/types/mount"
 	"github.com/docker/docker/client"
+	"github.com/gloo-foo/vsl/internal/app/hook"
+	"github.com/gloo-foo/vsl/internal/capability"
 	cont "github.com/gloo-foo/vsl/internal/container"
+	"github.com/gloo-foo/vsl/internal/container/stream"
+	"github.com/gloo-foo/vsl/internal/docker"
 	"github.com/gloo-foo/vsl/internal/git"
+	"github.com/gloo-foo/vsl/internal/history"
+	img "github.com/gloo-foo/vsl/internal/image"
+	mnt "github.com/gloo-foo/vsl/internal/mount"
+	"github.com/gloo-foo/vsl/internal/secret"
 )
 
 // Result holds the result of a container run.
 type Result struct {
-	Success     bool             `json:"success"`
-	ContainerID cont.ContainerID `json:"container_id"`
-	Image       cont.Image       `json:"image"`
-	WorkingDir  cont.WorkingDir  `json:"working_dir"`
-	Mounts      []MountInfo      `json:"mounts"`
-	GitRoot     cont.GitRoot     `json:"git_root,omitempty"`
-	ScriptPath  cont.ScriptPath  `json:"script_path,omitempty"`
-	Message     string           `json:"message"`
+	Success          bool             `json:"success"`
+	ContainerID      cont.ContainerID `json:"container_id"`
+	Image            cont.Image       `json:"image"`
+	Build            *img.BuildResult `json:"build,omitempty"`
+	Host             bool             `json:"host,omitempty"` // Command ran on the host rather than in a container
+	WorkingDir       cont.WorkingDir  `json:"working_dir"`
+	Mounts           []MountInfo      `json:"mounts"`
+	GitRoot          cont.GitRoot     `json:"git_root,omitempty"`
+	BareRepository   cont.GitDir      `json:"bare_repository,omitempty"`
+	GitRoots         []cont.GitRoot   `json:"git_roots,omitempty"` // Repository of the working directory and those nested below it
+	Git              *GitInfo         `json:"git,omitempty"`       // Repository the run started in
+	VCS              string           `json:"vcs,omitempty"`       // Version control system of that repository: git, jj or hg
+	Checkout         string           `json:"checkout,omitempty"`  // Directory --repo was checked out in
+	Worktree         string           `json:"worktree,omitempty"`  // Temporary worktree the run used, removed since
+	Snapshot         string           `json:"snapshot,omitempty"`  // Snapshot of the worktree taken before the run
+	GitHooksDisabled bool             `json:"git_hooks_disabled,omitempty"`
+	Ownership        *OwnershipReport `json:"ownership,omitempty"` // Files left owned by another user
+	ScriptPath       cont.ScriptPath  `json:"script_path,omitempty"`
+	Profile          string           `json:"profile,omitempty"`
+	Session          cont.Session     `json:"session,omitempty"`
+	ToolVersion      string           `json:"tool_version,omitempty"`
+	VolumesFrom      []string         `json:"volumes_from,omitempty"`
+	ImportedEnv      []string         `json:"imported_env,omitempty"` // Names of variables imported from --env-from-output
+	Secrets          []string         `json:"secrets,omitempty"`      // Names of the secrets injected
+	Steps            []StepResult     `json:"steps,omitempty"`
+	Hooks            []hook.Result    `json:"hooks,omitempty"`  // Host commands run before and after the container
+	Export           string           `json:"export,omitempty"` // Run rendered for docker by inspection
+	ExitCode         *int             `json:"exit_code,omitempty"`
+	DurationMs       int64            `json:"duration_ms,omitempty"`
+	Output           *stream.Output   `json:"output,omitempty"`
+	Message          string           `json:"message"`
+	Warnings         []string         `json:"warnings,omitempty"`
+	Error            *ErrorInfo       `json:"error,omitempty"`
 }
 
 // MountInfo represents mount information for JSON output.
 type MountInfo struct {
-	Source string `json:"source"`
-	Target string `json:"target"`
+	Type        string       `json:"type,omitempty"`
+	Source      string       `json:"source"`
+	Target      string       `json:"target"`
+	ReadOnly    bool         `json:"read_only,omitempty"`
+	Propagation string       `json:"propagation,omitempty"`
+	Relabel     cont.Relabel `json:"relabel,omitempty"`
+	Consistency string       `json:"consistency,omitempty"`
 }
 
 // MarshalJSON implements json.Marshaler
@@ -40,73 +80,302 @@ func (r Result) MarshalJSON() ([]byte, error) {
 	return json.Marshal((Alias)(r))
 }
 
+// Failed implements app.Failable
+func (r Result) Failed(err error) json.Marshaler {
+	r.Success = false
+	r.Error = NewErrorInfo(err)
+	r.Message = "Container run failed"
+	return r
+}
+
 // Run executes the container run logic.
 func Run(ctx context.Context, logger *slog.Logger, cfg Config) (Result, error) {
+	if cfg.RecordFixture != "" {
+		return record(ctx, logger, cfg)
+	}
+	// Explained runs are confirmed once, before hooks, steps, checkouts or
+	// snapshots touch the host
+	if cfg.Explain && cfg.Inspect == "" {
+		if err := explainRun(ctx, logger, cfg); err != nil {
+			result := Result{
+				Image:      cfg.Image,
+				Mounts:     []MountInfo{},
+				ScriptPath: cfg.ScriptPath,
+				Profile:    cfg.Profile,
+				Session:    cfg.Session,
+			}
+			return result, err
+		}
+		cfg.Explain = false
+	}
+	if (len(cfg.Before) > 0 || len(cfg.After) > 0) && cfg.Inspect == "" {
+		return runHooked(ctx, logger, cfg)
+	}
+	if len(cfg.Steps) > 0 && cfg.Inspect == "" {
+		return runSteps(ctx, logger, cfg)
+	}
+
 	logger.Info("Starting container run",
 		"image", cfg.Image,
 		"interactive", cfg.Interactive,
 		"no_git", cfg.NoGit,
+		"no_mount_cwd", cfg.NoMountCwd,
+		"no_auto_mounts", cfg.NoAutoMounts,
+		"session", cfg.Session,
 	)
 
+	// Partial result, returned alongside any error so failures remain machine-readable
+	result := Result{
+		Image:      cfg.Image,
+		Mounts:     []MountInfo{},
+		ScriptPath: cfg.ScriptPath,
+		Profile:    cfg.Profile,
+		Session:    cfg.Session,
+	}
+
+	if err := validate(cfg); err != nil {
+		return result, err
+	}
+
+	leave, err := enterWorkspace(ctx, logger, &cfg, &result)
+	if err != nil {
+		return result, err
+	}
+	defer leave()
+
+	// Get current working directory
+	pwd, err := os.Getwd()
+	if err != nil {
+		return result, Fail(ErrorInvalidConfig, fmt.Errorf("failed to get current directory: %w", err))
+	}
+
+	// The configuration as requested is kept for the history, so runs can be re-created
+	requested := cfg
+	system, discoveredRoot, err := findRoot(cfg, pwd)
+	if err != nil && cfg.GitRoot != "" {
+		return result, Fail(ErrorInvalidConfig, err)
+	}
+	// Interpolate ${PWD}, ${GIT_ROOT}, ${HOME}, script arguments and environment
+	// variables in paths and script values
+	cfg, err = expandConfig(cfg, mnt.NewVars(pwd, discoveredRoot))
+	if err != nil {
+		return result, Fail(ErrorInvalidConfig, err)
+	}
+	if err := pinImage(logger, &cfg, pwd); err != nil {
+		return result, err
+	}
+	result.Image = cfg.Image
+
+	describeGit(ctx, logger, cfg, system, discoveredRoot, &result)
+
+	// Inspected runs are rendered for docker instead of run
+	if cfg.Inspect != "" {
+		return inspect(ctx, logger, cfg, pwd, result)
+	}
+	if err := takeSnapshot(ctx, logger, cfg, system, discoveredRoot, &result); err != nil {
+		return result, err
+	}
+
+	// Host steps run the command directly, without a container or mounts
+	if cfg.Host {
+		return runHost(ctx, logger, cfg, requested, pwd, result)
+	}
+
+	p, err := newPlan(ctx, logger, cfg, pwd, &result)
+	if err != nil {
+		return result, err
+	}
+	if p.bridge != nil {
+		defer closeBridge(logger, p.bridge)
+	}
+
 	// Initialize Docker client
-	dockerCli, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
+	dockerCli, err := docker.NewClient(docker.WithRetry(logger), docker.WithTransport(cfg.Transport))
 	if err != nil {
-		return Result{}, fmt.Errorf("failed to create docker client: %w", err)
+		return result, Fail(ErrorDaemonUnreachable, err)
+	}
+	defer docker.Close(dockerCli)
+
+	containerConfig, hostConfig, err := p.containerConfig(ctx, logger, dockerCli, cfg, pwd, &result)
+	if err != nil {
+		return result, err
+	}
+
+	if cfg.PrintArgv {
+		printArgv(os.Stderr, p.entrypoint, p.cmd, p.secrets.RedactEnv(p.env), p.workingDir)
 	}
-	defer func(dockerCli *client.Client) {
-		err := dockerCli.Close()
+
+	// Detached containers keep running after vsl exits
+	if cfg.Detach {
+		err := detach(ctx, logger, dockerCli, containerConfig, hostConfig, networkingConfig(cfg), cfg.Name, p.secrets, &result)
+		addHistory(logger, requested, pwd, p.cmd, result, err)
 		if err != nil {
-			panic(err)
+			return result, err
 		}
-	}(dockerCli)
+		result.Success = true
+		result.Message = "Container started in the background"
+		return result, nil
+	}
 
-	// Get current working directory
-	pwd, err := os.Getwd()
+	// Create container
+	logger.Info("Creating container")
+	resp, err := dockerCli.ContainerCreate(ctx, containerConfig, hostConfig, nil, nil, "")
 	if err != nil {
-		return Result{}, fmt.Errorf("failed to get current directory: %w", err)
-	}
-
-	// Build base mounts
-	mounts := []mount.Mount{
-		{
-			Type:   mount.TypeBind,
-			Source: pwd,
-			Target: pwd,
-		},
-	}
-
-	var gitRoot cont.GitRoot
-
-	// Handle git repository discovery
-	if !cfg.NoGit {
-		logger.Debug("Discovering git repository")
-		foundGitRoot, err := git.FindRoot(pwd)
-		if err == nil && foundGitRoot != "" && string(foundGitRoot) != pwd {
-			gitRoot = foundGitRoot
-			logger.Info("Found git repository", "root", gitRoot)
-			mounts = append(mounts, mount.Mount{
-				Type:   mount.TypeBind,
-				Source: string(foundGitRoot),
-				Target: string(foundGitRoot),
-			})
-
-			realGitDir, err := git.FindRealGitDir(foundGitRoot)
-			if err == nil && realGitDir != "" {
-				gitDirPath := filepath.Join(string(foundGitRoot), ".git")
-				if string(realGitDir) != gitDirPath {
-					logger.Debug("Mounting real git directory", "path", realGitDir)
-					mounts = append(mounts, mount.Mount{
-						Type:   mount.TypeBind,
-						Source: string(realGitDir),
-						Target: gitDirPath,
-					})
-				}
-			}
+		return result, Fail(ErrorCreateFailed, fmt.Errorf("failed to create container: %w", err))
+	}
+
+	result.ContainerID = cont.ContainerID(resp.ID)
+	logger.Info("Container created", "id", result.ContainerID)
+
+	started := time.Now()
+	err = execute(ctx, logger, dockerCli, resp.ID, containerConfig.Tty, cfg, &result)
+	result.DurationMs = time.Since(started).Milliseconds()
+	redactOutput(result.Output, p.secrets)
+	if dir := ownershipDir(cfg, pwd, result.GitRoot); dir != "" && (cfg.AuditOwnership || cfg.FixOwnership) {
+		checkOwnership(ctx, logger, dockerCli, cfg, cont.Image(containerConfig.Image), dir, &result)
+	}
+	addHistory(logger, requested, pwd, p.cmd, result, err)
+	if err != nil {
+		return result, err
+	}
+
+	logger.Info("Container completed successfully")
+
+	result.Success = true
+	result.Message = "Container executed successfully"
+	return result, nil
+}
+
+// validate checks the settings of cfg that are invalid regardless of the host.
+func validate(cfg Config) error {
+	if !mnt.ValidRelabel(cfg.SELinuxRelabel) {
+		return Fail(ErrorInvalidConfig, fmt.Errorf("invalid SELinux relabel mode %q, expected z or Z", cfg.SELinuxRelabel))
+	}
+	if !mnt.ValidConsistency(cfg.Consistency) {
+		return Fail(ErrorInvalidConfig, fmt.Errorf("invalid consistency %q, expected consistent, cached, or delegated", cfg.Consistency))
+	}
+	if cfg.Detach && (cfg.Attach || cfg.Capture || cfg.Pipe || cfg.Timeout > 0) {
+		return Fail(ErrorInvalidConfig, fmt.Errorf("detached containers cannot be attached to, captured, piped or timed out"))
+	}
+	if (cfg.GitTrackedOnly || cfg.MaskIgnored) && (cfg.NoGit || cfg.NoAutoMounts) {
+		return Fail(ErrorInvalidConfig, fmt.Errorf("masking untracked or ignored files needs the automatic git mounts"))
+	}
+	if cfg.Worktree != "" && (cfg.Repo != "" || cfg.Detach) {
+		return Fail(ErrorInvalidConfig, fmt.Errorf("temporary worktrees are removed when the run ends, so they cannot be used with --repo or --detach"))
+	}
+	if cfg.Ref != "" && cfg.Repo == "" {
+		return Fail(ErrorInvalidConfig, fmt.Errorf("a ref needs the repository to check out"))
+	}
+	switch cfg.Inspect {
+	case "", ExportDockerRun, ExportCompose, ExportSettings:
+	default:
+		return Fail(ErrorInvalidConfig, fmt.Errorf("invalid format %q, expected %s, %s or %s", cfg.Inspect, ExportDockerRun, ExportCompose, ExportSettings))
+	}
+	if cfg.Inspect != "" && cfg.Inspect != ExportSettings && (len(cfg.Steps) > 0 || cfg.Host) {
+		return Fail(ErrorInvalidConfig, fmt.Errorf("only runs of a single container can be exported, not steps or host commands"))
+	}
+	if cfg.Detach && cfg.GitCredentialBridge {
+		return Fail(ErrorInvalidConfig, fmt.Errorf("the git credential bridge only serves containers while vsl waits for them, not detached ones"))
+	}
+	return nil
+}
+
+// enterWorkspace enters the checkout of a remote repository or the temporary
+// worktree of another ref of the current repository the run asks for; the
+// script stays where it was found. The returned function leaves and removes
+// a temporary worktree.
+func enterWorkspace(ctx context.Context, logger *slog.Logger, cfg *Config, result *Result) (func(), error) {
+	if abs, err := filepath.Abs(string(cfg.ScriptPath)); err == nil && cfg.ScriptPath != "" && (cfg.Repo != "" || cfg.Worktree != "") {
+		cfg.ScriptPath = cont.ScriptPath(abs)
+	}
+	if cfg.Repo != "" {
+		dir, err := checkoutRepo(ctx, logger, *cfg)
+		if err != nil {
+			return nil, Fail(ErrorCheckoutFailed, err)
+		}
+		if err := os.Chdir(dir); err != nil {
+			return nil, Fail(ErrorCheckoutFailed, fmt.Errorf("failed to enter checkout: %w", err))
 		}
+		result.Checkout = dir
 	}
+	if cfg.Worktree != "" {
+		dir, leave, err := enterWorktree(ctx, logger, *cfg)
+		if err != nil {
+			return nil, Fail(ErrorCheckoutFailed, err)
+		}
+		result.Worktree = dir
+		return leave, nil
+	}
+	return func() {}, nil
+}
+
+// plan is a run resolved as far as possible without the daemon.
+type plan struct {
+	image      cont.Image
+	cmd        []string
+	entrypoint []string
+	env        []string
+	workingDir string
+	mounts     []mnt.Mount
+	proj       cont.Project
+	secrets    secret.Values
+	bridge     *git.CredentialBridge // Serves the container's git credential requests until closed
+}
+
+// newPlan resolves the mounts, command and environment of a run from pwd.
+func newPlan(ctx context.Context, logger *slog.Logger, cfg Config, pwd string, result *Result) (plan, error) {
+	// Build automatic pwd and git mounts
+	mounts, gitRoot := autoMounts(ctx, logger, cfg, pwd, result)
+	mounts = withConsistency(mounts, cfg.Consistency)
+
+	// Failed plans still report the mounts resolved so far
+	fail := func(category ErrorCategory, err error) (plan, error) {
+		result.Mounts = mountInfos(mounts)
+		return plan{}, Fail(category, err)
+	}
+
+	// Add user-specified volumes, expanding glob patterns in their sources
+	volumes, err := mnt.ExpandVolumes(cfg.Volumes)
+	if err != nil {
+		return fail(ErrorInvalidConfig, err)
+	}
+	for _, vol := range volumes {
+		m, err := mnt.ParseVolume(vol)
+		if err != nil && cfg.LenientMounts {
+			logger.Warn("Skipping invalid volume", "volume", vol, "error", err)
+			result.Warnings = append(result.Warnings, err.Error())
+			continue
+		}
+		if err != nil {
+			return fail(ErrorInvalidConfig, err)
+		}
+		mounts = append(mounts, *m)
+	}
+
+	// Add advanced mount specifications
+	for _, spec := range cfg.Mounts {
+		m, err := mnt.ParseMount(spec)
+		if err != nil {
+			return fail(ErrorInvalidConfig, err)
+		}
+		mounts = append(mounts, *m)
+	}
+
+	// Mask excluded subpaths, ordered after the bind mounts they cover
+	masks, err := maskMounts(cfg, pwd, mounts)
+	if err != nil {
+		return fail(ErrorInvalidConfig, err)
+	}
+	mounts = append(mounts, masks...)
+
+	// Hide what git does not track, or ignores, in the mounted worktrees
+	untracked, err := worktreeMasks(ctx, logger, cfg, pwd, result, mounts)
+	if err != nil {
+		return fail(ErrorInvalidConfig, err)
+	}
+	mounts = append(mounts, untracked...)
 
 	// Configure from script or CLI
-	image := cfg.Image
 	cmd := make([]string, len(cfg.Command))
 	for i, c := range cfg.Command {
 		cmd[i] = string(c)
@@ -115,16 +384,14 @@ func Run(ctx context.Context, logger *slog.Logger, cfg Config) (Result, error) {
 	for i, e := range cfg.Entrypoint {
 		entrypoint[i] = string(e)
 	}
+	// The daemon keeps the last entry of a variable: the variables vsl sets
+	// below are prepended to the explicit environment, which overrides them,
+	// and secrets are appended after it
 	env := make([]string, len(cfg.Environment))
 	for i, e := range cfg.Environment {
 		env[i] = string(e)
 	}
-	workingDir := string(cfg.WorkingDir)
-	user := string(cfg.User)
-	networkMode := string(cfg.NetworkMode)
-	stdinOpen := cfg.Interactive
-	tty := cfg.Interactive
-	privileged := cfg.Privileged
+	workingDir := mnt.ContainerPath(string(cfg.WorkingDir))
 
 	// If running from script, append script args to command
 	if cfg.ScriptPath != "" {
@@ -132,87 +399,428 @@ func Run(ctx context.Context, logger *slog.Logger, cfg Config) (Result, error) {
 		cmd = append(cmd, cfg.ScriptArgs...)
 	}
 
-	// Default working dir to pwd if not specified
-	if workingDir == "" {
-		workingDir = pwd
+	// Default working dir to pwd if not specified and pwd is mounted
+	if workingDir == "" && mountsCwd(cfg) {
+		workingDir = mnt.ContainerPath(pwd)
 	}
 
 	logger.Debug("Container configuration",
-		"image", image,
+		"image", cfg.Image,
 		"working_dir", workingDir,
-		"user", user,
-		"privileged", privileged,
-		"network_mode", networkMode,
+		"user", cfg.User,
+		"privileged", cfg.Privileged,
+		"network_mode", cfg.NetworkMode,
 	)
 
-	// Container configuration
+	// Mount per-project cache volumes
+	proj := project(cfg, pwd)
+	caches, err := cacheMounts(cfg, proj, workingDir)
+	if err != nil {
+		return fail(ErrorInvalidConfig, err)
+	}
+	mounts = append(mounts, caches...)
+
+	// Mount the host git identity into the container's home directory
+	identity, err := identityMounts(cfg)
+	if err != nil {
+		return fail(ErrorInvalidConfig, err)
+	}
+	if (cfg.GitIdentity || cfg.GitCredentials) && len(identity) == 0 {
+		logger.Warn("No git identity found", "looked_in", "~/.gitconfig, $XDG_CONFIG_HOME/git/config")
+		result.Warnings = append(result.Warnings, "git identity requested but no global git configuration found")
+	}
+	mounts = append(mounts, identity...)
+
+	// Mount the host git-lfs binary for images without it
+	lfs, found, err := lfsBinaryMount(cfg)
+	if err != nil {
+		return fail(ErrorInvalidConfig, err)
+	}
+	if cfg.GitLFS && !found {
+		logger.Warn("git-lfs not found in the host PATH")
+		result.Warnings = append(result.Warnings, "git-lfs requested but not found in the host PATH")
+	}
+	if found {
+		mounts = append(mounts, lfs)
+	}
+
+	// Import variables written by previous steps and let this one add more
+	if cfg.EnvFromOutput != "" {
+		imported, m, err := envOutput(cfg.EnvFromOutput, pwd)
+		if err != nil {
+			return fail(ErrorInvalidConfig, err)
+		}
+		logger.Info("Imported step variables", "file", cfg.EnvFromOutput, "count", len(imported))
+		for _, e := range imported {
+			key, _, _ := strings.Cut(e, "=")
+			result.ImportedEnv = append(result.ImportedEnv, key)
+		}
+		env = append(append(imported, EnvOutputVar+"="+EnvOutputPath), env...)
+		mounts = append(mounts, m)
+	}
+
+	// Point git in the container at split repositories, mounted at their host paths
+	if !cfg.NoGit && !cfg.NoAutoMounts {
+		forwarded := forwardedGitEnv(gitRoot)
+		if result.BareRepository != "" {
+			forwarded = append(forwarded, git.EnvGitDir+"="+mnt.ContainerPath(string(result.BareRepository)))
+		}
+		if len(forwarded) > 0 {
+			logger.Info("Forwarding git location variables", "env", forwarded)
+			env = append(forwarded, env...)
+		}
+	}
+
+	// Keep hooks written for the host from running in the container
+	var gitConfig []gitSetting
+	if len(result.GitRoots) > 0 && !cfg.AllowGitHooks {
+		logger.Debug("Disabling git hooks in the container")
+		result.GitHooksDisabled = true
+		gitConfig = append(gitConfig, hooksDisabled)
+	}
+
+	// Describe the host repository to the container
+	if cfg.GitEnv {
+		if result.Git == nil {
+			logger.Warn("Git metadata requested outside a git repository")
+			result.Warnings = append(result.Warnings, "git env requested but no git repository found")
+		} else {
+			env = append(result.Git.env(), env...)
+		}
+	}
+
+	// Fetch declared secrets just in time; inspected runs leave their values
+	// to the environment of the export
+	var secrets secret.Values
+	if cfg.Inspect != "" {
+		for _, ref := range cfg.Secrets {
+			secrets = append(secrets, secret.Value{Ref: ref})
+		}
+	} else {
+		secrets, err = secret.Resolve(ctx, cfg.Secrets, func(argv []string) error { return allowHost(cfg.HostCommands, argv) })
+	}
+	if err != nil {
+		return fail(ErrorSecretUnavailable, err)
+	}
+	if len(secrets) > 0 {
+		logger.Info("Injecting secrets", "secrets", secrets.Names())
+		result.Secrets = secrets.Names()
+		env = append(env, secrets.Env()...)
+	}
+
+	// Share volumes of existing containers
+	result.VolumesFrom, err = volumesFrom(cfg)
+	if err != nil {
+		return fail(ErrorInvalidConfig, err)
+	}
+
+	// Answer git credential requests of the container with the host's helpers
+	var bridge *git.CredentialBridge
+	if cfg.GitCredentialBridge && cfg.Inspect == "" {
+		var bridgeMounts []mnt.Mount
+		var settings []gitSetting
+		bridge, bridgeMounts, settings, err = bridgeCredentials(ctx, logger, result.GitRoots)
+		if err != nil {
+			logger.Warn("Git credential bridge unavailable", "error", err)
+			result.Warnings = append(result.Warnings, "git credential bridge unavailable: "+err.Error())
+		} else {
+			logger.Info("Bridging git credentials", "socket", bridge.Socket)
+			mounts = append(mounts, bridgeMounts...)
+			gitConfig = append(gitConfig, settings...)
+		}
+	}
+	if len(gitConfig) > 0 {
+		env = append(gitConfigEnv(gitConfig), env...)
+	}
+
+	// Drop duplicate and redundant mounts, warning about conflicting ones
+	mounts, conflicts := mnt.Normalize(mounts)
+	for _, w := range conflicts {
+		logger.Warn("Conflicting mounts", "detail", w)
+		result.Warnings = append(result.Warnings, w)
+	}
+
+	result.WorkingDir = cont.WorkingDir(workingDir)
+	result.GitRoot = gitRoot
+	result.Mounts = mountInfos(mounts)
+
+	// Git features degrade to pure-Go fallbacks without the git binary
+	if features := gitFeatures(cfg); gitRoot != "" && len(features) > 0 && !git.Available() {
+		logger.Warn("git binary not found, using built-in fallbacks", "features", features)
+		result.Warnings = append(result.Warnings, git.ErrNotInstalled.Error()+"; "+strings.Join(features, ", ")+" use built-in fallbacks")
+	}
+
+	return plan{
+		image:      cfg.Image,
+		cmd:        cmd,
+		entrypoint: entrypoint,
+		env:        env,
+		workingDir: workingDir,
+		mounts:     mounts,
+		proj:       proj,
+		secrets:    secrets,
+		bridge:     bridge,
+	}, nil
+}
+
+// gitFeatures names the requested features of the run that fall back to
+// pure-Go implementations without the git binary.
+func gitFeatures(cfg Config) []string {
+	var features []string
+	if cfg.Worktree != "" {
+		features = append(features, "worktree")
+	}
+	if cfg.Snapshot {
+		features = append(features, "snapshot")
+	}
+	if cfg.GitCredentialBridge {
+		features = append(features, "credential bridge")
+	}
+	if cfg.GitTrackedOnly || cfg.MaskIgnored {
+		features = append(features, "ignore masking")
+	}
+	return features
+}
+
+// containerConfig builds the image when the run has a Dockerfile, checks the
+// capabilities the run requires and returns the configuration to create its
+// container with.
+func (p *plan) containerConfig(ctx context.Context, logger *slog.Logger, dockerCli *client.Client, cfg Config, pwd string, result *Result) (*container.Config, *container.HostConfig, error) {
+	// Build the image from the script's Dockerfile
+	if cfg.Build != nil {
+		built, err := buildImage(ctx, logger, dockerCli, cfg, pwd, p.proj)
+		result.Build = &built
+		if err != nil {
+			return nil, nil, err
+		}
+		p.image = built.Image
+		result.Image = built.Image
+	}
+
+	// Check capabilities required by the script
+	decisions, err := preflight(ctx, dockerCli, cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, d := range decisions {
+		if d.Warning != "" {
+			logger.Warn("Capability unavailable", "capability", d.Capability, "detail", d.Warning)
+			result.Warnings = append(result.Warnings, d.Warning)
+		}
+	}
+	for _, w := range consistencyWarnings(ctx, dockerCli, p.mounts) {
+		logger.Warn("Bind mount performance", "detail", w)
+		result.Warnings = append(result.Warnings, w)
+	}
+
+	// Translate Windows host paths into the form the daemon expects
+	p.mounts = translator(ctx, dockerCli).Apply(p.mounts)
+	result.Mounts = mountInfos(p.mounts)
+
+	// Create missing named volumes
+	if err := ensureVolumes(ctx, logger, dockerCli, p.mounts, provenance(cfg, p.proj)); err != nil {
+		return nil, nil, Fail(ErrorCreateFailed, err)
+	}
+	return p.createConfig(cfg, result, decisions)
+}
+
+// createConfig returns the configuration to create the container of the
+// plan with, using the capabilities granted.
+func (p *plan) createConfig(cfg Config, result *Result, decisions []capability.Decision) (*container.Config, *container.HostConfig, error) {
 	containerConfig := &container.Config{
-		Image:        string(image),
-		Cmd:          cmd,
-		Entrypoint:   entrypoint,
-		WorkingDir:   workingDir,
-		Env:          env,
-		User:         user,
-		Tty:          tty,
-		AttachStdin:  stdinOpen,
+		Image:        string(p.image),
+		Cmd:          p.cmd,
+		Entrypoint:   p.entrypoint,
+		WorkingDir:   p.workingDir,
+		Env:          p.env,
+		User:         string(cfg.User),
+		Tty:          cfg.Interactive,
+		AttachStdin:  cfg.Interactive,
 		AttachStdout: true,
 		AttachStderr: true,
-		OpenStdin:    stdinOpen,
+		OpenStdin:    cfg.Interactive,
+		Labels:       cont.Labels(provenance(cfg, p.proj)),
+		Healthcheck:  cfg.Healthcheck.config(),
+	}
+	for key, value := range cfg.Labels {
+		containerConfig.Labels[key] = value
 	}
 
+	apiMounts, binds, err := mnt.Split(p.mounts)
+	if err != nil {
+		return nil, nil, Fail(ErrorInvalidConfig, err)
+	}
 	hostConfig := &container.HostConfig{
-		Mounts:      mounts,
-		AutoRemove:  true,
-		Privileged:  privileged,
-		NetworkMode: container.NetworkMode(networkMode),
+		Mounts:      apiMounts,
+		Binds:       binds,
+		VolumesFrom: result.VolumesFrom,
+		AutoRemove:  !cfg.Detach,
+		Privileged:  cfg.Privileged,
+		NetworkMode: container.NetworkMode(cfg.NetworkMode),
+		ShmSize:     int64(cfg.ShmSize),
+		Resources:   container.Resources{Memory: int64(cfg.Memory)},
+	}
+	if err := applyResources(containerConfig, hostConfig, cfg); err != nil {
+		return nil, nil, Fail(ErrorInvalidConfig, err)
 	}
+	applyCapabilities(hostConfig, decisions)
+	return containerConfig, hostConfig, nil
+}
 
-	// Create container
-	logger.Info("Creating container")
-	resp, err := dockerCli.ContainerCreate(ctx, containerConfig, hostConfig, nil, nil, "")
-	if err != nil {
-		return Result{}, fmt.Errorf("failed to create container: %w", err)
+// execute starts the created container and waits for it to exit, streaming
+// its output according to the configured mode and recording the exit code.
+func execute(ctx context.Context, logger *slog.Logger, dockerCli client.ContainerAPIClient, id string, tty bool, cfg Config, result *Result) error {
+	mode := stream.Mode{Pipe: cfg.Pipe, Capture: cfg.Capture}
+
+	// Attach before starting so no output is missed
+	type copyResult struct {
+		output *stream.Output
+		err    error
 	}
+	var copied chan copyResult
+	if mode.Enabled() || cfg.Attach {
+		attach, err := dockerCli.ContainerAttach(ctx, id, container.AttachOptions{Stream: true, Stdin: cfg.Attach, Stdout: true, Stderr: true})
+		if err != nil {
+			return Fail(ErrorStartFailed, fmt.Errorf("failed to attach to container: %w", err))
+		}
+		defer attach.Close()
 
-	containerID := cont.ContainerID(resp.ID)
-	logger.Info("Container created", "id", containerID)
+		copied = make(chan copyResult, 1)
+		go func() {
+			if cfg.Attach {
+				copied <- copyResult{err: stream.Attach(attach, tty)}
+				return
+			}
+			output, err := stream.Copy(attach.Reader, tty, mode)
+			copied <- copyResult{output, err}
+		}()
+	}
 
 	// Start container
 	logger.Info("Starting container")
-	if err := dockerCli.ContainerStart(ctx, resp.ID, container.StartOptions{}); err != nil {
-		return Result{}, fmt.Errorf("failed to start container: %w", err)
+	if err := dockerCli.ContainerStart(ctx, id, container.StartOptions{}); err != nil {
+		return Fail(ErrorStartFailed, fmt.Errorf("failed to start container: %w", err))
+	}
+
+	// Record toolchain versions while the container runs
+	if cfg.Probe != "" {
+		version, err := probe(ctx, dockerCli, id, cfg.Probe)
+		if err != nil {
+			logger.Warn("Toolchain version probe failed", "error", err)
+			result.Warnings = append(result.Warnings, err.Error())
+		} else {
+			logger.Info("Toolchain version", "version", version)
+			result.ToolVersion = version
+		}
 	}
 
-	// Wait for container to finish
-	logger.Debug("Waiting for container to complete")
-	statusCh, errCh := dockerCli.ContainerWait(ctx, resp.ID, container.WaitConditionNotRunning)
+	// Wait for container to finish, stopping it when it exceeds the timeout
+	logger.Debug("Waiting for container to complete", "timeout", cfg.Timeout)
+	waitCtx := ctx
+	if cfg.Timeout > 0 {
+		var cancel context.CancelFunc
+		waitCtx, cancel = context.WithTimeout(ctx, time.Duration(cfg.Timeout))
+		defer cancel()
+	}
+	statusCh, errCh := dockerCli.ContainerWait(waitCtx, id, container.WaitConditionNotRunning)
+	var status container.WaitResponse
 	select {
 	case err := <-errCh:
+		if err != nil && errors.Is(waitCtx.Err(), context.DeadlineExceeded) {
+			logger.Warn("Container exceeded timeout, stopping it", "timeout", cfg.Timeout)
+			if stopErr := dockerCli.ContainerStop(ctx, id, container.StopOptions{}); stopErr != nil {
+				logger.Warn("Failed to stop container", "error", stopErr)
+			}
+			return Fail(ErrorTimeout, fmt.Errorf("container exceeded timeout of %s", cfg.Timeout))
+		}
 		if err != nil {
-			return Result{}, fmt.Errorf("error waiting for container: %w", err)
+			return Fail(ErrorWaitFailed, fmt.Errorf("error waiting for container: %w", err))
 		}
-	case <-statusCh:
+	case status = <-statusCh:
 	}
 
-	logger.Info("Container completed successfully")
+	if copied != nil {
+		c := <-copied
+		result.Output = c.output
+		if c.err != nil {
+			logger.Warn("Container output incomplete", "error", c.err)
+			result.Warnings = append(result.Warnings, c.err.Error())
+		}
+	}
+
+	exitCode := int(status.StatusCode)
+	result.ExitCode = &exitCode
+	if exitCode != 0 {
+		return Fail(ErrorExited, &stream.ExitError{Code: exitCode})
+	}
+	return nil
+}
+
+// addHistory records the finished run with the configuration that produced it,
+// logging rather than failing on errors.
+func addHistory(logger *slog.Logger, cfg Config, pwd string, cmd []string, result Result, err error) {
+	if cfg.NoHistory {
+		return
+	}
+	config, marshalErr := json.Marshal(cfg)
+	if marshalErr != nil {
+		logger.Warn("Failed to record run configuration", "error", marshalErr)
+	}
+	kind := history.KindRun
+	if cfg.Host {
+		kind = history.KindHost
+	}
+	entry := history.Entry{
+		Time:        time.Now(),
+		Kind:        kind,
+		ContainerID: result.ContainerID,
+		Image:       result.Image,
+		Command:     cmd,
+		HostDir:     pwd,
+		WorkingDir:  string(result.WorkingDir),
+		ScriptPath:  cfg.ScriptPath,
+		Session:     cfg.Session,
+		ToolVersion: result.ToolVersion,
+		ExitCode:    result.ExitCode,
+		DurationMs:  result.DurationMs,
+		Success:     err == nil,
+		Config:      config,
+	}
+	if err != nil {
+		entry.Error = err.Error()
+	}
+	if err := history.Append(entry); err != nil {
+		logger.Warn("Failed to record history", "error", err)
+	}
+}
 
-	// Build mount info for result
-	mountInfo := make([]MountInfo, len(mounts))
+// mountInfos converts mounts to their JSON representation.
+func mountInfos(mounts []mnt.Mount) []MountInfo {
+	infos := make([]MountInfo, len(mounts))
 	for i, m := range mounts {
-		mountInfo[i] = MountInfo{
-			Source: m.Source,
-			Target: m.Target,
+		infos[i] = MountInfo{
+			Type:        string(m.Type),
+			Source:      m.Source,
+			Target:      m.Target,
+			ReadOnly:    m.ReadOnly,
+			Relabel:     m.Relabel,
+			Consistency: string(m.Consistency),
+		}
+		if m.BindOptions != nil {
+			infos[i].Propagation = string(m.BindOptions.Propagation)
 		}
 	}
+	return infos
+}
 
-	return Result{
-		Success:     true,
-		ContainerID: containerID,
-		Image:       image,
-		WorkingDir:  cont.WorkingDir(workingDir),
-		Mounts:      mountInfo,
-		GitRoot:     gitRoot,
-		ScriptPath:  cfg.ScriptPath,
-		Message:     "Container executed successfully",
-	}, nil
+// provenance describes the origin of the container for its labels.
+func provenance(cfg Config, proj cont.Project) cont.Provenance {
+	p := cont.Provenance{Session: cfg.Session, Project: proj, Persistent: cfg.Detach}
+	if cfg.ScriptPath != "" {
+		p.Script = cfg.ScriptPath
+		if abs, err := filepath.Abs(string(cfg.ScriptPath)); err == nil {
+			p.Script = cont.ScriptPath(abs)
+		}
+	}
+	return p
 }
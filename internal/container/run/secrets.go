@@ -0,0 +1,15 @@
+package run
+
+import (
+	"github.com/gloo-foo/vsl/internal/container/stream"
+	"github.com/gloo-foo/vsl/internal/secret"
+)
+
+// redactOutput masks the values of secrets in captured output.
+func redactOutput(output *stream.Output, secrets secret.Values) {
+	if output == nil || len(secrets) == 0 {
+		return
+	}
+	output.Stdout = secrets.Redact(output.Stdout)
+	output.Stderr = secrets.Redact(output.Stderr)
+}
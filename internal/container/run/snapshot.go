@@ -0,0 +1,44 @@
+package run
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	cont "github.com/gloo-foo/vsl/internal/container"
+	"github.com/gloo-foo/vsl/internal/git"
+	"github.com/gloo-foo/vsl/internal/vcs"
+)
+
+// takeSnapshot records the worktree of the repository at root before the
+// run may change it, so `vsl restore` can bring it back. Read-only roots
+// cannot change and are not recorded.
+func takeSnapshot(ctx context.Context, logger *slog.Logger, cfg Config, system vcs.VCS, root cont.GitRoot, result *Result) error {
+	if !cfg.Snapshot {
+		return nil
+	}
+	if root == "" || cfg.NoGit || system.Name() == vcs.NameMercurial {
+		return Fail(ErrorInvalidConfig, fmt.Errorf("--snapshot needs a git repository"))
+	}
+	if cfg.GitReadonlyRoot {
+		logger.Info("Not taking a snapshot of a read-only git root", "root", root)
+		return nil
+	}
+
+	command := make([]string, len(cfg.Command))
+	for i, c := range cfg.Command {
+		command[i] = string(c)
+	}
+	message := fmt.Sprintf("Before vsl run of %s: %s", cfg.Image, strings.Join(command, " "))
+	if cfg.ScriptPath != "" {
+		message = fmt.Sprintf("Before vsl run of %s", cfg.ScriptPath)
+	}
+	snapshot, err := git.TakeSnapshot(ctx, root, message)
+	if err != nil {
+		return Fail(ErrorSnapshotFailed, fmt.Errorf("failed to take snapshot: %w", err))
+	}
+	logger.Info("Took snapshot of the worktree", "root", root, "id", snapshot.ID)
+	result.Snapshot = snapshot.ID
+	return nil
+}
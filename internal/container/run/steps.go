@@ -0,0 +1,166 @@
+package run
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+	"time"
+
+	cont "github.com/gloo-foo/vsl/internal/container"
+)
+
+// Step is one command of a multi-step script. Steps run in order, each in
+// its own container with the script's mounts and environment; the values a
+// step sets replace the script's, and its environment is added to it.
+type Step struct {
+	Name            string             `json:"name"`
+	Image           cont.Image         `json:"image,omitempty"`
+	Command         []cont.Command     `json:"command,omitempty"`
+	Entrypoint      []cont.Entrypoint  `json:"entrypoint,omitempty"`
+	Environment     []cont.Environment `json:"env,omitempty"`
+	WorkingDir      cont.WorkingDir    `json:"workdir,omitempty"`
+	Host            bool               `json:"host,omitempty"`              // Run on the host, subject to HostCommands
+	ContinueOnError bool               `json:"continue_on_error,omitempty"` // Run the next steps even when this one fails
+}
+
+// StepStatus is the outcome of a step.
+type StepStatus string
+
+// Step outcomes.
+const (
+	StepSucceeded StepStatus = "succeeded"
+	StepFailed    StepStatus = "failed"
+	StepSkipped   StepStatus = "skipped" // Not run, after an earlier step failed
+)
+
+// StepResult holds the outcome of a step.
+type StepResult struct {
+	Name        string           `json:"name"`
+	Status      StepStatus       `json:"status"`
+	Image       cont.Image       `json:"image,omitempty"`
+	ContainerID cont.ContainerID `json:"container_id,omitempty"`
+	ExitCode    *int             `json:"exit_code,omitempty"`
+	DurationMs  int64            `json:"duration_ms,omitempty"`
+	Error       string           `json:"error,omitempty"`
+}
+
+// runSteps runs the steps of a script in order, stopping at the first
+// failure unless the failed step continues on error. The result is that of
+// the last step run, with the outcome of every step.
+func runSteps(ctx context.Context, logger *slog.Logger, cfg Config) (Result, error) {
+	result := Result{
+		Image:      cfg.Image,
+		Mounts:     []MountInfo{},
+		ScriptPath: cfg.ScriptPath,
+		Profile:    cfg.Profile,
+		Session:    cfg.Session,
+		Steps:      make([]StepResult, 0, len(cfg.Steps)),
+	}
+	if err := validate(cfg); err != nil {
+		return result, err
+	}
+	if cfg.Detach {
+		return result, Fail(ErrorInvalidConfig, fmt.Errorf("steps run one after the other and cannot be detached"))
+	}
+
+	// Every step runs in the same checkout or worktree
+	leave, err := enterWorkspace(ctx, logger, &cfg, &result)
+	if err != nil {
+		return result, err
+	}
+	defer leave()
+	checkout, worktree := result.Checkout, result.Worktree
+	cfg.Repo, cfg.Ref, cfg.Worktree = "", "", ""
+
+	// Variables pass between the steps of this run only
+	if cfg.EnvFromOutput != "" {
+		pwd, err := os.Getwd()
+		if err != nil {
+			return result, Fail(ErrorInvalidConfig, fmt.Errorf("failed to get current directory: %w", err))
+		}
+		if err := resetEnvOutput(cfg.EnvFromOutput, pwd); err != nil {
+			return result, Fail(ErrorInvalidConfig, err)
+		}
+	}
+
+	var failure error
+	var warnings []string
+	for i, step := range cfg.Steps {
+		if step.Name == "" {
+			step.Name = fmt.Sprint(i + 1)
+		}
+		if failure != nil {
+			result.Steps = append(result.Steps, StepResult{Name: step.Name, Status: StepSkipped})
+			continue
+		}
+
+		logger.Info("Running step", "step", step.Name, "number", i+1, "of", len(cfg.Steps))
+		stepCfg := stepConfig(cfg, step)
+		// The worktree is recorded once, before the first step
+		stepCfg.Snapshot = cfg.Snapshot && i == 0
+
+		start := time.Now()
+		stepResult, err := Run(ctx, logger, stepCfg)
+		sr := StepResult{
+			Name:        step.Name,
+			Status:      StepSucceeded,
+			Image:       stepResult.Image,
+			ContainerID: stepResult.ContainerID,
+			ExitCode:    stepResult.ExitCode,
+			DurationMs:  time.Since(start).Milliseconds(),
+		}
+		warnings = append(warnings, stepResult.Warnings...)
+		if err != nil {
+			sr.Status, sr.Error = StepFailed, err.Error()
+			if step.ContinueOnError {
+				logger.Warn("Step failed, continuing", "step", step.Name, "error", err)
+				warnings = append(warnings, fmt.Sprintf("step %s failed: %v", step.Name, err))
+			} else {
+				logger.Warn("Step failed", "step", step.Name, "error", err)
+				failure = err
+			}
+		}
+		steps := result.Steps
+		result = stepResult
+		result.Steps = append(steps, sr)
+	}
+
+	result.Checkout, result.Worktree = checkout, worktree
+	result.Warnings = warnings
+	if failure != nil {
+		return result, failure
+	}
+	result.Success = true
+	result.Error = nil
+	succeeded := 0
+	for _, sr := range result.Steps {
+		if sr.Status == StepSucceeded {
+			succeeded++
+		}
+	}
+	result.Message = fmt.Sprintf("%d of %d steps executed successfully", succeeded, len(cfg.Steps))
+	return result, nil
+}
+
+// stepConfig returns the configuration step runs with.
+func stepConfig(cfg Config, step Step) Config {
+	cfg.Steps = nil
+	if step.Image != "" {
+		cfg.Image = step.Image
+		cfg.Build = nil
+	}
+	if len(step.Command) > 0 {
+		cfg.Command = step.Command
+	}
+	if len(step.Entrypoint) > 0 {
+		cfg.Entrypoint = step.Entrypoint
+	}
+	cfg.Environment = append(slices.Clone(cfg.Environment), step.Environment...)
+	if step.WorkingDir != "" {
+		cfg.WorkingDir = step.WorkingDir
+	}
+	cfg.Host = cfg.Host || step.Host
+	return cfg
+}
@@ -0,0 +1,170 @@
+package run
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	cont "github.com/gloo-foo/vsl/internal/container"
+	mnt "github.com/gloo-foo/vsl/internal/mount"
+)
+
+// expandConfig returns a copy of cfg with variables interpolated in its
+// volumes, mount specifications, working directory and env output file and,
+// for scripts, in their other values. The arguments passed to a script are
+// available as ARG1, ARG2 and so on, as 1, 2 and @, and as args.NAME for
+// the inputs and the names the script gives them. Options setting inputs are
+// removed from the arguments, also when values are used literally.
+func expandConfig(cfg Config, vars mnt.Vars) (Config, error) {
+	named, args, err := bindInputs(cfg)
+	if err != nil {
+		return cfg, err
+	}
+	cfg.ScriptArgs = args
+	if cfg.NoInterpolate {
+		return cfg, nil
+	}
+	for i, arg := range cfg.ScriptArgs {
+		vars[fmt.Sprintf("ARG%d", i+1)] = arg
+		vars[strconv.Itoa(i+1)] = arg
+	}
+	for name, value := range named {
+		vars["args."+name] = value
+	}
+	vars["@"] = strings.Join(cfg.ScriptArgs, " ")
+
+	volumes := make([]cont.Volume, len(cfg.Volumes))
+	for i, vol := range cfg.Volumes {
+		// Values are escaped so colons in them do not split the volume spec
+		expanded, err := vars.Expand(string(vol), mnt.EscapeVolumePath)
+		if err != nil {
+			return cfg, err
+		}
+		volumes[i] = cont.Volume(expanded)
+	}
+
+	mounts := make([]cont.MountSpec, len(cfg.Mounts))
+	for i, spec := range cfg.Mounts {
+		expanded, err := vars.Expand(string(spec), nil)
+		if err != nil {
+			return cfg, err
+		}
+		mounts[i] = cont.MountSpec(expanded)
+	}
+
+	workingDir, err := vars.Expand(string(cfg.WorkingDir), nil)
+	if err != nil {
+		return cfg, err
+	}
+
+	envFromOutput, err := vars.Expand(cfg.EnvFromOutput, nil)
+	if err != nil {
+		return cfg, err
+	}
+
+	cfg.Volumes = volumes
+	cfg.Mounts = mounts
+	cfg.WorkingDir = cont.WorkingDir(workingDir)
+	cfg.EnvFromOutput = envFromOutput
+	if cfg.ScriptPath != "" {
+		return expandScript(cfg, vars)
+	}
+	return cfg, nil
+}
+
+// expandScript interpolates ${VAR} references in the image, user, command,
+// entrypoint and environment a script declares. Unbraced references are left
+// to the shell in the container. A command that places the script's
+// arguments itself no longer has them appended.
+func expandScript(cfg Config, vars mnt.Vars) (Config, error) {
+	image, err := vars.ExpandBraced(string(cfg.Image))
+	if err != nil {
+		return cfg, err
+	}
+	user, err := vars.ExpandBraced(string(cfg.User))
+	if err != nil {
+		return cfg, err
+	}
+	command, placed, err := expandCommand(cfg.Command, cfg.ScriptArgs, vars)
+	if err != nil {
+		return cfg, err
+	}
+	if placed {
+		cfg.ScriptArgs = nil
+	}
+	entrypoint, err := expandAll(cfg.Entrypoint, vars)
+	if err != nil {
+		return cfg, err
+	}
+	env, err := expandAll(cfg.Environment, vars)
+	if err != nil {
+		return cfg, err
+	}
+
+	cfg.Image = cont.Image(image)
+	cfg.User = cont.User(user)
+	cfg.Command = command
+	cfg.Entrypoint = entrypoint
+	cfg.Environment = env
+	return cfg, nil
+}
+
+// expandAll interpolates ${VAR} references in each of values.
+func expandAll[T ~string](values []T, vars mnt.Vars) ([]T, error) {
+	expanded := make([]T, len(values))
+	for i, value := range values {
+		s, err := vars.ExpandBraced(string(value))
+		if err != nil {
+			return nil, err
+		}
+		expanded[i] = T(s)
+	}
+	return expanded, nil
+}
+
+// argReference matches the references to script arguments within a command
+// element: ${1}, ${@} and ${args.NAME}, unless escaped as $${.
+var argReference = regexp.MustCompile(`(^|[^$])\$\{(@|[0-9]+|args\.[^}]+)\}`)
+
+// argElement matches a command element that is only a positional argument,
+// such as $1, which is substituted although unbraced.
+var argElement = regexp.MustCompile(`^\$([1-9][0-9]*)$`)
+
+// expandCommand interpolates ${VAR} references in a script's command and
+// places the script's arguments where it references them: an element that
+// is only $@ or ${@} is replaced by every argument, one element each, and an
+// element that is only $N by the Nth argument. It reports whether the command
+// references its arguments, which are otherwise appended to it.
+func expandCommand(command []cont.Command, args []string, vars mnt.Vars) ([]cont.Command, bool, error) {
+	expanded := make([]cont.Command, 0, len(command))
+	placed := false
+	for _, c := range command {
+		s := string(c)
+		if s == "$@" || s == "${@}" {
+			for _, arg := range args {
+				expanded = append(expanded, cont.Command(arg))
+			}
+			placed = true
+			continue
+		}
+		if m := argElement.FindStringSubmatch(s); m != nil {
+			n, _ := strconv.Atoi(m[1])
+			if n > len(args) {
+				return nil, false, fmt.Errorf("%q: undefined variable %d", s, n)
+			}
+			expanded = append(expanded, cont.Command(args[n-1]))
+			placed = true
+			continue
+		}
+		if argReference.MatchString(s) {
+			placed = true
+		}
+		e, err := vars.ExpandBraced(s)
+		if err != nil {
+			return nil, false, err
+		}
+		expanded = append(expanded, cont.Command(e))
+	}
+	return expanded, placed, nil
+}
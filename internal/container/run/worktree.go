@@ -0,0 +1,67 @@
+package run
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+
+	"github.com/gloo-foo/vsl/internal/git"
+	"github.com/gloo-foo/vsl/internal/state"
+	"github.com/gloo-foo/vsl/internal/vcs"
+)
+
+// worktreeDir is the directory of the vsl cache holding temporary worktrees.
+const worktreeDir = "worktrees"
+
+// enterWorktree checks out cfg.Worktree of the repository containing the
+// current directory in a temporary linked worktree and enters it, in the
+// same subdirectory when the ref has it. The returned function goes back to
+// the current directory and removes the worktree with any changes made in it.
+func enterWorktree(ctx context.Context, logger *slog.Logger, cfg Config) (string, func(), error) {
+	pwd, err := os.Getwd()
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to get current directory: %w", err)
+	}
+	system, root, err := findRoot(cfg, pwd)
+	if err != nil || system.Name() == vcs.NameMercurial {
+		return "", nil, fmt.Errorf("worktrees need a git repository")
+	}
+	cache, err := state.CacheDir()
+	if err != nil {
+		return "", nil, err
+	}
+	if err := os.MkdirAll(filepath.Join(cache, worktreeDir), 0o700); err != nil {
+		return "", nil, fmt.Errorf("failed to create worktree directory: %w", err)
+	}
+	dir, err := os.MkdirTemp(filepath.Join(cache, worktreeDir), filepath.Base(string(root))+"-")
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to create worktree directory: %w", err)
+	}
+
+	logger.Info("Creating worktree", "ref", cfg.Worktree, "path", dir)
+	if err := git.AddWorktree(ctx, root, dir, cfg.Worktree); err != nil {
+		_ = os.Remove(dir)
+		return "", nil, fmt.Errorf("failed to create worktree of %s: %w", cfg.Worktree, err)
+	}
+	leave := func() {
+		_ = os.Chdir(pwd)
+		logger.Info("Removing worktree", "path", dir)
+		if err := git.RemoveWorktree(context.WithoutCancel(ctx), root, dir); err != nil {
+			logger.Warn("Failed to remove worktree", "path", dir, "error", err)
+		}
+	}
+
+	workDir := dir
+	if rel, err := filepath.Rel(string(root), pwd); err == nil && filepath.IsLocal(rel) {
+		if info, err := os.Stat(filepath.Join(dir, rel)); err == nil && info.IsDir() {
+			workDir = filepath.Join(dir, rel)
+		}
+	}
+	if err := os.Chdir(workDir); err != nil {
+		leave()
+		return "", nil, fmt.Errorf("failed to enter worktree: %w", err)
+	}
+	return dir, leave, nil
+}
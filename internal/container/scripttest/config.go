@@ -0,0 +1,20 @@
+package scripttest
+
+import (
+	"github.com/gloo-foo/vsl/internal/app"
+	"github.com/gloo-foo/vsl/internal/app/log"
+	"github.com/gloo-foo/vsl/internal/container"
+)
+
+// Config holds configuration for testing UP scripts.
+type Config struct {
+	Scripts []container.ScriptPath // Scripts whose tests block is run
+	DryRun  bool                   // Validate scripts and their test cases without running containers
+
+	// Output and logging
+	Output  app.FilePath
+	Logging log.Config
+}
+
+func (c Config) OutputFilePath() app.FilePath { return c.Output }
+func (c Config) LoggerConfig() log.Config     { return c.Logging }
@@ -0,0 +1,232 @@
+// Package scripttest runs the test cases declared in UP scripts, so scripts
+// can be tested in CI like code.
+package scripttest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"regexp"
+	"time"
+
+	"github.com/gloo-foo/vsl/internal/container"
+	"github.com/gloo-foo/vsl/internal/container/run"
+	"github.com/gloo-foo/vsl/internal/container/stream"
+	"github.com/gloo-foo/vsl/internal/docker"
+	"github.com/gloo-foo/vsl/internal/script"
+)
+
+// ErrFailed is returned when a script or one of its test cases fails.
+var ErrFailed = errors.New("script tests failed")
+
+// Status is the outcome of a test case.
+type Status string
+
+// Test case outcomes.
+const (
+	StatusPassed  Status = "passed"
+	StatusFailed  Status = "failed"
+	StatusSkipped Status = "skipped" // Not run, as in a dry run or without a daemon
+)
+
+// pingTimeout bounds the check for a Docker daemon to run test cases in.
+const pingTimeout = 5 * time.Second
+
+// Result holds the outcome of testing scripts.
+type Result struct {
+	Success  bool           `json:"success"`
+	DryRun   bool           `json:"dry_run,omitempty"`
+	NoDaemon bool           `json:"no_daemon,omitempty"` // The Docker daemon was unavailable, so cases were skipped
+	Summary  Summary        `json:"summary"`
+	Scripts  []ScriptResult `json:"scripts"`
+	Error    string         `json:"error,omitempty"`
+}
+
+// Summary counts test cases by outcome. Invalid scripts count as failures.
+type Summary struct {
+	Passed  int `json:"passed"`
+	Failed  int `json:"failed"`
+	Skipped int `json:"skipped"`
+}
+
+// ScriptResult holds the outcome of a script's test cases.
+type ScriptResult struct {
+	Script container.ScriptPath `json:"script"`
+	Cases  []CaseResult         `json:"cases"`
+	Error  string               `json:"error,omitempty"` // The script or its tests block is invalid
+}
+
+// CaseResult holds the outcome of a test case.
+type CaseResult struct {
+	Name       string   `json:"name"`
+	Status     Status   `json:"status"`
+	ExitCode   *int     `json:"exit_code,omitempty"`
+	DurationMs int64    `json:"duration_ms,omitempty"`
+	Failures   []string `json:"failures,omitempty"`
+}
+
+// MarshalJSON implements json.Marshaler
+func (r Result) MarshalJSON() ([]byte, error) {
+	type Alias Result
+	return json.Marshal((Alias)(r))
+}
+
+// Failed implements app.Failable
+func (r Result) Failed(err error) json.Marshaler {
+	r.Success = false
+	r.Error = err.Error()
+	return r
+}
+
+// Test runs the test cases of each script. Each case runs the script in an
+// ephemeral container with the case's arguments and checks the exit code,
+// output patterns, and produced files. A dry run only validates the scripts
+// and their test declarations, as does a run without a Docker daemon.
+func Test(ctx context.Context, logger *slog.Logger, cfg Config) (Result, error) {
+	result := Result{DryRun: cfg.DryRun, Scripts: make([]ScriptResult, 0, len(cfg.Scripts))}
+
+	skip := cfg.DryRun
+	if !skip {
+		if err := pingDaemon(ctx); err != nil {
+			logger.Warn("Docker daemon unavailable, skipping test cases", "error", err)
+			result.NoDaemon, skip = true, true
+		}
+	}
+
+	for _, path := range cfg.Scripts {
+		sr := testScript(ctx, logger, path, skip)
+		if sr.Error != "" {
+			result.Summary.Failed++
+		}
+		for _, c := range sr.Cases {
+			switch c.Status {
+			case StatusPassed:
+				result.Summary.Passed++
+			case StatusFailed:
+				result.Summary.Failed++
+			case StatusSkipped:
+				result.Summary.Skipped++
+			}
+		}
+		result.Scripts = append(result.Scripts, sr)
+	}
+
+	logger.Info("Script tests finished", "passed", result.Summary.Passed, "failed", result.Summary.Failed, "skipped", result.Summary.Skipped)
+	if result.Summary.Failed > 0 {
+		return result, ErrFailed
+	}
+	result.Success = true
+	return result, nil
+}
+
+// pingDaemon checks that the Docker daemon answers.
+func pingDaemon(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, pingTimeout)
+	defer cancel()
+	dockerCli, err := docker.NewClient()
+	if err != nil {
+		return err
+	}
+	defer docker.Close(dockerCli)
+	_, err = dockerCli.Ping(ctx)
+	return err
+}
+
+// testScript parses a script and runs its test cases, or only validates them
+// when skip is set.
+func testScript(ctx context.Context, logger *slog.Logger, path container.ScriptPath, skip bool) ScriptResult {
+	sr := ScriptResult{Script: path, Cases: []CaseResult{}}
+
+	cfg, err := script.ParseFile(string(path))
+	if err != nil {
+		sr.Error = err.Error()
+		return sr
+	}
+	tests, err := script.ParseTests(string(path))
+	if err != nil {
+		sr.Error = err.Error()
+		return sr
+	}
+	if len(tests) == 0 {
+		logger.Warn("Script declares no tests", "script", path)
+	}
+
+	for _, test := range tests {
+		if skip {
+			sr.Cases = append(sr.Cases, CaseResult{Name: test.Name, Status: StatusSkipped})
+			continue
+		}
+		logger.Info("Running test", "script", path, "test", test.Name)
+		c := runTest(ctx, logger, *cfg, path, test)
+		if c.Status == StatusFailed {
+			logger.Warn("Test failed", "script", path, "test", test.Name, "failures", c.Failures)
+		}
+		sr.Cases = append(sr.Cases, c)
+	}
+	return sr
+}
+
+// runTest runs the script with the case's arguments and checks its assertions.
+func runTest(ctx context.Context, logger *slog.Logger, cfg run.Config, path container.ScriptPath, test script.Test) CaseResult {
+	c := CaseResult{Name: test.Name, Status: StatusPassed}
+
+	// Output is captured without a TTY so stdout and stderr stay separate
+	cfg.ScriptPath = path
+	cfg.ScriptArgs = test.Args
+	cfg.Interactive = false
+	cfg.Capture = true
+	cfg.Quiet = true
+
+	start := time.Now()
+	runResult, err := run.Run(ctx, logger, cfg)
+	c.DurationMs = time.Since(start).Milliseconds()
+	c.ExitCode = runResult.ExitCode
+
+	var exitErr *stream.ExitError
+	if err != nil && !errors.As(err, &exitErr) {
+		c.Status = StatusFailed
+		c.Failures = append(c.Failures, fmt.Sprintf("run failed: %v", err))
+		return c
+	}
+
+	if c.ExitCode == nil || *c.ExitCode != test.ExitCode {
+		got := "none"
+		if c.ExitCode != nil {
+			got = fmt.Sprint(*c.ExitCode)
+		}
+		c.Failures = append(c.Failures, fmt.Sprintf("exit code: expected %d, got %s", test.ExitCode, got))
+	}
+
+	var output stream.Output
+	if runResult.Output != nil {
+		output = *runResult.Output
+	}
+	c.Failures = append(c.Failures, matchOutput("stdout", test.Stdout, output.Stdout)...)
+	c.Failures = append(c.Failures, matchOutput("stderr", test.Stderr, output.Stderr)...)
+
+	for _, f := range test.Files {
+		info, err := os.Stat(f)
+		switch {
+		case err != nil:
+			c.Failures = append(c.Failures, fmt.Sprintf("file %s: not produced", f))
+		case info.ModTime().Before(start.Truncate(time.Second)):
+			c.Failures = append(c.Failures, fmt.Sprintf("file %s: not modified by the run", f))
+		}
+	}
+
+	if len(c.Failures) > 0 {
+		c.Status = StatusFailed
+	}
+	return c
+}
+
+// matchOutput checks a captured stream against an expected pattern.
+func matchOutput(name string, pattern *regexp.Regexp, output string) []string {
+	if pattern == nil || pattern.MatchString(output) {
+		return nil
+	}
+	return []string{fmt.Sprintf("%s: does not match %q", name, pattern.String())}
+}
@@ -0,0 +1,22 @@
+package selftest
+
+import (
+	"github.com/gloo-foo/vsl/internal/app"
+	"github.com/gloo-foo/vsl/internal/app/log"
+	"github.com/gloo-foo/vsl/internal/container"
+)
+
+// DefaultImage is the small image the checks run when none is configured.
+const DefaultImage container.Image = "busybox:latest"
+
+// Config holds configuration for the self test.
+type Config struct {
+	Image container.Image // Image the checks run, pulled first; needs sh, cat, id and touch
+
+	// Output and logging
+	Output  app.FilePath
+	Logging log.Config
+}
+
+func (c Config) OutputFilePath() app.FilePath { return c.Output }
+func (c Config) LoggerConfig() log.Config     { return c.Logging }
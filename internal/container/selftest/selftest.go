@@ -0,0 +1,380 @@
+// Package selftest exercises the run pipeline against a small image and
+// reports which capabilities work on the current host.
+package selftest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	dcontainer "github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/client"
+	"golang.org/x/term"
+
+	"github.com/gloo-foo/vsl/internal/container"
+	"github.com/gloo-foo/vsl/internal/container/run"
+	"github.com/gloo-foo/vsl/internal/container/stream"
+	"github.com/gloo-foo/vsl/internal/docker"
+	"github.com/gloo-foo/vsl/internal/image"
+	"github.com/gloo-foo/vsl/internal/paths"
+)
+
+// Files the checks exchange with the container through the mounted directory.
+const (
+	markerFile  = "marker"
+	writtenFile = "written"
+	ownedFile   = "owned"
+)
+
+// Waiting for auto-removed containers, whose removal completes shortly after they exit.
+const (
+	cleanupWait = 10 * time.Second
+	cleanupPoll = 250 * time.Millisecond
+)
+
+// Status is the outcome of a check.
+type Status string
+
+// Check outcomes.
+const (
+	StatusPass Status = "pass"
+	StatusFail Status = "fail"
+	StatusSkip Status = "skip" // Not applicable on this host, or blocked by an earlier failure
+)
+
+// Check is the outcome of one capability check.
+type Check struct {
+	Name       string `json:"name"`
+	Status     Status `json:"status"`
+	Detail     string `json:"detail,omitempty"`
+	DurationMs int64  `json:"duration_ms,omitempty"`
+}
+
+// Result holds the outcome of every check.
+type Result struct {
+	Success  bool            `json:"success"`
+	Image    container.Image `json:"image"`
+	Platform string          `json:"platform"`
+	Checks   []Check         `json:"checks"`
+	Passes   int             `json:"passed"`
+	Failures int             `json:"failed"`
+	Skips    int             `json:"skipped"`
+	Message  string          `json:"message"`
+	Error    string          `json:"error,omitempty"`
+}
+
+// MarshalJSON implements json.Marshaler
+func (r Result) MarshalJSON() ([]byte, error) {
+	type Alias Result
+	return json.Marshal((Alias)(r))
+}
+
+// Failed implements app.Failable
+func (r Result) Failed(err error) json.Marshaler {
+	r.Success = false
+	r.Error = err.Error()
+	return r
+}
+
+// skipped is returned by checks that do not apply on this host.
+type skipped string
+
+func (s skipped) Error() string { return string(s) }
+
+// harness is the state shared by the checks.
+type harness struct {
+	logger    *slog.Logger
+	image     container.Image
+	session   container.Session
+	dir       string
+	dockerCli *client.Client
+	blocked   string // Why the remaining checks cannot run
+}
+
+// checks run in order, each relying on those before it.
+var checks = []struct {
+	name string
+	run  func(*harness, context.Context) (string, error)
+}{
+	{"daemon", (*harness).daemon},
+	{"pull", (*harness).pull},
+	{"mount", (*harness).mount},
+	{"map_user", (*harness).mapUser},
+	{"interactive", (*harness).interactive},
+	{"exit_code", (*harness).exitCode},
+	{"cleanup", (*harness).cleanup},
+}
+
+// SelfTest runs containers from a small image through the same pipeline as
+// vsl run, checking the daemon connection, image pulls, directory mounts,
+// user mapping, TTY allocation, exit code propagation and container removal.
+// The outcome of each check is also written to stderr as a table, and the
+// self test fails when any check fails.
+func SelfTest(ctx context.Context, logger *slog.Logger, cfg Config) (Result, error) {
+	if cfg.Image == "" {
+		cfg.Image = DefaultImage
+	}
+	result := Result{
+		Image:    cfg.Image,
+		Platform: runtime.GOOS + "/" + runtime.GOARCH,
+		Checks:   []Check{},
+	}
+
+	dir, err := os.MkdirTemp("", "vsl-selftest-")
+	if err != nil {
+		return result, fmt.Errorf("failed to create test directory: %w", err)
+	}
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	// Runs mount the current directory
+	previous, err := os.Getwd()
+	if err != nil {
+		return result, fmt.Errorf("failed to get current directory: %w", err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		return result, fmt.Errorf("failed to enter test directory: %w", err)
+	}
+	defer func() { _ = os.Chdir(previous) }()
+
+	h := &harness{
+		logger:  logger,
+		image:   cfg.Image,
+		session: container.Session(fmt.Sprintf("selftest-%d", os.Getpid())),
+		dir:     dir,
+	}
+	defer func() {
+		if h.dockerCli != nil {
+			docker.Close(h.dockerCli)
+		}
+	}()
+
+	for _, c := range checks {
+		check := Check{Name: c.name}
+		if h.blocked != "" {
+			check.Status, check.Detail = StatusSkip, h.blocked
+			result.Checks = append(result.Checks, check)
+			continue
+		}
+
+		logger.Info("Running check", "check", c.name)
+		started := time.Now()
+		detail, err := c.run(h, ctx)
+		check.DurationMs = time.Since(started).Milliseconds()
+		var skip skipped
+		switch {
+		case errors.As(err, &skip):
+			check.Status, check.Detail = StatusSkip, skip.Error()
+		case err != nil:
+			check.Status, check.Detail = StatusFail, err.Error()
+		default:
+			check.Status, check.Detail = StatusPass, detail
+		}
+		result.Checks = append(result.Checks, check)
+	}
+
+	for _, c := range result.Checks {
+		switch c.Status {
+		case StatusPass:
+			result.Passes++
+		case StatusFail:
+			result.Failures++
+		case StatusSkip:
+			result.Skips++
+		}
+	}
+	writeMatrix(os.Stderr, result.Checks)
+
+	if result.Failures > 0 {
+		return result, fmt.Errorf("%d of %d checks failed", result.Failures, len(result.Checks))
+	}
+	result.Success = true
+	result.Message = fmt.Sprintf("%d checks passed, %d skipped", result.Passes, result.Skips)
+	return result, nil
+}
+
+// writeMatrix writes the outcome of the checks as a table.
+func writeMatrix(w io.Writer, checks []Check) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "CHECK\tSTATUS\tDETAIL")
+	for _, c := range checks {
+		_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\n", c.Name, c.Status, c.Detail)
+	}
+	_ = tw.Flush()
+}
+
+// run runs script with sh in a container of the test image, capturing its
+// output, after configure adjusts the configuration.
+func (h *harness) run(ctx context.Context, script string, configure func(*run.Config)) (run.Result, error) {
+	cfg := run.Config{
+		Image:     h.image,
+		Command:   []container.Command{"sh", "-c", container.Command(script)},
+		Session:   h.session,
+		NoGit:     true,
+		Capture:   true,
+		NoHistory: true,
+	}
+	if configure != nil {
+		configure(&cfg)
+	}
+	return run.Run(ctx, h.logger, cfg)
+}
+
+// stdout returns the captured output of a run without surrounding whitespace.
+func stdout(result run.Result) string {
+	if result.Output == nil {
+		return ""
+	}
+	return strings.TrimSpace(result.Output.Stdout)
+}
+
+// daemon connects to the Docker daemon.
+func (h *harness) daemon(ctx context.Context) (string, error) {
+	dockerCli, err := docker.NewClient(docker.WithRetry(h.logger))
+	if err != nil {
+		h.blocked = "no Docker client"
+		return "", err
+	}
+	h.dockerCli = dockerCli
+	version, err := dockerCli.ServerVersion(ctx)
+	if err != nil {
+		h.blocked = "Docker daemon unreachable"
+		return "", fmt.Errorf("failed to query daemon version: %w", err)
+	}
+	return fmt.Sprintf("Docker %s, API %s, %s/%s", version.Version, version.APIVersion, version.Os, version.Arch), nil
+}
+
+// pull pulls the test image. The remaining checks still run when the pull
+// fails but the image is present, e.g. offline.
+func (h *harness) pull(ctx context.Context) (string, error) {
+	err := image.Pull(ctx, h.logger, h.dockerCli, h.image)
+	if err == nil {
+		return "pulled " + string(h.image), nil
+	}
+	if _, inspectErr := h.dockerCli.ImageInspect(ctx, string(h.image)); inspectErr != nil {
+		h.blocked = fmt.Sprintf("image %s unavailable", h.image)
+	}
+	return "", err
+}
+
+// mount checks that the container reads and writes the mounted directory.
+func (h *harness) mount(ctx context.Context) (string, error) {
+	token := fmt.Sprintf("vsl-selftest-%d", time.Now().UnixNano())
+	if err := os.WriteFile(filepath.Join(h.dir, markerFile), []byte(token+"\n"), 0o644); err != nil {
+		return "", fmt.Errorf("failed to write marker file: %w", err)
+	}
+	result, err := h.run(ctx, "cat "+markerFile+" && echo ok > "+writtenFile, nil)
+	if err != nil {
+		return "", err
+	}
+	if got := stdout(result); got != token {
+		return "", fmt.Errorf("container read %q from the mounted directory, expected %q", got, token)
+	}
+	if _, err := os.Stat(filepath.Join(h.dir, writtenFile)); err != nil {
+		return "", fmt.Errorf("file written by the container is missing on the host: %w", err)
+	}
+	return "read and wrote " + h.dir, nil
+}
+
+// mapUser checks that a run as the invoking user creates files it owns.
+func (h *harness) mapUser(ctx context.Context) (string, error) {
+	uid, gid, ok := paths.CurrentOwner()
+	if !ok {
+		return "", skipped("user mapping does not apply on " + runtime.GOOS)
+	}
+	user := fmt.Sprintf("%d:%d", uid, gid)
+	result, err := h.run(ctx, "id -u && touch "+ownedFile, func(c *run.Config) {
+		c.User = container.User(user)
+	})
+	if err != nil {
+		return "", err
+	}
+	if got := stdout(result); got != strconv.Itoa(uid) {
+		return "", fmt.Errorf("container ran as uid %q, expected %d", got, uid)
+	}
+	info, err := os.Stat(filepath.Join(h.dir, ownedFile))
+	if err != nil {
+		return "", fmt.Errorf("file created by the container is missing on the host: %w", err)
+	}
+	if owner, _, ok := paths.Owner(info); ok && owner != uid {
+		return "", fmt.Errorf("file created by the container is owned by uid %d on the host, expected %d", owner, uid)
+	}
+	return "ran as " + user, nil
+}
+
+// interactive checks that only interactive runs get a TTY.
+func (h *harness) interactive(ctx context.Context) (string, error) {
+	const probe = "if [ -t 1 ]; then echo tty; else echo notty; fi"
+	for _, want := range []struct {
+		interactive bool
+		output      string
+	}{{true, "tty"}, {false, "notty"}} {
+		result, err := h.run(ctx, probe, func(c *run.Config) {
+			c.Interactive = want.interactive
+		})
+		if err != nil {
+			return "", err
+		}
+		if got := stdout(result); got != want.output {
+			return "", fmt.Errorf("interactive %t: container saw %q, expected %q", want.interactive, got, want.output)
+		}
+	}
+	terminal := "not a terminal"
+	if term.IsTerminal(int(os.Stdin.Fd())) {
+		terminal = "a terminal"
+	}
+	return "TTY allocated for interactive runs only; stdin here is " + terminal, nil
+}
+
+// exitCode checks that the exit code of the container is reported.
+func (h *harness) exitCode(ctx context.Context) (string, error) {
+	const code = 3
+	result, err := h.run(ctx, fmt.Sprintf("exit %d", code), nil)
+	var exitErr *stream.ExitError
+	if !errors.As(err, &exitErr) {
+		if err == nil {
+			return "", fmt.Errorf("exit %d was reported as success", code)
+		}
+		return "", err
+	}
+	if exitErr.Code != code || result.ExitCode == nil || *result.ExitCode != code {
+		return "", fmt.Errorf("exit %d was reported as exit %d", code, exitErr.Code)
+	}
+	return fmt.Sprintf("exit %d propagated", code), nil
+}
+
+// cleanup checks that the containers of the previous checks were removed.
+func (h *harness) cleanup(ctx context.Context) (string, error) {
+	args := filters.NewArgs()
+	for _, label := range container.LabelFilter(h.session) {
+		args.Add("label", label)
+	}
+	deadline := time.Now().Add(cleanupWait)
+	for {
+		left, err := h.dockerCli.ContainerList(ctx, dcontainer.ListOptions{All: true, Filters: args})
+		if err != nil {
+			return "", fmt.Errorf("failed to list containers: %w", err)
+		}
+		if len(left) == 0 {
+			return "no containers left behind", nil
+		}
+		if time.Now().After(deadline) {
+			return "", fmt.Errorf("%d containers left behind in session %s", len(left), h.session)
+		}
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case <-time.After(cleanupPoll):
+		}
+	}
+}
@@ -0,0 +1,27 @@
+package services
+
+import (
+	"time"
+
+	"github.com/gloo-foo/vsl/internal/app"
+	"github.com/gloo-foo/vsl/internal/app/log"
+	"github.com/gloo-foo/vsl/internal/container"
+	"github.com/gloo-foo/vsl/internal/units"
+)
+
+// DefaultWaitTimeout is how long a service may take to become healthy.
+const DefaultWaitTimeout = units.Duration(2 * time.Minute)
+
+// Config holds configuration for starting or removing the services of a script.
+type Config struct {
+	Script      container.ScriptPath // Script declaring the services
+	Wait        bool                 // Wait for every service to be healthy, not only those others depend on
+	WaitTimeout units.Duration       // How long a service may take to become healthy
+
+	// Output and logging
+	Output  app.FilePath
+	Logging log.Config
+}
+
+func (c Config) OutputFilePath() app.FilePath { return c.Output }
+func (c Config) LoggerConfig() log.Config     { return c.Logging }
@@ -0,0 +1,334 @@
+// Package services starts the services a script declares as a group of
+// detached containers on a shared network, in dependency order and waiting
+// for dependencies to be healthy, and removes the group again.
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/client"
+	cont "github.com/gloo-foo/vsl/internal/container"
+	"github.com/gloo-foo/vsl/internal/container/run"
+	"github.com/gloo-foo/vsl/internal/docker"
+	"github.com/gloo-foo/vsl/internal/script"
+)
+
+// Service start actions.
+const (
+	ActionCreated = "created" // The service had no container yet
+)
+
+// healthPoll is the interval between checks of a starting service.
+const healthPoll = 500 * time.Millisecond
+
+// unsafeName matches characters not allowed in container and network names.
+var unsafeName = regexp.MustCompile(`[^a-zA-Z0-9_.-]+`)
+
+// Result holds the services of a script started or removed.
+type Result struct {
+	Success    bool               `json:"success"`
+	Group      string             `json:"group"`
+	Network    string             `json:"network,omitempty"`
+	Services   []ServiceResult    `json:"services,omitempty"`
+	Containers []cont.ContainerID `json:"containers,omitempty"` // Removed by down
+	Message    string             `json:"message"`
+	Error      string             `json:"error,omitempty"`
+}
+
+// ServiceResult holds the container of a service started by Up.
+type ServiceResult struct {
+	Name        string           `json:"name"`
+	ContainerID cont.ContainerID `json:"container_id,omitempty"`
+	Action      string           `json:"action,omitempty"` // created, restarted or recreated
+	Health      string           `json:"health,omitempty"` // healthy or running, when it was waited for
+	Error       string           `json:"error,omitempty"`
+}
+
+// MarshalJSON implements json.Marshaler
+func (r Result) MarshalJSON() ([]byte, error) {
+	type Alias Result
+	return json.Marshal((Alias)(r))
+}
+
+// Failed implements app.Failable
+func (r Result) Failed(err error) json.Marshaler {
+	r.Success = false
+	r.Error = err.Error()
+	return r
+}
+
+// Group returns the name of the group of services of the script at path,
+// which names their network, session and containers.
+func Group(path cont.ScriptPath) (string, error) {
+	abs, err := filepath.Abs(string(path))
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256([]byte(abs))
+	base := strings.TrimSuffix(filepath.Base(abs), filepath.Ext(abs))
+	return "vsl-" + unsafeName.ReplaceAllString(base, "-") + "-" + hex.EncodeToString(sum[:])[:8], nil
+}
+
+// Up starts the services of a script, each in a detached container named
+// after the group and the service and reachable by the service name on the
+// group's network. Services start after those they depend on are healthy;
+// services already running are converged like vsl restart does.
+func Up(ctx context.Context, logger *slog.Logger, cfg Config) (Result, error) {
+	group, err := Group(cfg.Script)
+	if err != nil {
+		return Result{}, err
+	}
+	result := Result{Group: group, Network: group, Services: []ServiceResult{}}
+
+	declared, err := script.ParseServices(string(cfg.Script))
+	if err != nil {
+		return result, fmt.Errorf("script %s: %w", cfg.Script, err)
+	}
+	if len(declared) == 0 {
+		return result, fmt.Errorf("script %s declares no services", cfg.Script)
+	}
+	order, err := startOrder(declared)
+	if err != nil {
+		return result, err
+	}
+	needed := map[string]bool{}
+	for _, s := range declared {
+		for _, dep := range s.DependsOn {
+			needed[dep] = true
+		}
+	}
+
+	dockerCli, err := docker.NewClient(docker.WithRetry(logger))
+	if err != nil {
+		return result, err
+	}
+	defer docker.Close(dockerCli)
+
+	if err := ensureNetwork(ctx, logger, dockerCli, group, cfg.Script); err != nil {
+		return result, err
+	}
+	existing, err := groupContainers(ctx, dockerCli, group)
+	if err != nil {
+		return result, err
+	}
+
+	for _, s := range order {
+		sr := ServiceResult{Name: s.Name}
+		runCfg := serviceConfig(s, cfg.Script, group)
+		var r run.Result
+		if c, ok := existing[s.Name]; ok {
+			logger.Info("Converging service", "service", s.Name, "id", c.ID)
+			var convergence run.Convergence
+			r, convergence, err = run.Converge(ctx, logger, runCfg, c.ID)
+			sr.Action = string(convergence.Action)
+		} else {
+			logger.Info("Starting service", "service", s.Name)
+			r, err = run.Run(ctx, logger, runCfg)
+			sr.Action = ActionCreated
+		}
+		sr.ContainerID = r.ContainerID
+		if err == nil && (needed[s.Name] || cfg.Wait) {
+			logger.Info("Waiting for service to be healthy", "service", s.Name)
+			sr.Health, err = waitHealthy(ctx, dockerCli, string(r.ContainerID), time.Duration(cfg.WaitTimeout))
+		}
+		if err != nil {
+			sr.Error = err.Error()
+			result.Services = append(result.Services, sr)
+			return result, fmt.Errorf("service %s: %w", s.Name, err)
+		}
+		result.Services = append(result.Services, sr)
+	}
+
+	result.Success = true
+	result.Message = fmt.Sprintf("Started %d services", len(result.Services))
+	return result, nil
+}
+
+// Down removes the containers and the network of the services of a script.
+func Down(ctx context.Context, logger *slog.Logger, cfg Config) (Result, error) {
+	group, err := Group(cfg.Script)
+	if err != nil {
+		return Result{}, err
+	}
+	result := Result{Group: group, Containers: []cont.ContainerID{}}
+
+	dockerCli, err := docker.NewClient(docker.WithRetry(logger))
+	if err != nil {
+		return result, err
+	}
+	defer docker.Close(dockerCli)
+
+	existing, err := groupContainers(ctx, dockerCli, group)
+	if err != nil {
+		return result, err
+	}
+	names := make([]string, 0, len(existing))
+	for name := range existing {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		c := existing[name]
+		logger.Info("Removing service", "service", name, "id", c.ID)
+		if err := dockerCli.ContainerRemove(ctx, c.ID, container.RemoveOptions{Force: true}); err != nil {
+			return result, fmt.Errorf("failed to remove service %s: %w", name, err)
+		}
+		result.Containers = append(result.Containers, cont.ContainerID(c.ID))
+	}
+
+	networks, err := dockerCli.NetworkList(ctx, network.ListOptions{Filters: filters.NewArgs(filters.Arg("label", cont.LabelGroup+"="+group))})
+	if err != nil {
+		return result, fmt.Errorf("failed to list networks: %w", err)
+	}
+	for _, n := range networks {
+		logger.Info("Removing network", "name", n.Name)
+		if err := dockerCli.NetworkRemove(ctx, n.ID); err != nil {
+			return result, fmt.Errorf("failed to remove network %s: %w", n.Name, err)
+		}
+		result.Network = n.Name
+	}
+
+	result.Success = true
+	result.Message = fmt.Sprintf("Removed %d services", len(result.Containers))
+	return result, nil
+}
+
+// serviceConfig returns the configuration a service runs with: detached, on
+// the group's network under its own name.
+func serviceConfig(s script.Service, path cont.ScriptPath, group string) run.Config {
+	cfg := s.Config
+	cfg.ScriptPath = path
+	cfg.Session = cont.Session(group)
+	cfg.Detach = true
+	cfg.Interactive = false
+	cfg.Before, cfg.After = nil, nil
+	cfg.NetworkMode = cont.NetworkMode(group)
+	cfg.Name = group + "-" + s.Name
+	cfg.NetworkAliases = []string{s.Name}
+	cfg.Labels = map[string]string{cont.LabelGroup: group, cont.LabelService: s.Name}
+	return cfg
+}
+
+// startOrder sorts services so each comes after those it depends on, by
+// name among those ready to start.
+func startOrder(services []script.Service) ([]script.Service, error) {
+	byName := make(map[string]script.Service, len(services))
+	for _, s := range services {
+		byName[s.Name] = s
+	}
+	for _, s := range services {
+		for _, dep := range s.DependsOn {
+			if _, ok := byName[dep]; !ok {
+				return nil, fmt.Errorf("service %s depends on unknown service %s", s.Name, dep)
+			}
+		}
+	}
+
+	started := map[string]bool{}
+	order := make([]script.Service, 0, len(services))
+	for len(order) < len(services) {
+		progressed := false
+		for _, s := range services {
+			if started[s.Name] || !allStarted(s.DependsOn, started) {
+				continue
+			}
+			started[s.Name] = true
+			order = append(order, s)
+			progressed = true
+		}
+		if !progressed {
+			var waiting []string
+			for _, s := range services {
+				if !started[s.Name] {
+					waiting = append(waiting, s.Name)
+				}
+			}
+			return nil, fmt.Errorf("services depend on each other in a cycle: %s", strings.Join(waiting, ", "))
+		}
+	}
+	return order, nil
+}
+
+func allStarted(names []string, started map[string]bool) bool {
+	for _, name := range names {
+		if !started[name] {
+			return false
+		}
+	}
+	return true
+}
+
+// ensureNetwork creates the group's network unless it exists.
+func ensureNetwork(ctx context.Context, logger *slog.Logger, dockerCli client.NetworkAPIClient, group string, path cont.ScriptPath) error {
+	if _, err := dockerCli.NetworkInspect(ctx, group, network.InspectOptions{}); err == nil {
+		return nil
+	}
+	if abs, err := filepath.Abs(string(path)); err == nil {
+		path = cont.ScriptPath(abs)
+	}
+	labels := cont.Labels(cont.Provenance{Session: cont.Session(group), Script: path, Persistent: true})
+	labels[cont.LabelGroup] = group
+	logger.Info("Creating network", "name", group)
+	if _, err := dockerCli.NetworkCreate(ctx, group, network.CreateOptions{Labels: labels}); err != nil {
+		return fmt.Errorf("failed to create network %s: %w", group, err)
+	}
+	return nil
+}
+
+// groupContainers returns the containers of the group, keyed by service.
+func groupContainers(ctx context.Context, dockerCli client.ContainerAPIClient, group string) (map[string]container.Summary, error) {
+	containers, err := dockerCli.ContainerList(ctx, container.ListOptions{All: true, Filters: filters.NewArgs(filters.Arg("label", cont.LabelGroup+"="+group))})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list containers: %w", err)
+	}
+	byService := make(map[string]container.Summary, len(containers))
+	for _, c := range containers {
+		byService[c.Labels[cont.LabelService]] = c
+	}
+	return byService, nil
+}
+
+// waitHealthy waits for a container to report healthy or, without a health
+// check, to be running, returning which.
+func waitHealthy(ctx context.Context, dockerCli client.ContainerAPIClient, id string, timeout time.Duration) (string, error) {
+	deadline := time.Now().Add(timeout)
+	for {
+		inspect, err := dockerCli.ContainerInspect(ctx, id)
+		if err != nil {
+			return "", fmt.Errorf("failed to inspect container: %w", err)
+		}
+		state := inspect.State
+		switch {
+		case state == nil:
+		case !state.Running && state.Status != container.StateCreated:
+			return "", fmt.Errorf("container exited with code %d", state.ExitCode)
+		case state.Health == nil && state.Running:
+			return "running", nil
+		case state.Health != nil && state.Health.Status == container.Healthy:
+			return container.Healthy, nil
+		case state.Health != nil && state.Health.Status == container.Unhealthy:
+			return "", fmt.Errorf("container is unhealthy")
+		}
+		if time.Now().After(deadline) {
+			return "", fmt.Errorf("not healthy after %s", timeout)
+		}
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case <-time.After(healthPoll):
+		}
+	}
+}
@@ -0,0 +1,26 @@
+package stop
+
+import (
+	"github.com/gloo-foo/vsl/internal/app"
+	"github.com/gloo-foo/vsl/internal/app/log"
+	"github.com/gloo-foo/vsl/internal/container"
+	"github.com/gloo-foo/vsl/internal/container/resolve"
+	"github.com/gloo-foo/vsl/internal/units"
+)
+
+// Config holds configuration for stopping or killing containers.
+type Config struct {
+	Containers []resolve.Reference // Containers to stop by name, ID prefix, script path or session
+	Project    container.Project   // Stop every container of the project of this directory
+	All        bool                // Stop every vsl container
+
+	Timeout units.Duration // Time to wait for containers to stop before killing them
+	Signal  string         // Signal sent by kill
+
+	// Output and logging
+	Output  app.FilePath
+	Logging log.Config
+}
+
+func (c Config) OutputFilePath() app.FilePath { return c.Output }
+func (c Config) LoggerConfig() log.Config     { return c.Logging }
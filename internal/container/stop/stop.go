@@ -0,0 +1,177 @@
+// Package stop contains the logic for stopping and killing vsl containers.
+package stop
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/client"
+	cont "github.com/gloo-foo/vsl/internal/container"
+	"github.com/gloo-foo/vsl/internal/container/resolve"
+	"github.com/gloo-foo/vsl/internal/docker"
+	"github.com/gloo-foo/vsl/internal/paths"
+)
+
+// Result holds the containers stopped or killed.
+type Result struct {
+	Success    bool               `json:"success"`
+	Containers []cont.ContainerID `json:"containers"`         // Containers stopped or killed
+	Failures   []Failure          `json:"failures,omitempty"` // Containers that could not be
+	Message    string             `json:"message"`
+	Error      string             `json:"error,omitempty"`
+}
+
+// Failure is a container that could not be stopped or killed.
+type Failure struct {
+	ID    cont.ContainerID `json:"id"`
+	Name  string           `json:"name"`
+	Error string           `json:"error"`
+}
+
+// MarshalJSON implements json.Marshaler
+func (r Result) MarshalJSON() ([]byte, error) {
+	type Alias Result
+	return json.Marshal((Alias)(r))
+}
+
+// Failed implements app.Failable
+func (r Result) Failed(err error) json.Marshaler {
+	r.Success = false
+	r.Error = err.Error()
+	return r
+}
+
+// Stop gracefully stops the selected containers: each is sent its stop
+// signal and killed when it has not exited after the timeout. Containers
+// of detached runs are kept, so they can be restarted.
+func Stop(ctx context.Context, logger *slog.Logger, cfg Config) (Result, error) {
+	timeout := int(time.Duration(cfg.Timeout).Round(time.Second) / time.Second)
+	result, err := each(ctx, logger, cfg, "stop", func(dockerCli client.ContainerAPIClient, id string) error {
+		return dockerCli.ContainerStop(ctx, id, container.StopOptions{Timeout: &timeout})
+	})
+	result.Message = fmt.Sprintf("Stopped %d containers", len(result.Containers))
+	return result, err
+}
+
+// Kill sends the configured signal, SIGKILL by default, to the selected
+// containers.
+func Kill(ctx context.Context, logger *slog.Logger, cfg Config) (Result, error) {
+	result, err := each(ctx, logger, cfg, "kill", func(dockerCli client.ContainerAPIClient, id string) error {
+		return dockerCli.ContainerKill(ctx, id, cfg.Signal)
+	})
+	result.Message = fmt.Sprintf("Killed %d containers", len(result.Containers))
+	return result, err
+}
+
+// each applies op, named verb in messages, to every selected container that
+// is running, in parallel so stopping several containers takes no longer
+// than the slowest. Containers op fails for are reported in the result
+// without stopping the others.
+func each(ctx context.Context, logger *slog.Logger, cfg Config, verb string, op func(client.ContainerAPIClient, string) error) (Result, error) {
+	result := Result{Containers: []cont.ContainerID{}}
+	dockerCli, err := docker.NewClient(docker.WithRetry(logger))
+	if err != nil {
+		return result, err
+	}
+	defer docker.Close(dockerCli)
+
+	targets, err := selectContainers(ctx, dockerCli, cfg)
+	if err != nil {
+		return result, err
+	}
+
+	errs := make([]error, len(targets))
+	var wg sync.WaitGroup
+	for i, c := range targets {
+		if !active(c) {
+			logger.Info("Container is not running", "name", resolve.Name(c), "state", c.State)
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			logger.Info("Signaling container", "action", verb, "name", resolve.Name(c), "id", resolve.ShortID(c.ID))
+			errs[i] = op(dockerCli, c.ID)
+		}()
+	}
+	wg.Wait()
+
+	var failed []string
+	for i, c := range targets {
+		switch {
+		case errs[i] != nil:
+			logger.Warn("Failed to "+verb+" container", "name", resolve.Name(c), "error", errs[i])
+			result.Failures = append(result.Failures, Failure{ID: cont.ContainerID(c.ID), Name: resolve.Name(c), Error: errs[i].Error()})
+			failed = append(failed, fmt.Sprintf("%s: %v", resolve.Name(c), errs[i]))
+		case active(c):
+			result.Containers = append(result.Containers, cont.ContainerID(c.ID))
+		}
+	}
+	if len(failed) > 0 {
+		return result, fmt.Errorf("failed to %s %s", verb, strings.Join(failed, "; "))
+	}
+	result.Success = true
+	return result, nil
+}
+
+// selectContainers resolves the configured references, project or --all to
+// containers.
+func selectContainers(ctx context.Context, dockerCli client.ContainerAPIClient, cfg Config) ([]container.Summary, error) {
+	if !cfg.All && cfg.Project == "" && len(cfg.Containers) == 0 {
+		return nil, fmt.Errorf("no containers selected, pass a container reference, --project or --all")
+	}
+
+	var targets []container.Summary
+	seen := map[string]bool{}
+	add := func(found []container.Summary) {
+		for _, c := range found {
+			if !seen[c.ID] {
+				seen[c.ID] = true
+				targets = append(targets, c)
+			}
+		}
+	}
+
+	if cfg.All || cfg.Project != "" {
+		args := filters.NewArgs()
+		for _, label := range cont.LabelFilter("") {
+			args.Add("label", label)
+		}
+		found, err := dockerCli.ContainerList(ctx, container.ListOptions{All: true, Filters: args})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list containers: %w", err)
+		}
+		project := resolve.ProjectDir(cfg.Project)
+		for _, c := range found {
+			if cfg.All || paths.Equal(c.Labels[cont.LabelProject], project) {
+				add([]container.Summary{c})
+			}
+		}
+	}
+
+	// Names and ID prefixes must each select one container
+	for _, ref := range cfg.Containers {
+		found, err := resolve.Select(ctx, dockerCli, ref)
+		if err != nil {
+			return nil, err
+		}
+		add(found)
+	}
+	return targets, nil
+}
+
+// active reports whether a container has a process to stop.
+func active(c container.Summary) bool {
+	switch c.State {
+	case "running", "paused", "restarting":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,47 @@
+package stream
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/pkg/stdcopy"
+	"golang.org/x/term"
+)
+
+// Attach connects the terminal to a hijacked container connection: stdin is
+// forwarded to the container and its output is written to stdout and stderr
+// until the output ends. With a TTY the terminal is put into raw mode so
+// keystrokes such as Ctrl-C reach the container's shell.
+func Attach(conn types.HijackedResponse, tty bool) error {
+	fd := int(os.Stdin.Fd())
+	if tty && term.IsTerminal(fd) {
+		state, err := term.MakeRaw(fd)
+		if err != nil {
+			return fmt.Errorf("failed to set terminal to raw mode: %w", err)
+		}
+		defer func() {
+			if err := term.Restore(fd, state); err != nil {
+				panic(err)
+			}
+		}()
+	}
+
+	go func() {
+		// Closing the write side signals end of input to the container
+		_, _ = io.Copy(conn.Conn, os.Stdin)
+		_ = conn.CloseWrite()
+	}()
+
+	var err error
+	if tty {
+		_, err = io.Copy(stdout, conn.Reader)
+	} else {
+		_, err = stdcopy.StdCopy(stdout, stderr, conn.Reader)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read container output: %w", err)
+	}
+	return nil
+}
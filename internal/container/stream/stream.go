@@ -0,0 +1,113 @@
+// Package stream copies container output to the terminal and captures it for
+// JSON results, shared by the run and exec commands.
+package stream
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/docker/docker/pkg/stdcopy"
+)
+
+// captureLimit bounds the captured output of each stream.
+const captureLimit = 1 << 20
+
+// Mode selects what happens to container output.
+type Mode struct {
+	Pipe    bool // Copy output to stdout and stderr as it arrives
+	Capture bool // Include output in the JSON result
+}
+
+// Enabled reports whether the output needs to be attached at all.
+func (m Mode) Enabled() bool { return m.Pipe || m.Capture }
+
+// Output is container output captured for the JSON result.
+type Output struct {
+	Stdout    string `json:"stdout"`
+	Stderr    string `json:"stderr"`
+	Truncated bool   `json:"truncated,omitempty"`
+}
+
+// Terminal destinations, replaceable for tests.
+var (
+	stdout io.Writer = os.Stdout
+	stderr io.Writer = os.Stderr
+)
+
+// Copy reads container output from r until it ends. Unless tty is set the
+// stream is multiplexed and split into stdout and stderr. The captured output
+// is nil when capturing is disabled.
+func Copy(r io.Reader, tty bool, mode Mode) (*Output, error) {
+	outs, errs, captured := Writers(mode)
+
+	var err error
+	if tty {
+		_, err = io.Copy(outs, r)
+	} else {
+		_, err = stdcopy.StdCopy(outs, errs, r)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read container output: %w", err)
+	}
+	return captured(), nil
+}
+
+// Writers returns destinations for stdout and stderr according to mode, for
+// output that is not a container stream (such as host processes). The
+// captured function returns the output written so far, or nil when capturing
+// is disabled.
+func Writers(mode Mode) (io.Writer, io.Writer, func() *Output) {
+	var outs, errs []io.Writer
+	if mode.Pipe {
+		outs = append(outs, stdout)
+		errs = append(errs, stderr)
+	}
+	capturedOut := &limitedBuffer{}
+	capturedErr := &limitedBuffer{}
+	if mode.Capture {
+		outs = append(outs, capturedOut)
+		errs = append(errs, capturedErr)
+	}
+
+	captured := func() *Output {
+		if !mode.Capture {
+			return nil
+		}
+		return &Output{
+			Stdout:    capturedOut.String(),
+			Stderr:    capturedErr.String(),
+			Truncated: capturedOut.truncated || capturedErr.truncated,
+		}
+	}
+	return io.MultiWriter(outs...), io.MultiWriter(errs...), captured
+}
+
+// limitedBuffer keeps the first captureLimit bytes written to it and
+// silently drops the rest so the stream keeps flowing.
+type limitedBuffer struct {
+	data      []byte
+	truncated bool
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	room := captureLimit - len(b.data)
+	if len(p) > room {
+		b.data = append(b.data, p[:room]...)
+		b.truncated = true
+	} else {
+		b.data = append(b.data, p...)
+	}
+	return len(p), nil
+}
+
+func (b *limitedBuffer) String() string { return string(b.data) }
+
+// ExitError reports a container process that exited with a non-zero code.
+// It implements cli.ExitCoder so vsl exits with the same code.
+type ExitError struct {
+	Code int
+}
+
+func (e *ExitError) Error() string { return fmt.Sprintf("process exited with code %d", e.Code) }
+func (e *ExitError) ExitCode() int { return e.Code }
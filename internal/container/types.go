@@ -42,3 +42,45 @@ type MountSource string
 
 // MountTarget represents the target path for a bind mount.
 type MountTarget string
+
+// Session represents a named group of resources created during a working session.
+type Session string
+
+// Relabel represents an SELinux relabeling mode for bind mounts (z for shared, Z for private).
+type Relabel string
+
+// Consistency represents a bind mount consistency mode for Docker Desktop on macOS (consistent, cached, delegated).
+type Consistency string
+
+// VolumesFrom represents a container whose volumes are shared, by name or ID with an optional :ro or :rw mode.
+type VolumesFrom string
+
+// MountSpec represents a docker-compatible mount specification (type=bind,src=...,dst=...).
+type MountSpec string
+
+// CachePath represents a container directory backed by a per-project cache volume.
+type CachePath string
+
+// Project represents the host directory identifying a project (its git root, or the working directory outside git).
+type Project string
+
+// Capability represents an optional host capability a script may require (gpu, kata, buildkit).
+type Capability string
+
+// CapabilityStrategy represents how a missing capability is handled (fail or fallback).
+type CapabilityStrategy string
+
+// MaskPath represents a path hidden from the container by an empty overlay mount.
+type MaskPath string
+
+// Port represents a published port such as 8080:80, 127.0.0.1:5432:5432 or 53:53/udp.
+type Port string
+
+// Device represents a host device made available to the container, as host[:container][:permissions].
+type Device string
+
+// CapAdd represents a Linux capability added to the container (NET_ADMIN, SYS_PTRACE).
+type CapAdd string
+
+// MaskMode represents the kind of empty mount used to mask a path (tmpfs or volume).
+type MaskMode string
@@ -0,0 +1,177 @@
+package credentials
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/gloo-foo/vsl/internal/state"
+)
+
+// keySize is the AES-256 key length.
+const keySize = 32
+
+// Keychain entry identifying the store key.
+const (
+	keychainService = "vsl"
+	keychainAccount = "credentials-key"
+)
+
+// keyFileName is the key file used when no OS keychain is available.
+const keyFileName = "credentials.key"
+
+// errNoKey is returned by key sources that hold no key yet.
+var errNoKey = errors.New("no encryption key")
+
+// KeySource loads and saves the key encrypting the credential store.
+type KeySource interface {
+	Name() string
+	Load() ([]byte, error)
+	Save(key []byte) error
+}
+
+// newKey generates a random store key.
+func newKey() ([]byte, error) {
+	key := make([]byte, keySize)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("failed to generate key: %w", err)
+	}
+	return key, nil
+}
+
+// keySource picks where the key lives: an existing key file keeps being used,
+// otherwise the OS keychain when its tool is installed, else a key file.
+func keySource() (KeySource, error) {
+	dir, err := state.ConfigDir()
+	if err != nil {
+		return nil, err
+	}
+	file := fileKey{path: filepath.Join(dir, keyFileName)}
+	if _, err := os.Stat(file.path); err == nil {
+		return file, nil
+	}
+	if keychain, ok := osKeychain(); ok {
+		return keychain, nil
+	}
+	return file, nil
+}
+
+// fileKey keeps the key in a file readable only by the user.
+type fileKey struct {
+	path string
+}
+
+func (f fileKey) Name() string { return "file:" + f.path }
+
+func (f fileKey) Load() ([]byte, error) {
+	data, err := os.ReadFile(f.path)
+	if os.IsNotExist(err) {
+		return nil, errNoKey
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read key file: %w", err)
+	}
+	return decodeKey(string(data))
+}
+
+func (f fileKey) Save(key []byte) error {
+	return writeFileAtomic(f.path, []byte(hex.EncodeToString(key)+"\n"))
+}
+
+// keychain keeps the key in the OS keychain through its command line tool.
+type keychain struct {
+	tool string
+	load []string
+	save func(hexKey string) *exec.Cmd
+}
+
+// osKeychain returns the keychain of this OS if its tool is installed:
+// security on macOS and secret-tool (libsecret) on Linux.
+func osKeychain() (keychain, bool) {
+	var k keychain
+	switch runtime.GOOS {
+	case "darwin":
+		k = keychain{
+			tool: "security",
+			load: []string{"find-generic-password", "-s", keychainService, "-a", keychainAccount, "-w"},
+			// Commands read by security -i keep the key out of the arguments
+			// other processes can list
+			save: func(hexKey string) *exec.Cmd {
+				cmd := exec.Command("security", "-i")
+				cmd.Stdin = strings.NewReader(fmt.Sprintf("add-generic-password -U -s %s -a %s -w %s\n", keychainService, keychainAccount, hexKey))
+				return cmd
+			},
+		}
+	case "linux":
+		k = keychain{
+			tool: "secret-tool",
+			load: []string{"lookup", "service", keychainService, "account", keychainAccount},
+			save: func(hexKey string) *exec.Cmd {
+				cmd := exec.Command("secret-tool", "store", "--label=vsl credentials", "service", keychainService, "account", keychainAccount)
+				cmd.Stdin = strings.NewReader(hexKey)
+				return cmd
+			},
+		}
+	default:
+		return k, false
+	}
+	if _, err := exec.LookPath(k.tool); err != nil {
+		return k, false
+	}
+	return k, true
+}
+
+func (k keychain) Name() string { return "keychain:" + k.tool }
+
+func (k keychain) Load() ([]byte, error) {
+	out, err := exec.Command(k.tool, k.load...).Output()
+	if err != nil || len(bytes.TrimSpace(out)) == 0 {
+		// Both tools fail when the entry does not exist
+		return nil, errNoKey
+	}
+	return decodeKey(string(out))
+}
+
+func (k keychain) Save(key []byte) error {
+	if out, err := k.save(hex.EncodeToString(key)).CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to store key in keychain: %w: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// decodeKey parses a hex-encoded key.
+func decodeKey(s string) ([]byte, error) {
+	key, err := hex.DecodeString(strings.TrimSpace(s))
+	if err != nil || len(key) != keySize {
+		return nil, fmt.Errorf("invalid encryption key")
+	}
+	return key, nil
+}
+
+// writeFileAtomic replaces path with data, readable only by the user.
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := os.Chmod(tmp.Name(), 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
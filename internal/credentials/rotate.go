@@ -0,0 +1,54 @@
+package credentials
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+
+	"github.com/gloo-foo/vsl/internal/app"
+	"github.com/gloo-foo/vsl/internal/app/log"
+)
+
+// RotateConfig holds configuration for rotating the credential store key.
+type RotateConfig struct {
+	// Output and logging
+	Output  app.FilePath
+	Logging log.Config
+}
+
+func (c RotateConfig) OutputFilePath() app.FilePath { return c.Output }
+func (c RotateConfig) LoggerConfig() log.Config     { return c.Logging }
+
+// RotateResult holds the result of a key rotation.
+type RotateResult struct {
+	Success   bool   `json:"success"`
+	KeySource string `json:"key_source"`
+	Entries   int    `json:"entries"`
+	Message   string `json:"message"`
+}
+
+// MarshalJSON implements json.Marshaler
+func (r RotateResult) MarshalJSON() ([]byte, error) {
+	type Alias RotateResult
+	return json.Marshal((Alias)(r))
+}
+
+// RotateKey re-encrypts the credential store with a freshly generated key.
+func RotateKey(_ context.Context, logger *slog.Logger, _ RotateConfig) (RotateResult, error) {
+	store, err := Open()
+	if err != nil {
+		return RotateResult{}, err
+	}
+
+	logger.Info("Rotating credential store key", "entries", len(store.values))
+	if err := store.RotateKey(); err != nil {
+		return RotateResult{}, err
+	}
+
+	return RotateResult{
+		Success:   true,
+		KeySource: store.KeySource(),
+		Entries:   len(store.values),
+		Message:   "Credential store key rotated",
+	}, nil
+}
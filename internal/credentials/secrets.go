@@ -0,0 +1,100 @@
+package credentials
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/gloo-foo/vsl/internal/app"
+	"github.com/gloo-foo/vsl/internal/app/log"
+)
+
+// stdin is where secret values are read from, so they never appear in the
+// arguments other processes can list.
+var stdin io.Reader = os.Stdin
+
+// SecretConfig holds configuration for storing or deleting a secret.
+type SecretConfig struct {
+	Name string // Name of the secret, read by scripts as store:NAME
+
+	// Output and logging
+	Output  app.FilePath
+	Logging log.Config
+}
+
+func (c SecretConfig) OutputFilePath() app.FilePath { return c.Output }
+func (c SecretConfig) LoggerConfig() log.Config     { return c.Logging }
+
+// SecretResult holds the result of storing or deleting a secret.
+type SecretResult struct {
+	Success   bool     `json:"success"`
+	Name      string   `json:"name"`
+	KeySource string   `json:"key_source"`
+	Names     []string `json:"names"` // Secrets in the store afterwards
+	Message   string   `json:"message"`
+}
+
+// MarshalJSON implements json.Marshaler
+func (r SecretResult) MarshalJSON() ([]byte, error) {
+	type Alias SecretResult
+	return json.Marshal((Alias)(r))
+}
+
+// SetSecret stores the value read from stdin, without its trailing newline,
+// under the configured name.
+func SetSecret(_ context.Context, logger *slog.Logger, cfg SecretConfig) (SecretResult, error) {
+	if cfg.Name == "" {
+		return SecretResult{}, fmt.Errorf("expected the name of the secret")
+	}
+	value, err := io.ReadAll(stdin)
+	if err != nil {
+		return SecretResult{}, fmt.Errorf("failed to read the secret from stdin: %w", err)
+	}
+	if len(value) == 0 {
+		return SecretResult{}, fmt.Errorf("no value on stdin for secret %s", cfg.Name)
+	}
+
+	store, err := Open()
+	if err != nil {
+		return SecretResult{}, err
+	}
+	logger.Info("Storing secret", "name", cfg.Name)
+	store.Set(cfg.Name, strings.TrimRight(string(value), "\r\n"))
+	if err := store.Save(); err != nil {
+		return SecretResult{}, err
+	}
+	return SecretResult{
+		Success:   true,
+		Name:      cfg.Name,
+		KeySource: store.KeySource(),
+		Names:     store.Names(),
+		Message:   "Secret " + cfg.Name + " stored",
+	}, nil
+}
+
+// DeleteSecret removes the configured secret from the store.
+func DeleteSecret(_ context.Context, logger *slog.Logger, cfg SecretConfig) (SecretResult, error) {
+	store, err := Open()
+	if err != nil {
+		return SecretResult{}, err
+	}
+	if _, ok := store.Get(cfg.Name); !ok {
+		return SecretResult{}, fmt.Errorf("no secret %q in the store", cfg.Name)
+	}
+	logger.Info("Deleting secret", "name", cfg.Name)
+	store.Delete(cfg.Name)
+	if err := store.Save(); err != nil {
+		return SecretResult{}, err
+	}
+	return SecretResult{
+		Success:   true,
+		Name:      cfg.Name,
+		KeySource: store.KeySource(),
+		Names:     store.Names(),
+		Message:   "Secret " + cfg.Name + " deleted",
+	}, nil
+}
@@ -0,0 +1,208 @@
+// Package credentials keeps the secrets stored with vsl config set-secret
+// encrypted at rest under the vsl config directory, with the key held in the
+// OS keychain or a user-only key file. Scripts read them with the store
+// secret backend.
+package credentials
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/gloo-foo/vsl/internal/state"
+)
+
+// storeFileName is the encrypted store inside the vsl config directory.
+const storeFileName = "credentials.enc"
+
+// storeVersion identifies the sealed file format.
+const storeVersion = 1
+
+// sealed is the on-disk form of the store.
+type sealed struct {
+	Version int    `json:"version"`
+	Nonce   []byte `json:"nonce"`
+	Data    []byte `json:"data"`
+}
+
+// Store is the decrypted credential store.
+type Store struct {
+	path   string
+	key    KeySource
+	values map[string]string
+}
+
+// Open loads the credential store, which is empty until something is saved.
+func Open() (*Store, error) {
+	dir, err := state.ConfigDir()
+	if err != nil {
+		return nil, err
+	}
+	source, err := keySource()
+	if err != nil {
+		return nil, err
+	}
+	s := &Store{path: filepath.Join(dir, storeFileName), key: source, values: map[string]string{}}
+
+	data, err := os.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read credential store: %w", err)
+	}
+	key, err := source.Load()
+	if errors.Is(err, errNoKey) {
+		return nil, fmt.Errorf("credential store %s exists but its key is missing from %s", s.path, source.Name())
+	}
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := unseal(key, data)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(plaintext, &s.values); err != nil {
+		return nil, fmt.Errorf("failed to decode credential store: %w", err)
+	}
+	return s, nil
+}
+
+// KeySource returns where the store key is kept.
+func (s *Store) KeySource() string { return s.key.Name() }
+
+// Get returns a stored secret.
+func (s *Store) Get(name string) (string, bool) {
+	value, ok := s.values[name]
+	return value, ok
+}
+
+// Set stores a secret; call Save to persist it.
+func (s *Store) Set(name, value string) { s.values[name] = value }
+
+// Delete removes a secret; call Save to persist the removal.
+func (s *Store) Delete(name string) { delete(s.values, name) }
+
+// Names returns the names of the stored secrets, sorted.
+func (s *Store) Names() []string {
+	names := make([]string, 0, len(s.values))
+	for name := range s.values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Save encrypts and writes the store, creating the key on first use.
+func (s *Store) Save() error {
+	key, err := s.key.Load()
+	if errors.Is(err, errNoKey) {
+		if key, err = newKey(); err != nil {
+			return err
+		}
+		if err = s.saveKey(key); err != nil {
+			return err
+		}
+	} else if err != nil {
+		return err
+	}
+	return s.write(key)
+}
+
+// RotateKey re-encrypts the store with a new key and replaces the old key.
+func (s *Store) RotateKey() error {
+	key, err := newKey()
+	if err != nil {
+		return err
+	}
+
+	// Stage the re-encrypted store so the old key stays valid until the new one is saved
+	data, err := s.seal(key)
+	if err != nil {
+		return err
+	}
+	staged := s.path + ".rotating"
+	if err := writeFileAtomic(staged, data); err != nil {
+		return err
+	}
+	if err := s.saveKey(key); err != nil {
+		_ = os.Remove(staged)
+		return err
+	}
+	return os.Rename(staged, s.path)
+}
+
+// saveKey stores the key, falling back to a key file when the keychain refuses it
+// (e.g. a locked or headless keyring).
+func (s *Store) saveKey(key []byte) error {
+	err := s.key.Save(key)
+	if _, isKeychain := s.key.(keychain); err == nil || !isKeychain {
+		return err
+	}
+	file := fileKey{path: filepath.Join(filepath.Dir(s.path), keyFileName)}
+	if fileErr := file.Save(key); fileErr != nil {
+		return errors.Join(err, fileErr)
+	}
+	s.key = file
+	return nil
+}
+
+// write encrypts the store with key and writes it.
+func (s *Store) write(key []byte) error {
+	data, err := s.seal(key)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(s.path, data)
+}
+
+// seal encrypts the store contents with AES-256-GCM.
+func (s *Store) seal(key []byte) ([]byte, error) {
+	plaintext, err := json.Marshal(s.values)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode credential store: %w", err)
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	return json.Marshal(sealed{Version: storeVersion, Nonce: nonce, Data: gcm.Seal(nil, nonce, plaintext, nil)})
+}
+
+// unseal decrypts a sealed store.
+func unseal(key, data []byte) ([]byte, error) {
+	var file sealed
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to decode credential store: %w", err)
+	}
+	if file.Version != storeVersion {
+		return nil, fmt.Errorf("unsupported credential store version %d", file.Version)
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := gcm.Open(nil, file.Nonce, file.Data, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt credential store: wrong key or corrupted file")
+	}
+	return plaintext, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,43 @@
+// Package docker provides helpers for talking to the Docker daemon.
+package docker
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/docker/docker/client"
+)
+
+// TransportWrapper wraps the HTTP transport used to reach the daemon,
+// e.g. to record or replay API interactions.
+type TransportWrapper func(http.RoundTripper) http.RoundTripper
+
+// NewClient creates a Docker client configured from the environment.
+func NewClient(opts ...client.Opt) (*client.Client, error) {
+	opts = append([]client.Opt{client.FromEnv, client.WithAPIVersionNegotiation()}, opts...)
+	dockerCli, err := client.NewClientWithOpts(opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create docker client: %w", err)
+	}
+	return dockerCli, nil
+}
+
+// WithTransport wraps the client's HTTP transport. A nil wrapper leaves it unchanged.
+func WithTransport(wrap TransportWrapper) client.Opt {
+	return func(c *client.Client) error {
+		if wrap == nil {
+			return nil
+		}
+		httpClient := c.HTTPClient()
+		httpClient.Transport = wrap(httpClient.Transport)
+		return client.WithHTTPClient(httpClient)(c)
+	}
+}
+
+// Close closes the Docker client, panicking on failure like the other
+// deferred closers in this project.
+func Close(dockerCli *client.Client) {
+	if err := dockerCli.Close(); err != nil {
+		panic(err)
+	}
+}
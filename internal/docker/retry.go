@@ -0,0 +1,149 @@
+package docker
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/docker/docker/client"
+)
+
+// maxBackoff caps the delay between retries.
+const maxBackoff = 10 * time.Second
+
+// RetryPolicy configures how daemon API calls are retried after transient
+// failures, such as a restarting daemon or a 500 from Docker Desktop.
+type RetryPolicy struct {
+	Attempts int           // Attempts per call, including the first; 1 disables retries
+	Backoff  time.Duration // Delay before the first retry, doubled for each further one
+}
+
+// Retries is the policy applied by WithRetry and Do, set from the global flags.
+var Retries = RetryPolicy{Attempts: 3, Backoff: 500 * time.Millisecond}
+
+// WithRetry retries API calls that are safe to repeat when they fail
+// transiently, logging each retry as a warning. Calls with side effects, such
+// as creating or starting a container, are never retried.
+func WithRetry(logger *slog.Logger) client.Opt {
+	return WithTransport(func(next http.RoundTripper) http.RoundTripper {
+		return &retryTransport{next: next, policy: Retries, logger: logger}
+	})
+}
+
+// Do runs fn, an operation that is safe to repeat, retrying it while it
+// fails transiently. It covers failures while reading streamed responses,
+// such as an EOF in the middle of a pull, which happen after the transport.
+func (p RetryPolicy) Do(ctx context.Context, logger *slog.Logger, operation string, fn func() error) error {
+	delay := p.Backoff
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil || attempt >= p.Attempts || !transientError(err) || ctx.Err() != nil {
+			return err
+		}
+		logger.Warn("Retrying after transient daemon error", "operation", operation, "attempt", attempt, "attempts", p.Attempts, "error", err, "backoff", delay)
+		if err := sleep(ctx, delay); err != nil {
+			return err
+		}
+		delay = min(delay*2, maxBackoff)
+	}
+}
+
+// retryTransport retries idempotent requests on transient failures.
+type retryTransport struct {
+	next   http.RoundTripper
+	policy RetryPolicy
+	logger *slog.Logger
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.policy.Attempts <= 1 || !idempotent(req) {
+		return t.next.RoundTrip(req)
+	}
+
+	delay := t.policy.Backoff
+	for attempt := 1; ; attempt++ {
+		resp, err := t.next.RoundTrip(req)
+		reason := transientResponse(resp, err)
+		if reason == "" || attempt >= t.policy.Attempts || req.Context().Err() != nil {
+			return resp, err
+		}
+		if resp != nil {
+			_, _ = io.Copy(io.Discard, resp.Body)
+			_ = resp.Body.Close()
+		}
+
+		t.logger.Warn("Retrying daemon API call", "method", req.Method, "path", req.URL.Path, "attempt", attempt, "attempts", t.policy.Attempts, "reason", reason, "backoff", delay)
+		if err := sleep(req.Context(), delay); err != nil {
+			return nil, err
+		}
+		delay = min(delay*2, maxBackoff)
+
+		if req.Body != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req = req.Clone(req.Context())
+			req.Body = body
+		}
+	}
+}
+
+// idempotent reports whether a request can be repeated without side effects:
+// reads, and pulls, which the daemon resumes. Bodies must be replayable.
+func idempotent(req *http.Request) bool {
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return false
+	}
+	switch req.Method {
+	case http.MethodGet, http.MethodHead:
+		return true
+	case http.MethodPost:
+		return strings.HasSuffix(req.URL.Path, "/images/create")
+	}
+	return false
+}
+
+// transientResponse describes why a round trip failed transiently, or
+// returns "" when it succeeded or failed for good.
+func transientResponse(resp *http.Response, err error) string {
+	if err != nil {
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			return ""
+		}
+		return err.Error()
+	}
+	switch resp.StatusCode {
+	case http.StatusInternalServerError, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return resp.Status
+	}
+	return ""
+}
+
+// transientError reports whether err is a dropped or refused connection,
+// as seen while the daemon restarts.
+func transientError(err error) bool {
+	return errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.EPIPE)
+}
+
+// sleep waits for d or until ctx is done.
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,81 @@
+// Package fixture records and replays Docker API interactions so user
+// scenarios can be reproduced without a daemon.
+package fixture
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+// Dir is a directory holding a recorded fixture.
+type Dir string
+
+// fileName is the name of the fixture file inside a fixture directory.
+const fileName = "fixture.json"
+
+// capturedEnv lists the environment variables that influence a run and are
+// restored on replay.
+var capturedEnv = []string{"HOME", "USER", "DOCKER_HOST", "DOCKER_API_VERSION", "DOCKER_CERT_PATH", "DOCKER_TLS_VERIFY"}
+
+// Interaction is a single recorded API request and its response.
+type Interaction struct {
+	Method       string      `json:"method"`
+	Path         string      `json:"path"`
+	Query        string      `json:"query,omitempty"`
+	RequestBody  []byte      `json:"request_body,omitempty"`
+	Status       int         `json:"status"`
+	Header       http.Header `json:"header,omitempty"`
+	ResponseBody []byte      `json:"response_body,omitempty"`
+}
+
+// Fixture is a recorded run: its configuration, environment, API interactions and outcome.
+type Fixture struct {
+	WorkingDir   string            `json:"working_dir"`
+	Environment  map[string]string `json:"environment,omitempty"`
+	Config       json.RawMessage   `json:"config"`
+	Interactions []Interaction     `json:"interactions"`
+	Result       json.RawMessage   `json:"result"`
+	Error        string            `json:"error,omitempty"`
+}
+
+// CaptureEnvironment returns the current values of the variables restored on replay.
+func CaptureEnvironment() map[string]string {
+	env := map[string]string{}
+	for _, name := range capturedEnv {
+		if value, ok := os.LookupEnv(name); ok {
+			env[name] = value
+		}
+	}
+	return env
+}
+
+// Save writes the fixture into dir, creating it if needed.
+func (f Fixture) Save(dir Dir) error {
+	if err := os.MkdirAll(string(dir), 0o755); err != nil {
+		return fmt.Errorf("failed to create fixture directory: %w", err)
+	}
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode fixture: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(string(dir), fileName), data, 0o644); err != nil {
+		return fmt.Errorf("failed to write fixture: %w", err)
+	}
+	return nil
+}
+
+// Load reads the fixture recorded in dir.
+func Load(dir Dir) (Fixture, error) {
+	var f Fixture
+	data, err := os.ReadFile(filepath.Join(string(dir), fileName))
+	if err != nil {
+		return f, fmt.Errorf("failed to read fixture: %w", err)
+	}
+	if err := json.Unmarshal(data, &f); err != nil {
+		return f, fmt.Errorf("failed to decode fixture: %w", err)
+	}
+	return f, nil
+}
@@ -0,0 +1,116 @@
+package fixture
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/gloo-foo/vsl/internal/secret"
+)
+
+// Recorder is an http.RoundTripper that forwards requests and records every interaction.
+type Recorder struct {
+	next         http.RoundTripper
+	mu           sync.Mutex
+	interactions []Interaction
+}
+
+// Wrap makes the recorder forward to next and returns it, matching docker.TransportWrapper.
+func (r *Recorder) Wrap(next http.RoundTripper) http.RoundTripper {
+	r.next = next
+	return r
+}
+
+// RoundTrip forwards req and records the fully-read response. Fixtures are
+// attached to bug reports, so the values of container environments, which
+// carry the run's secrets, are masked in what is recorded.
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	interaction := Interaction{
+		Method: req.Method,
+		Path:   req.URL.Path,
+		Query:  req.URL.RawQuery,
+	}
+	if req.Body != nil {
+		body, err := io.ReadAll(req.Body)
+		if err != nil {
+			return nil, err
+		}
+		interaction.RequestBody = redactEnv(body)
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	resp, err := r.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	// Streaming responses are read to completion so they can be replayed verbatim
+	body, err := io.ReadAll(resp.Body)
+	closeErr := resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	if closeErr != nil {
+		return nil, closeErr
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	interaction.Status = resp.StatusCode
+	interaction.Header = resp.Header.Clone()
+	interaction.ResponseBody = redactEnv(body)
+
+	r.mu.Lock()
+	r.interactions = append(r.interactions, interaction)
+	r.mu.Unlock()
+	return resp, nil
+}
+
+// Interactions returns the interactions recorded so far.
+func (r *Recorder) Interactions() []Interaction {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Interaction(nil), r.interactions...)
+}
+
+// redactEnv masks the values of the environment in a JSON body: the Env of
+// a container or exec creation request, or the Config.Env of an inspected
+// container. Other bodies, such as streamed progress, are returned as they are.
+func redactEnv(body []byte) []byte {
+	var doc map[string]json.RawMessage
+	if json.Unmarshal(body, &doc) != nil {
+		return body
+	}
+	masked := maskEnv(doc)
+	var config map[string]json.RawMessage
+	if json.Unmarshal(doc["Config"], &config) == nil && maskEnv(config) {
+		doc["Config"], _ = json.Marshal(config)
+		masked = true
+	}
+	if !masked {
+		return body
+	}
+	redacted, err := json.Marshal(doc)
+	if err != nil {
+		return body
+	}
+	return redacted
+}
+
+// maskEnv replaces the values of the NAME=value entries of the Env of doc by
+// secret.Mask, reporting whether there were any.
+func maskEnv(doc map[string]json.RawMessage) bool {
+	var env []string
+	if json.Unmarshal(doc["Env"], &env) != nil || len(env) == 0 {
+		return false
+	}
+	for i, e := range env {
+		if name, _, ok := strings.Cut(e, "="); ok {
+			env[i] = name + "=" + secret.Mask
+		}
+	}
+	doc["Env"], _ = json.Marshal(env)
+	return true
+}
@@ -0,0 +1,76 @@
+package fixture
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+)
+
+// Replayer is an http.RoundTripper that answers requests from recorded
+// interactions, in order, without contacting a daemon.
+type Replayer struct {
+	mu           sync.Mutex
+	interactions []Interaction
+	next         int
+	mismatches   []string
+}
+
+// NewReplayer returns a replayer serving the given interactions.
+func NewReplayer(interactions []Interaction) *Replayer {
+	return &Replayer{interactions: interactions}
+}
+
+// Wrap ignores the real transport and returns the replayer, matching docker.TransportWrapper.
+func (r *Replayer) Wrap(http.RoundTripper) http.RoundTripper {
+	return r
+}
+
+// RoundTrip serves the next recorded response, failing when the request
+// diverges from the recording.
+func (r *Replayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.next >= len(r.interactions) {
+		err := fmt.Errorf("unexpected request %s %s: fixture exhausted", req.Method, req.URL.Path)
+		r.mismatches = append(r.mismatches, err.Error())
+		return nil, err
+	}
+	interaction := r.interactions[r.next]
+	r.next++
+
+	if interaction.Method != req.Method || interaction.Path != req.URL.Path {
+		err := fmt.Errorf("interaction %d: expected %s %s, got %s %s",
+			r.next, interaction.Method, interaction.Path, req.Method, req.URL.Path)
+		r.mismatches = append(r.mismatches, err.Error())
+		return nil, err
+	}
+
+	return &http.Response{
+		Status:        http.StatusText(interaction.Status),
+		StatusCode:    interaction.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        interaction.Header.Clone(),
+		Body:          io.NopCloser(bytes.NewReader(interaction.ResponseBody)),
+		ContentLength: int64(len(interaction.ResponseBody)),
+		Request:       req,
+	}, nil
+}
+
+// Replayed returns how many interactions have been served.
+func (r *Replayer) Replayed() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.next
+}
+
+// Mismatches returns the divergences observed so far.
+func (r *Replayer) Mismatches() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.mismatches...)
+}
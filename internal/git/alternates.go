@@ -0,0 +1,70 @@
+package git
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/gloo-foo/vsl/internal/container"
+)
+
+// AlternateObjectDirs returns the object directories a repository borrows
+// objects from, as listed in objects/info/alternates of its common git
+// directory by clones made with --reference or --shared, followed by the
+// alternates of those directories in turn.
+func AlternateObjectDirs(commonDir container.GitDir) []container.GitDir {
+	var dirs []container.GitDir
+	seen := map[string]bool{}
+	var visit func(objects string)
+	visit = func(objects string) {
+		for _, alt := range readAlternates(objects) {
+			if seen[alt] {
+				continue
+			}
+			seen[alt] = true
+			dirs = append(dirs, container.GitDir(alt))
+			visit(alt)
+		}
+	}
+	visit(filepath.Join(string(commonDir), "objects"))
+	return dirs
+}
+
+// readAlternates reads the alternates file of an object directory. Relative
+// paths are relative to the object directory, and paths may be quoted.
+func readAlternates(objects string) []string {
+	f, err := os.Open(filepath.Join(objects, "info", "alternates"))
+	if err != nil {
+		return nil
+	}
+	defer func(f *os.File) {
+		err := f.Close()
+		if err != nil {
+			panic(err)
+		}
+	}(f)
+
+	var found []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if strings.HasPrefix(line, `"`) {
+			unquoted, err := strconv.Unquote(line)
+			if err != nil {
+				continue
+			}
+			line = unquoted
+		}
+		p := filepath.FromSlash(line)
+		if !filepath.IsAbs(p) {
+			p = filepath.Join(objects, p)
+		}
+		found = append(found, filepath.Clean(p))
+	}
+	return found
+}
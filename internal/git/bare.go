@@ -0,0 +1,70 @@
+package git
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/gloo-foo/vsl/internal/container"
+)
+
+// errNotBare is returned by FindBare outside bare repositories.
+var errNotBare = errors.New("not in a bare repository")
+
+// FindBare returns the git directory of the bare repository containing
+// startDir, or that a .git file in startDir or above it references, without
+// entering the ceiling directories. Such repositories have no working tree
+// for FindRoot to return. Without the git binary, a git directory is
+// recognized by its HEAD, objects and refs entries and core.bare setting.
+// GIT_DIR disables the search, as it does for git.
+func FindBare(startDir string, ceilings []string) (container.GitDir, error) {
+	if EnvOverrides().GitDir != "" {
+		return "", errNotBare
+	}
+	if Available() {
+		env := []string{EnvCeilingDirectories + "=" + strings.Join(ceilings, string(filepath.ListSeparator))}
+		out, err := runEnv(context.Background(), container.GitRoot(startDir), env, "rev-parse", "--is-bare-repository", "--absolute-git-dir")
+		if lines := strings.Split(out, "\n"); err == nil && len(lines) == 2 && lines[0] == "true" {
+			return container.GitDir(logical(startDir, lines[1])), nil
+		}
+		if err == nil {
+			return "", errNotBare
+		}
+	}
+
+	dir := startDir
+	for {
+		if isBareDir(dir) {
+			return container.GitDir(dir), nil
+		}
+		if gitDir, err := gitDirOf(container.GitRoot(dir)); err == nil {
+			if isBareDir(gitDir) {
+				return container.GitDir(filepath.Clean(gitDir)), nil
+			}
+			return "", errNotBare
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir || slices.Contains(ceilings, parent) {
+			return "", errNotBare
+		}
+		dir = parent
+	}
+}
+
+// isBareDir reports whether dir is the git directory of a bare repository.
+func isBareDir(dir string) bool {
+	for _, entry := range []string{"HEAD", "objects", "refs"} {
+		if _, err := os.Stat(filepath.Join(dir, entry)); err != nil {
+			return false
+		}
+	}
+	content, err := os.ReadFile(filepath.Join(dir, "config"))
+	if err != nil {
+		return false
+	}
+	bare, _ := configValue(string(content), "[core]", "bare")
+	return bare == "true"
+}
@@ -0,0 +1,163 @@
+package git
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gloo-foo/vsl/internal/container"
+	"github.com/gloo-foo/vsl/internal/state"
+)
+
+// DiskCache keeps discovery results in the vsl cache directory between
+// runs, set from the global flags. Results are always cached in process.
+var DiskCache bool
+
+// Discovery cache settings.
+const (
+	discoveryCacheFile = "git-discovery.json" // Inside the vsl cache directory
+	maxDiscoveries     = 512                  // Entries kept on disk, oldest dropped first
+)
+
+// discovery is a cached FindRootBelow result, valid as long as the
+// modification times it depends on are unchanged: those of the directories
+// walked, which change when a .git entry appears or disappears in them, and
+// of the .git entry of the root found.
+type discovery struct {
+	Root   container.GitRoot `json:"root,omitempty"` // Empty when no repository was found
+	Stamps map[string]int64  `json:"stamps"`         // Modification times in nanoseconds, -1 for missing paths
+	Found  int64             `json:"found"`          // Unix time of the lookup
+}
+
+var (
+	discoveryMu sync.Mutex
+	discoveries map[string]discovery
+)
+
+// cachedRoot returns the cached root of startDir, if still valid.
+func cachedRoot(key string) (container.GitRoot, bool) {
+	discoveryMu.Lock()
+	defer discoveryMu.Unlock()
+	loadDiscoveries()
+	d, ok := discoveries[key]
+	if !ok {
+		return "", false
+	}
+	for path, stamp := range d.Stamps {
+		if modTime(path) != stamp {
+			delete(discoveries, key)
+			return "", false
+		}
+	}
+	return d.Root, true
+}
+
+// cacheRoot records the root found for startDir.
+func cacheRoot(key, startDir string, root container.GitRoot, ceilings []string) {
+	d := discovery{Root: root, Stamps: map[string]int64{}, Found: time.Now().Unix()}
+	for dir := startDir; ; {
+		d.Stamps[dir] = modTime(dir)
+		parent := filepath.Dir(dir)
+		if dir == string(root) || parent == dir || slices.Contains(ceilings, parent) {
+			break
+		}
+		dir = parent
+	}
+	if root != "" {
+		git := filepath.Join(string(root), ".git")
+		d.Stamps[git] = modTime(git)
+	}
+
+	discoveryMu.Lock()
+	defer discoveryMu.Unlock()
+	loadDiscoveries()
+	discoveries[key] = d
+	saveDiscoveries()
+}
+
+// discoveryKey identifies a lookup; the environment variables locating the
+// repository change the answer for the same directory.
+func discoveryKey(startDir string, ceilings []string) string {
+	o := EnvOverrides()
+	return strings.Join(append([]string{startDir, o.GitDir, o.WorkTree}, ceilings...), "\x00")
+}
+
+// modTime returns the modification time of path, or -1 when it is missing.
+func modTime(path string) int64 {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return -1
+	}
+	return info.ModTime().UnixNano()
+}
+
+// discoveryCachePath returns the on-disk cache location.
+func discoveryCachePath() (string, error) {
+	dir, err := state.CacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, discoveryCacheFile), nil
+}
+
+// loadDiscoveries initializes the in-process cache, from disk when enabled.
+// An unreadable cache is ignored. discoveryMu must be held.
+func loadDiscoveries() {
+	if discoveries != nil {
+		return
+	}
+	discoveries = map[string]discovery{}
+	if !DiskCache {
+		return
+	}
+	path, err := discoveryCachePath()
+	if err != nil {
+		return
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return
+	}
+	_ = json.Unmarshal(data, &discoveries)
+}
+
+// saveDiscoveries writes the cache to disk when enabled, replacing the file
+// atomically so concurrent runs never read a partial cache. Failures only
+// cost the next run a lookup. discoveryMu must be held.
+func saveDiscoveries() {
+	if !DiskCache {
+		return
+	}
+	if len(discoveries) > maxDiscoveries {
+		keys := make([]string, 0, len(discoveries))
+		for key := range discoveries {
+			keys = append(keys, key)
+		}
+		sort.Slice(keys, func(i, j int) bool { return discoveries[keys[i]].Found < discoveries[keys[j]].Found })
+		for _, key := range keys[:len(keys)-maxDiscoveries] {
+			delete(discoveries, key)
+		}
+	}
+	path, err := discoveryCachePath()
+	if err != nil {
+		return
+	}
+	data, err := json.Marshal(discoveries)
+	if err != nil {
+		return
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), discoveryCacheFile+".*")
+	if err != nil {
+		return
+	}
+	_, werr := tmp.Write(data)
+	cerr := tmp.Close()
+	if werr != nil || cerr != nil || os.Rename(tmp.Name(), path) != nil {
+		_ = os.Remove(tmp.Name())
+	}
+}
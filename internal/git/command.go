@@ -0,0 +1,209 @@
+package git
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/gloo-foo/vsl/internal/container"
+)
+
+// ErrNotInstalled is returned by operations that need the git binary when it is absent.
+// Callers should degrade gracefully, typically by recording a warning.
+var ErrNotInstalled = errors.New("git binary not found in PATH")
+
+var (
+	lookPathOnce sync.Once
+	gitBinary    string
+)
+
+// Available reports whether the git binary can be executed.
+func Available() bool {
+	lookPathOnce.Do(func() {
+		gitBinary, _ = exec.LookPath("git")
+	})
+	return gitBinary != ""
+}
+
+// run executes git in root and returns its trimmed standard output.
+func run(ctx context.Context, root container.GitRoot, args ...string) (string, error) {
+	return runEnv(ctx, root, nil, args...)
+}
+
+// runEnv executes git in root with env added to the environment and returns
+// its trimmed standard output.
+func runEnv(ctx context.Context, root container.GitRoot, env []string, args ...string) (string, error) {
+	if !Available() {
+		return "", ErrNotInstalled
+	}
+
+	cmd := exec.CommandContext(ctx, gitBinary, append([]string{"-C", string(root)}, args...)...)
+	if len(env) > 0 {
+		cmd.Env = append(os.Environ(), env...)
+	}
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("git %s: %w: %s", strings.Join(args, " "), err, strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
+
+// Branch returns the checked-out branch, or "" for a detached HEAD.
+// Without the git binary, HEAD is read directly from the repository.
+func Branch(ctx context.Context, root container.GitRoot) (string, error) {
+	if Available() {
+		branch, err := run(ctx, root, "symbolic-ref", "--quiet", "--short", "HEAD")
+		if err != nil {
+			// symbolic-ref fails on a detached HEAD
+			return "", nil
+		}
+		return branch, nil
+	}
+
+	head, err := readHead(root)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimPrefix(head.ref, "refs/heads/"), nil
+}
+
+// Commit returns the full SHA of the HEAD commit.
+// Without the git binary, the ref is resolved from loose and packed refs.
+func Commit(ctx context.Context, root container.GitRoot) (string, error) {
+	if Available() {
+		return run(ctx, root, "rev-parse", "HEAD")
+	}
+
+	head, err := readHead(root)
+	if err != nil {
+		return "", err
+	}
+	return head.commit, nil
+}
+
+// Dirty reports whether the worktree has uncommitted changes.
+// It requires the git binary and returns ErrNotInstalled without it.
+func Dirty(ctx context.Context, root container.GitRoot) (bool, error) {
+	out, err := run(ctx, root, "status", "--porcelain", "--untracked-files=no")
+	if err != nil {
+		return false, err
+	}
+	return out != "", nil
+}
+
+// RemoteURL returns the URL of the named remote.
+// Without the git binary, the repository config file is read directly.
+func RemoteURL(ctx context.Context, root container.GitRoot, remote string) (string, error) {
+	if Available() {
+		return run(ctx, root, "remote", "get-url", remote)
+	}
+	return readRemoteURL(root, remote)
+}
+
+// ListFiles returns the tracked and untracked files of the worktree at root
+// matching a pathspec such as "*.up", relative to root. Files excluded by
+// .gitignore and the other ignore files are left out.
+func ListFiles(ctx context.Context, root container.GitRoot, pathspec string) ([]string, error) {
+	out, err := run(ctx, root, "ls-files", "-z", "--cached", "--others", "--exclude-standard", "--deduplicate", "--", pathspec)
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, f := range strings.Split(out, "\x00") {
+		if f != "" {
+			files = append(files, f)
+		}
+	}
+	return files, nil
+}
+
+// UntrackedPaths returns the paths below dir, a directory of a worktree, that
+// git does not track, ignored ones included, relative to dir. Directories
+// holding no tracked file are reported once, with a trailing slash.
+func UntrackedPaths(ctx context.Context, dir string) ([]string, error) {
+	out, err := run(ctx, container.GitRoot(dir), "ls-files", "-z", "--others", "--directory")
+	if err != nil {
+		return nil, err
+	}
+	var paths []string
+	for _, p := range strings.Split(out, "\x00") {
+		if p != "" {
+			paths = append(paths, p)
+		}
+	}
+	return paths, nil
+}
+
+// IgnoredDirs returns the directories below dir, a directory of a worktree,
+// that the ignore files exclude entirely, relative to dir with a trailing
+// slash. Directories holding tracked files are never reported.
+func IgnoredDirs(ctx context.Context, dir string) ([]string, error) {
+	out, err := run(ctx, container.GitRoot(dir), "ls-files", "-z", "--others", "--ignored", "--exclude-standard", "--directory")
+	if err != nil {
+		return nil, err
+	}
+	var dirs []string
+	for _, p := range strings.Split(out, "\x00") {
+		if strings.HasSuffix(p, "/") {
+			dirs = append(dirs, p)
+		}
+	}
+	return dirs, nil
+}
+
+// ShallowCheckout checks out ref of the repository at url in dir, fetching
+// only that commit. dir is created as needed and reused between calls: ref
+// is fetched again, so branches move forward, and local changes and
+// untracked files are discarded, ignored files such as dependencies kept.
+// An empty ref checks out the remote HEAD.
+func ShallowCheckout(ctx context.Context, dir, url, ref string) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create checkout directory: %w", err)
+	}
+	if ref == "" {
+		ref = "HEAD"
+	}
+	root := container.GitRoot(dir)
+	if _, err := os.Stat(filepath.Join(dir, ".git")); err != nil {
+		if _, err := run(ctx, root, "init", "-q"); err != nil {
+			return err
+		}
+		if _, err := run(ctx, root, "remote", "add", "origin", url); err != nil {
+			return err
+		}
+	} else if _, err := run(ctx, root, "remote", "set-url", "origin", url); err != nil {
+		return err
+	}
+	for _, args := range [][]string{
+		{"fetch", "-q", "--depth", "1", "origin", ref},
+		{"checkout", "-q", "--detach", "--force", "FETCH_HEAD"},
+		{"clean", "-q", "-ffd"},
+	} {
+		if _, err := run(ctx, root, args...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AddWorktree checks out ref of the repository at root, detached, in a new
+// linked worktree at dir, which must not exist or be empty.
+func AddWorktree(ctx context.Context, root container.GitRoot, dir, ref string) error {
+	_, err := run(ctx, root, "worktree", "add", "--quiet", "--detach", dir, ref)
+	return err
+}
+
+// RemoveWorktree deletes the linked worktree at dir of the repository at
+// root, with any changes made in it.
+func RemoveWorktree(ctx context.Context, root container.GitRoot, dir string) error {
+	_, err := run(ctx, root, "worktree", "remove", "--force", dir)
+	return err
+}
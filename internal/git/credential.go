@@ -0,0 +1,247 @@
+package git
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/gloo-foo/vsl/internal/container"
+)
+
+// credentialSocketName is the socket file inside the bridge's directory.
+const credentialSocketName = "credential.sock"
+
+// CredentialTarget is a protocol and host, with its port when it has one,
+// the credential bridge answers requests for.
+type CredentialTarget struct {
+	Protocol string
+	Host     string
+}
+
+// CredentialBridge serves the host's git credential helpers on a unix
+// socket. A request is the helper action on the first line followed by the
+// credential description of the git credential protocol. Only get is
+// answered, with the answer of git credential fill, and only for the
+// targets the bridge was started with: the container can neither write nor
+// erase the host's credentials, nor ask for those of other hosts.
+type CredentialBridge struct {
+	Socket string // Path of the socket on the host
+
+	logger   *slog.Logger
+	targets  []CredentialTarget
+	dir      string
+	listener net.Listener
+	wg       sync.WaitGroup
+}
+
+// StartCredentialBridge listens on a socket in a new private directory and
+// serves credential requests for targets until Close.
+func StartCredentialBridge(ctx context.Context, logger *slog.Logger, targets []CredentialTarget) (*CredentialBridge, error) {
+	if !Available() {
+		return nil, ErrNotInstalled
+	}
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("the mounted repositories have no remotes to answer credential requests for")
+	}
+	dir, err := os.MkdirTemp("", "vsl-git-credential-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create credential socket directory: %w", err)
+	}
+	socket := filepath.Join(dir, credentialSocketName)
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		_ = os.RemoveAll(dir)
+		return nil, fmt.Errorf("failed to listen on credential socket: %w", err)
+	}
+	// The directory keeps other host users out; in the container, where only
+	// the socket is mounted, any user the image runs as may ask
+	if err := os.Chmod(socket, 0o666); err != nil {
+		_ = listener.Close()
+		_ = os.RemoveAll(dir)
+		return nil, fmt.Errorf("failed to open credential socket: %w", err)
+	}
+
+	b := &CredentialBridge{Socket: socket, logger: logger, targets: targets, dir: dir, listener: listener}
+	b.wg.Add(1)
+	go b.serve(ctx)
+	return b, nil
+}
+
+// Close stops serving, waiting for requests in progress, and removes the socket.
+func (b *CredentialBridge) Close() error {
+	err := b.listener.Close()
+	b.wg.Wait()
+	return errors.Join(err, os.RemoveAll(b.dir))
+}
+
+func (b *CredentialBridge) serve(ctx context.Context) {
+	defer b.wg.Done()
+	for {
+		conn, err := b.listener.Accept()
+		if err != nil {
+			return
+		}
+		b.wg.Add(1)
+		go func() {
+			defer b.wg.Done()
+			b.handle(ctx, conn)
+		}()
+	}
+}
+
+// handle answers one request with the host's git credential command, which
+// must not prompt on the terminal the container may be using.
+func (b *CredentialBridge) handle(ctx context.Context, conn net.Conn) {
+	defer func() { _ = conn.Close() }()
+
+	reader := bufio.NewReader(conn)
+	action, err := reader.ReadString('\n')
+	if err != nil {
+		return
+	}
+	action = strings.TrimSpace(action)
+	var target CredentialTarget
+	var path, username string
+	for {
+		line, err := reader.ReadString('\n')
+		key, value, _ := strings.Cut(strings.TrimSpace(line), "=")
+		switch key {
+		case "protocol":
+			target.Protocol = value
+		case "host":
+			target.Host = value
+		case "path":
+			path = value
+		case "username":
+			username = value
+		}
+		if err != nil || strings.TrimSpace(line) == "" {
+			break
+		}
+	}
+
+	// Storing and erasing would let the container change the host's helpers
+	if action != "get" {
+		b.logger.Warn("Refusing git credential request", "action", action, "host", target.Host)
+		return
+	}
+	if !b.allowed(target) {
+		b.logger.Warn("Refusing git credential request for a host that is not a remote of the mounted repositories",
+			"protocol", target.Protocol, "host", target.Host)
+		return
+	}
+	b.logger.Info("Serving git credential request", "action", action, "host", target.Host)
+	// The request is rebuilt from the checked fields, as a url attribute
+	// would otherwise name another host
+	var request bytes.Buffer
+	_, _ = fmt.Fprintf(&request, "protocol=%s\nhost=%s\n", target.Protocol, target.Host)
+	if path != "" {
+		_, _ = fmt.Fprintf(&request, "path=%s\n", path)
+	}
+	if username != "" {
+		_, _ = fmt.Fprintf(&request, "username=%s\n", username)
+	}
+	request.WriteString("\n")
+	cmd := exec.CommandContext(ctx, gitBinary, "credential", "fill")
+	cmd.Stdin = &request
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+	out, err := cmd.Output()
+	if err != nil {
+		b.logger.Warn("Host git credential helper failed", "action", action, "host", target.Host, "error", err)
+		return
+	}
+	_, _ = conn.Write(out)
+}
+
+// allowed reports whether the bridge answers requests for target.
+func (b *CredentialBridge) allowed(target CredentialTarget) bool {
+	for _, t := range b.targets {
+		if t.Protocol == target.Protocol && strings.EqualFold(t.Host, target.Host) {
+			return true
+		}
+	}
+	return false
+}
+
+// RemoteTargets returns the protocols and hosts of the remotes of the
+// repositories at roots, after the URL rewrites of their configuration.
+func RemoteTargets(ctx context.Context, roots []container.GitRoot) []CredentialTarget {
+	var targets []CredentialTarget
+	seen := map[CredentialTarget]bool{}
+	for _, root := range roots {
+		out, err := run(ctx, root, "remote", "-v")
+		if err != nil {
+			continue
+		}
+		for _, line := range strings.Split(out, "\n") {
+			fields := strings.Fields(line)
+			if len(fields) < 2 {
+				continue
+			}
+			target, ok := remoteTarget(fields[1])
+			if ok && !seen[target] {
+				seen[target] = true
+				targets = append(targets, target)
+			}
+		}
+	}
+	return targets
+}
+
+// remoteTarget returns the protocol and host of a remote URL, either a URL
+// or the scp-like user@host:path form, reporting false for local paths.
+func remoteTarget(remote string) (CredentialTarget, bool) {
+	if strings.Contains(remote, "://") {
+		u, err := url.Parse(remote)
+		if err != nil || u.Host == "" {
+			return CredentialTarget{}, false
+		}
+		return CredentialTarget{Protocol: u.Scheme, Host: u.Host}, true
+	}
+	host, _, ok := strings.Cut(remote, ":")
+	if !ok || strings.Contains(host, "/") {
+		return CredentialTarget{}, false
+	}
+	if _, h, ok := strings.Cut(host, "@"); ok {
+		host = h
+	}
+	return CredentialTarget{Protocol: "ssh", Host: host}, host != ""
+}
+
+// CredentialHelper forwards a credential helper request read from in to the
+// bridge listening on socket and copies the answer to out. It is the
+// credential.helper git runs in the container.
+func CredentialHelper(socket, action string, in io.Reader, out io.Writer) error {
+	conn, err := net.Dial("unix", socket)
+	if err != nil {
+		return fmt.Errorf("failed to reach credential bridge: %w", err)
+	}
+	defer func() { _ = conn.Close() }()
+
+	if _, err := io.WriteString(conn, action+"\n"); err != nil {
+		return fmt.Errorf("failed to send credential request: %w", err)
+	}
+	if _, err := io.Copy(conn, in); err != nil {
+		return fmt.Errorf("failed to send credential request: %w", err)
+	}
+	if unixConn, ok := conn.(*net.UnixConn); ok {
+		if err := unixConn.CloseWrite(); err != nil {
+			return fmt.Errorf("failed to send credential request: %w", err)
+		}
+	}
+	if _, err := io.Copy(out, conn); err != nil {
+		return fmt.Errorf("failed to read credential answer: %w", err)
+	}
+	return nil
+}
@@ -2,18 +2,89 @@
 package git
 
 import (
+	"context"
 	"fmt"
-	"io"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 
 	"github.com/gloo-foo/vsl/internal/container"
 )
 
-// FindRoot finds the root directory of a git repository starting from startDir.
-// It walks up the directory tree until it finds a .git directory or reaches the filesystem root.
+// EnvCeilingDirectories lists the directories, separated like PATH, that
+// git does not enter when searching upward for a repository.
+const EnvCeilingDirectories = "GIT_CEILING_DIRECTORIES"
+
+// Ceilings returns the directories upward discovery does not enter: those
+// of GIT_CEILING_DIRECTORIES and extra, and the parent of the home
+// directory, so a repository is never found above it.
+func Ceilings(extra ...string) []string {
+	var ceilings []string
+	for _, dir := range filepath.SplitList(os.Getenv(EnvCeilingDirectories)) {
+		if filepath.IsAbs(dir) {
+			ceilings = append(ceilings, filepath.Clean(dir))
+		}
+	}
+	for _, dir := range extra {
+		ceilings = append(ceilings, filepath.Clean(dir))
+	}
+	if home, err := os.UserHomeDir(); err == nil && filepath.IsAbs(home) {
+		if parent := filepath.Dir(filepath.Clean(home)); parent != home {
+			ceilings = append(ceilings, parent)
+		}
+	}
+	return ceilings
+}
+
+// FindRoot finds the root directory of the working tree containing startDir,
+// searching no higher than the default Ceilings.
 func FindRoot(startDir string) (container.GitRoot, error) {
+	return FindRootBelow(startDir, Ceilings())
+}
+
+// FindRootBelow finds the root directory of the working tree containing
+// startDir without entering the ceiling directories, as git does for
+// GIT_CEILING_DIRECTORIES: a repository rooted at a ceiling is only found
+// from the ceiling itself. With the git binary, git resolves the root with
+// its own semantics (GIT_DIR and GIT_WORK_TREE, .git files, bare
+// repositories). Without it, or when git refuses the repository, e.g. for
+// unsafe ownership, GIT_WORK_TREE is used when GIT_DIR is set, and otherwise
+// the directory tree is walked up until a .git entry is found.
+//
+// Results are cached per directory until the directories walked or the
+// .git entry found change, in the vsl cache directory too with DiskCache.
+func FindRootBelow(startDir string, ceilings []string) (container.GitRoot, error) {
+	key := discoveryKey(startDir, ceilings)
+	root, ok := cachedRoot(key)
+	if !ok {
+		root, _ = findRootBelow(startDir, ceilings)
+		cacheRoot(key, startDir, root, ceilings)
+	}
+	if root == "" {
+		return "", fmt.Errorf("no git repository found")
+	}
+	return root, nil
+}
+
+// findRootBelow is FindRootBelow without the cache.
+func findRootBelow(startDir string, ceilings []string) (container.GitRoot, error) {
+	if Available() {
+		env := []string{EnvCeilingDirectories + "=" + strings.Join(ceilings, string(filepath.ListSeparator))}
+		top, err := runEnv(context.Background(), container.GitRoot(startDir), env, "rev-parse", "--show-toplevel")
+		if err == nil && top != "" {
+			return container.GitRoot(logical(startDir, top)), nil
+		}
+	}
+
+	// Without a work tree, git treats the current directory as its top level
+	if o := EnvOverrides(); o.GitDir != "" {
+		if o.WorkTree != "" {
+			return container.GitRoot(o.WorkTree), nil
+		}
+		return container.GitRoot(startDir), nil
+	}
+
 	dir := startDir
 	for {
 		gitPath := filepath.Join(dir, ".git")
@@ -22,61 +93,77 @@ func FindRoot(startDir string) (container.GitRoot, error) {
 		}
 
 		parent := filepath.Dir(dir)
-		if parent == dir {
+		if parent == dir || slices.Contains(ceilings, parent) {
 			return "", fmt.Errorf("no git repository found")
 		}
 		dir = parent
 	}
 }
 
-// FindRealGitDir finds the actual .git directory, resolving worktree references if necessary.
-// Git worktrees use a .git file that points to the real git directory.
-func FindRealGitDir(gitRoot container.GitRoot) (container.GitDir, error) {
-	gitPath := filepath.Join(string(gitRoot), ".git")
-
-	info, err := os.Stat(gitPath)
-	if err != nil {
-		return "", err
+// WorktreeGitDirs returns the git directory of the checkout at root and the
+// common directory shared by all worktrees of its repository. A linked
+// worktree's .git file points to its own git directory (HEAD, index), whose
+// commondir file references the main repository's objects and refs; a
+// submodule's points into the superproject's .git/modules. Both are root/.git
+// for a regular checkout. GIT_DIR and GIT_COMMON_DIR take precedence for the
+// top-level repository, as for git, which resolves them when available.
+func WorktreeGitDirs(root container.GitRoot) (gitDir container.GitDir, commonDir container.GitDir, err error) {
+	// The .git file gives the paths as seen through root, which is how git
+	// in the container resolves them
+	dir, fileErr := gitDirOf(root)
+	if fileErr == nil {
+		dir = filepath.Clean(dir)
+		gitDir, commonDir = container.GitDir(dir), container.GitDir(commonDirOf(root, dir))
 	}
 
-	// If .git is a directory, return it directly
-	if info.IsDir() {
-		return container.GitDir(gitPath), nil
+	// git would resolve nested repositories and submodules through the
+	// overrides of the top-level repository, which they inherit
+	if o := EnvOverrides(); Available() && (o == (Overrides{}) || o.Applies(root)) {
+		out, err := run(context.Background(), root, "rev-parse", "--path-format=absolute", "--git-dir", "--git-common-dir")
+		if dirs := strings.Split(out, "\n"); err == nil && len(dirs) == 2 {
+			return agree(root, gitDir, dirs[0]), agree(root, commonDir, dirs[1]), nil
+		}
 	}
+	return gitDir, commonDir, fileErr
+}
 
-	// If .git is a file, read it to find the real git directory
-	file, err := os.Open(gitPath)
-	if err != nil {
-		return "", err
-	}
-	defer func(file *os.File) {
-		err := file.Close()
-		if err != nil {
-			panic(err)
+// agree returns the path read from the repository files when git resolves it
+// to the same location, keeping the user's view of symlinked directories, and
+// otherwise the path git resolved.
+func agree(root container.GitRoot, read container.GitDir, resolved string) container.GitDir {
+	if read != "" {
+		if r, err := filepath.EvalSymlinks(string(read)); err == nil && r == resolved {
+			return read
 		}
-	}(file)
+	}
+	return container.GitDir(logical(string(root), resolved))
+}
 
-	content, err := io.ReadAll(file)
+// logical maps a path git reports, in which symlinks are resolved, back onto
+// the path of base as the user sees it, when the path is base or inside it
+// (e.g. /tmp instead of /private/tmp on macOS). Other paths are returned as is.
+func logical(base, resolved string) string {
+	realBase, err := filepath.EvalSymlinks(base)
 	if err != nil {
-		return "", err
+		return resolved
 	}
 
-	lines := strings.Split(string(content), "\n")
-	for _, line := range lines {
-		if strings.HasPrefix(line, "gitdir: ") {
-			worktreeGitDir := strings.TrimSpace(strings.TrimPrefix(line, "gitdir:"))
-
-			// For worktrees, we want the main git directory, not the worktree-specific one
-			if strings.Contains(worktreeGitDir, "/worktrees/") {
-				parts := strings.Split(worktreeGitDir, "/worktrees/")
-				if len(parts) > 0 {
-					return container.GitDir(parts[0]), nil
-				}
-			}
-
-			return container.GitDir(worktreeGitDir), nil
-		}
+	// resolved is below base: rebase it
+	if rel, err := filepath.Rel(realBase, resolved); err == nil && filepath.IsLocal(rel) {
+		return filepath.Join(base, rel)
 	}
 
-	return container.GitDir(gitPath), nil
+	// resolved is an ancestor of base: strip as many levels from base
+	rel, err := filepath.Rel(resolved, realBase)
+	if err != nil || !filepath.IsLocal(rel) {
+		return resolved
+	}
+	dir := base
+	for range strings.Split(rel, string(filepath.Separator)) {
+		dir = filepath.Dir(dir)
+	}
+	if r, err := filepath.EvalSymlinks(dir); err == nil && r == resolved {
+		return dir
+	}
+	return resolved
 }
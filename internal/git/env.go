@@ -0,0 +1,87 @@
+package git
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/gloo-foo/vsl/internal/container"
+)
+
+// Environment variables pointing git at repositories split from their worktree.
+const (
+	EnvGitDir    = "GIT_DIR"
+	EnvWorkTree  = "GIT_WORK_TREE"
+	EnvCommonDir = "GIT_COMMON_DIR"
+)
+
+// Overrides are the GIT_DIR, GIT_WORK_TREE and GIT_COMMON_DIR settings of
+// the environment, as absolute paths. Unset variables are empty.
+type Overrides struct {
+	GitDir    string
+	WorkTree  string
+	CommonDir string
+}
+
+// EnvOverrides reads the git location variables of the environment,
+// resolving relative paths against the current directory as git does.
+func EnvOverrides() Overrides {
+	abs := func(name string) string {
+		v := os.Getenv(name)
+		if v == "" {
+			return ""
+		}
+		if p, err := filepath.Abs(v); err == nil {
+			return p
+		}
+		return v
+	}
+	return Overrides{
+		GitDir:    abs(EnvGitDir),
+		WorkTree:  abs(EnvWorkTree),
+		CommonDir: abs(EnvCommonDir),
+	}
+}
+
+// Env returns the variables that are set, as NAME=value entries.
+func (o Overrides) Env() []string {
+	var env []string
+	for _, v := range []struct{ name, value string }{
+		{EnvGitDir, o.GitDir},
+		{EnvWorkTree, o.WorkTree},
+		{EnvCommonDir, o.CommonDir},
+	} {
+		if v.value != "" {
+			env = append(env, v.name+"="+v.value)
+		}
+	}
+	return env
+}
+
+// Applies reports whether the overrides locate the repository whose worktree
+// is root. They belong to the top-level repository only: the one at
+// GIT_WORK_TREE or, without it, in the current directory, which git then
+// takes as the top level. Nested repositories and submodules keep the git
+// directories their .git entries reference.
+func (o Overrides) Applies(root container.GitRoot) bool {
+	if o == (Overrides{}) {
+		return false
+	}
+	top := o.WorkTree
+	if top == "" {
+		wd, err := os.Getwd()
+		if err != nil {
+			return false
+		}
+		top = wd
+	}
+	return filepath.Clean(string(root)) == filepath.Clean(top)
+}
+
+// overridesFor returns the overrides of the environment when they apply to
+// the worktree at root, and none otherwise.
+func overridesFor(root container.GitRoot) Overrides {
+	if o := EnvOverrides(); o.Applies(root) {
+		return o
+	}
+	return Overrides{}
+}
@@ -0,0 +1,50 @@
+package git
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// IdentityFile is a file of the user's global git configuration.
+type IdentityFile struct {
+	Path string // Location on the host
+	Home string // Slash-separated location relative to the home directory, where git looks for it
+}
+
+// IdentityFiles returns the user's global git configuration files that exist on the host:
+// ~/.gitconfig and $XDG_CONFIG_HOME/git/config (or the file named by GIT_CONFIG_GLOBAL),
+// and with credentials the credential store files ~/.git-credentials and
+// $XDG_CONFIG_HOME/git/credentials. XDG_CONFIG_HOME defaults to ~/.config.
+func IdentityFiles(credentials bool) ([]IdentityFile, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve home directory: %w", err)
+	}
+	xdg := os.Getenv("XDG_CONFIG_HOME")
+	if xdg == "" || !filepath.IsAbs(xdg) {
+		xdg = filepath.Join(home, ".config")
+	}
+
+	candidates := []IdentityFile{
+		{Path: filepath.Join(home, ".gitconfig"), Home: ".gitconfig"},
+		{Path: filepath.Join(xdg, "git", "config"), Home: ".config/git/config"},
+	}
+	if global := os.Getenv("GIT_CONFIG_GLOBAL"); global != "" {
+		candidates = []IdentityFile{{Path: global, Home: ".gitconfig"}}
+	}
+	if credentials {
+		candidates = append(candidates,
+			IdentityFile{Path: filepath.Join(home, ".git-credentials"), Home: ".git-credentials"},
+			IdentityFile{Path: filepath.Join(xdg, "git", "credentials"), Home: ".config/git/credentials"},
+		)
+	}
+
+	var files []IdentityFile
+	for _, f := range candidates {
+		if info, err := os.Stat(f.Path); err == nil && info.Mode().IsRegular() {
+			files = append(files, f)
+		}
+	}
+	return files, nil
+}
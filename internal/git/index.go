@@ -0,0 +1,44 @@
+package git
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/gloo-foo/vsl/internal/container"
+)
+
+// indexSignature starts every git index file.
+const indexSignature = "DIRC"
+
+// TrackedFiles returns the number of files tracked in the checkout at root,
+// read from the header of its index without scanning the worktree, so it is
+// cheap even for giant repositories.
+func TrackedFiles(root container.GitRoot) (int, error) {
+	gitDir, _, err := WorktreeGitDirs(root)
+	if err != nil {
+		return 0, err
+	}
+	f, err := os.Open(filepath.Join(string(gitDir), "index"))
+	if err != nil {
+		return 0, err
+	}
+	defer func(f *os.File) {
+		err := f.Close()
+		if err != nil {
+			panic(err)
+		}
+	}(f)
+
+	// Signature, version and entry count, in network byte order
+	header := make([]byte, 12)
+	if _, err := io.ReadFull(f, header); err != nil {
+		return 0, fmt.Errorf("failed to read git index: %w", err)
+	}
+	if string(header[:4]) != indexSignature {
+		return 0, fmt.Errorf("invalid git index signature %q", header[:4])
+	}
+	return int(binary.BigEndian.Uint32(header[8:])), nil
+}
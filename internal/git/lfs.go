@@ -0,0 +1,67 @@
+package git
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/gloo-foo/vsl/internal/container"
+)
+
+// lfsSection is the git config section holding Git LFS settings.
+const lfsSection = "[lfs]"
+
+// LFSStorage returns the directory holding the Git LFS objects of the
+// repository at root, and whether the repository uses LFS: its
+// .gitattributes routes files through the lfs filter, or it already has an
+// object store. The store defaults to lfs in the common git directory and is
+// moved by lfs.storage, read from git config or the repository's .lfsconfig;
+// relative values are resolved against the common git directory.
+func LFSStorage(ctx context.Context, root container.GitRoot) (container.GitDir, bool) {
+	_, gitCommonDir, err := WorktreeGitDirs(root)
+	if err != nil {
+		return "", false
+	}
+	commonDir := string(gitCommonDir)
+
+	storage := lfsStorageSetting(ctx, root, commonDir)
+	if storage == "" {
+		storage = "lfs"
+	}
+	if !filepath.IsAbs(storage) {
+		storage = filepath.Join(commonDir, storage)
+	}
+	storage = filepath.Clean(storage)
+
+	if info, err := os.Stat(storage); err == nil && info.IsDir() {
+		return container.GitDir(storage), true
+	}
+	attributes, err := os.ReadFile(filepath.Join(string(root), ".gitattributes"))
+	if err == nil && strings.Contains(string(attributes), "filter=lfs") {
+		return container.GitDir(storage), true
+	}
+	return "", false
+}
+
+// lfsStorageSetting reads lfs.storage, preferring git config (including the
+// global and system files) over the repository's .lfsconfig, as git-lfs does.
+func lfsStorageSetting(ctx context.Context, root container.GitRoot, commonDir string) string {
+	if Available() {
+		// git config exits with an error when the key is unset
+		if value, err := run(ctx, root, "config", "--get", "lfs.storage"); err == nil && value != "" {
+			return value
+		}
+	} else if content, err := os.ReadFile(filepath.Join(commonDir, "config")); err == nil {
+		if value, ok := configValue(string(content), lfsSection, "storage"); ok {
+			return value
+		}
+	}
+
+	if content, err := os.ReadFile(filepath.Join(string(root), ".lfsconfig")); err == nil {
+		if value, ok := configValue(string(content), lfsSection, "storage"); ok {
+			return value
+		}
+	}
+	return ""
+}
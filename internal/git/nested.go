@@ -0,0 +1,42 @@
+package git
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/gloo-foo/vsl/internal/container"
+)
+
+// NestedRoots finds the repositories below dir, at most depth directory
+// levels deep, such as vendored repositories or the members of a
+// meta-repository. dir itself is not included, repositories nested in the
+// ones found are, and .git directories are not entered.
+func NestedRoots(dir string, depth int) []container.GitRoot {
+	var roots []container.GitRoot
+	if depth <= 0 {
+		return roots
+	}
+	_ = filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		// Unreadable directories are skipped
+		if err != nil || !d.IsDir() {
+			return nil
+		}
+		if d.Name() == ".git" {
+			return filepath.SkipDir
+		}
+		if p == dir {
+			return nil
+		}
+		if _, err := os.Lstat(filepath.Join(p, ".git")); err == nil {
+			roots = append(roots, container.GitRoot(p))
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil || len(strings.Split(rel, string(filepath.Separator))) >= depth {
+			return filepath.SkipDir
+		}
+		return nil
+	})
+	return roots
+}
@@ -0,0 +1,157 @@
+package git
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/gloo-foo/vsl/internal/container"
+)
+
+// head describes HEAD as read from the repository files.
+type head struct {
+	ref    string // Symbolic ref HEAD points to; empty when detached
+	commit string // Commit SHA HEAD resolves to
+}
+
+// gitDirOf returns the git directory of the worktree at root, following a
+// .git file to the worktree-specific directory without stripping it. GIT_DIR
+// takes precedence for the top-level repository, as for git.
+func gitDirOf(root container.GitRoot) (string, error) {
+	if dir := overridesFor(root).GitDir; dir != "" {
+		return dir, nil
+	}
+	gitPath := filepath.Join(string(root), ".git")
+	info, err := os.Stat(gitPath)
+	if err != nil {
+		return "", err
+	}
+	if info.IsDir() {
+		return gitPath, nil
+	}
+
+	content, err := os.ReadFile(gitPath)
+	if err != nil {
+		return "", err
+	}
+	for _, line := range strings.Split(string(content), "\n") {
+		if dir, ok := strings.CutPrefix(line, "gitdir:"); ok {
+			dir = strings.TrimSpace(dir)
+			if !filepath.IsAbs(dir) {
+				dir = filepath.Join(string(root), dir)
+			}
+			return dir, nil
+		}
+	}
+	return "", fmt.Errorf("%s does not reference a git directory", gitPath)
+}
+
+// commonDirOf returns the directory shared by all worktrees of a repository,
+// as referenced by the commondir file of the git directory of the worktree at
+// root, unless GIT_COMMON_DIR overrides it for the top-level repository.
+func commonDirOf(root container.GitRoot, gitDir string) string {
+	if dir := overridesFor(root).CommonDir; dir != "" {
+		return dir
+	}
+	content, err := os.ReadFile(filepath.Join(gitDir, "commondir"))
+	if err != nil {
+		return gitDir
+	}
+	dir := strings.TrimSpace(string(content))
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(gitDir, dir)
+	}
+	return filepath.Clean(dir)
+}
+
+// readHead reads and resolves HEAD without the git binary.
+func readHead(root container.GitRoot) (head, error) {
+	gitDir, err := gitDirOf(root)
+	if err != nil {
+		return head{}, err
+	}
+	content, err := os.ReadFile(filepath.Join(gitDir, "HEAD"))
+	if err != nil {
+		return head{}, err
+	}
+
+	value := strings.TrimSpace(string(content))
+	ref, symbolic := strings.CutPrefix(value, "ref: ")
+	if !symbolic {
+		return head{commit: value}, nil
+	}
+
+	commit, err := resolveRef(gitDir, commonDirOf(root, gitDir), ref)
+	if err != nil {
+		return head{}, err
+	}
+	return head{ref: ref, commit: commit}, nil
+}
+
+// resolveRef resolves a ref from loose ref files, falling back to packed-refs.
+// An unborn branch resolves to an empty commit.
+func resolveRef(gitDir, commonDir, ref string) (string, error) {
+	for _, dir := range []string{gitDir, commonDir} {
+		if content, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(ref))); err == nil {
+			return strings.TrimSpace(string(content)), nil
+		}
+	}
+
+	file, err := os.Open(filepath.Join(commonDir, "packed-refs"))
+	if err != nil {
+		return "", nil
+	}
+	defer func(file *os.File) {
+		err := file.Close()
+		if err != nil {
+			panic(err)
+		}
+	}(file)
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		sha, name, ok := strings.Cut(scanner.Text(), " ")
+		if ok && name == ref {
+			return sha, nil
+		}
+	}
+	return "", scanner.Err()
+}
+
+// readRemoteURL reads a remote's URL from the repository config without the git binary.
+func readRemoteURL(root container.GitRoot, remote string) (string, error) {
+	gitDir, err := gitDirOf(root)
+	if err != nil {
+		return "", err
+	}
+	content, err := os.ReadFile(filepath.Join(commonDirOf(root, gitDir), "config"))
+	if err != nil {
+		return "", err
+	}
+	if url, ok := configValue(string(content), fmt.Sprintf(`[remote "%s"]`, remote), "url"); ok {
+		return url, nil
+	}
+	return "", fmt.Errorf("remote %q not found", remote)
+}
+
+// configValue returns the value of key in the section header of a git
+// config file's content.
+func configValue(content, section, key string) (string, bool) {
+	inSection := false
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "[") {
+			inSection = line == section
+			continue
+		}
+		if !inSection {
+			continue
+		}
+		if k, value, ok := strings.Cut(line, "="); ok && strings.TrimSpace(k) == key {
+			return strings.TrimSpace(value), true
+		}
+	}
+	return "", false
+}
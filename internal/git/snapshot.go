@@ -0,0 +1,223 @@
+package git
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gloo-foo/vsl/internal/container"
+)
+
+// Snapshot settings.
+const (
+	SnapshotRefPrefix = "refs/vsl/snapshots/" // Snapshots are kept as commits under these refs
+	maxSnapshots      = 20                    // Snapshots kept per repository, oldest deleted first
+	snapshotIDLength  = 12                    // Length of the abbreviated commit identifying a snapshot
+)
+
+// snapshotIdentity authors snapshot commits, so no user identity is needed.
+var snapshotIdentity = []string{
+	"GIT_AUTHOR_NAME=vsl", "GIT_AUTHOR_EMAIL=vsl@localhost",
+	"GIT_COMMITTER_NAME=vsl", "GIT_COMMITTER_EMAIL=vsl@localhost",
+}
+
+// Snapshot is a recorded state of a worktree: every file that is tracked or
+// not ignored, as a commit outside the branches.
+type Snapshot struct {
+	ID      string    `json:"id"`             // Abbreviated commit
+	Commit  string    `json:"commit"`         // Snapshot commit
+	Head    string    `json:"head,omitempty"` // HEAD when the snapshot was taken; empty on an unborn branch
+	Time    time.Time `json:"time"`
+	Message string    `json:"message"`
+}
+
+// TakeSnapshot records the worktree at root without touching it, its index
+// or its branches, and keeps the newest snapshots only. The index is built
+// in a temporary file seeded from the real one, so unchanged files need not
+// be hashed again.
+func TakeSnapshot(ctx context.Context, root container.GitRoot, message string) (Snapshot, error) {
+	index, cleanup, err := tempIndex(root)
+	if err != nil {
+		return Snapshot{}, err
+	}
+	defer cleanup()
+
+	env := append([]string{"GIT_INDEX_FILE=" + index}, snapshotIdentity...)
+	if _, err := runEnv(ctx, root, env, "add", "--all", "--", "."); err != nil {
+		return Snapshot{}, err
+	}
+	tree, err := runEnv(ctx, root, env, "write-tree")
+	if err != nil {
+		return Snapshot{}, err
+	}
+	args := []string{"commit-tree", tree, "-m", message}
+	head, headErr := run(ctx, root, "rev-parse", "--quiet", "--verify", "HEAD^{commit}")
+	if headErr == nil {
+		args = append(args, "-p", head)
+	}
+	commit, err := runEnv(ctx, root, env, args...)
+	if err != nil {
+		return Snapshot{}, err
+	}
+	if _, err := run(ctx, root, "update-ref", SnapshotRefPrefix+commit, commit); err != nil {
+		return Snapshot{}, err
+	}
+
+	snapshots, err := Snapshots(ctx, root)
+	if err == nil && len(snapshots) > maxSnapshots {
+		for _, old := range snapshots[maxSnapshots:] {
+			_, _ = run(ctx, root, "update-ref", "-d", SnapshotRefPrefix+old.Commit)
+		}
+	}
+	return Snapshot{ID: commit[:snapshotIDLength], Commit: commit, Head: head, Time: time.Now(), Message: message}, nil
+}
+
+// Snapshots returns the snapshots of the repository at root, newest first.
+func Snapshots(ctx context.Context, root container.GitRoot) ([]Snapshot, error) {
+	out, err := run(ctx, root, "for-each-ref", "--sort=-committerdate",
+		"--format=%(objectname)%00%(parent)%00%(committerdate:unix)%00%(contents:subject)", SnapshotRefPrefix)
+	if err != nil {
+		return nil, err
+	}
+	var snapshots []Snapshot
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.SplitN(line, "\x00", 4)
+		if len(fields) != 4 {
+			continue
+		}
+		unix, _ := strconv.ParseInt(fields[2], 10, 64)
+		snapshots = append(snapshots, Snapshot{
+			ID:      fields[0][:min(len(fields[0]), snapshotIDLength)],
+			Commit:  fields[0],
+			Head:    fields[1],
+			Time:    time.Unix(unix, 0),
+			Message: fields[3],
+		})
+	}
+	return snapshots, nil
+}
+
+// FindSnapshot returns the snapshot whose ID or commit starts with id, or
+// the newest one when id is empty.
+func FindSnapshot(ctx context.Context, root container.GitRoot, id string) (Snapshot, error) {
+	snapshots, err := Snapshots(ctx, root)
+	if err != nil {
+		return Snapshot{}, err
+	}
+	var found []Snapshot
+	for _, s := range snapshots {
+		if id == "" || strings.HasPrefix(s.Commit, id) {
+			found = append(found, s)
+		}
+	}
+	switch {
+	case len(found) == 0 && id == "":
+		return Snapshot{}, fmt.Errorf("no snapshots in %s", root)
+	case len(found) == 0:
+		return Snapshot{}, fmt.Errorf("snapshot %q not found", id)
+	case len(found) > 1 && id != "":
+		return Snapshot{}, fmt.Errorf("snapshot %q is ambiguous", id)
+	}
+	return found[0], nil
+}
+
+// RestoreSnapshot puts the worktree at root back in the state of the
+// snapshot: files it holds are rewritten, and files created since that are
+// not ignored are deleted. Ignored files, the index and the branches are
+// left alone.
+func RestoreSnapshot(ctx context.Context, root container.GitRoot, snapshot Snapshot) error {
+	return withSnapshotIndex(ctx, root, snapshot, func(env []string) error {
+		for _, args := range [][]string{
+			{"checkout-index", "--all", "--force"},
+			{"clean", "-q", "--force", "-d", "--", "."},
+		} {
+			if _, err := runEnv(ctx, root, env, args...); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// SnapshotRemovals returns the paths, relative to root, that restoring the
+// snapshot deletes: the files and directories created since that are not
+// ignored. Directories end in a slash.
+func SnapshotRemovals(ctx context.Context, root container.GitRoot, snapshot Snapshot) ([]string, error) {
+	var removals []string
+	err := withSnapshotIndex(ctx, root, snapshot, func(env []string) error {
+		// The messages of git clean are translated; parse the untranslated ones
+		out, err := runEnv(ctx, root, append(env, "LC_ALL=C"), "clean", "--dry-run", "-d", "--", ".")
+		if err != nil {
+			return err
+		}
+		for _, line := range strings.Split(out, "\n") {
+			if path, ok := strings.CutPrefix(line, "Would remove "); ok {
+				removals = append(removals, path)
+			}
+		}
+		return nil
+	})
+	return removals, err
+}
+
+// withSnapshotIndex calls fn with the environment of git commands working on
+// a temporary index holding the files of the snapshot.
+func withSnapshotIndex(ctx context.Context, root container.GitRoot, snapshot Snapshot, fn func(env []string) error) error {
+	index, cleanup, err := tempIndex(root)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	env := []string{"GIT_INDEX_FILE=" + index}
+	if _, err := runEnv(ctx, root, env, "read-tree", snapshot.Commit); err != nil {
+		return err
+	}
+	return fn(env)
+}
+
+// tempIndex creates a temporary index file for root, a copy of its index
+// when it has one, and returns its path with a function removing it.
+func tempIndex(root container.GitRoot) (string, func(), error) {
+	if !Available() {
+		return "", nil, ErrNotInstalled
+	}
+	dir, err := os.MkdirTemp("", "vsl-index-")
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to create temporary index: %w", err)
+	}
+	cleanup := func() { _ = os.RemoveAll(dir) }
+	index := filepath.Join(dir, "index")
+
+	gitDir, _, err := WorktreeGitDirs(root)
+	if err != nil {
+		return index, cleanup, nil
+	}
+	src, err := os.Open(filepath.Join(string(gitDir), "index"))
+	if err != nil {
+		return index, cleanup, nil
+	}
+	defer func(f *os.File) {
+		err := f.Close()
+		if err != nil {
+			panic(err)
+		}
+	}(src)
+	dst, err := os.Create(index)
+	if err != nil {
+		cleanup()
+		return "", nil, fmt.Errorf("failed to create temporary index: %w", err)
+	}
+	_, copyErr := io.Copy(dst, src)
+	if err := dst.Close(); err != nil || copyErr != nil {
+		cleanup()
+		return "", nil, fmt.Errorf("failed to copy index: %w", errors.Join(copyErr, err))
+	}
+	return index, cleanup, nil
+}
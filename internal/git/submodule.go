@@ -0,0 +1,72 @@
+package git
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/gloo-foo/vsl/internal/container"
+)
+
+// moduleGitDir returns the git directory of root when root is a checked-out
+// submodule whose .git file points into the superproject's .git/modules.
+// ok is false for uninitialized submodules and embedded repositories.
+func moduleGitDir(root container.GitRoot) (container.GitDir, bool) {
+	// Without its own .git, git would resolve the superproject instead
+	info, err := os.Lstat(filepath.Join(string(root), ".git"))
+	if err != nil || info.IsDir() {
+		return "", false
+	}
+	gitDir, _, err := WorktreeGitDirs(root)
+	if err != nil {
+		return "", false
+	}
+	return gitDir, true
+}
+
+// SubmoduleGitDirs returns the git directories of the checked-out submodules
+// of the repository at root, as listed in its .gitmodules, including nested
+// submodules. Submodules that are not initialized are skipped.
+func SubmoduleGitDirs(root container.GitRoot) []container.GitDir {
+	var dirs []container.GitDir
+	for _, p := range submodulePaths(root) {
+		sub := container.GitRoot(filepath.Join(string(root), p))
+		gitDir, ok := moduleGitDir(sub)
+		if !ok {
+			continue
+		}
+		dirs = append(dirs, gitDir)
+		dirs = append(dirs, SubmoduleGitDirs(sub)...)
+	}
+	return dirs
+}
+
+// submodulePaths reads the path of each submodule from root's .gitmodules.
+// Paths leaving the repository are ignored.
+func submodulePaths(root container.GitRoot) []string {
+	f, err := os.Open(filepath.Join(string(root), ".gitmodules"))
+	if err != nil {
+		return nil
+	}
+	defer func(f *os.File) {
+		err := f.Close()
+		if err != nil {
+			panic(err)
+		}
+	}(f)
+
+	var found []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(strings.TrimSpace(scanner.Text()), "=")
+		if !ok || strings.TrimSpace(key) != "path" {
+			continue
+		}
+		p := filepath.FromSlash(strings.Trim(strings.TrimSpace(value), `"`))
+		if filepath.IsLocal(p) {
+			found = append(found, p)
+		}
+	}
+	return found
+}
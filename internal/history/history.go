@@ -0,0 +1,129 @@
+// Package history records completed runs and execs so they can be inspected later.
+package history
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/gloo-foo/vsl/internal/container"
+	"github.com/gloo-foo/vsl/internal/state"
+)
+
+// fileName is the history file inside the vsl cache directory.
+const fileName = "history.jsonl"
+
+// Kind is the command that produced a history entry.
+type Kind string
+
+// History entry kinds.
+const (
+	KindRun  Kind = "run"
+	KindExec Kind = "exec"
+	KindHost Kind = "host" // Script command run on the host instead of a container
+)
+
+// Entry describes one completed run or exec.
+type Entry struct {
+	Time        time.Time             `json:"time"`
+	Kind        Kind                  `json:"kind"`
+	ContainerID container.ContainerID `json:"container_id,omitempty"`
+	Image       container.Image       `json:"image,omitempty"`
+	Command     []string              `json:"command,omitempty"`
+	HostDir     string                `json:"host_dir,omitempty"`
+	WorkingDir  string                `json:"working_dir,omitempty"`
+	ScriptPath  container.ScriptPath  `json:"script_path,omitempty"`
+	Session     container.Session     `json:"session,omitempty"`
+	ToolVersion string                `json:"tool_version,omitempty"`
+	ExitCode    *int                  `json:"exit_code,omitempty"`
+	DurationMs  int64                 `json:"duration_ms"`
+	Success     bool                  `json:"success"`
+	Error       string                `json:"error,omitempty"`
+	Config      json.RawMessage       `json:"config,omitempty"`
+}
+
+// path returns the location of the history file.
+func path() (string, error) {
+	dir, err := state.CacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, fileName), nil
+}
+
+// Append adds an entry to the history file.
+func Append(e Entry) error {
+	p, err := path()
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("failed to encode history entry: %w", err)
+	}
+
+	f, err := os.OpenFile(p, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to open history: %w", err)
+	}
+	defer func(f *os.File) {
+		err := f.Close()
+		if err != nil {
+			panic(err)
+		}
+	}(f)
+
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write history: %w", err)
+	}
+	return nil
+}
+
+// LastFailed returns the most recent failed entry of the given kind.
+func LastFailed(kind Kind) (Entry, error) {
+	entries, err := Load()
+	if err != nil {
+		return Entry{}, err
+	}
+	for i := len(entries) - 1; i >= 0; i-- {
+		if entries[i].Kind == kind && !entries[i].Success {
+			return entries[i], nil
+		}
+	}
+	return Entry{}, fmt.Errorf("no failed %s in history", kind)
+}
+
+// Load returns all history entries, oldest first. Unreadable lines are skipped.
+func Load() ([]Entry, error) {
+	p, err := path()
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(p)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open history: %w", err)
+	}
+	defer func(f *os.File) {
+		err := f.Close()
+		if err != nil {
+			panic(err)
+		}
+	}(f)
+
+	var entries []Entry
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1<<20)
+	for scanner.Scan() {
+		var e Entry
+		if json.Unmarshal(scanner.Bytes(), &e) == nil {
+			entries = append(entries, e)
+		}
+	}
+	return entries, scanner.Err()
+}
@@ -0,0 +1,77 @@
+package image
+
+import (
+	"fmt"
+
+	"github.com/distribution/reference"
+	"github.com/docker/cli/cli/config"
+	"github.com/docker/cli/cli/config/types"
+	"github.com/docker/docker/api/types/registry"
+	"github.com/gloo-foo/vsl/internal/container"
+)
+
+// dockerHubAuthAddress is the legacy index address Docker Hub credentials are stored under.
+const dockerHubAuthAddress = "https://index.docker.io/v1/"
+
+// RegistryAuth resolves the host's Docker credentials for the registry of ref
+// and returns them encoded for the X-Registry-Auth header of a pull.
+//
+// Credentials come from ~/.docker/config.json (or $DOCKER_CONFIG), including
+// its credsStore and credHelpers, so helpers such as docker-credential-ecr-login
+// or docker-credential-gcloud are executed like the docker CLI would. An empty
+// result means the pull is anonymous.
+func RegistryAuth(ref container.Image) (string, error) {
+	named, err := reference.ParseNormalizedNamed(string(ref))
+	if err != nil {
+		return "", fmt.Errorf("invalid image reference %s: %w", ref, err)
+	}
+
+	host := reference.Domain(named)
+	if host == "docker.io" {
+		host = dockerHubAuthAddress
+	}
+
+	cf, err := config.Load(config.Dir())
+	if err != nil {
+		return "", fmt.Errorf("failed to load docker config: %w", err)
+	}
+	auth, err := cf.GetAuthConfig(host)
+	if err != nil {
+		return "", fmt.Errorf("failed to get credentials for %s: %w", host, err)
+	}
+	if auth.Username == "" && auth.Password == "" && auth.Auth == "" && auth.IdentityToken == "" && auth.RegistryToken == "" {
+		return "", nil
+	}
+	return registry.EncodeAuthConfig(toAuthConfig(auth))
+}
+
+// registryAuthConfigs returns the host's credentials for every configured
+// registry, which builds need to pull private base images.
+func registryAuthConfigs() (map[string]registry.AuthConfig, error) {
+	cf, err := config.Load(config.Dir())
+	if err != nil {
+		return nil, fmt.Errorf("failed to load docker config: %w", err)
+	}
+	all, err := cf.GetAllCredentials()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get registry credentials: %w", err)
+	}
+
+	configs := make(map[string]registry.AuthConfig, len(all))
+	for host, auth := range all {
+		configs[host] = toAuthConfig(auth)
+	}
+	return configs, nil
+}
+
+// toAuthConfig converts a docker CLI credential to its API form.
+func toAuthConfig(auth types.AuthConfig) registry.AuthConfig {
+	return registry.AuthConfig{
+		Username:      auth.Username,
+		Password:      auth.Password,
+		Auth:          auth.Auth,
+		ServerAddress: auth.ServerAddress,
+		IdentityToken: auth.IdentityToken,
+		RegistryToken: auth.RegistryToken,
+	}
+}
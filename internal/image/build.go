@@ -0,0 +1,126 @@
+package image
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types/build"
+	"github.com/docker/docker/client"
+	"github.com/gloo-foo/vsl/internal/container"
+)
+
+// defaultDockerfile is the Dockerfile used when a build names none.
+const defaultDockerfile = "Dockerfile"
+
+// BuildSpec describes an image built from a Dockerfile before a run.
+type BuildSpec struct {
+	Context    string            // Build context directory
+	Dockerfile string            // Dockerfile path relative to the context (default Dockerfile)
+	Target     string            // Build stage to stop at
+	Args       map[string]string // Build arguments
+	CacheFrom  []string          // Images used as additional cache sources
+	NoCache    bool              // Do not use the build cache
+	Pull       bool              // Always pull newer versions of base images
+}
+
+// BuildResult describes a built image.
+type BuildResult struct {
+	Image      container.Image `json:"image"`
+	ID         string          `json:"id,omitempty"`
+	BuildKit   bool            `json:"buildkit"`
+	DurationMs int64           `json:"duration_ms"`
+}
+
+// BuildTag returns the tag for an image built from spec when none is given.
+// It is stable for a context, Dockerfile and target, so rebuilds replace the
+// previous image and reuse its cache.
+func BuildTag(spec BuildSpec) container.Image {
+	key := strings.Join([]string{spec.Context, spec.Dockerfile, spec.Target}, "\x00")
+	sum := sha256.Sum256([]byte(key))
+	return container.Image("vsl-build-" + hex.EncodeToString(sum[:])[:12] + ":latest")
+}
+
+// Build builds spec into an image tagged tag, writing build progress to out.
+// BuildKit is used when the daemon defaults to it, and the host's registry
+// credentials are passed on for private base images.
+func Build(ctx context.Context, dockerCli client.APIClient, spec BuildSpec, tag container.Image, labels map[string]string, out io.Writer) (BuildResult, error) {
+	result := BuildResult{Image: tag}
+	started := time.Now()
+
+	dockerfile := spec.Dockerfile
+	if dockerfile == "" {
+		dockerfile = defaultDockerfile
+	}
+	if !filepath.IsLocal(dockerfile) {
+		return result, fmt.Errorf("dockerfile %s must be inside the build context", dockerfile)
+	}
+
+	ping, err := dockerCli.Ping(ctx)
+	if err != nil {
+		return result, fmt.Errorf("failed to reach daemon: %w", err)
+	}
+	result.BuildKit = ping.BuilderVersion == build.BuilderBuildKit
+
+	authConfigs, err := registryAuthConfigs()
+	if err != nil {
+		return result, err
+	}
+
+	buildContext, err := contextArchive(spec.Context, dockerfile)
+	if err != nil {
+		return result, fmt.Errorf("failed to read build context %s: %w", spec.Context, err)
+	}
+	defer func(buildContext io.ReadCloser) {
+		err := buildContext.Close()
+		if err != nil {
+			panic(err)
+		}
+	}(buildContext)
+
+	args := make(map[string]*string, len(spec.Args))
+	for name, value := range spec.Args {
+		args[name] = &value
+	}
+	version := build.BuilderV1
+	if result.BuildKit {
+		version = build.BuilderBuildKit
+	}
+
+	resp, err := dockerCli.ImageBuild(ctx, buildContext, build.ImageBuildOptions{
+		Tags:        []string{string(tag)},
+		Dockerfile:  filepath.ToSlash(dockerfile),
+		Target:      spec.Target,
+		BuildArgs:   args,
+		CacheFrom:   spec.CacheFrom,
+		NoCache:     spec.NoCache,
+		PullParent:  spec.Pull,
+		Remove:      true,
+		Labels:      labels,
+		AuthConfigs: authConfigs,
+		Version:     version,
+	})
+	if err != nil {
+		return result, fmt.Errorf("failed to build %s: %w", tag, err)
+	}
+	defer func(body io.ReadCloser) {
+		err := body.Close()
+		if err != nil {
+			panic(err)
+		}
+	}(resp.Body)
+
+	p := &progress{out: out}
+	err = p.read(resp.Body)
+	result.ID = p.imageID
+	result.DurationMs = time.Since(started).Milliseconds()
+	if err != nil {
+		return result, fmt.Errorf("failed to build %s: %w", tag, err)
+	}
+	return result, nil
+}
@@ -0,0 +1,139 @@
+package image
+
+import (
+	"archive/tar"
+	"bufio"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// ignorePatterns reads the .dockerignore of a build context. Patterns are
+// slash-separated paths relative to the context, with ! marking exceptions.
+func ignorePatterns(dir string) ([]string, error) {
+	f, err := os.Open(filepath.Join(dir, ".dockerignore"))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer func(f *os.File) {
+		err := f.Close()
+		if err != nil {
+			panic(err)
+		}
+	}(f)
+
+	var patterns []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		exception := strings.HasPrefix(line, "!")
+		line = path.Clean(strings.TrimPrefix(filepath.ToSlash(strings.TrimPrefix(line, "!")), "/"))
+		if exception {
+			line = "!" + line
+		}
+		patterns = append(patterns, line)
+	}
+	return patterns, scanner.Err()
+}
+
+// ignored reports whether rel, or a directory containing it, matches the
+// patterns. Later patterns override earlier ones, as in .dockerignore.
+func ignored(rel string, patterns []string) bool {
+	excluded := false
+	for _, p := range patterns {
+		exception := strings.HasPrefix(p, "!")
+		p = strings.TrimPrefix(p, "!")
+		for dir := rel; dir != "."; dir = path.Dir(dir) {
+			if ok, _ := path.Match(p, dir); ok {
+				excluded = !exception
+				break
+			}
+		}
+	}
+	return excluded
+}
+
+// contextArchive streams the build context in dir as a tar archive, leaving
+// out paths matched by its .dockerignore. The Dockerfile and .dockerignore
+// are always sent, since the builder needs them.
+func contextArchive(dir string, dockerfile string) (io.ReadCloser, error) {
+	patterns, err := ignorePatterns(dir)
+	if err != nil {
+		return nil, err
+	}
+	keep := map[string]bool{path.Clean(filepath.ToSlash(dockerfile)): true, ".dockerignore": true}
+
+	reader, writer := io.Pipe()
+	go func() {
+		tw := tar.NewWriter(writer)
+		err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			rel, err := filepath.Rel(dir, p)
+			if err != nil || rel == "." {
+				return err
+			}
+			rel = filepath.ToSlash(rel)
+			if ignored(rel, patterns) && !keep[rel] {
+				if d.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			return addFile(tw, p, rel, d)
+		})
+		if err == nil {
+			err = tw.Close()
+		}
+		writer.CloseWithError(err)
+	}()
+	return reader, nil
+}
+
+// addFile writes one context entry to the archive.
+func addFile(tw *tar.Writer, p, rel string, d fs.DirEntry) error {
+	info, err := d.Info()
+	if err != nil {
+		return err
+	}
+	var link string
+	if info.Mode()&fs.ModeSymlink != 0 {
+		if link, err = os.Readlink(p); err != nil {
+			return err
+		}
+	}
+	header, err := tar.FileInfoHeader(info, link)
+	if err != nil {
+		return err
+	}
+	header.Name = rel
+	if err := tw.WriteHeader(header); err != nil {
+		return err
+	}
+	if !info.Mode().IsRegular() {
+		return nil
+	}
+
+	f, err := os.Open(p)
+	if err != nil {
+		return err
+	}
+	defer func(f *os.File) {
+		err := f.Close()
+		if err != nil {
+			panic(err)
+		}
+	}(f)
+	_, err = io.Copy(tw, f)
+	return err
+}
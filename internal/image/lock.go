@@ -0,0 +1,99 @@
+package image
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/docker/docker/client"
+	"github.com/gloo-foo/vsl/internal/container"
+)
+
+// LockFile is the name of the file pinning image tags to digests, kept at
+// the root of the workspace and committed with it.
+const LockFile = "vsl.lock"
+
+// Lock pins the image references of a workspace's scripts to the digests
+// they resolved to.
+type Lock struct {
+	Images map[container.Image]string `json:"images"`
+}
+
+// FindLock returns the lock file of the workspace containing dir, looking in
+// dir and its parents up to the root of its repository.
+func FindLock(dir string) (string, bool) {
+	for {
+		path := filepath.Join(dir, LockFile)
+		if info, err := os.Stat(path); err == nil && info.Mode().IsRegular() {
+			return path, true
+		}
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+			return "", false
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", false
+		}
+		dir = parent
+	}
+}
+
+// LoadLock reads a lock file. A missing file is an empty lock.
+func LoadLock(path string) (Lock, error) {
+	lock := Lock{Images: map[container.Image]string{}}
+	content, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return lock, nil
+	}
+	if err != nil {
+		return lock, err
+	}
+	if err := json.Unmarshal(content, &lock); err != nil {
+		return lock, fmt.Errorf("%s: %w", path, err)
+	}
+	if lock.Images == nil {
+		lock.Images = map[container.Image]string{}
+	}
+	return lock, nil
+}
+
+// Save writes the lock file, with its images sorted so changes diff cleanly.
+func (l Lock) Save(path string) error {
+	content, err := json.MarshalIndent(l, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(content, '\n'), 0o644)
+}
+
+// Pin returns ref with the digest the lock records for it. References that
+// already name a digest, and those the lock does not know, are not pinned.
+func (l Lock) Pin(ref container.Image) (container.Image, bool) {
+	if strings.Contains(string(ref), "@") {
+		return ref, false
+	}
+	digest, ok := l.Images[ref]
+	if !ok {
+		return ref, false
+	}
+	return container.Image(string(ref) + "@" + digest), true
+}
+
+// Digest resolves ref to the digest of its manifest in the registry, with
+// the host's registry credentials.
+func Digest(ctx context.Context, dockerCli client.DistributionAPIClient, ref container.Image) (string, error) {
+	auth, err := RegistryAuth(ref)
+	if err != nil {
+		return "", err
+	}
+	inspect, err := dockerCli.DistributionInspect(ctx, string(ref), auth)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve %s: %w", ref, err)
+	}
+	return inspect.Descriptor.Digest.String(), nil
+}
@@ -0,0 +1,20 @@
+package lock
+
+import (
+	"github.com/gloo-foo/vsl/internal/app"
+	"github.com/gloo-foo/vsl/internal/app/log"
+	"github.com/gloo-foo/vsl/internal/container"
+)
+
+// Config holds configuration for locking image digests.
+type Config struct {
+	Scripts []container.ScriptPath // Scripts whose images are locked; empty locks every script of the workspace
+	Update  bool                   // Resolve images the lock file already pins again
+
+	// Output and logging
+	Output  app.FilePath
+	Logging log.Config
+}
+
+func (c Config) OutputFilePath() app.FilePath { return c.Output }
+func (c Config) LoggerConfig() log.Config     { return c.Logging }
@@ -0,0 +1,171 @@
+// Package lock pins the images of a workspace's scripts to the digests their
+// tags resolve to, so every run of the team uses the same images.
+package lock
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/gloo-foo/vsl/internal/container"
+	"github.com/gloo-foo/vsl/internal/docker"
+	"github.com/gloo-foo/vsl/internal/image"
+	"github.com/gloo-foo/vsl/internal/script"
+	"github.com/gloo-foo/vsl/internal/task"
+)
+
+// Result holds the result of locking image digests.
+type Result struct {
+	Success  bool          `json:"success"`
+	LockFile string        `json:"lock_file"`
+	Images   []ImageStatus `json:"images"`
+	Pruned   int           `json:"pruned,omitempty"` // Pins removed as no script uses their image
+	Message  string        `json:"message"`
+}
+
+// ImageStatus reports the digest an image is pinned to.
+type ImageStatus struct {
+	Image   container.Image `json:"image"`
+	Digest  string          `json:"digest,omitempty"`
+	Changed bool            `json:"changed"` // The digest was added or differs from the previous pin
+	Error   string          `json:"error,omitempty"`
+}
+
+// MarshalJSON implements json.Marshaler
+func (r Result) MarshalJSON() ([]byte, error) {
+	type Alias Result
+	return json.Marshal((Alias)(r))
+}
+
+// Lock resolves the image tags of scripts to digests and records them in the
+// workspace's lock file, which runs then use. Images already pinned keep
+// their digest unless Update is set. Locking the whole workspace drops the
+// pins of images no script uses any more.
+func Lock(ctx context.Context, logger *slog.Logger, cfg Config) (Result, error) {
+	pwd, err := os.Getwd()
+	if err != nil {
+		return Result{}, fmt.Errorf("failed to get current directory: %w", err)
+	}
+	path, ok := image.FindLock(pwd)
+	if !ok {
+		path = filepath.Join(task.Root(pwd), image.LockFile)
+	}
+	result := Result{LockFile: path, Images: []ImageStatus{}}
+
+	scripts := cfg.Scripts
+	if len(scripts) == 0 {
+		tasks, err := task.Discover(ctx, filepath.Dir(path))
+		if err != nil {
+			return result, err
+		}
+		for _, t := range tasks {
+			if t.Error != "" {
+				logger.Warn("Skipping script that cannot be parsed", "script", t.Script, "error", t.Error)
+				continue
+			}
+			scripts = append(scripts, t.Script)
+		}
+	}
+	refs, err := images(scripts)
+	if err != nil {
+		return result, err
+	}
+
+	lock, err := image.LoadLock(path)
+	if err != nil {
+		return result, err
+	}
+	if len(cfg.Scripts) == 0 {
+		used := make(map[container.Image]string, len(refs))
+		for _, ref := range refs {
+			if digest, ok := lock.Images[ref]; ok {
+				used[ref] = digest
+			}
+		}
+		result.Pruned = len(lock.Images) - len(used)
+		lock.Images = used
+	}
+
+	dockerCli, err := docker.NewClient(docker.WithRetry(logger))
+	if err != nil {
+		return result, err
+	}
+	defer docker.Close(dockerCli)
+
+	failed := 0
+	for _, ref := range refs {
+		status := ImageStatus{Image: ref, Digest: lock.Images[ref]}
+		if status.Digest == "" || cfg.Update {
+			logger.Info("Resolving image digest", "image", ref)
+			digest, err := image.Digest(ctx, dockerCli, ref)
+			if err != nil {
+				logger.Warn("Failed to resolve image digest", "image", ref, "error", err)
+				status.Error = err.Error()
+				failed++
+			} else {
+				status.Changed = digest != status.Digest
+				status.Digest = digest
+				lock.Images[ref] = digest
+			}
+		}
+		result.Images = append(result.Images, status)
+	}
+
+	if err := lock.Save(path); err != nil {
+		return result, err
+	}
+	result.Success = failed == 0
+	result.Message = fmt.Sprintf("Locked %d of %d images in %s", len(refs)-failed, len(refs), path)
+	if !result.Success {
+		return result, errors.New(result.Message)
+	}
+	return result, nil
+}
+
+// images returns the registry images of scripts, sorted: the image of each
+// script, of its steps and of its services. Built images, host commands and
+// images given by variables or already by digest cannot be locked.
+func images(scripts []container.ScriptPath) ([]container.Image, error) {
+	seen := map[container.Image]bool{}
+	add := func(ref container.Image) {
+		if ref != "" && !strings.ContainsAny(string(ref), "$@") {
+			seen[ref] = true
+		}
+	}
+	for _, path := range scripts {
+		cfg, err := script.ParseFile(string(path))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		if cfg.Build == nil && !cfg.Host {
+			add(cfg.Image)
+		}
+		for _, step := range cfg.Steps {
+			if !step.Host {
+				add(step.Image)
+			}
+		}
+		services, err := script.ParseServices(string(path))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		for _, s := range services {
+			if s.Config.Build == nil {
+				add(s.Config.Image)
+			}
+		}
+	}
+
+	refs := make([]container.Image, 0, len(seen))
+	for ref := range seen {
+		refs = append(refs, ref)
+	}
+	sort.Slice(refs, func(i, j int) bool { return refs[i] < refs[j] })
+	return refs, nil
+}
@@ -0,0 +1,21 @@
+package prewarm
+
+import (
+	"time"
+
+	"github.com/gloo-foo/vsl/internal/app"
+	"github.com/gloo-foo/vsl/internal/app/log"
+)
+
+// Config holds configuration for pre-warming images.
+type Config struct {
+	Manifest ManifestSource // Path or URL of the manifest listing images
+	Every    time.Duration  // Repeat the pull on this interval until interrupted; zero runs once
+
+	// Output and logging
+	Output  app.FilePath
+	Logging log.Config
+}
+
+func (c Config) OutputFilePath() app.FilePath { return c.Output }
+func (c Config) LoggerConfig() log.Config     { return c.Logging }
@@ -0,0 +1,84 @@
+package prewarm
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/gloo-foo/vsl/internal/container"
+	up "github.com/uplang/go"
+)
+
+// ManifestSource represents the path or URL of a pre-warm manifest.
+type ManifestSource string
+
+// Manifest lists the images to keep warm.
+type Manifest struct {
+	Images []container.Image
+}
+
+// LoadManifest reads a manifest from a local file or an http(s) URL.
+// The manifest is an UP document with an images list:
+//
+//	images [
+//	  golang:1.22
+//	  node:20-alpine
+//	]
+func LoadManifest(ctx context.Context, source ManifestSource) (Manifest, error) {
+	content, err := read(ctx, source)
+	if err != nil {
+		return Manifest{}, err
+	}
+
+	doc, err := up.NewParser().ParseDocument(strings.NewReader(string(content)))
+	if err != nil {
+		return Manifest{}, fmt.Errorf("failed to parse manifest %s: %w", source, err)
+	}
+
+	var manifest Manifest
+	for _, node := range doc.Nodes {
+		if node.Key != "images" {
+			continue
+		}
+		list, ok := node.Value.(up.List)
+		if !ok {
+			return Manifest{}, fmt.Errorf("manifest %s: images must be a list", source)
+		}
+		for _, item := range list {
+			if ref, ok := item.(string); ok && ref != "" {
+				manifest.Images = append(manifest.Images, container.Image(ref))
+			}
+		}
+	}
+	return manifest, nil
+}
+
+func read(ctx context.Context, source ManifestSource) ([]byte, error) {
+	s := string(source)
+	if !strings.HasPrefix(s, "http://") && !strings.HasPrefix(s, "https://") {
+		return os.ReadFile(s)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch manifest: %w", err)
+	}
+	defer func(body io.ReadCloser) {
+		err := body.Close()
+		if err != nil {
+			panic(err)
+		}
+	}(resp.Body)
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch manifest %s: %s", s, resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
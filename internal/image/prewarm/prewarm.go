@@ -0,0 +1,120 @@
+// Package prewarm keeps the images listed in a shared manifest pulled and up to date.
+package prewarm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/docker/docker/client"
+	"github.com/gloo-foo/vsl/internal/container"
+	"github.com/gloo-foo/vsl/internal/docker"
+	"github.com/gloo-foo/vsl/internal/image"
+)
+
+// Result holds the result of a pre-warm pass.
+type Result struct {
+	Success  bool           `json:"success"`
+	Manifest ManifestSource `json:"manifest"`
+	Images   []ImageStatus  `json:"images"`
+	Passes   int            `json:"passes"`
+	Message  string         `json:"message"`
+}
+
+// ImageStatus reports the outcome of pulling one image.
+type ImageStatus struct {
+	Image  container.Image `json:"image"`
+	Pulled bool            `json:"pulled"`
+	Error  string          `json:"error,omitempty"`
+}
+
+// MarshalJSON implements json.Marshaler
+func (r Result) MarshalJSON() ([]byte, error) {
+	type Alias Result
+	return json.Marshal((Alias)(r))
+}
+
+// Prewarm pulls every image in the manifest. With a non-zero interval it
+// keeps re-reading the manifest and pulling until the context is cancelled,
+// reporting the last pass; a pass that cannot load the manifest is logged
+// and retried at the next interval, keeping the statuses of the pass before.
+func Prewarm(ctx context.Context, logger *slog.Logger, cfg Config) (Result, error) {
+	if cfg.Manifest == "" {
+		return Result{}, fmt.Errorf("no manifest specified")
+	}
+
+	dockerCli, err := docker.NewClient(docker.WithRetry(logger))
+	if err != nil {
+		return Result{}, err
+	}
+	defer docker.Close(dockerCli)
+
+	result := Result{Manifest: cfg.Manifest}
+	for {
+		result.Passes++
+		images, err := pass(ctx, logger, dockerCli, cfg.Manifest)
+		switch {
+		case err == nil:
+			result.Images = images
+		case cfg.Every <= 0:
+			return result, err
+		case ctx.Err() != nil:
+			logger.Info("Stopping pre-warm")
+			return summarize(result), nil
+		default:
+			logger.Warn("Pre-warm pass failed, retrying at the next pass", "error", err, "every", cfg.Every)
+		}
+
+		if cfg.Every <= 0 {
+			break
+		}
+		logger.Info("Waiting for next pre-warm pass", "every", cfg.Every)
+		select {
+		case <-ctx.Done():
+			logger.Info("Stopping pre-warm")
+			return summarize(result), nil
+		case <-time.After(cfg.Every):
+		}
+	}
+
+	result = summarize(result)
+	if !result.Success {
+		return result, errors.New(result.Message)
+	}
+	return result, nil
+}
+
+// pass loads the manifest and pulls each image in it.
+func pass(ctx context.Context, logger *slog.Logger, dockerCli client.ImageAPIClient, source ManifestSource) ([]ImageStatus, error) {
+	manifest, err := LoadManifest(ctx, source)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]ImageStatus, len(manifest.Images))
+	for i, ref := range manifest.Images {
+		logger.Info("Pulling image", "image", ref)
+		statuses[i] = ImageStatus{Image: ref, Pulled: true}
+		if err := image.Pull(ctx, logger, dockerCli, ref); err != nil {
+			logger.Warn("Failed to pull image", "image", ref, "error", err)
+			statuses[i] = ImageStatus{Image: ref, Error: err.Error()}
+		}
+	}
+	return statuses, nil
+}
+
+// summarize sets the success flag and message from the image statuses.
+func summarize(result Result) Result {
+	failed := 0
+	for _, s := range result.Images {
+		if !s.Pulled {
+			failed++
+		}
+	}
+	result.Success = failed == 0
+	result.Message = fmt.Sprintf("Pulled %d of %d images", len(result.Images)-failed, len(result.Images))
+	return result
+}
@@ -0,0 +1,164 @@
+package image
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// auxBuildKitTrace identifies aux messages holding BuildKit status updates.
+const auxBuildKitTrace = "moby.buildkit.trace"
+
+// buildMessage is one line of the daemon's JSON build stream.
+type buildMessage struct {
+	ID          string                   `json:"id"`
+	Stream      string                   `json:"stream"`
+	Status      string                   `json:"status"`
+	Error       string                   `json:"error"`
+	ErrorDetail struct{ Message string } `json:"errorDetail"`
+	Aux         json.RawMessage          `json:"aux"`
+}
+
+// progress renders a build stream for people and extracts the built image ID.
+type progress struct {
+	out      io.Writer
+	imageID  string
+	vertexes map[string]string // Names of BuildKit steps by digest
+	done     map[string]bool   // BuildKit steps already reported as finished
+}
+
+// read consumes the build stream, writing progress to out.
+// A failed build is returned as an error.
+func (p *progress) read(stream io.Reader) error {
+	p.vertexes = map[string]string{}
+	p.done = map[string]bool{}
+
+	decoder := json.NewDecoder(stream)
+	for {
+		var msg buildMessage
+		if err := decoder.Decode(&msg); errors.Is(err, io.EOF) {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("failed to read build output: %w", err)
+		}
+
+		switch {
+		case msg.Error != "":
+			return errors.New(strings.TrimSpace(msg.Error))
+		case msg.ErrorDetail.Message != "":
+			return errors.New(strings.TrimSpace(msg.ErrorDetail.Message))
+		case msg.Stream != "":
+			_, _ = io.WriteString(p.out, msg.Stream)
+		case msg.Status != "":
+			_, _ = fmt.Fprintln(p.out, msg.Status)
+		case len(msg.Aux) > 0:
+			p.aux(msg.ID, msg.Aux)
+		}
+	}
+}
+
+// aux handles auxiliary messages: BuildKit status traces, and the image ID
+// (unnamed from the legacy builder, moby.image.id from BuildKit).
+func (p *progress) aux(id string, data json.RawMessage) {
+	if id == auxBuildKitTrace {
+		var trace []byte
+		if json.Unmarshal(data, &trace) == nil {
+			p.trace(trace)
+		}
+		return
+	}
+
+	var image struct{ ID string }
+	if json.Unmarshal(data, &image) == nil && image.ID != "" {
+		p.imageID = image.ID
+	}
+}
+
+// trace renders a BuildKit StatusResponse, which is protobuf encoded:
+// field 1 holds the build steps (vertexes) and field 3 the output of their commands.
+func (p *progress) trace(b []byte) {
+	forEachField(b, func(num protowire.Number, value []byte) {
+		switch num {
+		case 1:
+			p.vertex(value)
+		case 3:
+			forEachField(value, func(num protowire.Number, msg []byte) {
+				if num == 4 {
+					_, _ = p.out.Write(msg)
+				}
+			})
+		}
+	})
+}
+
+// vertex reports a build step when it starts and when it finishes.
+// Vertex fields: 1 digest, 3 name, 4 cached, 6 completed, 7 error.
+func (p *progress) vertex(b []byte) {
+	var digest, name, failure string
+	var cached, completed bool
+	forEachField(b, func(num protowire.Number, value []byte) {
+		switch num {
+		case 1:
+			digest = string(value)
+		case 3:
+			name = string(value)
+		case 4:
+			cached = len(value) > 0 && value[0] != 0
+		case 6:
+			completed = true
+		case 7:
+			failure = string(value)
+		}
+	})
+
+	if _, seen := p.vertexes[digest]; !seen {
+		p.vertexes[digest] = name
+		_, _ = fmt.Fprintf(p.out, "=> %s\n", name)
+	}
+	if (completed || cached) && !p.done[digest] {
+		p.done[digest] = true
+		switch {
+		case failure != "":
+			_, _ = fmt.Fprintf(p.out, "=> ERROR %s: %s\n", name, failure)
+		case cached:
+			_, _ = fmt.Fprintf(p.out, "=> CACHED %s\n", name)
+		}
+	}
+}
+
+// forEachField calls fn with the number and raw value of each field of a
+// protobuf message. Varints are passed as a single byte holding whether they
+// are non-zero; malformed input ends the iteration.
+func forEachField(b []byte, fn func(protowire.Number, []byte)) {
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return
+		}
+		b = b[n:]
+
+		var value []byte
+		switch typ {
+		case protowire.BytesType:
+			value, n = protowire.ConsumeBytes(b)
+		case protowire.VarintType:
+			var v uint64
+			v, n = protowire.ConsumeVarint(b)
+			value = []byte{0}
+			if v != 0 {
+				value[0] = 1
+			}
+		default:
+			n = protowire.ConsumeFieldValue(num, typ, b)
+		}
+		if n < 0 {
+			return
+		}
+		b = b[n:]
+		fn(num, value)
+	}
+}
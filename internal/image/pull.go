@@ -0,0 +1,46 @@
+// Package image provides helpers for working with container images.
+package image
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+
+	"github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/client"
+	"github.com/gloo-foo/vsl/internal/container"
+	"github.com/gloo-foo/vsl/internal/docker"
+)
+
+// Pull pulls an image with the host's registry credentials, waiting for the pull to complete.
+// Pulls interrupted by a dropped connection are retried, as the daemon resumes them.
+func Pull(ctx context.Context, logger *slog.Logger, dockerCli client.ImageAPIClient, ref container.Image) error {
+	auth, err := RegistryAuth(ref)
+	if err != nil {
+		return fmt.Errorf("failed to pull %s: %w", ref, err)
+	}
+	return docker.Retries.Do(ctx, logger, "pull "+string(ref), func() error {
+		return pull(ctx, dockerCli, ref, auth)
+	})
+}
+
+// pull makes one attempt at pulling an image.
+func pull(ctx context.Context, dockerCli client.ImageAPIClient, ref container.Image, auth string) error {
+	reader, err := dockerCli.ImagePull(ctx, string(ref), image.PullOptions{RegistryAuth: auth})
+	if err != nil {
+		return fmt.Errorf("failed to pull %s: %w", ref, err)
+	}
+	defer func(reader io.ReadCloser) {
+		err := reader.Close()
+		if err != nil {
+			panic(err)
+		}
+	}(reader)
+
+	// The pull only completes once its progress stream has been consumed
+	if _, err := io.Copy(io.Discard, reader); err != nil {
+		return fmt.Errorf("failed to pull %s: %w", ref, err)
+	}
+	return nil
+}
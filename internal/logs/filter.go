@@ -0,0 +1,135 @@
+// Package logs provides filtering and decoration of container log streams.
+package logs
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"regexp"
+	"sync"
+)
+
+// Level is a log severity detected from a line's text.
+type Level int
+
+// Detected log levels.
+const (
+	LevelUnknown Level = iota
+	LevelDebug
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+// levelPatterns recognize the level markers of common log formats:
+// logfmt (level=warn), JSON ("level":"warn"), bracketed ([WARN]), and bare upper-case words.
+var levelPatterns = []struct {
+	level   Level
+	pattern *regexp.Regexp
+}{
+	{LevelError, regexp.MustCompile(`(?i)(level"?[=:]\s*"?(error|err|fatal|panic|critical)\b)|\[(ERROR|ERR|FATAL|PANIC|CRITICAL)\]|\b(ERROR|FATAL|PANIC|CRITICAL)\b`)},
+	{LevelWarn, regexp.MustCompile(`(?i)(level"?[=:]\s*"?(warn|warning)\b)|\[(WARN|WARNING)\]|\b(WARN|WARNING)\b`)},
+	{LevelInfo, regexp.MustCompile(`(?i)(level"?[=:]\s*"?info\b)|\[INFO\]|\bINFO\b`)},
+	{LevelDebug, regexp.MustCompile(`(?i)(level"?[=:]\s*"?(debug|trace)\b)|\[(DEBUG|TRACE)\]|\b(DEBUG|TRACE)\b`)},
+}
+
+// DetectLevel returns the most severe level marker found in line.
+func DetectLevel(line string) Level {
+	for _, p := range levelPatterns {
+		if p.pattern.MatchString(line) {
+			return p.level
+		}
+	}
+	return LevelUnknown
+}
+
+// ANSI color sequences per level.
+var levelColors = map[Level]string{
+	LevelDebug: "\x1b[2m",
+	LevelWarn:  "\x1b[33m",
+	LevelError: "\x1b[31m",
+}
+
+const colorReset = "\x1b[0m"
+
+// Filter selects and decorates log lines.
+type Filter struct {
+	Grep   *regexp.Regexp // Only lines matching this expression are shown
+	Color  bool           // Color lines by detected level
+	Prefix string         // Prepended to every line, e.g. the container name
+}
+
+// Apply returns the decorated line and whether it should be shown.
+func (f Filter) Apply(line string) (string, bool) {
+	if f.Grep != nil && !f.Grep.MatchString(line) {
+		return "", false
+	}
+	if f.Color {
+		if color, ok := levelColors[DetectLevel(line)]; ok {
+			line = color + line + colorReset
+		}
+	}
+	return f.Prefix + line, true
+}
+
+// LineWriter splits a byte stream into lines, filters them, and writes the
+// result to a destination that may be shared with other writers.
+type LineWriter struct {
+	dst    io.Writer
+	mu     *sync.Mutex
+	filter Filter
+	buf    []byte
+	lines  int
+}
+
+// NewLineWriter creates a LineWriter. Writers sharing dst must share mu.
+func NewLineWriter(dst io.Writer, mu *sync.Mutex, filter Filter) *LineWriter {
+	return &LineWriter{dst: dst, mu: mu, filter: filter}
+}
+
+// Write implements io.Writer.
+func (w *LineWriter) Write(p []byte) (int, error) {
+	w.buf = append(w.buf, p...)
+	for {
+		idx := bytes.IndexByte(w.buf, '\n')
+		if idx < 0 {
+			return len(p), nil
+		}
+		line := string(w.buf[:idx])
+		w.buf = w.buf[idx+1:]
+		if err := w.emit(line); err != nil {
+			return len(p), err
+		}
+	}
+}
+
+// Flush writes any buffered partial line.
+func (w *LineWriter) Flush() error {
+	if len(w.buf) == 0 {
+		return nil
+	}
+	line := string(w.buf)
+	w.buf = nil
+	return w.emit(line)
+}
+
+// Lines returns the number of lines written so far.
+func (w *LineWriter) Lines() int { return w.lines }
+
+func (w *LineWriter) emit(line string) error {
+	out, ok := w.filter.Apply(line)
+	if !ok {
+		return nil
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.lines++
+	_, err := io.WriteString(w.dst, out+"\n")
+	return err
+}
+
+// IsTerminal reports whether f is attached to a terminal.
+func IsTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,41 @@
+package mount
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+
+	"github.com/docker/docker/api/types/mount"
+	"github.com/gloo-foo/vsl/internal/container"
+	"github.com/gloo-foo/vsl/internal/paths"
+)
+
+// cacheVolumePrefix starts the name of every cache volume.
+const cacheVolumePrefix = "vsl-cache-"
+
+// CacheVolumeName returns the named volume backing target for a project.
+// Names are stable per project and directory so repeated runs reuse the cache.
+func CacheVolumeName(project container.Project, target string) string {
+	return cacheVolumePrefix + shortHash(paths.Normalize(string(project)), 12) + "-" + shortHash(target, 8)
+}
+
+// IsCacheVolume reports whether a volume name belongs to a cache volume.
+func IsCacheVolume(name string) bool {
+	return strings.HasPrefix(name, cacheVolumePrefix)
+}
+
+// Cache creates a mount of the project's cache volume at target.
+func Cache(project container.Project, target string) Mount {
+	return Mount{
+		Mount: mount.Mount{
+			Type:   mount.TypeVolume,
+			Source: CacheVolumeName(project, target),
+			Target: target,
+		},
+	}
+}
+
+func shortHash(s string, n int) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])[:n]
+}
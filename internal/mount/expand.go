@@ -0,0 +1,60 @@
+package mount
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/gloo-foo/vsl/internal/container"
+)
+
+// ExpandVolumes expands glob patterns in volume sources into one volume per match.
+// Matches are sorted so the resulting mounts are deterministic. When a pattern
+// matches several paths the target must be a directory ending in "/", under
+// which each match is mounted by its base name. A pattern that matches
+// nothing is an error.
+func ExpandVolumes(vols []container.Volume) ([]container.Volume, error) {
+	expanded := make([]container.Volume, 0, len(vols))
+	for _, vol := range vols {
+		parts := splitVolume(string(vol))
+		if len(parts) < 2 || !hasGlob(parts[0]) {
+			expanded = append(expanded, vol)
+			continue
+		}
+
+		matches, err := filepath.Glob(expandPath(parts[0]))
+		if err != nil {
+			return nil, fmt.Errorf("volume %q: invalid pattern: %w", vol, err)
+		}
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("volume %q: pattern %q matches nothing", vol, parts[0])
+		}
+		sort.Strings(matches)
+
+		target := parts[1]
+		intoDir := strings.HasSuffix(target, "/")
+		if len(matches) > 1 && !intoDir {
+			return nil, fmt.Errorf("volume %q: pattern matches %d paths, end the target with / to mount them into a directory", vol, len(matches))
+		}
+
+		for _, match := range matches {
+			t := target
+			if intoDir {
+				t = path.Join(target, filepath.Base(match))
+			}
+			spec := EscapeVolumePath(match) + ":" + EscapeVolumePath(t)
+			if len(parts) >= 3 {
+				spec += ":" + parts[2]
+			}
+			expanded = append(expanded, container.Volume(spec))
+		}
+	}
+	return expanded, nil
+}
+
+// hasGlob reports whether a path contains glob metacharacters.
+func hasGlob(p string) bool {
+	return strings.ContainsAny(p, "*?[")
+}
@@ -0,0 +1,33 @@
+package mount
+
+import (
+	"fmt"
+
+	"github.com/docker/docker/api/types/mount"
+	"github.com/gloo-foo/vsl/internal/container"
+)
+
+// Masking modes.
+const (
+	MaskTmpfs  container.MaskMode = "tmpfs"
+	MaskVolume container.MaskMode = "volume"
+)
+
+// Mask creates an empty mount that hides whatever is mounted below target.
+// A tmpfs mask is discarded when the container exits; a volume mask uses an
+// anonymous volume so its contents survive until the container is removed.
+func Mask(target string, mode container.MaskMode) (Mount, error) {
+	switch mode {
+	case "", MaskTmpfs:
+		return Mount{Mount: mount.Mount{Type: mount.TypeTmpfs, Target: target}}, nil
+	case MaskVolume:
+		return Mount{Mount: mount.Mount{Type: mount.TypeVolume, Target: target}}, nil
+	}
+	return Mount{}, fmt.Errorf("unknown mask mode %q, expected tmpfs or volume", mode)
+}
+
+// MaskFile hides the file at target behind the empty, read-only /dev/null,
+// as files cannot be covered by the directory mounts of Mask.
+func MaskFile(target string) Mount {
+	return Mount{Mount: mount.Mount{Type: mount.TypeBind, Source: "/dev/null", Target: target, ReadOnly: true}}
+}
@@ -0,0 +1,88 @@
+package mount
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/docker/docker/api/types/mount"
+	"github.com/gloo-foo/vsl/internal/container"
+)
+
+// SELinux relabeling modes.
+const (
+	RelabelShared  container.Relabel = "z"
+	RelabelPrivate container.Relabel = "Z"
+)
+
+// Mount is a Docker mount plus options the mount API cannot express.
+type Mount struct {
+	mount.Mount
+	Relabel container.Relabel // SELinux relabeling, applied through the legacy bind syntax
+}
+
+// Bind creates a read-write bind mount of source at target.
+func Bind(source, target string, relabel container.Relabel) Mount {
+	return Mount{
+		Mount: mount.Mount{
+			Type:   mount.TypeBind,
+			Source: source,
+			Target: target,
+		},
+		Relabel: relabel,
+	}
+}
+
+// consistencies lists the bind consistency modes accepted as volume options.
+var consistencies = map[string]mount.Consistency{
+	string(mount.ConsistencyDefault):   mount.ConsistencyDefault,
+	string(mount.ConsistencyFull):      mount.ConsistencyFull,
+	string(mount.ConsistencyCached):    mount.ConsistencyCached,
+	string(mount.ConsistencyDelegated): mount.ConsistencyDelegated,
+}
+
+// ValidConsistency reports whether c is empty or a supported consistency mode.
+func ValidConsistency(c container.Consistency) bool {
+	_, ok := consistencies[string(c)]
+	return c == "" || ok
+}
+
+// ValidRelabel reports whether r is empty or a supported relabeling mode.
+func ValidRelabel(r container.Relabel) bool {
+	return r == "" || r == RelabelShared || r == RelabelPrivate
+}
+
+// Split separates mounts into mount API entries and legacy bind strings.
+// The Docker mount API rejects SELinux relabeling, so relabeled bind mounts
+// are expressed as HostConfig.Binds entries (source:target:options) instead.
+// The legacy syntax has no escaping, so relabeled paths must not contain colons.
+func Split(mounts []Mount) ([]mount.Mount, []string, error) {
+	var apiMounts []mount.Mount
+	var binds []string
+	for _, m := range mounts {
+		if m.Relabel == "" || m.Type != mount.TypeBind {
+			apiMounts = append(apiMounts, m.Mount)
+			continue
+		}
+		if strings.Contains(m.Source, ":") || strings.Contains(m.Target, ":") {
+			return nil, nil, fmt.Errorf("cannot apply SELinux relabeling to %q: path contains a colon", m.Source)
+		}
+		binds = append(binds, bindString(m))
+	}
+	return apiMounts, binds, nil
+}
+
+// bindString renders a bind mount in the source:target:options syntax.
+func bindString(m Mount) string {
+	options := []string{optionReadWrite}
+	if m.ReadOnly {
+		options[0] = optionReadOnly
+	}
+	if m.BindOptions != nil && m.BindOptions.Propagation != "" {
+		options = append(options, string(m.BindOptions.Propagation))
+	}
+	if m.Consistency != "" {
+		options = append(options, string(m.Consistency))
+	}
+	options = append(options, string(m.Relabel))
+	return m.Source + ":" + m.Target + ":" + strings.Join(options, ",")
+}
@@ -0,0 +1,126 @@
+package mount
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/docker/docker/api/types/mount"
+	"github.com/gloo-foo/vsl/internal/paths"
+)
+
+// Normalize removes duplicate and redundant mounts and orders the rest
+// parents-first, returning warnings for conflicts it resolved.
+//
+//   - Identical mounts are kept once.
+//   - Mounts with the same target but different sources conflict; the later
+//     one wins, so user volumes override automatic mounts.
+//   - A bind mount already exposed at the same place by a parent bind mount
+//     with the same options (e.g. the working directory inside the mounted
+//     git root) is dropped, unless another mount between the two hides the
+//     parent's files there.
+func Normalize(mounts []Mount) ([]Mount, []string) {
+	var warnings []string
+
+	// Same target: the later mount wins
+	byTarget := map[string]int{}
+	var unique []Mount
+	for _, m := range mounts {
+		target := paths.Normalize(m.Target)
+		i, seen := byTarget[target]
+		if !seen {
+			byTarget[target] = len(unique)
+			unique = append(unique, m)
+			continue
+		}
+		if !equivalent(unique[i], m) {
+			warnings = append(warnings, fmt.Sprintf("mount target %s: %s overrides %s", m.Target, describe(m), describe(unique[i])))
+		}
+		unique[i] = m
+	}
+
+	// Binds covered by a parent bind
+	normalized := make([]Mount, 0, len(unique))
+	for i, m := range unique {
+		if !coveredByOther(m, unique, i) {
+			normalized = append(normalized, m)
+		}
+	}
+
+	// Parents before children, as the daemon applies them
+	sort.SliceStable(normalized, func(i, j int) bool {
+		return depth(normalized[i].Target) < depth(normalized[j].Target)
+	})
+	return normalized, warnings
+}
+
+// coveredByOther reports whether the bind mount m is exposed identically by
+// another bind mount of a parent directory, with no mount in between that
+// overrides the parent on the way to m's target.
+func coveredByOther(m Mount, mounts []Mount, self int) bool {
+	if m.Type != mount.TypeBind {
+		return false
+	}
+	for i, parent := range mounts {
+		if i == self || parent.Type != mount.TypeBind || !sameOptions(parent, m) {
+			continue
+		}
+		rel, err := filepath.Rel(paths.Normalize(parent.Source), paths.Normalize(m.Source))
+		if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			continue
+		}
+		if paths.Equal(path.Join(parent.Target, filepath.ToSlash(rel)), m.Target) && !overridden(parent, m, mounts) {
+			return true
+		}
+	}
+	return false
+}
+
+// overridden reports whether a mount other than parent and m has a target
+// strictly between theirs, so that m's target does not show parent's files.
+func overridden(parent, m Mount, mounts []Mount) bool {
+	for _, other := range mounts {
+		if paths.Equal(other.Target, parent.Target) || paths.Equal(other.Target, m.Target) {
+			continue
+		}
+		if paths.Within(other.Target, parent.Target) && paths.Within(m.Target, other.Target) {
+			return true
+		}
+	}
+	return false
+}
+
+// equivalent reports whether two mounts with the same target are interchangeable.
+func equivalent(a, b Mount) bool {
+	return a.Type == b.Type && paths.Equal(a.Source, b.Source) && sameOptions(a, b)
+}
+
+// sameOptions reports whether two mounts share the options that affect access.
+func sameOptions(a, b Mount) bool {
+	return a.ReadOnly == b.ReadOnly &&
+		a.Relabel == b.Relabel &&
+		a.Consistency == b.Consistency &&
+		propagation(a) == propagation(b)
+}
+
+func propagation(m Mount) mount.Propagation {
+	if m.BindOptions == nil {
+		return ""
+	}
+	return m.BindOptions.Propagation
+}
+
+// describe renders a mount source for warnings.
+func describe(m Mount) string {
+	if m.Source == "" {
+		return string(m.Type)
+	}
+	return fmt.Sprintf("%s %s", m.Type, m.Source)
+}
+
+// depth counts the path components of a container path.
+func depth(target string) int {
+	return strings.Count(path.Clean(target), "/")
+}
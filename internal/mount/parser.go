@@ -2,43 +2,197 @@
 package mount
 
 import (
+	"fmt"
 	"os"
+	"path"
 	"path/filepath"
+	"regexp"
 	"strings"
 
 	"github.com/docker/docker/api/types/mount"
 	"github.com/gloo-foo/vsl/internal/container"
 )
 
-// ParseVolume parses a volume specification string (source:target[:ro]) and creates a mount.
-// Returns nil if the source path doesn't exist or the specification is invalid.
-func ParseVolume(vol container.Volume) *mount.Mount {
-	parts := strings.Split(string(vol), ":")
-	if len(parts) < 2 {
-		return nil
+// Volume option names accepted after the target in a volume specification.
+const (
+	optionReadOnly  = "ro"
+	optionReadWrite = "rw"
+)
+
+// propagations lists the bind propagation modes accepted as volume options.
+var propagations = map[string]mount.Propagation{
+	string(mount.PropagationPrivate):  mount.PropagationPrivate,
+	string(mount.PropagationRPrivate): mount.PropagationRPrivate,
+	string(mount.PropagationShared):   mount.PropagationShared,
+	string(mount.PropagationRShared):  mount.PropagationRShared,
+	string(mount.PropagationSlave):    mount.PropagationSlave,
+	string(mount.PropagationRSlave):   mount.PropagationRSlave,
+}
+
+// ParseVolume parses a volume specification string (source:target[:options]) and creates a mount.
+// Options are comma separated and may include ro/rw, a bind propagation mode
+// (private, rprivate, shared, rshared, slave, rslave), a Docker Desktop
+// consistency mode (consistent, cached, delegated), and an SELinux
+// relabeling mode (z, Z), e.g. "/src:/dst:ro,rshared,z".
+// Colons that are part of a path are escaped with a backslash ("/a\:b:/dst"),
+// and a literal backslash is written as "\\"; use --mount for full control.
+// A source that is a bare name rather than a path (e.g. "pgdata:/var/lib/postgresql/data")
+// refers to a named Docker volume.
+// Invalid specifications return a VolumeError describing the problem and a fix.
+func ParseVolume(vol container.Volume) (*Mount, error) {
+	return parseVolume(vol, true)
+}
+
+// CheckVolume checks a volume specification like ParseVolume, without
+// requiring its host path source to exist.
+func CheckVolume(vol container.Volume) error {
+	_, err := parseVolume(vol, false)
+	return err
+}
+
+// parseVolume parses a volume specification, checking that a host path
+// source exists when stat is set.
+func parseVolume(vol container.Volume, stat bool) (*Mount, error) {
+	parts := splitVolume(string(vol))
+	invalid := func(reason, hint string) error {
+		return &VolumeError{Spec: vol, Reason: reason, Hint: hint}
 	}
 
-	source := parts[0]
-	target := parts[1]
-	readonly := len(parts) == 3 && parts[2] == "ro"
+	switch {
+	case len(parts) < 2:
+		return nil, invalid("missing target", fmt.Sprintf("use source:target[:options], e.g. %s:/data", vol))
+	case len(parts) > 3:
+		return nil, invalid("too many ':' separated fields", `escape colons that are part of a path with "\:", or use --mount`)
+	}
 
-	source = expandPath(source)
+	source, target := parts[0], parts[1]
+	switch {
+	case source == "":
+		return nil, invalid("empty source", "name a host path or volume before the ':'")
+	case target == "":
+		return nil, invalid("empty target", fmt.Sprintf("add the container path after the ':', e.g. %s:%s", source, suggestTarget(source)))
+	case !path.IsAbs(target) && !drivePath.MatchString(target):
+		return nil, invalid(fmt.Sprintf("target %q is not an absolute container path", target), fmt.Sprintf("use %s", path.Join("/", target)))
+	}
 
-	// Check if source exists
-	if _, err := os.Stat(source); err != nil {
-		return nil
+	m := &Mount{Mount: mount.Mount{Type: mount.TypeVolume, Source: source, Target: target}}
+	if !IsVolumeName(source) {
+		m.Type = mount.TypeBind
+		m.Source = expandPath(source)
+		if _, err := os.Stat(m.Source); stat && err != nil {
+			return nil, invalid(fmt.Sprintf("source %s does not exist", m.Source), "create it first, or check the path for typos")
+		}
 	}
 
-	return &mount.Mount{
-		Type:     mount.TypeBind,
-		Source:   source,
-		Target:   target,
-		ReadOnly: readonly,
+	if len(parts) == 3 {
+		if reason, hint := applyOptions(m, parts[2]); reason != "" {
+			return nil, invalid(reason, hint)
+		}
+	}
+	return m, nil
+}
+
+// VolumeError describes an invalid volume specification.
+type VolumeError struct {
+	Spec   container.Volume
+	Reason string
+	Hint   string
+}
+
+func (e *VolumeError) Error() string {
+	return fmt.Sprintf("invalid volume %q: %s; %s", e.Spec, e.Reason, e.Hint)
+}
+
+// suggestTarget proposes a container path for a host source.
+func suggestTarget(source string) string {
+	if IsVolumeName(source) {
+		return "/" + source
 	}
+	return ContainerPath(expandPath(source))
 }
 
-// expandPath expands ~ and relative paths to absolute paths.
+// splitVolume splits a volume specification on unescaped colons,
+// unescaping "\:" and "\\" in each part. On Windows and WSL the colon of a
+// leading drive path (C:\src) is part of the source.
+func splitVolume(spec string) []string {
+	var parts []string
+	var part strings.Builder
+	start := 0
+	if hasDrivePrefix(spec) {
+		part.WriteString(spec[:2])
+		start = 2
+	}
+	for i := start; i < len(spec); i++ {
+		switch c := spec[i]; {
+		case c == '\\' && i+1 < len(spec) && (spec[i+1] == ':' || spec[i+1] == '\\'):
+			i++
+			part.WriteByte(spec[i])
+		case c == ':':
+			parts = append(parts, part.String())
+			part.Reset()
+		default:
+			part.WriteByte(c)
+		}
+	}
+	return append(parts, part.String())
+}
+
+// EscapeVolumePath escapes a path for use in the colon-delimited volume syntax.
+func EscapeVolumePath(path string) string {
+	return strings.NewReplacer(`\`, `\\`, ":", `\:`).Replace(path)
+}
+
+// volumeNamePattern matches the names Docker accepts for named volumes.
+var volumeNamePattern = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]*$`)
+
+// IsVolumeName reports whether a volume source names a Docker volume rather than a host path.
+func IsVolumeName(source string) bool {
+	return source != "." && source != ".." && volumeNamePattern.MatchString(source)
+}
+
+// applyOptions applies comma-separated volume options to a mount. Unknown
+// options and options that do not apply to the mount type are rejected with
+// a reason and a hint.
+func applyOptions(m *Mount, options string) (reason, hint string) {
+	for _, opt := range strings.Split(options, ",") {
+		opt = strings.TrimSpace(opt)
+		propagation, isPropagation := propagations[opt]
+		consistency, isConsistency := consistencies[opt]
+		bindOnly := isPropagation || isConsistency || opt == string(RelabelShared) || opt == string(RelabelPrivate)
+
+		switch {
+		case bindOnly && m.Type != mount.TypeBind:
+			return fmt.Sprintf("option %q only applies to host path mounts", opt), "remove it, or use ./" + m.Source + " for a relative host path"
+		case opt == "nocopy" && m.Type != mount.TypeVolume:
+			return fmt.Sprintf("option %q only applies to named volumes", opt), "remove it"
+		}
+
+		switch {
+		case opt == optionReadOnly:
+			m.ReadOnly = true
+		case opt == optionReadWrite:
+			m.ReadOnly = false
+		case opt == string(RelabelShared), opt == string(RelabelPrivate):
+			m.Relabel = container.Relabel(opt)
+		case opt == "nocopy":
+			m.VolumeOptions = &mount.VolumeOptions{NoCopy: true}
+		case isPropagation:
+			m.BindOptions = &mount.BindOptions{Propagation: propagation}
+		case isConsistency:
+			m.Consistency = consistency
+		default:
+			return fmt.Sprintf("unknown option %q", opt),
+				"valid options are ro, rw, z, Z, nocopy, a propagation mode (e.g. rshared), or a consistency (cached, delegated, consistent)"
+		}
+	}
+	return "", ""
+}
+
+// expandPath expands ~ and relative paths to absolute paths, after
+// converting Windows-form paths for the host.
 func expandPath(path string) string {
+	path = HostPath(path)
+
 	// Expand ~/ to home directory
 	if strings.HasPrefix(path, "~/") {
 		home, err := os.UserHomeDir()
@@ -0,0 +1,334 @@
+package mount
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/docker/docker/api/types/mount"
+	"github.com/gloo-foo/vsl/internal/container"
+	"golang.org/x/text/unicode/norm"
+)
+
+// withPlatform makes CurrentPlatform report platform for the rest of the test.
+func withPlatform(t *testing.T, platform Platform) {
+	t.Helper()
+	saved := CurrentPlatform
+	CurrentPlatform = func() Platform { return platform }
+	t.Cleanup(func() { CurrentPlatform = saved })
+}
+
+func TestSplitVolume(t *testing.T) {
+	tests := []struct {
+		name     string
+		platform Platform
+		spec     string
+		want     []string
+	}{
+		{"plain", PlatformNative, "/src:/dst", []string{"/src", "/dst"}},
+		{"options", PlatformNative, "/src:/dst:ro,z", []string{"/src", "/dst", "ro,z"}},
+		{"escaped colon", PlatformNative, `/a\:b:/dst`, []string{"/a:b", "/dst"}},
+		{"escaped colon in target", PlatformNative, `/src:/x\:y:ro`, []string{"/src", "/x:y", "ro"}},
+		{"escaped backslash", PlatformNative, `/a\\b:/dst`, []string{`/a\b`, "/dst"}},
+		{"escaped backslash before colon", PlatformNative, `/a\\:/dst`, []string{`/a\`, "/dst"}},
+		{"lone backslash", PlatformNative, `/a\b:/dst`, []string{`/a\b`, "/dst"}},
+		{"spaces", PlatformNative, "/my src/dir:/my dst", []string{"/my src/dir", "/my dst"}},
+		{"unicode", PlatformNative, "/données/café:/日本", []string{"/données/café", "/日本"}},
+		{"missing target", PlatformNative, "/src", []string{"/src"}},
+		{"empty", PlatformNative, "", []string{""}},
+		{"drive letter on native", PlatformNative, `C:\src:/dst`, []string{"C", `\src`, "/dst"}},
+		{"drive letter on windows", PlatformWindows, `C:\src:/dst`, []string{`C:\src`, "/dst"}},
+		{"forward slash drive on windows", PlatformWindows, "c:/src:/dst:ro", []string{"c:/src", "/dst", "ro"}},
+		{"drive letter on wsl", PlatformWSL, `D:\my files\a\:b:/dst`, []string{`D:\my files\a:b`, "/dst"}},
+		{"drive letter not leading", PlatformWindows, `/src:C:\dst`, []string{"/src", "C", `\dst`}},
+		{"bare drive on windows", PlatformWindows, "C:/dst", []string{"C:/dst"}},
+		{"letter name on windows", PlatformWindows, "c:dst", []string{"c", "dst"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			withPlatform(t, tt.platform)
+			if got := splitVolume(tt.spec); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("splitVolume(%q) = %q, want %q", tt.spec, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEscapeVolumePath(t *testing.T) {
+	withPlatform(t, PlatformNative)
+	for _, p := range []string{"/plain", "/a:b", `/a\b`, `/a\:b`, "/with space/x", "/données/café"} {
+		spec := EscapeVolumePath(p) + ":" + EscapeVolumePath("/dst:1")
+		if got, want := splitVolume(spec), []string{p, "/dst:1"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("splitVolume(%q) = %q, want %q", spec, got, want)
+		}
+	}
+}
+
+func TestParseVolume(t *testing.T) {
+	withPlatform(t, PlatformNative)
+	dir := t.TempDir()
+	nfc := norm.NFC.String("café")
+	nfd := norm.NFD.String("café")
+	for _, name := range []string{"with space", "a:b", `back\slash`, nfc, "日本"} {
+		if err := os.Mkdir(filepath.Join(dir, name), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name string
+		spec string
+		want Mount
+	}{
+		{
+			name: "space in source and target",
+			spec: dir + "/with space:/my data",
+			want: Mount{Mount: mount.Mount{Type: mount.TypeBind, Source: dir + "/with space", Target: "/my data"}},
+		},
+		{
+			name: "escaped colon",
+			spec: EscapeVolumePath(dir+"/a:b") + ":/a\\:b:ro",
+			want: Mount{Mount: mount.Mount{Type: mount.TypeBind, Source: dir + "/a:b", Target: "/a:b", ReadOnly: true}},
+		},
+		{
+			name: "escaped backslash",
+			spec: EscapeVolumePath(dir+`/back\slash`) + ":/dst",
+			want: Mount{Mount: mount.Mount{Type: mount.TypeBind, Source: dir + `/back\slash`, Target: "/dst"}},
+		},
+		{
+			name: "composed unicode",
+			spec: dir + "/" + nfc + ":/" + nfc + ":z",
+			want: Mount{Mount: mount.Mount{Type: mount.TypeBind, Source: dir + "/" + nfc, Target: "/" + nfc}, Relabel: RelabelShared},
+		},
+		{
+			name: "unicode outside latin",
+			spec: dir + "/日本:/日本:ro,rshared",
+			want: Mount{
+				Mount: mount.Mount{
+					Type: mount.TypeBind, Source: dir + "/日本", Target: "/日本", ReadOnly: true,
+					BindOptions: &mount.BindOptions{Propagation: mount.PropagationRShared},
+				},
+			},
+		},
+		{
+			name: "named volume",
+			spec: "pgdata:/var/lib/postgresql/data:nocopy",
+			want: Mount{Mount: mount.Mount{
+				Type: mount.TypeVolume, Source: "pgdata", Target: "/var/lib/postgresql/data",
+				VolumeOptions: &mount.VolumeOptions{NoCopy: true},
+			}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseVolume(container.Volume(tt.spec))
+			if err != nil {
+				t.Fatalf("ParseVolume(%q) error: %v", tt.spec, err)
+			}
+			if !reflect.DeepEqual(*got, tt.want) {
+				t.Errorf("ParseVolume(%q) = %+v, want %+v", tt.spec, *got, tt.want)
+			}
+		})
+	}
+
+	// The source is kept as written; a decomposed spelling only resolves
+	// where the file system normalizes names (macOS)
+	t.Run("decomposed unicode", func(t *testing.T) {
+		spec := container.Volume(dir + "/" + nfd + ":/dst")
+		got, err := ParseVolume(spec)
+		if _, statErr := os.Stat(dir + "/" + nfd); statErr != nil {
+			if err == nil {
+				t.Fatalf("ParseVolume(%q) accepted a missing source", spec)
+			}
+			return
+		}
+		if err != nil {
+			t.Fatalf("ParseVolume(%q) error: %v", spec, err)
+		}
+		if got.Source != dir+"/"+nfd {
+			t.Errorf("ParseVolume(%q) source = %q, want it unchanged", spec, got.Source)
+		}
+	})
+}
+
+func TestParseVolumeDrivePath(t *testing.T) {
+	withPlatform(t, PlatformWindows)
+	got, err := parseVolume(`C:\Users\me\my src:/src:ro`, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Type != mount.TypeBind || got.Target != "/src" || !got.ReadOnly {
+		t.Errorf("parseVolume drive path = %+v, want a read-only bind at /src", *got)
+	}
+}
+
+func TestCheckVolume(t *testing.T) {
+	withPlatform(t, PlatformNative)
+	tests := []struct {
+		name   string
+		spec   string
+		reason string // Empty when the specification is valid
+	}{
+		{"missing source is fine", "/does/not/exist:/dst", ""},
+		{"space", "/my src:/my dst:ro", ""},
+		{"escaped colon", `/a\:b:/c\:d`, ""},
+		{"drive path target", `/src:C\:/dst`, ""},
+		{"unicode", "/données:/données", ""},
+		{"missing target", "/src", "missing target"},
+		{"unescaped colon", "/a:b:/dst:ro", "too many ':' separated fields"},
+		{"drive letter on native", `C:\src:/dst`, `target "\\src" is not an absolute container path`},
+		{"empty source", ":/dst", "empty source"},
+		{"empty target", "/src:", "empty target"},
+		{"relative target", "/src:dst", `target "dst" is not an absolute container path`},
+		{"unknown option", "/src:/dst:rx", `unknown option "rx"`},
+		{"bind option on volume", "data:/dst:rshared", `option "rshared" only applies to host path mounts`},
+		{"volume option on bind", "/src:/dst:nocopy", `option "nocopy" only applies to named volumes`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckVolume(container.Volume(tt.spec))
+			if tt.reason == "" {
+				if err != nil {
+					t.Fatalf("CheckVolume(%q) error: %v", tt.spec, err)
+				}
+				return
+			}
+			var volErr *VolumeError
+			if !errors.As(err, &volErr) {
+				t.Fatalf("CheckVolume(%q) = %v, want a VolumeError", tt.spec, err)
+			}
+			if volErr.Reason != tt.reason {
+				t.Errorf("CheckVolume(%q) reason = %q, want %q", tt.spec, volErr.Reason, tt.reason)
+			}
+		})
+	}
+}
+
+func TestParseMount(t *testing.T) {
+	withPlatform(t, PlatformNative)
+	nfd := norm.NFD.String("/café")
+	tests := []struct {
+		name    string
+		spec    string
+		want    Mount
+		wantErr bool
+	}{
+		{
+			name: "space",
+			spec: "src=/my src,dst=/my dst,ro",
+			want: Mount{Mount: mount.Mount{Type: mount.TypeBind, Source: "/my src", Target: "/my dst", ReadOnly: true}},
+		},
+		{
+			name: "quoted comma and colon",
+			spec: `type=bind,"src=/a,b:c","dst=/x:y"`,
+			want: Mount{Mount: mount.Mount{Type: mount.TypeBind, Source: "/a,b:c", Target: "/x:y"}},
+		},
+		{
+			name: "unicode kept as written",
+			spec: "source=" + nfd + ",target=/données",
+			want: Mount{Mount: mount.Mount{Type: mount.TypeBind, Source: nfd, Target: "/données"}},
+		},
+		{
+			name: "tmpfs",
+			spec: "type=tmpfs,dst=/tmp cache,tmpfs-size=64m",
+			want: Mount{Mount: mount.Mount{Type: mount.TypeTmpfs, Target: "/tmp cache", TmpfsOptions: &mount.TmpfsOptions{SizeBytes: 64 << 20}}},
+		},
+		{name: "missing target", spec: "src=/a", wantErr: true},
+		{name: "unterminated quote", spec: `"src=/a,dst=/b`, wantErr: true},
+		{name: "source on tmpfs", spec: "type=tmpfs,src=/a,dst=/b", wantErr: true},
+		{name: "unknown option", spec: "src=/a,dst=/b,colour=red", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseMount(container.MountSpec(tt.spec))
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("ParseMount(%q) = %+v, want an error", tt.spec, *got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseMount(%q) error: %v", tt.spec, err)
+			}
+			if !reflect.DeepEqual(*got, tt.want) {
+				t.Errorf("ParseMount(%q) = %+v, want %+v", tt.spec, *got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeUnicodeTargets(t *testing.T) {
+	nfc := norm.NFC.String("/café")
+	nfd := norm.NFD.String("/café")
+	got, warnings := Normalize([]Mount{Bind("/a", nfd, ""), Bind("/b", nfc, "")})
+	if len(got) != 1 || got[0].Source != "/b" {
+		t.Errorf("Normalize kept %+v, want only the later mount", got)
+	}
+	if len(warnings) != 1 {
+		t.Errorf("Normalize warnings = %q, want one override", warnings)
+	}
+}
+
+func TestNormalizeCoveredBinds(t *testing.T) {
+	tests := []struct {
+		name   string
+		mounts []Mount
+		want   []string // Targets kept
+	}{
+		{
+			name:   "child of parent bind",
+			mounts: []Mount{Bind("/repo", "/work", ""), Bind("/repo/sub", "/work/sub", "")},
+			want:   []string{"/work"},
+		},
+		{
+			name: "intermediate volume overrides parent",
+			mounts: []Mount{
+				Bind("/repo", "/work", ""),
+				{Mount: mount.Mount{Type: mount.TypeVolume, Source: "deps", Target: "/work/vendor"}},
+				Bind("/repo/vendor/lib", "/work/vendor/lib", ""),
+			},
+			want: []string{"/work", "/work/vendor", "/work/vendor/lib"},
+		},
+		{
+			name: "intermediate bind of another source",
+			mounts: []Mount{
+				Bind("/repo", "/work", ""),
+				Bind("/elsewhere", "/work/a", ""),
+				Bind("/repo/a/b", "/work/a/b", ""),
+			},
+			want: []string{"/work", "/work/a", "/work/a/b"},
+		},
+		{
+			name: "intermediate bind of the same tree",
+			mounts: []Mount{
+				Bind("/repo", "/work", ""),
+				Bind("/repo/a", "/work/a", ""),
+				Bind("/repo/a/b", "/work/a/b", ""),
+			},
+			want: []string{"/work"},
+		},
+		{
+			name: "sibling mount does not override",
+			mounts: []Mount{
+				Bind("/repo", "/work", ""),
+				{Mount: mount.Mount{Type: mount.TypeTmpfs, Target: "/work/tmp"}},
+				Bind("/repo/a", "/work/a", ""),
+			},
+			want: []string{"/work", "/work/tmp"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, _ := Normalize(tt.mounts)
+			var targets []string
+			for _, m := range got {
+				targets = append(targets, m.Target)
+			}
+			if !reflect.DeepEqual(targets, tt.want) {
+				t.Errorf("Normalize kept %q, want %q", targets, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,168 @@
+package mount
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/docker/docker/api/types/mount"
+	"github.com/docker/go-units"
+	"github.com/gloo-foo/vsl/internal/container"
+)
+
+// ParseMount parses a docker-compatible --mount specification such as
+// "type=bind,src=/path,dst=/target,ro" or "type=tmpfs,dst=/tmp,tmpfs-size=64m".
+// Fields are comma separated and may be double-quoted, so paths containing
+// commas or colons can be expressed. The type defaults to bind.
+func ParseMount(spec container.MountSpec) (*Mount, error) {
+	reader := csv.NewReader(strings.NewReader(string(spec)))
+	fields, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("invalid mount %q: %w", spec, err)
+	}
+
+	m := &Mount{Mount: mount.Mount{Type: mount.TypeBind}}
+	var volumeOpts []string
+	for _, field := range fields {
+		key, value, hasValue := strings.Cut(strings.TrimSpace(field), "=")
+		key = strings.ToLower(key)
+
+		switch key {
+		case "type":
+			m.Type = mount.Type(strings.ToLower(value))
+		case "source", "src":
+			m.Source = value
+		case "target", "destination", "dst":
+			m.Target = value
+		case "readonly", "ro":
+			if m.ReadOnly, err = boolValue(value, hasValue); err != nil {
+				return nil, fmt.Errorf("invalid mount %q: %s: %w", spec, key, err)
+			}
+		case "bind-propagation":
+			propagation, ok := propagations[value]
+			if !ok {
+				return nil, fmt.Errorf("invalid mount %q: unknown bind-propagation %q", spec, value)
+			}
+			bindOptions(m).Propagation = propagation
+		case "bind-nonrecursive":
+			if bindOptions(m).NonRecursive, err = boolValue(value, hasValue); err != nil {
+				return nil, fmt.Errorf("invalid mount %q: %s: %w", spec, key, err)
+			}
+		case "consistency":
+			if !ValidConsistency(container.Consistency(value)) {
+				return nil, fmt.Errorf("invalid mount %q: unknown consistency %q", spec, value)
+			}
+			m.Consistency = mount.Consistency(value)
+		case "volume-driver":
+			volumeOptions(m).DriverConfig = &mount.Driver{Name: value}
+		case "volume-opt":
+			volumeOpts = append(volumeOpts, value)
+		case "volume-nocopy":
+			if volumeOptions(m).NoCopy, err = boolValue(value, hasValue); err != nil {
+				return nil, fmt.Errorf("invalid mount %q: %s: %w", spec, key, err)
+			}
+		case "volume-subpath":
+			volumeOptions(m).Subpath = value
+		case "tmpfs-size":
+			size, err := units.RAMInBytes(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid mount %q: tmpfs-size: %w", spec, err)
+			}
+			tmpfsOptions(m).SizeBytes = size
+		case "tmpfs-mode":
+			mode, err := strconv.ParseUint(value, 8, 32)
+			if err != nil {
+				return nil, fmt.Errorf("invalid mount %q: tmpfs-mode: %w", spec, err)
+			}
+			tmpfsOptions(m).Mode = os.FileMode(mode)
+		default:
+			return nil, fmt.Errorf("invalid mount %q: unknown option %q", spec, key)
+		}
+	}
+
+	if len(volumeOpts) > 0 {
+		opts := volumeOptions(m)
+		if opts.DriverConfig == nil {
+			opts.DriverConfig = &mount.Driver{}
+		}
+		opts.DriverConfig.Options = map[string]string{}
+		for _, opt := range volumeOpts {
+			k, v, _ := strings.Cut(opt, "=")
+			opts.DriverConfig.Options[k] = v
+		}
+	}
+
+	if err := validateMount(m); err != nil {
+		return nil, fmt.Errorf("invalid mount %q: %w", spec, err)
+	}
+	return m, nil
+}
+
+// validateMount checks that the fields set are consistent with the mount type.
+func validateMount(m *Mount) error {
+	if m.Target == "" {
+		return fmt.Errorf("target is required")
+	}
+
+	switch m.Type {
+	case mount.TypeBind:
+		if m.Source == "" {
+			return fmt.Errorf("source is required for bind mounts")
+		}
+		if m.VolumeOptions != nil || m.TmpfsOptions != nil {
+			return fmt.Errorf("volume and tmpfs options are not valid for bind mounts")
+		}
+		m.Source = expandPath(m.Source)
+	case mount.TypeVolume:
+		if m.BindOptions != nil || m.TmpfsOptions != nil {
+			return fmt.Errorf("bind and tmpfs options are not valid for volume mounts")
+		}
+	case mount.TypeTmpfs:
+		if m.Source != "" {
+			return fmt.Errorf("source is not valid for tmpfs mounts")
+		}
+		if m.BindOptions != nil || m.VolumeOptions != nil {
+			return fmt.Errorf("bind and volume options are not valid for tmpfs mounts")
+		}
+	default:
+		return fmt.Errorf("unsupported type %q, expected bind, volume, or tmpfs", m.Type)
+	}
+	return nil
+}
+
+// boolValue parses an optional boolean mount option; a bare key means true.
+func boolValue(value string, hasValue bool) (bool, error) {
+	if !hasValue {
+		return true, nil
+	}
+	switch strings.ToLower(value) {
+	case "1", "true":
+		return true, nil
+	case "0", "false":
+		return false, nil
+	}
+	return false, fmt.Errorf("invalid boolean %q", value)
+}
+
+func bindOptions(m *Mount) *mount.BindOptions {
+	if m.BindOptions == nil {
+		m.BindOptions = &mount.BindOptions{}
+	}
+	return m.BindOptions
+}
+
+func volumeOptions(m *Mount) *mount.VolumeOptions {
+	if m.VolumeOptions == nil {
+		m.VolumeOptions = &mount.VolumeOptions{}
+	}
+	return m.VolumeOptions
+}
+
+func tmpfsOptions(m *Mount) *mount.TmpfsOptions {
+	if m.TmpfsOptions == nil {
+		m.TmpfsOptions = &mount.TmpfsOptions{}
+	}
+	return m.TmpfsOptions
+}
@@ -0,0 +1,121 @@
+package mount
+
+import (
+	"os"
+	"path"
+	"regexp"
+	"runtime"
+	"strings"
+	"sync"
+
+	"github.com/docker/docker/api/types/mount"
+)
+
+// Platform identifies how host paths relate to the paths the daemon expects.
+type Platform string
+
+// Supported platforms.
+const (
+	PlatformNative  Platform = "native"  // Host paths are daemon paths (Linux, macOS)
+	PlatformWindows Platform = "windows" // vsl runs on Windows
+	PlatformWSL     Platform = "wsl"     // vsl runs inside WSL
+)
+
+// Locations of Windows drives as seen from WSL and from the Docker Desktop VM.
+const (
+	wslDriveRoot     = "/mnt"
+	desktopDriveRoot = "/run/desktop/mnt/host"
+)
+
+var (
+	// drivePath matches Windows drive paths such as C:\src or C:/src.
+	drivePath = regexp.MustCompile(`^([A-Za-z]):[\\/]`)
+	// wslUNCPath matches paths into a WSL distribution such as \\wsl$\Ubuntu\home.
+	wslUNCPath = regexp.MustCompile(`^\\\\wsl(?:\$|\.localhost)\\([^\\]+)(\\.*)?$`)
+)
+
+// CurrentPlatform returns the platform vsl is running on.
+var CurrentPlatform = sync.OnceValue(func() Platform {
+	switch runtime.GOOS {
+	case "windows":
+		return PlatformWindows
+	case "linux":
+		if os.Getenv("WSL_DISTRO_NAME") != "" {
+			return PlatformWSL
+		}
+		if release, err := os.ReadFile("/proc/sys/kernel/osrelease"); err == nil &&
+			strings.Contains(strings.ToLower(string(release)), "microsoft") {
+			return PlatformWSL
+		}
+	}
+	return PlatformNative
+})
+
+// HostPath converts a path written in Windows form into one usable on this
+// host. Inside WSL, C:\src becomes /mnt/c/src and \\wsl$\<distro>\home
+// becomes /home for the current distribution; elsewhere paths are unchanged.
+func HostPath(p string) string {
+	if CurrentPlatform() != PlatformWSL {
+		return p
+	}
+	if drivePath.MatchString(p) {
+		return drivePathUnder(wslDriveRoot, p)
+	}
+	if m := wslUNCPath.FindStringSubmatch(p); m != nil && m[1] == os.Getenv("WSL_DISTRO_NAME") {
+		return path.Join("/", strings.ReplaceAll(m[2], `\`, "/"))
+	}
+	return p
+}
+
+// ContainerPath converts a Windows drive path mirrored into the container,
+// such as the working directory, into a Linux path (C:\src becomes /mnt/c/src,
+// matching its location under WSL). Other paths are unchanged.
+func ContainerPath(p string) string {
+	if !drivePath.MatchString(p) {
+		return p
+	}
+	return drivePathUnder(wslDriveRoot, p)
+}
+
+// Translator converts host paths into the form the daemon expects.
+type Translator struct {
+	Platform      Platform
+	DockerDesktop bool // The daemon is Docker Desktop rather than a native engine
+}
+
+// Source returns the daemon-side path of a bind mount source. From Windows,
+// Docker Desktop sees drives under its host mount, while a native engine
+// (e.g. dockerd inside WSL) sees them under /mnt.
+func (t Translator) Source(p string) string {
+	if t.Platform != PlatformWindows || !drivePath.MatchString(p) {
+		return p
+	}
+	if t.DockerDesktop {
+		return drivePathUnder(desktopDriveRoot, p)
+	}
+	return drivePathUnder(wslDriveRoot, p)
+}
+
+// Apply translates bind mount sources and all mount targets.
+func (t Translator) Apply(mounts []Mount) []Mount {
+	for i := range mounts {
+		if mounts[i].Type == mount.TypeBind {
+			mounts[i].Source = t.Source(mounts[i].Source)
+		}
+		mounts[i].Target = ContainerPath(mounts[i].Target)
+	}
+	return mounts
+}
+
+// drivePathUnder maps a Windows drive path below root, e.g. C:\src to root/c/src.
+func drivePathUnder(root, p string) string {
+	drive := strings.ToLower(p[:1])
+	rest := strings.ReplaceAll(p[3:], `\`, "/")
+	return path.Join(root, drive, rest)
+}
+
+// hasDrivePrefix reports whether spec starts with a Windows drive path whose
+// colon must not split a volume specification.
+func hasDrivePrefix(spec string) bool {
+	return CurrentPlatform() != PlatformNative && drivePath.MatchString(spec)
+}
@@ -0,0 +1,105 @@
+package mount
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/gloo-foo/vsl/internal/container"
+)
+
+// Vars holds the vsl-provided variables available to volume, mount and
+// working directory specs. Other names resolve from the host environment.
+type Vars map[string]string
+
+// NewVars returns the vsl-provided variables PWD, HOME and, inside a
+// repository, GIT_ROOT.
+func NewVars(pwd string, gitRoot container.GitRoot) Vars {
+	vars := Vars{"PWD": pwd}
+	if home, err := os.UserHomeDir(); err == nil {
+		vars["HOME"] = home
+	}
+	if gitRoot != "" {
+		vars["GIT_ROOT"] = string(gitRoot)
+	}
+	return vars
+}
+
+// Expand interpolates $VAR and ${VAR} references in s, with "$$" producing a
+// literal "$". Substituted values are passed through quote when it is non-nil.
+// Referencing an undefined variable is an error rather than an empty path.
+func (v Vars) Expand(s string, quote func(string) string) (string, error) {
+	var missing []string
+	out := os.Expand(s, func(name string) string {
+		if name == "$" {
+			return "$"
+		}
+		value, ok := v[name]
+		if !ok {
+			value, ok = os.LookupEnv(name)
+		}
+		if !ok {
+			missing = append(missing, name)
+			return ""
+		}
+		if quote != nil {
+			return quote(value)
+		}
+		return value
+	})
+	if len(missing) > 0 {
+		return "", fmt.Errorf("%q: undefined variable %s", s, strings.Join(missing, ", "))
+	}
+	return out, nil
+}
+
+// bracedName matches the names ExpandBraced interpolates: variables, script
+// arguments by position and @ for all of them. Other braced forms, such as
+// the ${VAR:-default} and ${#VAR} of a shell, are not names.
+var bracedName = regexp.MustCompile(`^(?:[A-Za-z_][A-Za-z0-9_.]*|[0-9]+|@)$`)
+
+// ExpandBraced interpolates only ${VAR} references in s, with "$${" producing
+// a literal "${". Other dollar signs, such as the $VAR and $$ of a shell
+// command, and braced shell parameter expansions are left alone for the
+// shell in the container.
+func (v Vars) ExpandBraced(s string) (string, error) {
+	original := s
+	var b strings.Builder
+	var missing []string
+	for {
+		i := strings.Index(s, "${")
+		if i < 0 {
+			break
+		}
+		if i > 0 && s[i-1] == '$' {
+			b.WriteString(s[:i-1] + "${")
+			s = s[i+2:]
+			continue
+		}
+		end := strings.IndexByte(s[i:], '}')
+		if end < 0 {
+			break
+		}
+		name := s[i+2 : i+end]
+		if !bracedName.MatchString(name) {
+			b.WriteString(s[:i+end+1])
+			s = s[i+end+1:]
+			continue
+		}
+		value, ok := v[name]
+		if !ok {
+			value, ok = os.LookupEnv(name)
+		}
+		if !ok {
+			missing = append(missing, name)
+		}
+		b.WriteString(s[:i] + value)
+		s = s[i+end+1:]
+	}
+	b.WriteString(s)
+	if len(missing) > 0 {
+		return "", fmt.Errorf("%q: undefined variable %s", original, strings.Join(missing, ", "))
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,23 @@
+//go:build !windows
+
+package paths
+
+import (
+	"io/fs"
+	"os"
+	"syscall"
+)
+
+// CurrentOwner returns the uid and gid of the invoking user.
+func CurrentOwner() (uid, gid int, ok bool) {
+	return os.Getuid(), os.Getgid(), true
+}
+
+// Owner returns the uid and gid owning a file.
+func Owner(info fs.FileInfo) (uid, gid int, ok bool) {
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, 0, false
+	}
+	return int(st.Uid), int(st.Gid), true
+}
@@ -0,0 +1,17 @@
+//go:build windows
+
+package paths
+
+import "io/fs"
+
+// CurrentOwner returns the uid and gid of the invoking user. Windows has no
+// numeric owners, so ok is always false.
+func CurrentOwner() (uid, gid int, ok bool) {
+	return 0, 0, false
+}
+
+// Owner returns the uid and gid owning a file. Windows has no numeric
+// owners, so ok is always false.
+func Owner(fs.FileInfo) (uid, gid int, ok bool) {
+	return 0, 0, false
+}
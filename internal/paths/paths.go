@@ -0,0 +1,26 @@
+// Package paths provides host path comparisons that are robust to unicode normalization.
+package paths
+
+import (
+	"path/filepath"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// Normalize cleans a path and converts it to unicode NFC form.
+// macOS file systems report names in decomposed (NFD) form while user input
+// is usually composed, so paths must be normalized before they are compared.
+func Normalize(path string) string {
+	return norm.NFC.String(filepath.Clean(path))
+}
+
+// Equal reports whether two paths refer to the same location after normalization.
+func Equal(a, b string) bool {
+	return Normalize(a) == Normalize(b)
+}
+
+// Within reports whether path is dir or located inside it after normalization.
+func Within(path, dir string) bool {
+	rel, err := filepath.Rel(Normalize(dir), Normalize(path))
+	return err == nil && filepath.IsLocal(rel)
+}
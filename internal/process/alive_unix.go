@@ -0,0 +1,18 @@
+//go:build !windows
+
+// Package process provides helpers for inspecting host processes.
+package process
+
+import (
+	"errors"
+	"syscall"
+)
+
+// Alive reports whether a process with the given PID exists on this host.
+func Alive(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+	err := syscall.Kill(pid, 0)
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
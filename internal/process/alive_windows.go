@@ -0,0 +1,19 @@
+//go:build windows
+
+// Package process provides helpers for inspecting host processes.
+package process
+
+import "os"
+
+// Alive reports whether a process with the given PID exists on this host.
+func Alive(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	_ = p.Release()
+	return true
+}
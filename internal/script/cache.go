@@ -0,0 +1,178 @@
+package script
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	runpkg "github.com/gloo-foo/vsl/internal/container/run"
+	"github.com/gloo-foo/vsl/internal/state"
+)
+
+// DiskCache keeps parsed scripts in the vsl cache directory between runs,
+// set from the global flags.
+var DiskCache = true
+
+// Parsed script cache settings.
+const (
+	parsedDir    = "parsed" // Inside the vsl cache directory
+	maxParsed    = 256      // Entries kept, least recently written dropped first
+	parsedFormat = 1        // Bumped when the parser or the cached Config change
+)
+
+// parsedScript is a script parsed with a profile, valid as long as the files
+// it was read from keep their content and no other configuration file comes
+// to apply to it. The files are the script, the fragments it includes, the
+// parent it extends and the configuration files.
+type parsedScript struct {
+	Files   map[string]string          `json:"files"` // sha256 of each file read, by path
+	Config  *runpkg.Config             `json:"config"`
+	Origins map[string][]runpkg.Origin `json:"origins,omitempty"`
+}
+
+// parsedKey identifies a parse of the script at path with profile, by this
+// vsl on this platform; the content of the files read validates the entry.
+func parsedKey(path, profile string) string {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return ""
+	}
+	parts := []string{strconv.Itoa(parsedFormat), buildID(), runtime.GOOS, runtime.GOARCH, abs, profile}
+	sum := sha256.Sum256([]byte(strings.Join(parts, "\x00")))
+	return hex.EncodeToString(sum[:])[:32]
+}
+
+// buildID identifies the running vsl: its release version, or for
+// development builds, whose parser may change without a version, the size
+// and modification time of the executable.
+func buildID() string {
+	if Version != "" {
+		return Version
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		return ""
+	}
+	info, err := os.Stat(exe)
+	if err != nil {
+		return ""
+	}
+	return fmt.Sprintf("%s:%d:%d", exe, info.Size(), info.ModTime().UnixNano())
+}
+
+// cachedScript returns the configuration cached under key for the script in
+// dir, if its files are unchanged. An unreadable entry is ignored.
+func cachedScript(key, dir string) (*runpkg.Config, bool) {
+	path, ok := parsedPath(key)
+	if !ok {
+		return nil, false
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, false
+	}
+	var entry parsedScript
+	if err := json.Unmarshal(data, &entry); err != nil || entry.Config == nil {
+		return nil, false
+	}
+	configs, err := ConfigFiles(dir)
+	if err != nil {
+		return nil, false
+	}
+	for _, file := range configs {
+		if _, ok := entry.Files[file]; !ok {
+			return nil, false
+		}
+	}
+	for file, sum := range entry.Files {
+		if fileSum(file) != sum {
+			return nil, false
+		}
+	}
+	entry.Config.Origins = entry.Origins
+	return entry.Config, true
+}
+
+// cacheScript records the configuration parsed from files under key,
+// replacing the file atomically so concurrent runs never read a partial
+// entry. Failures only cost the next run a parse.
+func cacheScript(key string, files map[string]bool, config *runpkg.Config) {
+	path, ok := parsedPath(key)
+	if !ok {
+		return
+	}
+	entry := parsedScript{Files: map[string]string{}, Config: config, Origins: config.Origins}
+	for file := range files {
+		if entry.Files[file] = fileSum(file); entry.Files[file] == "" {
+			return
+		}
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), key+".*")
+	if err != nil {
+		return
+	}
+	_, werr := tmp.Write(data)
+	cerr := tmp.Close()
+	if werr != nil || cerr != nil || os.Rename(tmp.Name(), path) != nil {
+		_ = os.Remove(tmp.Name())
+	}
+	trimParsed(filepath.Dir(path))
+}
+
+// trimParsed removes the entries of dir written longest ago beyond
+// maxParsed.
+func trimParsed(dir string) {
+	entries, err := os.ReadDir(dir)
+	if err != nil || len(entries) <= maxParsed {
+		return
+	}
+	written := map[string]time.Time{}
+	for _, entry := range entries {
+		if info, err := entry.Info(); err == nil {
+			written[entry.Name()] = info.ModTime()
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool { return written[entries[i].Name()].Before(written[entries[j].Name()]) })
+	for _, entry := range entries[:len(entries)-maxParsed] {
+		_ = os.Remove(filepath.Join(dir, entry.Name()))
+	}
+}
+
+// parsedPath returns the location of the cache entry key, when the cache is
+// enabled.
+func parsedPath(key string) (string, bool) {
+	if !DiskCache || key == "" {
+		return "", false
+	}
+	dir, err := state.CacheDir()
+	if err != nil {
+		return "", false
+	}
+	return filepath.Join(dir, parsedDir, key+".json"), true
+}
+
+// fileSum returns the hex sha256 of the content of file, empty when it
+// cannot be read.
+func fileSum(file string) string {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
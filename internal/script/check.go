@@ -0,0 +1,461 @@
+package script
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/docker/go-connections/nat"
+	up "github.com/uplang/go"
+
+	"github.com/gloo-foo/vsl/internal/capability"
+	"github.com/gloo-foo/vsl/internal/container"
+	"github.com/gloo-foo/vsl/internal/mount"
+)
+
+// Problem is an error found in a script, at the line of the key it concerns
+// when it can be told.
+type Problem struct {
+	File    string `json:"file"`
+	Line    int    `json:"line,omitempty"`
+	Key     string `json:"key,omitempty"` // Dotted path of the key, such as services.db.image
+	Message string `json:"message"`
+}
+
+// String formats the problem like a compiler error, file:line: key: message.
+func (p Problem) String() string {
+	location := p.File
+	if p.Line > 0 {
+		location += ":" + strconv.Itoa(p.Line)
+	}
+	if p.Key == "" {
+		return location + ": " + p.Message
+	}
+	return fmt.Sprintf("%s: %s: %s", location, p.Key, p.Message)
+}
+
+// scriptKeys are the keys a script sets besides those of its configuration.
+var scriptKeys = map[string]bool{
+	"description": true,
+	"tests":       true,
+	includeKey:    true,
+	extendsKey:    true,
+	requiresKey:   true,
+	profilesKey:   true,
+	servicesKey:   true,
+	whenKey:       true,
+}
+
+// lineError matches the line number in errors of the script parsers.
+var lineError = regexp.MustCompile(`line (\d+)[^:]*: (.*)`)
+
+// Check validates the script at path, and the fragments it includes,
+// against the script schema: unknown keys, values of the wrong type, invalid
+// volumes, mounts and ports, unclosed blocks, and scripts that run nothing.
+// Problems are reported at the line of the key they concern. An error is
+// returned only when the script cannot be read.
+func Check(path string) ([]Problem, error) {
+	if _, err := os.Stat(path); err != nil {
+		return nil, err
+	}
+	problems := checkFile(path, nil)
+	if len(problems) > 0 {
+		return problems, nil
+	}
+
+	// The script as composed from its fragments and profiles is checked once
+	// each of its files is valid
+	fail := func(err error) []Problem {
+		return []Problem{{File: path, Message: err.Error()}}
+	}
+	nodes, err := parseNodes(path)
+	if err != nil {
+		return fail(err), nil
+	}
+	var profiles []string
+	services := false
+	for _, node := range nodes {
+		switch node.Key {
+		case profilesKey:
+			if block, ok := node.Value.(up.Block); ok {
+				for name := range block {
+					profiles = append(profiles, name)
+				}
+			}
+		case servicesKey:
+			services = true
+		}
+	}
+	if _, err := ParseTests(path); err != nil {
+		return fail(err), nil
+	}
+	if services {
+		if _, err := ParseServices(path); err != nil {
+			return fail(err), nil
+		}
+		return nil, nil
+	}
+	if _, err := ParseFileProfile(path, ""); err != nil {
+		return fail(err), nil
+	}
+	sort.Strings(profiles)
+	for _, profile := range profiles {
+		if _, err := ParseFileProfile(path, profile); err != nil {
+			return fail(fmt.Errorf("profile %s: %w", profile, err)), nil
+		}
+	}
+	return nil, nil
+}
+
+// checker collects the problems of one file and of the fragments it includes.
+type checker struct {
+	file     string
+	dir      string
+	lines    map[string][]int
+	problems []Problem
+	included []Problem
+}
+
+// checkFile checks the keys of the file at path and of the fragments it
+// includes, included from the files in stack.
+func checkFile(path string, stack []string) []Problem {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return []Problem{{File: path, Message: err.Error()}}
+	}
+	content, err := os.ReadFile(abs)
+	if err != nil {
+		return []Problem{{File: path, Message: err.Error()}}
+	}
+	stack = append(stack, abs)
+
+	c := &checker{file: path, dir: filepath.Dir(abs)}
+	format := DetectFormat(abs, content)
+	var doc *up.Document
+	if format == FormatUP {
+		var unclosed []int
+		c.lines, unclosed = keyLines(string(content))
+		for _, line := range unclosed {
+			c.problems = append(c.problems, Problem{File: path, Line: line, Message: "block is not closed"})
+		}
+		doc, err = parseDocument(abs)
+	} else {
+		doc, c.lines, err = decodeDocument(blankShebang(content), format)
+	}
+	if err != nil {
+		p := Problem{File: path, Message: err.Error()}
+		if m := lineError.FindStringSubmatch(err.Error()); m != nil {
+			p.Line, _ = strconv.Atoi(m[1])
+			if format == FormatUP && strings.HasPrefix(string(content), "#!") {
+				p.Line++
+			}
+			p.Message = m[2]
+		}
+		return append(c.problems, p)
+	}
+
+	occurrences := map[string]int{}
+	for _, node := range doc.Nodes {
+		line := c.line(node.Key, occurrences[node.Key])
+		occurrences[node.Key]++
+		switch node.Key {
+		case includeKey:
+			c.checkIncludes(node.Value, line, stack)
+		case requiresKey:
+			if err := checkRequires(node.Value); err != nil {
+				c.report(node.Key, line, "%v", err)
+			}
+		case extendsKey:
+			if occurrences[node.Key] > 1 {
+				c.report(node.Key, line, "a script can extend only one parent")
+			} else if parent, ok := node.Value.(string); ok {
+				c.checkFiles(extendsKey, []string{parent}, line, stack)
+			} else {
+				c.report(node.Key, line, "expected the path of a script")
+			}
+		case profilesKey, servicesKey, whenKey:
+			c.checkNamedBlocks(node.Key, node.Value)
+		case "tests":
+			if _, ok := node.Value.(up.Block); !ok {
+				c.report(node.Key, line, "expected a block of test cases")
+			}
+		case "description":
+			if _, ok := node.Value.(string); !ok {
+				c.report(node.Key, line, "expected a value")
+			}
+		default:
+			c.checkKey(node.Key, node.Key, line, node.Value)
+		}
+	}
+	sort.SliceStable(c.problems, func(i, j int) bool { return c.problems[i].Line < c.problems[j].Line })
+	return append(c.problems, c.included...)
+}
+
+// checkIncludes checks the fragments an include names.
+func (c *checker) checkIncludes(value up.Value, line int, stack []string) {
+	fragments := extractList(value)
+	if scalar, ok := value.(string); ok {
+		fragments = []string{scalar}
+	}
+	if len(fragments) == 0 {
+		c.report(includeKey, line, "expected a path or a list of paths")
+	}
+	c.checkFiles(includeKey, fragments, line, stack)
+}
+
+// checkFiles checks the files a script includes or extends.
+func (c *checker) checkFiles(key string, fragments []string, line int, stack []string) {
+	for _, fragment := range fragments {
+		if !filepath.IsAbs(fragment) {
+			fragment = filepath.Join(c.dir, fragment)
+		}
+		abs, _ := filepath.Abs(fragment)
+		if slices.Contains(stack, abs) {
+			c.report(key, line, "%s cycle: %s", key, strings.Join(append(stack, abs), " -> "))
+			continue
+		}
+		if _, err := os.Stat(fragment); err != nil {
+			c.report(key, line, "%v", err)
+			continue
+		}
+		c.included = append(c.included, checkFile(fragment, stack)...)
+	}
+}
+
+// checkNamedBlocks checks the profiles, services or when blocks of a
+// script, each a block of script keys.
+func (c *checker) checkNamedBlocks(key string, value up.Value) {
+	block, ok := value.(up.Block)
+	if !ok {
+		c.report(key, c.line(key, 0), "expected a block of names")
+		return
+	}
+	for _, name := range sortedKeys(block) {
+		path := key + "." + name
+		entries, ok := block[name].(up.Block)
+		if !ok {
+			c.report(path, c.line(path, 0), "expected a block")
+			continue
+		}
+		for _, k := range sortedKeys(entries) {
+			keyPath := path + "." + k
+			line := c.line(keyPath, 0)
+			switch {
+			case k == "depends_on" && key == servicesKey:
+				deps := extractInlineList(entries[k])
+				if len(deps) == 0 {
+					c.report(keyPath, line, "expected a list of services")
+				}
+				for _, dep := range deps {
+					if _, ok := block[dep]; !ok {
+						c.report(keyPath, line, "unknown service %s", dep)
+					}
+				}
+			case conditionKeys[k] && key == whenKey:
+				if _, err := matches(up.Block{k: entries[k]}); err != nil {
+					c.report(keyPath, line, "%v", err)
+				}
+			case scriptKeys[k]:
+				c.report(keyPath, line, "cannot be set in %s", key)
+			default:
+				c.checkKey(keyPath, k, line, entries[k])
+			}
+		}
+	}
+}
+
+// checkKey checks the value of a configuration key.
+func (c *checker) checkKey(path, key string, line int, value up.Value) {
+	scalar, isScalar := value.(string)
+	var err error
+	switch canonicalKey(key) {
+	case "build":
+		_, err = extractBuild(value)
+	case "healthcheck":
+		_, err = extractHealthcheck(value)
+	case "steps":
+		_, err = extractSteps(value)
+	case "inputs":
+		_, err = extractInputs(value)
+	case "before", "after":
+		_, err = extractHooks(value)
+	case "secret":
+		_, err = extractSecrets(value, c.dir)
+	case "env":
+		if _, ok := value.(up.Block); !ok {
+			if _, e := listItems(value); e != nil {
+				err = fmt.Errorf("expected a list of NAME=value or a block")
+			}
+		}
+	case "volume":
+		if _, err = listItems(value); err != nil {
+			break
+		}
+		for _, vol := range extractVolumes(value) {
+			if !strings.Contains(vol, "$") {
+				if err = mount.CheckVolume(container.Volume(vol)); err != nil {
+					break
+				}
+			}
+		}
+	case "mounts":
+		err = checkList(value, func(spec string) error {
+			_, err := mount.ParseMount(container.MountSpec(spec))
+			return err
+		})
+	case "ports":
+		err = checkList(value, func(port string) error {
+			_, _, err := nat.ParsePortSpecs([]string{port})
+			return err
+		})
+	case "selinux_relabel":
+		if !isScalar || !mount.ValidRelabel(container.Relabel(scalar)) {
+			err = fmt.Errorf("expected z or Z")
+		}
+	case "consistency":
+		if !isScalar || !mount.ValidConsistency(container.Consistency(scalar)) {
+			err = fmt.Errorf("expected consistent, cached or delegated")
+		}
+	case "exclude_with":
+		if mode := container.MaskMode(scalar); mode != mount.MaskTmpfs && mode != mount.MaskVolume {
+			err = fmt.Errorf("expected tmpfs or volume")
+		}
+	case "capability_fallback":
+		if s := container.CapabilityStrategy(scalar); s != capability.StrategyFail && s != capability.StrategyFallback {
+			err = fmt.Errorf("expected fail or fallback")
+		}
+	default:
+		field, ok := configFields[canonicalKey(key)]
+		if !ok {
+			c.report(path, line, "unknown key")
+			return
+		}
+		_, err = decodeValue(field.Type, value)
+	}
+	if err != nil {
+		c.report(path, line, "%v", err)
+	}
+}
+
+// checkList checks that value is a list of values, each passing check when
+// it is set and uses no variables.
+func checkList(value up.Value, check func(string) error) error {
+	list, err := listItems(value)
+	if err != nil {
+		return err
+	}
+	for _, item := range list {
+		scalar, ok := item.(string)
+		if !ok {
+			return fmt.Errorf("expected a list of values")
+		}
+		if check != nil && !strings.Contains(scalar, "$") {
+			if err := check(scalar); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// report records a problem with the key at path.
+func (c *checker) report(path string, line int, format string, args ...any) {
+	c.problems = append(c.problems, Problem{File: c.file, Line: line, Key: path, Message: fmt.Sprintf(format, args...)})
+}
+
+// line returns the line of the nth occurrence of the key at path, or of the
+// closest enclosing key found.
+func (c *checker) line(path string, n int) int {
+	for {
+		if lines := c.lines[path]; n < len(lines) {
+			return lines[n]
+		}
+		i := strings.LastIndex(path, ".")
+		if i < 0 {
+			return 0
+		}
+		path, n = path[:i], 0
+	}
+}
+
+// keyLines maps the keys of a script to the lines they are set on, in order,
+// following the UP syntax: nested keys are joined to their block's by dots,
+// and blocks in lists by their position, as in steps.2.image. It also
+// returns the lines opening blocks and lists that are not closed.
+func keyLines(content string) (map[string][]int, []int) {
+	type frame struct {
+		prefix string
+		list   bool
+		items  int
+		line   int
+	}
+	lines := map[string][]int{}
+	var stack []frame
+	multiline := false
+	for i, text := range strings.Split(content, "\n") {
+		number := i + 1
+		text = strings.TrimSpace(text)
+		switch {
+		case i == 0 && strings.HasPrefix(text, "#!"):
+			continue
+		case multiline:
+			multiline = text != "```"
+			continue
+		case text == "" || strings.HasPrefix(text, "#"):
+			continue
+		case len(stack) > 0 && (text == "}" || text == "]"):
+			stack = stack[:len(stack)-1]
+			continue
+		}
+
+		prefix := ""
+		if len(stack) > 0 {
+			top := &stack[len(stack)-1]
+			prefix = top.prefix
+			if top.list {
+				top.items++
+				if strings.HasPrefix(text, "{") {
+					stack = append(stack, frame{prefix: prefix + strconv.Itoa(top.items) + ".", line: number})
+				}
+				continue
+			}
+		}
+
+		key, value := text, ""
+		if i := strings.IndexAny(text, " \t"); i >= 0 {
+			key, value = text[:i], strings.TrimSpace(text[i:])
+		}
+		key, annotation, _ := strings.Cut(key, "!")
+		path := prefix + key
+		lines[path] = append(lines[path], number)
+		switch {
+		case strings.HasPrefix(value, "```"):
+			multiline = true
+		case value == "{" || (annotation == "table" && strings.HasPrefix(value, "{")):
+			stack = append(stack, frame{prefix: path + ".", line: number})
+		case value == "[":
+			stack = append(stack, frame{prefix: path + ".", list: true, line: number})
+		}
+	}
+
+	unclosed := make([]int, 0, len(stack))
+	for _, f := range stack {
+		unclosed = append(unclosed, f.line)
+	}
+	return lines, unclosed
+}
+
+// sortedKeys returns the keys of block in order.
+func sortedKeys(block up.Block) []string {
+	keys := make([]string, 0, len(block))
+	for key := range block {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
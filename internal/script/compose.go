@@ -0,0 +1,440 @@
+package script
+
+import (
+	"bufio"
+	"encoding/csv"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/google/shlex"
+	"gopkg.in/yaml.v3"
+
+	"github.com/gloo-foo/vsl/internal/container"
+	runpkg "github.com/gloo-foo/vsl/internal/container/run"
+	"github.com/gloo-foo/vsl/internal/image"
+	"github.com/gloo-foo/vsl/internal/mount"
+	"github.com/gloo-foo/vsl/internal/units"
+)
+
+// composeFile is the part of a docker compose file vsl reads.
+type composeFile struct {
+	Services map[string]composeService `yaml:"services"`
+}
+
+// composeService is a compose service, with the keys that map to a run
+// configuration. Keys about other services, such as depends_on and
+// networks, are ignored: only the service's own container is run.
+type composeService struct {
+	Image       string              `yaml:"image"`
+	Build       *composeBuild       `yaml:"build"`
+	Command     composeCommand      `yaml:"command"`
+	Entrypoint  composeCommand      `yaml:"entrypoint"`
+	Environment composeMapping      `yaml:"environment"`
+	EnvFile     composeList         `yaml:"env_file"`
+	Volumes     []yaml.Node         `yaml:"volumes"`
+	Ports       []yaml.Node         `yaml:"ports"`
+	WorkingDir  string              `yaml:"working_dir"`
+	User        string              `yaml:"user"`
+	Privileged  bool                `yaml:"privileged"`
+	CapAdd      []string            `yaml:"cap_add"`
+	Devices     []string            `yaml:"devices"`
+	NetworkMode string              `yaml:"network_mode"`
+	MemLimit    string              `yaml:"mem_limit"`
+	ShmSize     string              `yaml:"shm_size"`
+	CPUs        string              `yaml:"cpus"`
+	Tty         bool                `yaml:"tty"`
+	Healthcheck *composeHealthcheck `yaml:"healthcheck"`
+}
+
+// composeBuild is a build given as a context directory or a block.
+type composeBuild struct {
+	Context    string         `yaml:"context"`
+	Dockerfile string         `yaml:"dockerfile"`
+	Target     string         `yaml:"target"`
+	Args       composeMapping `yaml:"args"`
+	CacheFrom  []string       `yaml:"cache_from"`
+}
+
+// composeHealthcheck is a compose health check.
+type composeHealthcheck struct {
+	Test        composeList `yaml:"test"`
+	Interval    string      `yaml:"interval"`
+	Timeout     string      `yaml:"timeout"`
+	StartPeriod string      `yaml:"start_period"`
+	Retries     int         `yaml:"retries"`
+	Disable     bool        `yaml:"disable"`
+}
+
+// composeCommand is a command given as a list or as a string split like a
+// shell does.
+type composeCommand []string
+
+// composeList is a list that may also be given as a single string.
+type composeList []string
+
+// composeMapping is a mapping that may also be given as a list of
+// KEY=VALUE strings. Keys without a value take the host's.
+type composeMapping map[string]*string
+
+// UnmarshalYAML implements yaml.Unmarshaler
+func (b *composeBuild) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		b.Context = node.Value
+		return nil
+	}
+	type plain composeBuild
+	return node.Decode((*plain)(b))
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler
+func (c *composeCommand) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		words, err := shlex.Split(node.Value)
+		if err != nil {
+			return fmt.Errorf("line %d: %w", node.Line, err)
+		}
+		*c = words
+		return nil
+	}
+	return node.Decode((*[]string)(c))
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler
+func (l *composeList) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		*l = []string{node.Value}
+		return nil
+	}
+	return node.Decode((*[]string)(l))
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler
+func (m *composeMapping) UnmarshalYAML(node *yaml.Node) error {
+	*m = composeMapping{}
+	if node.Kind == yaml.SequenceNode {
+		var items []string
+		if err := node.Decode(&items); err != nil {
+			return err
+		}
+		for _, item := range items {
+			key, value, ok := strings.Cut(item, "=")
+			if ok {
+				(*m)[key] = &value
+			} else {
+				(*m)[key] = nil
+			}
+		}
+		return nil
+	}
+	return node.Decode((*map[string]*string)(m))
+}
+
+// ComposeServices returns the names of the services of a compose file, in order.
+func ComposeServices(path string) ([]string, error) {
+	file, err := readCompose(path)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(file.Services))
+	for name := range file.Services {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// ParseCompose converts a service of a docker compose file to a run
+// configuration: its image or build, command, entrypoint, environment,
+// volumes, ports and settings. Relative host paths resolve against the
+// directory of the compose file, and anonymous volumes become caches. The
+// automatic mounts of the current directory and git repository apply on top.
+func ParseCompose(path, service string) (*runpkg.Config, error) {
+	file, err := readCompose(path)
+	if err != nil {
+		return nil, err
+	}
+	svc, ok := file.Services[service]
+	if !ok {
+		names, _ := ComposeServices(path)
+		return nil, fmt.Errorf("compose file %s has no service %s (services: %s)", path, service, strings.Join(names, ", "))
+	}
+	dir, err := filepath.Abs(filepath.Dir(path))
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := &runpkg.Config{
+		Image:       container.Image(svc.Image),
+		WorkingDir:  container.WorkingDir(svc.WorkingDir),
+		User:        container.User(svc.User),
+		NetworkMode: container.NetworkMode(svc.NetworkMode),
+		Privileged:  svc.Privileged,
+		Interactive: svc.Tty,
+	}
+	if svc.Build != nil {
+		cfg.Build = &image.BuildSpec{
+			Context:    resolvePath(dir, svc.Build.Context),
+			Dockerfile: svc.Build.Dockerfile,
+			Target:     svc.Build.Target,
+			Args:       svc.Build.Args.resolve(),
+			CacheFrom:  svc.Build.CacheFrom,
+		}
+	}
+	if cfg.Image == "" && cfg.Build == nil {
+		return nil, fmt.Errorf("service %s has neither image nor build", service)
+	}
+	for _, c := range svc.Command {
+		cfg.Command = append(cfg.Command, container.Command(c))
+	}
+	for _, e := range svc.Entrypoint {
+		cfg.Entrypoint = append(cfg.Entrypoint, container.Entrypoint(e))
+	}
+
+	// Variables of env files come first, so the environment overrides them
+	for _, envFile := range svc.EnvFile {
+		env, err := readEnvFile(resolvePath(dir, envFile))
+		if err != nil {
+			return nil, fmt.Errorf("service %s: %w", service, err)
+		}
+		for _, e := range env {
+			cfg.Environment = append(cfg.Environment, container.Environment(e))
+		}
+	}
+	env := svc.Environment.resolve()
+	for _, key := range sortedStrings(env) {
+		cfg.Environment = append(cfg.Environment, container.Environment(key+"="+env[key]))
+	}
+
+	for _, node := range svc.Volumes {
+		if err := composeVolume(cfg, dir, &node); err != nil {
+			return nil, fmt.Errorf("service %s: volumes: %w", service, err)
+		}
+	}
+	for _, node := range svc.Ports {
+		port, err := composePort(&node)
+		if err != nil {
+			return nil, fmt.Errorf("service %s: ports: %w", service, err)
+		}
+		cfg.Ports = append(cfg.Ports, container.Port(port))
+	}
+	for _, d := range svc.Devices {
+		cfg.Devices = append(cfg.Devices, container.Device(d))
+	}
+	for _, c := range svc.CapAdd {
+		cfg.CapAdd = append(cfg.CapAdd, container.CapAdd(c))
+	}
+
+	if svc.MemLimit != "" {
+		if cfg.Memory, err = units.ParseBytes(svc.MemLimit); err != nil {
+			return nil, fmt.Errorf("service %s: mem_limit: %w", service, err)
+		}
+	}
+	if svc.ShmSize != "" {
+		if cfg.ShmSize, err = units.ParseBytes(svc.ShmSize); err != nil {
+			return nil, fmt.Errorf("service %s: shm_size: %w", service, err)
+		}
+	}
+	if svc.CPUs != "" {
+		if cfg.CPUs, err = strconv.ParseFloat(svc.CPUs, 64); err != nil {
+			return nil, fmt.Errorf("service %s: cpus: %w", service, err)
+		}
+	}
+	if svc.Healthcheck != nil && !svc.Healthcheck.Disable {
+		if cfg.Healthcheck, err = svc.Healthcheck.convert(); err != nil {
+			return nil, fmt.Errorf("service %s: healthcheck: %w", service, err)
+		}
+	}
+	return cfg, nil
+}
+
+// readCompose reads a compose file.
+func readCompose(path string) (*composeFile, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var file composeFile
+	if err := yaml.Unmarshal(content, &file); err != nil {
+		return nil, fmt.Errorf("compose file %s: %w", path, err)
+	}
+	return &file, nil
+}
+
+// composeVolume adds a volume in the short syntax, source:target[:mode], or
+// the long syntax, a block with type, source, target and read_only.
+func composeVolume(cfg *runpkg.Config, dir string, node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		parts := strings.SplitN(node.Value, ":", 2)
+		if len(parts) == 1 {
+			cfg.Caches = append(cfg.Caches, container.CachePath(parts[0]))
+			return nil
+		}
+		if !mount.IsVolumeName(parts[0]) {
+			parts[0] = mount.EscapeVolumePath(resolvePath(dir, parts[0]))
+		}
+		cfg.Volumes = append(cfg.Volumes, container.Volume(parts[0]+":"+parts[1]))
+		return nil
+	}
+
+	var long struct {
+		Type     string `yaml:"type"`
+		Source   string `yaml:"source"`
+		Target   string `yaml:"target"`
+		ReadOnly bool   `yaml:"read_only"`
+	}
+	if err := node.Decode(&long); err != nil {
+		return err
+	}
+	if long.Type == "" {
+		long.Type = "volume"
+	}
+	if long.Type == "volume" && long.Source == "" {
+		cfg.Caches = append(cfg.Caches, container.CachePath(long.Target))
+		return nil
+	}
+	if long.Type == "bind" {
+		long.Source = resolvePath(dir, long.Source)
+	}
+	fields := []string{"type=" + long.Type, "dst=" + long.Target}
+	if long.Source != "" {
+		fields = append(fields, "src="+long.Source)
+	}
+	if long.ReadOnly {
+		fields = append(fields, "ro")
+	}
+	var spec strings.Builder
+	w := csv.NewWriter(&spec)
+	if err := w.Write(fields); err != nil {
+		return err
+	}
+	w.Flush()
+	cfg.Mounts = append(cfg.Mounts, container.MountSpec(strings.TrimSuffix(spec.String(), "\n")))
+	return nil
+}
+
+// composePort converts a port in the short syntax, [ip:][published:]target[/protocol],
+// or the long syntax, a block with target, published, host_ip and protocol.
+func composePort(node *yaml.Node) (string, error) {
+	if node.Kind == yaml.ScalarNode {
+		return node.Value, nil
+	}
+	var long struct {
+		Target    string `yaml:"target"`
+		Published string `yaml:"published"`
+		HostIP    string `yaml:"host_ip"`
+		Protocol  string `yaml:"protocol"`
+	}
+	if err := node.Decode(&long); err != nil {
+		return "", err
+	}
+	if long.Target == "" {
+		return "", fmt.Errorf("line %d: target is required", node.Line)
+	}
+	port := long.Target
+	if long.Published != "" {
+		port = long.Published + ":" + port
+		if long.HostIP != "" {
+			port = long.HostIP + ":" + port
+		}
+	}
+	if long.Protocol != "" {
+		port += "/" + long.Protocol
+	}
+	return port, nil
+}
+
+// convert returns the health check of a compose test, given as
+// [CMD, argv...], [CMD-SHELL, command] or a command.
+func (h *composeHealthcheck) convert() (*runpkg.Healthcheck, error) {
+	health := &runpkg.Healthcheck{Retries: h.Retries}
+	switch {
+	case len(h.Test) == 0:
+		return nil, fmt.Errorf("test is required")
+	case h.Test[0] == "NONE":
+		return nil, nil
+	case h.Test[0] == "CMD":
+		health.Test, health.Exec = h.Test[1:], true
+	case h.Test[0] == "CMD-SHELL":
+		health.Test = h.Test[1:]
+	default:
+		health.Test = h.Test
+	}
+	for _, d := range []struct {
+		value  string
+		target *units.Duration
+	}{{h.Interval, &health.Interval}, {h.Timeout, &health.Timeout}, {h.StartPeriod, &health.StartPeriod}} {
+		if d.value == "" {
+			continue
+		}
+		duration, err := units.ParseDuration(d.value)
+		if err != nil {
+			return nil, err
+		}
+		*d.target = duration
+	}
+	return health, nil
+}
+
+// resolve returns the values of the mapping, taking those without a value
+// from the host environment and leaving out the ones it does not set.
+func (m composeMapping) resolve() map[string]string {
+	resolved := make(map[string]string, len(m))
+	for key, value := range m {
+		if value != nil {
+			resolved[key] = *value
+		} else if host, ok := os.LookupEnv(key); ok {
+			resolved[key] = host
+		}
+	}
+	return resolved
+}
+
+// readEnvFile reads the KEY=VALUE lines of an env file.
+func readEnvFile(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func(f *os.File) {
+		err := f.Close()
+		if err != nil {
+			panic(err)
+		}
+	}(f)
+
+	var env []string
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		if key, _, ok := strings.Cut(text, "="); !ok || key == "" {
+			return nil, fmt.Errorf("env file %s:%d: expected KEY=VALUE, got %q", path, line, text)
+		}
+		env = append(env, text)
+	}
+	return env, scanner.Err()
+}
+
+// resolvePath returns path resolved against dir when it is relative.
+func resolvePath(dir, path string) string {
+	if path == "" || filepath.IsAbs(path) || strings.HasPrefix(path, "~") {
+		return path
+	}
+	return filepath.Join(dir, path)
+}
+
+// sortedStrings returns the keys of m in order.
+func sortedStrings(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
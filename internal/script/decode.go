@@ -0,0 +1,196 @@
+package script
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	up "github.com/uplang/go"
+
+	runpkg "github.com/gloo-foo/vsl/internal/container/run"
+)
+
+// configFields maps the keys of a script's configuration to the fields they
+// set, from the up tags of runpkg.Config.
+var configFields = func() map[string]reflect.StructField {
+	fields := map[string]reflect.StructField{}
+	t := reflect.TypeOf(runpkg.Config{})
+	for i := 0; i < t.NumField(); i++ {
+		if key := t.Field(i).Tag.Get("up"); key != "" && key != "-" {
+			fields[key] = t.Field(i)
+		}
+	}
+	return fields
+}()
+
+// listLen returns the number of items of the list key sets in config, or
+// zero when it sets a value.
+func listLen(config *runpkg.Config, key string) int {
+	field, ok := configFields[key]
+	if !ok || field.Type.Kind() != reflect.Slice {
+		return 0
+	}
+	return reflect.ValueOf(config).Elem().FieldByIndex(field.Index).Len()
+}
+
+// setOrigin records the origin of the setting of key in config, a script
+// unless set, or of the items added to its list after the first before.
+func setOrigin(config *runpkg.Config, key string, origin runpkg.Origin, before int) {
+	field, ok := configFields[key]
+	if !ok {
+		return
+	}
+	if origin == "" {
+		origin = runpkg.OriginScript
+	}
+	if field.Type.Kind() == reflect.Slice {
+		config.AddOrigins(key, origin, listLen(config, key)-before)
+		return
+	}
+	config.SetOrigin(key, origin)
+}
+
+// ValueError is returned when a script sets a key to a value its type does
+// not accept, such as a bool set to maybe.
+type ValueError struct {
+	Key string
+	Err error
+}
+
+func (e *ValueError) Error() string { return e.Key + ": " + e.Err.Error() }
+
+func (e *ValueError) Unwrap() error { return e.Err }
+
+// setter is implemented by types parsing their own values, such as sizes
+// and durations.
+type setter interface {
+	Set(string) error
+}
+
+// decodeKey sets the field of config that key sets to value, adding to the
+// field when it is a list. Keys that set no field are ignored.
+func decodeKey(config *runpkg.Config, key string, value up.Value) error {
+	field, ok := configFields[canonicalKey(key)]
+	if !ok {
+		return nil
+	}
+	v := reflect.ValueOf(config).Elem().FieldByIndex(field.Index)
+	decoded, err := decodeValue(field.Type, value)
+	if err != nil {
+		return &ValueError{Key: key, Err: err}
+	}
+	if field.Type.Kind() == reflect.Slice {
+		decoded = reflect.AppendSlice(v, decoded)
+	}
+	v.Set(decoded)
+	return nil
+}
+
+// decodeValue converts value to type t: a list of values to a slice, and a
+// value to a bool, integer, number, string or type parsing its own values.
+func decodeValue(t reflect.Type, value up.Value) (reflect.Value, error) {
+	if t.Kind() == reflect.Slice {
+		list, err := listItems(value)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		result := reflect.MakeSlice(t, 0, len(list))
+		for i, item := range list {
+			decoded, err := decodeValue(t.Elem(), item)
+			if err != nil {
+				return reflect.Value{}, fmt.Errorf("item %d: %w", i+1, err)
+			}
+			result = reflect.Append(result, decoded)
+		}
+		return result, nil
+	}
+
+	scalar, ok := value.(string)
+	if !ok {
+		return reflect.Value{}, fmt.Errorf("expected a value, not a block or list")
+	}
+	result := reflect.New(t)
+	if s, ok := result.Interface().(setter); ok {
+		return result.Elem(), s.Set(scalar)
+	}
+	switch t.Kind() {
+	case reflect.String:
+		result.Elem().SetString(scalar)
+	case reflect.Bool:
+		b, err := parseBool(scalar)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		result.Elem().SetBool(b)
+	case reflect.Int:
+		n, err := parseInt(scalar)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		result.Elem().SetInt(int64(n))
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(scalar, 64)
+		if err != nil {
+			return reflect.Value{}, fmt.Errorf("expected a number, got %q", scalar)
+		}
+		result.Elem().SetFloat(f)
+	default:
+		return reflect.Value{}, fmt.Errorf("cannot be set from a value")
+	}
+	return result.Elem(), nil
+}
+
+// listItems returns the items of a list, given one item per line or inline
+// as [a, b], whose items may be quoted.
+func listItems(value up.Value) (up.List, error) {
+	if list, ok := value.(up.List); ok {
+		return list, nil
+	}
+	scalar, _ := value.(string)
+	scalar = strings.TrimSpace(scalar)
+	if !strings.HasPrefix(scalar, "[") || !strings.HasSuffix(scalar, "]") {
+		return nil, fmt.Errorf("expected a list, one item per line or [a, b]")
+	}
+	list := up.List{}
+	for _, item := range strings.Split(scalar[1:len(scalar)-1], ",") {
+		item = strings.TrimSpace(item)
+		if unquoted, err := strconv.Unquote(item); err == nil {
+			item = unquoted
+		}
+		if item != "" {
+			list = append(list, item)
+		}
+	}
+	return list, nil
+}
+
+// parseBool reads a boolean value: true, yes or on, false, no or off, in
+// any case. A key given without a value, such as a bare privileged line, is
+// true.
+func parseBool(value up.Value) (bool, error) {
+	scalar, ok := value.(string)
+	if !ok {
+		return false, fmt.Errorf("expected true or false, not a block or list")
+	}
+	switch strings.ToLower(scalar) {
+	case "", "true", "yes", "on":
+		return true, nil
+	case "false", "no", "off":
+		return false, nil
+	}
+	return false, fmt.Errorf("expected true or false, got %q", scalar)
+}
+
+// parseInt reads an integer value.
+func parseInt(value up.Value) (int, error) {
+	scalar, ok := value.(string)
+	if !ok {
+		return 0, fmt.Errorf("expected an integer, not a block or list")
+	}
+	n, err := strconv.Atoi(scalar)
+	if err != nil {
+		return 0, fmt.Errorf("expected an integer, got %q", scalar)
+	}
+	return n, nil
+}
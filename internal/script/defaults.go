@@ -0,0 +1,130 @@
+package script
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/gloo-foo/vsl/internal/container"
+	runpkg "github.com/gloo-foo/vsl/internal/container/run"
+	"github.com/gloo-foo/vsl/internal/git"
+	"github.com/gloo-foo/vsl/internal/secret"
+	"github.com/gloo-foo/vsl/internal/state"
+)
+
+// configFileKeys are the keys of the configuration files that are not run
+// defaults: the global secret backends and the project's task defaults.
+var configFileKeys = map[string]bool{
+	"secret_backends": true,
+	"tasks":           true,
+}
+
+// projectKeys are the run defaults a project configuration may set. The
+// project file is checked in with the code it runs, so keys that widen the
+// container's access to the host, such as privileged, volumes, secrets or
+// hooks, are only taken from scripts and the global configuration.
+var projectKeys = map[string]bool{
+	"image":    true,
+	"env":      true,
+	"workdir":  true,
+	"memory":   true,
+	"cpus":     true,
+	"shm_size": true,
+	"timeout":  true,
+}
+
+// ConfigFiles returns the configuration files whose keys are the defaults
+// of runs in dir, lowest precedence first: the global configuration config.up
+// in the vsl config directory, then the project configuration. Scripts and
+// flags override both.
+func ConfigFiles(dir string) ([]string, error) {
+	var files []string
+	global, err := state.ConfigFile(secret.ConfigFileName)
+	if err != nil {
+		return nil, err
+	}
+	if info, err := os.Stat(global); err == nil && !info.IsDir() {
+		files = append(files, global)
+	} else if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	if project, ok := FindProject(dir); ok {
+		files = append(files, project)
+	}
+	return files, nil
+}
+
+// FindProject returns the project configuration that applies in dir: the
+// .vsl.up in dir or the closest directory above it, up to the root of its
+// repository. Outside repositories only dir is looked in.
+func FindProject(dir string) (string, bool) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", false
+	}
+	var root container.GitRoot
+	if r, err := git.FindRoot(dir); err == nil {
+		root = r
+	}
+	for {
+		path := filepath.Join(dir, ProjectFile)
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path, true
+		}
+		parent := filepath.Dir(dir)
+		if root == "" || dir == string(root) || parent == dir {
+			return "", false
+		}
+		dir = parent
+	}
+}
+
+// Defaults returns the run defaults of the configuration files that apply in
+// dir, for runs without a script, and the keys they set.
+func Defaults(dir string) (*runpkg.Config, map[string]bool, error) {
+	nodes, err := defaultNodes(dir, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	config, err := parseSettings(nodes)
+	if err != nil {
+		return nil, nil, err
+	}
+	keys := make(map[string]bool, len(nodes))
+	for _, node := range nodes {
+		keys[canonicalKey(node.Key)] = true
+	}
+	return config, keys, nil
+}
+
+// defaultNodes reads the nodes of the configuration files that apply in dir,
+// with their includes, leaving out the keys that are not run defaults. Keys
+// outside projectKeys are refused in the project configuration. The files
+// read are added to files when set.
+func defaultNodes(dir string, files map[string]bool) ([]sourcedNode, error) {
+	paths, err := ConfigFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+	project, _ := FindProject(dir)
+	var nodes []sourcedNode
+	for _, path := range paths {
+		fileNodes, err := includeNodes(path, nil, files)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		for _, node := range fileNodes {
+			if configFileKeys[node.Key] {
+				continue
+			}
+			if path == project && !projectKeys[canonicalKey(node.Key)] {
+				return nil, fmt.Errorf("%s: %s cannot be set in the project configuration (allowed: image, env, workdir, memory, cpus, shm_size, timeout); set it in a script or in the global config.up", path, node.Key)
+			}
+			node.origin = runpkg.OriginConfig
+			nodes = append(nodes, node)
+		}
+	}
+	return nodes, nil
+}
@@ -0,0 +1,17 @@
+package script
+
+// ParseDescription returns the one-line description an UP script declares
+// with its description key, shown when listing tasks. It is empty when the
+// script declares none.
+func ParseDescription(path string) (string, error) {
+	doc, err := parseDocument(path)
+	if err != nil {
+		return "", err
+	}
+	for _, node := range doc.Nodes {
+		if scalar, ok := node.Value.(string); ok && node.Key == "description" {
+			return scalar, nil
+		}
+	}
+	return "", nil
+}
@@ -0,0 +1,320 @@
+package script
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gloo-foo/vsl/internal/container"
+	runpkg "github.com/gloo-foo/vsl/internal/container/run"
+	"github.com/gloo-foo/vsl/internal/image"
+)
+
+// DevcontainerFiles are where a Dev Container definition is looked for,
+// relative to the workspace.
+var DevcontainerFiles = []string{
+	filepath.Join(".devcontainer", "devcontainer.json"),
+	".devcontainer.json",
+}
+
+// devcontainer is the part of a devcontainer.json vsl reads. Features,
+// lifecycle commands other than postCreateCommand, and customizations are
+// ignored.
+type devcontainer struct {
+	Image             string             `json:"image"`
+	DockerFile        string             `json:"dockerFile"`
+	Context           string             `json:"context"`
+	Build             *devcontainerBuild `json:"build"`
+	ContainerEnv      map[string]string  `json:"containerEnv"`
+	RemoteEnv         map[string]string  `json:"remoteEnv"`
+	Mounts            []json.RawMessage  `json:"mounts"`
+	ContainerUser     string             `json:"containerUser"`
+	RemoteUser        string             `json:"remoteUser"`
+	ForwardPorts      []json.RawMessage  `json:"forwardPorts"`
+	Privileged        bool               `json:"privileged"`
+	CapAdd            []string           `json:"capAdd"`
+	PostCreateCommand json.RawMessage    `json:"postCreateCommand"`
+}
+
+// devcontainerBuild is the Dockerfile build of a Dev Container.
+type devcontainerBuild struct {
+	Dockerfile string            `json:"dockerfile"`
+	Context    string            `json:"context"`
+	Target     string            `json:"target"`
+	Args       map[string]string `json:"args"`
+	CacheFrom  json.RawMessage   `json:"cacheFrom"`
+}
+
+// devcontainerVar matches the variables of devcontainer.json values, such as
+// ${localWorkspaceFolder} and ${localEnv:HOME}.
+var devcontainerVar = regexp.MustCompile(`\$\{(localWorkspaceFolder|localWorkspaceFolderBasename|containerWorkspaceFolder|containerWorkspaceFolderBasename|localEnv:[^}:]+)(:[^}]*)?\}`)
+
+// FindDevcontainer returns the Dev Container definition of the workspace
+// containing dir: in dir, or else at the root of its repository.
+func FindDevcontainer(dir string) (string, bool) {
+	return findInWorkspace(dir, DevcontainerFiles)
+}
+
+// IsDevcontainer reports whether path names a Dev Container definition.
+func IsDevcontainer(path string) bool {
+	base := filepath.Base(path)
+	return base == ".devcontainer.json" || (base == "devcontainer.json" && filepath.Base(filepath.Dir(path)) == ".devcontainer")
+}
+
+// ParseDevcontainer converts a Dev Container definition to a run
+// configuration: its image or build, containerEnv and remoteEnv, mounts,
+// user, forwarded ports and capabilities. The postCreateCommand runs before
+// the command, or before a shell when there is none. The workspace is
+// mounted as vsl mounts the current directory.
+func ParseDevcontainer(path string, command []string) (*runpkg.Config, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var dc devcontainer
+	if err := json.Unmarshal(stripJSONC(content), &dc); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	dir, err := filepath.Abs(filepath.Dir(path))
+	if err != nil {
+		return nil, err
+	}
+	workspace := dir
+	if filepath.Base(dir) == ".devcontainer" {
+		workspace = filepath.Dir(dir)
+	}
+	expand := func(s string) string {
+		return devcontainerVar.ReplaceAllStringFunc(s, func(ref string) string {
+			m := devcontainerVar.FindStringSubmatch(ref)
+			name, fallback := m[1], strings.TrimPrefix(m[2], ":")
+			switch {
+			case name == "localWorkspaceFolder", name == "containerWorkspaceFolder":
+				return workspace
+			case name == "localWorkspaceFolderBasename", name == "containerWorkspaceFolderBasename":
+				return filepath.Base(workspace)
+			}
+			if value, ok := os.LookupEnv(strings.TrimPrefix(name, "localEnv:")); ok {
+				return value
+			}
+			return fallback
+		})
+	}
+
+	cfg := &runpkg.Config{
+		Image:      container.Image(dc.Image),
+		User:       container.User(dc.RemoteUser),
+		Privileged: dc.Privileged,
+	}
+	if cfg.User == "" {
+		cfg.User = container.User(dc.ContainerUser)
+	}
+	build := dc.Build
+	if build == nil && dc.DockerFile != "" {
+		build = &devcontainerBuild{Dockerfile: dc.DockerFile, Context: dc.Context}
+	}
+	if build != nil && build.Dockerfile != "" {
+		// The Dockerfile and context are relative to devcontainer.json, the
+		// Dockerfile of a build spec to its context
+		context := filepath.Join(dir, expand(build.Context))
+		dockerfile, err := filepath.Rel(context, filepath.Join(dir, expand(build.Dockerfile)))
+		if err != nil {
+			return nil, err
+		}
+		cfg.Build = &image.BuildSpec{Context: context, Dockerfile: dockerfile, Target: build.Target, Args: build.Args}
+		cfg.Build.CacheFrom = stringOrList(build.CacheFrom)
+	}
+	if cfg.Image == "" && cfg.Build == nil {
+		return nil, fmt.Errorf("%s: an image or a build is required; docker compose based definitions are not supported", path)
+	}
+
+	for _, env := range []map[string]string{dc.ContainerEnv, dc.RemoteEnv} {
+		keys := make([]string, 0, len(env))
+		for key := range env {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			cfg.Environment = append(cfg.Environment, container.Environment(key+"="+expand(env[key])))
+		}
+	}
+
+	for _, raw := range dc.Mounts {
+		spec, err := devcontainerMount(raw)
+		if err != nil {
+			return nil, fmt.Errorf("%s: mounts: %w", path, err)
+		}
+		cfg.Mounts = append(cfg.Mounts, container.MountSpec(expand(spec)))
+	}
+	for _, raw := range dc.ForwardPorts {
+		var port any
+		if err := json.Unmarshal(raw, &port); err != nil {
+			return nil, fmt.Errorf("%s: forwardPorts: %w", path, err)
+		}
+		switch p := port.(type) {
+		case float64:
+			n := strconv.Itoa(int(p))
+			cfg.Ports = append(cfg.Ports, container.Port(n+":"+n))
+		case string:
+			// host:port entries forward ports of other containers
+			if _, err := strconv.Atoi(p); err == nil {
+				cfg.Ports = append(cfg.Ports, container.Port(p+":"+p))
+			}
+		}
+	}
+	for _, c := range dc.CapAdd {
+		cfg.CapAdd = append(cfg.CapAdd, container.CapAdd(c))
+	}
+
+	postCreate, err := devcontainerCommand(dc.PostCreateCommand)
+	if err != nil {
+		return nil, fmt.Errorf("%s: postCreateCommand: %w", path, err)
+	}
+	switch {
+	case postCreate != "" && len(command) == 0:
+		command = []string{"/bin/sh", "-c", postCreate + ` && exec "${SHELL:-/bin/sh}"`}
+	case postCreate != "":
+		command = append([]string{"/bin/sh", "-c", postCreate + ` && exec "$@"`, "sh"}, command...)
+	}
+	for _, c := range command {
+		cfg.Command = append(cfg.Command, container.Command(expand(c)))
+	}
+	return cfg, nil
+}
+
+// devcontainerMount converts a mount given in the --mount syntax or as an
+// object with source, target and type.
+func devcontainerMount(raw json.RawMessage) (string, error) {
+	var spec string
+	if err := json.Unmarshal(raw, &spec); err == nil {
+		return spec, nil
+	}
+	var m struct {
+		Source string `json:"source"`
+		Target string `json:"target"`
+		Type   string `json:"type"`
+	}
+	if err := json.Unmarshal(raw, &m); err != nil {
+		return "", err
+	}
+	if m.Target == "" {
+		return "", fmt.Errorf("target is required")
+	}
+	spec = "type=" + m.Type + ",target=" + m.Target
+	if m.Type == "" {
+		spec = "type=bind,target=" + m.Target
+	}
+	if m.Source != "" {
+		spec += ",source=" + m.Source
+	}
+	return spec, nil
+}
+
+// devcontainerCommand returns a lifecycle command as a shell command: a
+// command string, an argv list, or an object of named commands run in turn.
+func devcontainerCommand(raw json.RawMessage) (string, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return "", nil
+	}
+	var command any
+	if err := json.Unmarshal(raw, &command); err != nil {
+		return "", err
+	}
+	switch c := command.(type) {
+	case string:
+		return c, nil
+	case []any:
+		words := make([]string, 0, len(c))
+		for _, word := range c {
+			words = append(words, fmt.Sprint(word))
+		}
+		return container.ShellJoin(words), nil
+	case map[string]any:
+		names := make([]string, 0, len(c))
+		for name := range c {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		commands := make([]string, 0, len(names))
+		for _, name := range names {
+			sub, err := json.Marshal(c[name])
+			if err != nil {
+				return "", err
+			}
+			s, err := devcontainerCommand(sub)
+			if err != nil {
+				return "", fmt.Errorf("%s: %w", name, err)
+			}
+			commands = append(commands, "("+s+")")
+		}
+		return strings.Join(commands, " && "), nil
+	}
+	return "", fmt.Errorf("expected a string, a list or an object")
+}
+
+// stringOrList decodes a JSON string or list of strings.
+func stringOrList(raw json.RawMessage) []string {
+	var list []string
+	if err := json.Unmarshal(raw, &list); err == nil {
+		return list
+	}
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil && s != "" {
+		return []string{s}
+	}
+	return nil
+}
+
+// stripJSONC removes the comments and trailing commas JSON with comments
+// allows, as used by devcontainer.json, leaving strings intact.
+func stripJSONC(content []byte) []byte {
+	out := make([]byte, 0, len(content))
+	inString := false
+	for i := 0; i < len(content); i++ {
+		c := content[i]
+		switch {
+		case inString:
+			out = append(out, c)
+			if c == '\\' && i+1 < len(content) {
+				i++
+				out = append(out, content[i])
+			} else if c == '"' {
+				inString = false
+			}
+		case c == '"':
+			inString = true
+			out = append(out, c)
+		case c == '/' && i+1 < len(content) && content[i+1] == '/':
+			for i < len(content) && content[i] != '\n' {
+				i++
+			}
+			if i < len(content) {
+				out = append(out, '\n')
+			}
+		case c == '/' && i+1 < len(content) && content[i+1] == '*':
+			end := strings.Index(string(content[i+2:]), "*/")
+			if end < 0 {
+				return out
+			}
+			i += end + 3
+		case c == '}' || c == ']':
+			// Drop a trailing comma before the closing bracket
+			j := len(out) - 1
+			for j >= 0 && strings.ContainsRune(" \t\r\n", rune(out[j])) {
+				j--
+			}
+			if j >= 0 && out[j] == ',' {
+				out = append(out[:j], out[j+1:]...)
+			}
+			out = append(out, c)
+		default:
+			out = append(out, c)
+		}
+	}
+	return out
+}
@@ -0,0 +1,61 @@
+package script
+
+import (
+	"fmt"
+	"path/filepath"
+
+	up "github.com/uplang/go"
+)
+
+// extendsKey names the parent script a script extends, as a path relative
+// to the extending file. The script's keys replace the parent's, except
+// those in mergedKeys, whose entries are added to the parent's:
+//
+//	extends ../base/go.up
+//	command [go, test, ./...]
+//	env {
+//	  CGO_ENABLED 0
+//	}
+const extendsKey = "extends"
+
+// mergedKeys are the keys whose entries an extending script adds to its
+// parent's instead of replacing them. Environment variables of the same
+// name override the parent's.
+var mergedKeys = map[string]bool{
+	"env":    true,
+	"volume": true,
+	"mounts": true,
+	"caches": true,
+	"secret": true,
+}
+
+// parentNodes reads the parent script named by the value of an extends key
+// in the file read from dir, extended from the files in stack, adding the
+// files it reads to files when set.
+func parentNodes(value up.Value, dir string, stack []string, files map[string]bool) ([]sourcedNode, error) {
+	parent, ok := value.(string)
+	if !ok || parent == "" {
+		return nil, fmt.Errorf("extends must be the path of a script")
+	}
+	if !filepath.IsAbs(parent) {
+		parent = filepath.Join(dir, parent)
+	}
+	return includeNodes(parent, stack, files)
+}
+
+// extend layers the nodes of a script over those of its parent.
+func extend(parent, nodes []sourcedNode) []sourcedNode {
+	replaced := map[string]bool{}
+	for _, node := range nodes {
+		if k := canonicalKey(node.Key); !mergedKeys[k] {
+			replaced[k] = true
+		}
+	}
+	result := make([]sourcedNode, 0, len(parent)+len(nodes))
+	for _, node := range parent {
+		if !replaced[canonicalKey(node.Key)] {
+			result = append(result, node)
+		}
+	}
+	return append(result, nodes...)
+}
@@ -0,0 +1,185 @@
+package script
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	up "github.com/uplang/go"
+	"gopkg.in/yaml.v3"
+)
+
+// Format is the language a script file is written in. Scripts written in
+// YAML, JSON or TOML use the same keys as UP scripts and are converted to UP
+// nodes, so includes, profiles and the configuration mapping apply alike.
+type Format string
+
+// Script formats.
+const (
+	FormatUP   Format = "up"
+	FormatYAML Format = "yaml"
+	FormatJSON Format = "json"
+	FormatTOML Format = "toml"
+)
+
+// formatExtensions maps file extensions to the format they name.
+var formatExtensions = map[string]Format{
+	".yaml": FormatYAML,
+	".yml":  FormatYAML,
+	".json": FormatJSON,
+	".toml": FormatTOML,
+}
+
+// tomlLine matches the table headers and key = value lines of TOML.
+var tomlLine = regexp.MustCompile(`^(\[\[?[\w.-]+\]\]?$|[\w.-]+\s*=)`)
+
+// DetectFormat returns the format of a script file, from its extension or,
+// for other files, from its first line after the shebang and comments: an
+// object for JSON, a document marker or key: value for YAML, a table header
+// or key = value for TOML, and UP otherwise.
+func DetectFormat(path string, content []byte) Format {
+	if format, ok := formatExtensions[strings.ToLower(filepath.Ext(path))]; ok {
+		return format
+	}
+	for i, line := range strings.Split(string(content), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") || (i == 0 && strings.HasPrefix(line, "#!")) {
+			continue
+		}
+		key, _, _ := strings.Cut(line, " ")
+		switch {
+		case strings.HasPrefix(line, "{"):
+			return FormatJSON
+		case line == "---" || strings.HasSuffix(key, ":"):
+			return FormatYAML
+		case tomlLine.MatchString(line):
+			return FormatTOML
+		}
+		return FormatUP
+	}
+	return FormatUP
+}
+
+// decodeDocument converts a YAML, JSON or TOML script to UP nodes, in the
+// order of its keys, with the lines of its keys in the form of keyLines when
+// the format records them.
+func decodeDocument(content []byte, format Format) (*up.Document, map[string][]int, error) {
+	switch format {
+	case FormatYAML, FormatJSON:
+		// JSON is read as the YAML it is a subset of, which keeps key order
+		var root yaml.Node
+		if err := yaml.Unmarshal(content, &root); err != nil {
+			return nil, nil, err
+		}
+		lines := map[string][]int{}
+		doc := &up.Document{}
+		if len(root.Content) == 0 {
+			return doc, lines, nil
+		}
+		top := root.Content[0]
+		if top.Kind != yaml.MappingNode {
+			return nil, nil, fmt.Errorf("line %d: a script must be a mapping of keys", top.Line)
+		}
+		for i := 0; i+1 < len(top.Content); i += 2 {
+			key := top.Content[i].Value
+			lines[key] = append(lines[key], top.Content[i].Line)
+			doc.Nodes = append(doc.Nodes, up.Node{Key: key, Value: yamlValue(top.Content[i+1], key+".", lines)})
+		}
+		return doc, lines, nil
+	case FormatTOML:
+		var values map[string]any
+		meta, err := toml.NewDecoder(bytes.NewReader(content)).Decode(&values)
+		if err != nil {
+			return nil, nil, err
+		}
+		doc := &up.Document{}
+		for _, key := range meta.Keys() {
+			if len(key) == 1 {
+				doc.Nodes = append(doc.Nodes, up.Node{Key: key[0], Value: tomlValue(values[key[0]])})
+			}
+		}
+		return doc, nil, nil
+	}
+	return nil, nil, fmt.Errorf("unsupported script format %s", format)
+}
+
+// yamlValue converts a YAML node to an UP value, recording the lines of the
+// keys of its mappings under prefix.
+func yamlValue(node *yaml.Node, prefix string, lines map[string][]int) up.Value {
+	switch node.Kind {
+	case yaml.AliasNode:
+		return yamlValue(node.Alias, prefix, lines)
+	case yaml.MappingNode:
+		block := up.Block{}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key := node.Content[i].Value
+			lines[prefix+key] = append(lines[prefix+key], node.Content[i].Line)
+			block[key] = yamlValue(node.Content[i+1], prefix+key+".", lines)
+		}
+		return block
+	case yaml.SequenceNode:
+		list := make(up.List, 0, len(node.Content))
+		for i, item := range node.Content {
+			list = append(list, yamlValue(item, prefix+strconv.Itoa(i+1)+".", lines))
+		}
+		return list
+	case yaml.ScalarNode:
+		if node.Tag == "!!null" {
+			return ""
+		}
+		return node.Value
+	}
+	return ""
+}
+
+// tomlValue converts a decoded TOML value to an UP value.
+func tomlValue(value any) up.Value {
+	switch v := value.(type) {
+	case map[string]any:
+		block := make(up.Block, len(v))
+		for key, item := range v {
+			block[key] = tomlValue(item)
+		}
+		return block
+	case []map[string]any:
+		list := make(up.List, 0, len(v))
+		for _, item := range v {
+			list = append(list, tomlValue(item))
+		}
+		return list
+	case []any:
+		list := make(up.List, 0, len(v))
+		for _, item := range v {
+			list = append(list, tomlValue(item))
+		}
+		return list
+	case string:
+		return v
+	case bool:
+		return strconv.FormatBool(v)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case time.Time:
+		return v.Format(time.RFC3339)
+	}
+	return fmt.Sprint(value)
+}
+
+// blankShebang replaces the shebang line of content by an empty line,
+// keeping line numbers.
+func blankShebang(content []byte) []byte {
+	if !bytes.HasPrefix(content, []byte("#!")) {
+		return content
+	}
+	if i := bytes.IndexByte(content, '\n'); i >= 0 {
+		return content[i:]
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package script
+
+import (
+	"fmt"
+
+	up "github.com/uplang/go"
+
+	"github.com/gloo-foo/vsl/internal/app/hook"
+	"github.com/gloo-foo/vsl/internal/units"
+)
+
+// extractHooks converts a before or after list of host commands, each a
+// command line run by the shell or a block:
+//
+//	before [
+//	  ./scripts/gen-certs.sh
+//	  {
+//	    command [mkcert, -install]
+//	    timeout 30s
+//	    continue_on_error true
+//	  }
+//	]
+//
+// A command given as a list runs directly instead of through the shell.
+func extractHooks(value up.Value) ([]hook.Hook, error) {
+	list, ok := value.(up.List)
+	if !ok {
+		return nil, fmt.Errorf("expected a list of commands or hook blocks")
+	}
+	hooks := make([]hook.Hook, 0, len(list))
+	for i, item := range list {
+		switch v := item.(type) {
+		case string:
+			hooks = append(hooks, hook.Hook{Command: []string{v}})
+		case up.Block:
+			h, err := parseHook(v)
+			if err != nil {
+				return nil, fmt.Errorf("hook %d: %w", i+1, err)
+			}
+			hooks = append(hooks, h)
+		default:
+			return nil, fmt.Errorf("hook %d must be a command or a block", i+1)
+		}
+	}
+	return hooks, nil
+}
+
+// parseHook converts a hook block.
+func parseHook(block up.Block) (hook.Hook, error) {
+	var h hook.Hook
+	for key, value := range block {
+		scalar, _ := value.(string)
+		var err error
+		switch key {
+		case "command":
+			// A list, inline or not, is the argv
+			h.Command = extractInlineList(value)
+			h.Exec = len(h.Command) != 1 || h.Command[0] != scalar
+		case "workdir", "working_dir":
+			h.WorkingDir = scalar
+		case "timeout":
+			h.Timeout, err = units.ParseDuration(scalar)
+		case "continue_on_error":
+			h.ContinueOnError, err = parseBool(value)
+		default:
+			err = fmt.Errorf("unknown key (expected command, workdir, timeout or continue_on_error)")
+		}
+		if err != nil {
+			return h, fmt.Errorf("%s: %w", key, err)
+		}
+	}
+	if len(h.Command) == 0 {
+		return h, fmt.Errorf("command is required")
+	}
+	return h, nil
+}
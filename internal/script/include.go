@@ -0,0 +1,109 @@
+package script
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	up "github.com/uplang/go"
+
+	runpkg "github.com/gloo-foo/vsl/internal/container/run"
+)
+
+// includeKey names the fragments a script is composed from, as one path or
+// a list of paths relative to the including file:
+//
+//	include common.up
+//	include [
+//	  ../base/go.up
+//	  caches.up
+//	]
+const includeKey = "include"
+
+// sourcedNode is a node of a script or of a fragment it includes, with the
+// directory of the file it was read from and, for the nodes of
+// configuration files, their origin.
+type sourcedNode struct {
+	up.Node
+	dir    string
+	origin runpkg.Origin
+}
+
+// parseNodes reads the script at path and replaces its includes by the nodes
+// of the included fragments, in place, so keys the script sets after an
+// include override the fragment's and lists extend it.
+func parseNodes(path string) ([]sourcedNode, error) {
+	return includeNodes(path, nil, nil)
+}
+
+// includeNodes reads path, included from the files in stack, adding the
+// files it reads to files when set.
+func includeNodes(path string, stack []string, files map[string]bool) ([]sourcedNode, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	if slices.Contains(stack, abs) {
+		return nil, fmt.Errorf("include cycle: %s", strings.Join(append(stack, abs), " -> "))
+	}
+	stack = append(stack, abs)
+	if files != nil {
+		files[abs] = true
+	}
+
+	doc, err := parseDocument(abs)
+	if err != nil {
+		if len(stack) > 1 {
+			return nil, fmt.Errorf("include %s: %w", path, err)
+		}
+		return nil, err
+	}
+
+	dir := filepath.Dir(abs)
+	var nodes, parent []sourcedNode
+	extended := false
+	for _, node := range doc.Nodes {
+		if node.Key == requiresKey {
+			if err := checkRequires(node.Value); err != nil {
+				return nil, fmt.Errorf("%s: %w", path, err)
+			}
+			continue
+		}
+		if node.Key == extendsKey {
+			if extended {
+				return nil, fmt.Errorf("%s: a script can extend only one parent", path)
+			}
+			extended = true
+			if parent, err = parentNodes(node.Value, dir, stack, files); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if node.Key != includeKey {
+			nodes = append(nodes, sourcedNode{Node: node, dir: dir})
+			continue
+		}
+		fragments := extractList(node.Value)
+		if scalar, ok := node.Value.(string); ok {
+			fragments = []string{scalar}
+		}
+		if len(fragments) == 0 {
+			return nil, fmt.Errorf("%s: include must be a path or a list of paths", path)
+		}
+		for _, fragment := range fragments {
+			if !filepath.IsAbs(fragment) {
+				fragment = filepath.Join(dir, fragment)
+			}
+			included, err := includeNodes(fragment, stack, files)
+			if err != nil {
+				return nil, err
+			}
+			nodes = append(nodes, included...)
+		}
+	}
+	if extended {
+		nodes = extend(parent, nodes)
+	}
+	return nodes, nil
+}
@@ -0,0 +1,147 @@
+package script
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	up "github.com/uplang/go"
+
+	runpkg "github.com/gloo-foo/vsl/internal/container/run"
+)
+
+// extractInputs extracts the inputs block of a script, sorted by name:
+//
+//	inputs {
+//	  package {
+//	    required true
+//	    description Package to test
+//	  }
+//	  count {
+//	    type int
+//	    default 1
+//	  }
+//	}
+func extractInputs(value up.Value) ([]runpkg.Input, error) {
+	block, ok := value.(up.Block)
+	if !ok {
+		return nil, fmt.Errorf("expected a block of input names")
+	}
+	inputs := make([]runpkg.Input, 0, len(block))
+	for name, v := range block {
+		fields, ok := v.(up.Block)
+		if !ok {
+			return nil, fmt.Errorf("%s: expected a block", name)
+		}
+		input := runpkg.Input{Name: name, Type: runpkg.InputString}
+		for key, field := range fields {
+			scalar, ok := field.(string)
+			if !ok {
+				return nil, fmt.Errorf("%s: %s: expected a value", name, key)
+			}
+			switch key {
+			case "type":
+				input.Type = runpkg.InputType(scalar)
+			case "default":
+				input.Default = scalar
+			case "required":
+				required, err := parseBool(scalar)
+				if err != nil {
+					return nil, fmt.Errorf("%s: %s: %w", name, key, err)
+				}
+				input.Required = required
+			case "description":
+				input.Description = scalar
+			default:
+				return nil, fmt.Errorf("%s: %s: unknown key (expected type, default, required or description)", name, key)
+			}
+		}
+		switch input.Type {
+		case runpkg.InputString, runpkg.InputInt, runpkg.InputNumber, runpkg.InputBool:
+		default:
+			return nil, fmt.Errorf("%s: type: expected string, int, number or bool", name)
+		}
+		if input.Default != "" {
+			if err := input.Check(input.Default); err != nil {
+				return nil, fmt.Errorf("default of %w", err)
+			}
+		}
+		inputs = append(inputs, input)
+	}
+	sort.Slice(inputs, func(i, j int) bool { return inputs[i].Name < inputs[j].Name })
+	return inputs, nil
+}
+
+// WantsHelp reports whether the arguments of a script with inputs ask for
+// its help with -h or --help before "--".
+func WantsHelp(cfg *runpkg.Config, args []string) bool {
+	if len(cfg.Inputs) == 0 {
+		return false
+	}
+	for _, arg := range args {
+		switch arg {
+		case "--":
+			return false
+		case "-h", "--help":
+			return true
+		}
+	}
+	return false
+}
+
+// Usage returns the help of a script with inputs: how it is invoked, its
+// description and its inputs, those args names first in their positions.
+func Usage(path string, cfg *runpkg.Config) (string, error) {
+	description, err := ParseDescription(path)
+	if err != nil {
+		return "", err
+	}
+	inputs := slices.Clone(cfg.Inputs)
+	position := func(name string) int {
+		if i := slices.Index(cfg.ArgNames, name); i >= 0 {
+			return i
+		}
+		return len(cfg.ArgNames)
+	}
+	sort.SliceStable(inputs, func(i, j int) bool { return position(inputs[i].Name) < position(inputs[j].Name) })
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Usage: vsl run %s [inputs]", path)
+	for _, name := range cfg.ArgNames {
+		fmt.Fprintf(&b, " [%s]", name)
+	}
+	b.WriteString(" [-- args...]\n")
+	if description != "" {
+		fmt.Fprintf(&b, "\n%s\n", description)
+	}
+
+	b.WriteString("\nInputs:\n")
+	flags := make([]string, len(inputs))
+	width := 0
+	for i, input := range inputs {
+		flags[i] = "--" + input.Name
+		if input.Type != runpkg.InputBool {
+			flags[i] += " " + string(input.Type)
+		}
+		width = max(width, len(flags[i]))
+	}
+	for i, input := range inputs {
+		var notes []string
+		if input.Required && input.Default == "" {
+			notes = append(notes, "required")
+		}
+		if input.Default != "" {
+			notes = append(notes, fmt.Sprintf("default %q", input.Default))
+		}
+		if p := position(input.Name); p < len(cfg.ArgNames) {
+			notes = append(notes, fmt.Sprintf("argument %d", p+1))
+		}
+		line := input.Description
+		if len(notes) > 0 {
+			line = strings.TrimSpace(line + " (" + strings.Join(notes, ", ") + ")")
+		}
+		fmt.Fprintf(&b, "  %-*s  %s\n", width, flags[i], line)
+	}
+	return b.String(), nil
+}
@@ -5,125 +5,204 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
+	"slices"
+	"sort"
 	"strings"
 
 	"github.com/gloo-foo/vsl/internal/container"
 	runpkg "github.com/gloo-foo/vsl/internal/container/run"
+	"github.com/gloo-foo/vsl/internal/image"
+	"github.com/gloo-foo/vsl/internal/mount"
+	"github.com/gloo-foo/vsl/internal/secret"
+	"github.com/gloo-foo/vsl/internal/units"
 	up "github.com/uplang/go"
 )
 
-// ParseFile parses an UP script file and returns the configuration.
+// ParseFile parses an UP script file, with the fragments it includes, over
+// the defaults of the configuration files that apply in its directory, and
+// returns the configuration with the selected Profile and the when blocks
+// matching the host applied.
 func ParseFile(path string) (*runpkg.Config, error) {
-	file, err := os.Open(path)
+	return ParseFileProfile(path, Profile)
+}
+
+// ParseFileProfile parses an UP script file like ParseFile, applying the
+// given profile instead.
+func ParseFileProfile(path, profile string) (*runpkg.Config, error) {
+	key := parsedKey(path, profile)
+	if config, ok := cachedScript(key, filepath.Dir(path)); ok {
+		return config, nil
+	}
+
+	files := map[string]bool{}
+	defaults, err := defaultNodes(filepath.Dir(path), files)
 	if err != nil {
 		return nil, err
 	}
-	defer func(file *os.File) {
-		err := file.Close()
-		if err != nil {
-			panic(err)
-		}
-	}(file)
-
-	// Read and skip shebang
-	content, err := io.ReadAll(file)
+	nodes, err := includeNodes(path, nil, files)
 	if err != nil {
 		return nil, err
 	}
-
-	lines := strings.Split(string(content), "\n")
-	if len(lines) > 0 && strings.HasPrefix(lines[0], "#!") {
-		// Remove shebang line
-		content = []byte(strings.Join(lines[1:], "\n"))
+	nodes, profiled, err := applyProfile(append(defaults, nodes...), profile)
+	if err != nil {
+		return nil, err
+	}
+	// Scripts with when blocks depend on the host, not only on their files
+	cacheable := !slices.ContainsFunc(nodes, func(node sourcedNode) bool { return node.Key == whenKey })
+	if nodes, err = applyWhen(nodes); err != nil {
+		return nil, err
+	}
+	config, err := parseConfig(nodes)
+	if err != nil {
+		return nil, err
+	}
+	if profiled {
+		config.Profile = profile
+	}
+	if cacheable {
+		cacheScript(key, files, config)
 	}
+	return config, nil
+}
 
-	// Parse UP document
-	parser := up.NewParser()
-	doc, err := parser.ParseDocument(strings.NewReader(string(content)))
+// parseConfig converts the nodes of a script to its configuration.
+func parseConfig(nodes []sourcedNode) (*runpkg.Config, error) {
+	config, err := parseSettings(nodes)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse UP document: %w", err)
+		return nil, err
 	}
+	if config.Image == "" && config.Build == nil && !config.Host && !stepsComplete(config.Steps) {
+		return nil, fmt.Errorf("script must specify image or build, or set host")
+	}
+	return config, nil
+}
 
+// parseSettings converts nodes to the settings they make, which need not
+// describe a complete run.
+func parseSettings(nodes []sourcedNode) (*runpkg.Config, error) {
 	config := &runpkg.Config{
 		Command:     []container.Command{},
 		Entrypoint:  []container.Entrypoint{},
 		Environment: []container.Environment{},
 		Volumes:     []container.Volume{},
+		Mounts:      []container.MountSpec{},
+		Caches:      []container.CachePath{},
+		Masks:       []container.MaskPath{},
 	}
 
 	// Extract values from UP document
-	for _, node := range doc.Nodes {
-		switch node.Key {
-		case "image":
-			if scalar, ok := node.Value.(string); ok {
-				config.Image = container.Image(scalar)
-			}
-		case "command":
-			for _, cmd := range extractList(node.Value) {
-				config.Command = append(config.Command, container.Command(cmd))
-			}
-		case "entrypoint":
-			for _, ep := range extractList(node.Value) {
-				config.Entrypoint = append(config.Entrypoint, container.Entrypoint(ep))
-			}
-		case "workdir", "working_dir":
-			if scalar, ok := node.Value.(string); ok {
-				config.WorkingDir = container.WorkingDir(scalar)
-			}
-		case "env", "environment":
+	for _, node := range nodes {
+		var err error
+		key := canonicalKey(node.Key)
+		before := listLen(config, key)
+		switch key {
+		case "build":
+			config.Build, err = extractBuild(node.Value)
+		case "inputs":
+			config.Inputs, err = extractInputs(node.Value)
+		case "env":
 			for _, env := range extractEnvironment(node.Value) {
 				config.Environment = append(config.Environment, container.Environment(env))
 			}
-		case "volume", "volumes":
-			for _, vol := range extractList(node.Value) {
+		case "volume":
+			for _, vol := range extractVolumes(node.Value) {
 				config.Volumes = append(config.Volumes, container.Volume(vol))
 			}
-		case "user":
-			if scalar, ok := node.Value.(string); ok {
-				config.User = container.User(scalar)
-			}
-		case "interactive":
-			if scalar, ok := node.Value.(string); ok {
-				config.Interactive = string(scalar) == "true"
-			}
-		case "privileged":
-			if scalar, ok := node.Value.(string); ok {
-				config.Privileged = string(scalar) == "true"
-			}
-		case "network_mode":
-			if scalar, ok := node.Value.(string); ok {
-				config.NetworkMode = container.NetworkMode(scalar)
+		case "healthcheck":
+			config.Healthcheck, err = extractHealthcheck(node.Value)
+		case "before":
+			config.Before, err = extractHooks(node.Value)
+		case "after":
+			config.After, err = extractHooks(node.Value)
+		case "steps":
+			var steps []runpkg.Step
+			steps, err = extractSteps(node.Value)
+			config.Steps = append(config.Steps, steps...)
+		case "secret":
+			var refs []secret.Ref
+			refs, err = extractSecrets(node.Value, node.dir)
+			config.Secrets = append(config.Secrets, refs...)
+		default:
+			// Keys setting values and lists are decoded by the type of the
+			// field they set
+			if err = decodeKey(config, node.Key, node.Value); err != nil {
+				return nil, err
 			}
 		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", node.Key, err)
+		}
+		setOrigin(config, key, node.origin, before)
+	}
+	return config, nil
+}
+
+// parseDocument reads a script file, skipping its shebang line. Scripts in
+// other formats than UP are converted to UP nodes.
+func parseDocument(path string) (*up.Document, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func(file *os.File) {
+		err := file.Close()
+		if err != nil {
+			panic(err)
+		}
+	}(file)
+
+	// Read and skip shebang
+	content, err := io.ReadAll(file)
+	if err != nil {
+		return nil, err
 	}
 
-	if config.Image == "" {
-		return nil, fmt.Errorf("script must specify image")
+	if format := DetectFormat(path, content); format != FormatUP {
+		doc, _, err := decodeDocument(blankShebang(content), format)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s document: %w", strings.ToUpper(string(format)), err)
+		}
+		return doc, nil
 	}
 
-	return config, nil
+	lines := strings.Split(string(content), "\n")
+	if len(lines) > 0 && strings.HasPrefix(lines[0], "#!") {
+		// Remove shebang line
+		content = []byte(strings.Join(lines[1:], "\n"))
+	}
+
+	// Parse UP document
+	parser := up.NewParser()
+	doc, err := parser.ParseDocument(strings.NewReader(string(content)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse UP document: %w", err)
+	}
+	return doc, nil
 }
 
 func extractList(value up.Value) []string {
-	if list, ok := value.(up.List); ok {
-		result := make([]string, 0, len(list))
-		for _, item := range list {
-			if scalar, ok := item.(string); ok {
-				result = append(result, scalar)
-			}
+	list, err := listItems(value)
+	if err != nil {
+		return nil
+	}
+	result := make([]string, 0, len(list))
+	for _, item := range list {
+		if scalar, ok := item.(string); ok {
+			result = append(result, scalar)
 		}
-		return result
 	}
-	return nil
+	return result
 }
 
 func extractEnvironment(value up.Value) []string {
 	var result []string
 
 	switch v := value.(type) {
-	case up.List:
+	case up.List, string:
 		// Array form: ["KEY=value", "KEY2=value2"]
-		for _, item := range v {
+		list, _ := listItems(v)
+		for _, item := range list {
 			if scalar, ok := item.(string); ok {
 				result = append(result, scalar)
 			}
@@ -139,3 +218,234 @@ func extractEnvironment(value up.Value) []string {
 
 	return result
 }
+
+// extractBuild extracts the Dockerfile build of a script, given either as a
+// context directory or as a block such as
+// { context ., dockerfile tools/Dockerfile, target dev, args { GO_VERSION 1.22 } }.
+func extractBuild(value up.Value) (*image.BuildSpec, error) {
+	if scalar, ok := value.(string); ok {
+		return &image.BuildSpec{Context: scalar}, nil
+	}
+	block, ok := value.(up.Block)
+	if !ok {
+		return nil, fmt.Errorf("build must be a context directory or a block")
+	}
+
+	spec := &image.BuildSpec{Context: "."}
+	for key, val := range block {
+		scalar, _ := val.(string)
+		var err error
+		switch key {
+		case "context":
+			spec.Context = scalar
+		case "dockerfile":
+			spec.Dockerfile = scalar
+		case "target":
+			spec.Target = scalar
+		case "args":
+			args, ok := val.(up.Block)
+			if !ok {
+				return nil, fmt.Errorf("build: args must be a block")
+			}
+			spec.Args = make(map[string]string, len(args))
+			for name, arg := range args {
+				spec.Args[name], _ = arg.(string)
+			}
+		case "cache_from":
+			spec.CacheFrom = extractInlineList(val)
+		case "no_cache":
+			spec.NoCache, err = parseBool(val)
+		case "pull":
+			spec.Pull, err = parseBool(val)
+		default:
+			return nil, fmt.Errorf("build: unknown key %q", key)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("build: %s: %w", key, err)
+		}
+	}
+	return spec, nil
+}
+
+// extractSecrets extracts secret declarations, given one per line as
+// secret db_pass { backend vault, path kv/app#password } or as a block of
+// names, secrets { db_pass { backend vault, path kv/app#password } }. File
+// paths of backends such as sops resolve against dir.
+func extractSecrets(value up.Value, dir string) ([]secret.Ref, error) {
+	if scalar, ok := value.(string); ok {
+		name, rest, _ := strings.Cut(strings.TrimSpace(scalar), " ")
+		block, err := extractInlineBlock(rest)
+		if err != nil {
+			return nil, err
+		}
+		ref, err := secretFromBlock(name, block, dir)
+		if err != nil {
+			return nil, err
+		}
+		return []secret.Ref{ref}, nil
+	}
+
+	block, ok := value.(up.Block)
+	if !ok {
+		return nil, fmt.Errorf("expected name { backend ..., path ... } or a block of secrets")
+	}
+	names := make([]string, 0, len(block))
+	for name := range block {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	refs := make([]secret.Ref, 0, len(names))
+	for _, name := range names {
+		settings, ok := block[name].(up.Block)
+		if !ok {
+			return nil, fmt.Errorf("secret %s must be a block", name)
+		}
+		fields := make(map[string]string, len(settings))
+		for key, v := range settings {
+			fields[key], _ = v.(string)
+		}
+		ref, err := secretFromBlock(name, fields, dir)
+		if err != nil {
+			return nil, err
+		}
+		refs = append(refs, ref)
+	}
+	return refs, nil
+}
+
+// secretFromBlock builds the reference to secret name from its backend, path
+// and env keys, or from a source given as backend:path, such as
+// from env:GITHUB_TOKEN, from file:~/.config/app/token or
+// from cmd:pass show app/token.
+func secretFromBlock(name string, fields map[string]string, dir string) (secret.Ref, error) {
+	if name == "" {
+		return secret.Ref{}, fmt.Errorf("secret has no name")
+	}
+	ref := secret.Ref{Name: name, Dir: dir}
+	for key, value := range fields {
+		switch key {
+		case "backend":
+			ref.Backend = value
+		case "path":
+			ref.Path = value
+		case "from":
+			backend, path, ok := strings.Cut(value, ":")
+			if !ok {
+				return secret.Ref{}, fmt.Errorf("secret %s: from must be backend:path, such as env:NAME, file:path or cmd:command", name)
+			}
+			ref.Backend, ref.Path = backend, path
+		case "env":
+			ref.Env = value
+		default:
+			return secret.Ref{}, fmt.Errorf("secret %s: unknown key %q", name, key)
+		}
+	}
+	if ref.Backend == "" || ref.Path == "" {
+		return secret.Ref{}, fmt.Errorf("secret %s needs a backend and a path, or from", name)
+	}
+	return ref, nil
+}
+
+// extractInlineBlock parses a one-line block such as { key value, key value }.
+func extractInlineBlock(scalar string) (map[string]string, error) {
+	scalar = strings.TrimSpace(scalar)
+	if !strings.HasPrefix(scalar, "{") || !strings.HasSuffix(scalar, "}") {
+		return nil, fmt.Errorf("expected { key value, ... }, got %q", scalar)
+	}
+	fields := map[string]string{}
+	for _, item := range strings.Split(scalar[1:len(scalar)-1], ",") {
+		if item = strings.TrimSpace(item); item == "" {
+			continue
+		}
+		key, value, _ := strings.Cut(item, " ")
+		fields[key] = strings.TrimSpace(value)
+	}
+	return fields, nil
+}
+
+// extractVolumes extracts volume specifications from a list whose items are
+// either "source:target[:options]" strings or blocks such as
+// { source /src, target /dst, read_only true, propagation rshared, relabel z }.
+func extractVolumes(value up.Value) []string {
+	list, err := listItems(value)
+	if err != nil {
+		return nil
+	}
+
+	result := make([]string, 0, len(list))
+	for _, item := range list {
+		switch v := item.(type) {
+		case string:
+			result = append(result, v)
+		case up.Block:
+			result = append(result, volumeFromBlock(v))
+		}
+	}
+	return result
+}
+
+// volumeFromBlock converts a volume block into the colon-delimited volume syntax.
+func volumeFromBlock(block up.Block) string {
+	source, _ := block["source"].(string)
+	target, _ := block["target"].(string)
+
+	var options []string
+	if readOnly, err := parseBool(block["read_only"]); err == nil && readOnly {
+		options = append(options, "ro")
+	}
+	if propagation, ok := block["propagation"].(string); ok && propagation != "" {
+		options = append(options, propagation)
+	}
+	if relabel, ok := block["relabel"].(string); ok && relabel != "" {
+		options = append(options, relabel)
+	}
+
+	spec := mount.EscapeVolumePath(source) + ":" + mount.EscapeVolumePath(target)
+	if len(options) > 0 {
+		spec += ":" + strings.Join(options, ",")
+	}
+	return spec
+}
+
+// extractHealthcheck extracts a health check given as a shell command or as
+// a block such as { test pg_isready -U postgres, interval 2s, retries 30 },
+// where a test list is run without the shell.
+func extractHealthcheck(value up.Value) (*runpkg.Healthcheck, error) {
+	if scalar, ok := value.(string); ok {
+		return &runpkg.Healthcheck{Test: []string{scalar}}, nil
+	}
+	block, ok := value.(up.Block)
+	if !ok {
+		return nil, fmt.Errorf("expected a command or a block")
+	}
+	health := &runpkg.Healthcheck{}
+	for key, v := range block {
+		scalar, _ := v.(string)
+		var err error
+		switch key {
+		case "test":
+			if list, ok := v.(up.List); ok {
+				health.Test, health.Exec = extractList(list), true
+			} else {
+				health.Test = []string{scalar}
+			}
+		case "interval":
+			health.Interval, err = units.ParseDuration(scalar)
+		case "timeout":
+			health.Timeout, err = units.ParseDuration(scalar)
+		case "start_period":
+			health.StartPeriod, err = units.ParseDuration(scalar)
+		case "retries":
+			health.Retries, err = parseInt(v)
+		default:
+			err = fmt.Errorf("unknown key (expected test, interval, timeout, start_period or retries)")
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", key, err)
+		}
+	}
+	if len(health.Test) == 0 {
+		return nil, fmt.Errorf("test is required")
+	}
+	return health, nil
+}
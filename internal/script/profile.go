@@ -0,0 +1,124 @@
+package script
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	up "github.com/uplang/go"
+)
+
+// Profile is the profile scripts are parsed with, set from the global
+// flags. Scripts are parsed without their profiles when it is empty, and
+// scripts defining no profiles are parsed as they are.
+var Profile string
+
+// ErrUnknownProfile is returned when the selected profile is not defined by
+// the script.
+var ErrUnknownProfile = errors.New("unknown profile")
+
+// profilesKey holds the named profiles of a script, each a block of keys
+// overriding the script's when the profile is selected:
+//
+//	profiles {
+//	  ci {
+//	    image golang:1.22
+//	    cpus 2
+//	  }
+//	  debug {
+//	    env {
+//	      LOG_LEVEL debug
+//	    }
+//	  }
+//	}
+const profilesKey = "profiles"
+
+// additiveKeys are the keys whose entries a profile adds to the script's
+// instead of replacing them.
+var additiveKeys = map[string]bool{"env": true}
+
+// keyAliases maps alternative spellings of script keys to the one used to
+// tell which keys a profile replaces.
+var keyAliases = map[string]string{
+	"environment": "env",
+	"volumes":     "volume",
+	"working_dir": "workdir",
+	"secrets":     "secret",
+}
+
+// applyProfile removes the profiles from nodes and, when profile is set and
+// the script defines profiles, replaces the keys the selected profile sets
+// by its values, returning whether it did. Environment variables are added,
+// overriding those of the same name.
+func applyProfile(nodes []sourcedNode, profile string) ([]sourcedNode, bool, error) {
+	var (
+		kept     []sourcedNode
+		selected *sourcedNode
+		names    []string
+	)
+	for _, node := range nodes {
+		if node.Key != profilesKey {
+			kept = append(kept, node)
+			continue
+		}
+		profiles, ok := node.Value.(up.Block)
+		if !ok {
+			return nil, false, fmt.Errorf("profiles must be a block of profile names")
+		}
+		for name, value := range profiles {
+			names = append(names, name)
+			if name != profile {
+				continue
+			}
+			if _, ok := value.(up.Block); !ok {
+				return nil, false, fmt.Errorf("profile %s must be a block", name)
+			}
+			selected = &sourcedNode{Node: up.Node{Key: name, Value: value}, dir: node.dir, origin: node.origin}
+		}
+	}
+	if profile == "" || len(names) == 0 {
+		return kept, false, nil
+	}
+	if selected == nil {
+		sort.Strings(names)
+		return nil, false, fmt.Errorf("%w %s (defined: %s)", ErrUnknownProfile, profile, strings.Join(names, ", "))
+	}
+
+	return override(kept, selected.Value.(up.Block), *selected), true, nil
+}
+
+// override replaces the keys of nodes that overrides sets by its values,
+// read from the file of source, adding environment variables instead.
+func override(nodes []sourcedNode, overrides up.Block, source sourcedNode) []sourcedNode {
+	keys := make([]string, 0, len(overrides))
+	for key := range overrides {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	replaced := map[string]bool{}
+	for _, key := range keys {
+		if k := canonicalKey(key); !additiveKeys[k] {
+			replaced[k] = true
+		}
+	}
+	result := make([]sourcedNode, 0, len(nodes)+len(keys))
+	for _, node := range nodes {
+		if !replaced[canonicalKey(node.Key)] {
+			result = append(result, node)
+		}
+	}
+	for _, key := range keys {
+		result = append(result, sourcedNode{Node: up.Node{Key: key, Value: overrides[key]}, dir: source.dir, origin: source.origin})
+	}
+	return result
+}
+
+// canonicalKey returns the spelling of key used to compare keys.
+func canonicalKey(key string) string {
+	if alias, ok := keyAliases[key]; ok {
+		return alias
+	}
+	return key
+}
@@ -0,0 +1,117 @@
+package script
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	up "github.com/uplang/go"
+
+	"github.com/gloo-foo/vsl/internal/container"
+	"github.com/gloo-foo/vsl/internal/git"
+)
+
+// ProjectFile is the project configuration at the workspace root.
+const ProjectFile = ".vsl.up"
+
+// ProjectScripts are the scripts vsl run uses when given neither an image
+// nor a script, in the order they are looked for.
+var ProjectScripts = []string{
+	"vsl.up",
+	filepath.Join(".vsl", "default.up"),
+}
+
+// FindProjectScript returns the default script of the workspace containing
+// dir: in dir, or else at the root of its repository.
+func FindProjectScript(dir string) (string, bool) {
+	return findInWorkspace(dir, ProjectScripts)
+}
+
+// findInWorkspace returns the first of names found in dir, or else at the
+// root of its repository.
+func findInWorkspace(dir string, names []string) (string, bool) {
+	dirs := []string{dir}
+	if root, err := git.FindRoot(dir); err == nil && root != "" && string(root) != dir {
+		dirs = append(dirs, string(root))
+	}
+	for _, d := range dirs {
+		for _, name := range names {
+			path := filepath.Join(d, name)
+			if info, err := os.Stat(path); err == nil && !info.IsDir() {
+				return path, true
+			}
+		}
+	}
+	return "", false
+}
+
+// TaskDefaults are the profile, arguments and environment the project
+// configuration sets for a task, overlaid when the task is run by name.
+type TaskDefaults struct {
+	Profile string                  `json:"profile,omitempty"` // Applied unless --profile selects one
+	Args    []string                `json:"args,omitempty"`    // Used when the task is run without arguments
+	Env     []container.Environment `json:"env,omitempty"`     // Added after the script's environment
+}
+
+// ParseProject reads the per-task defaults of a project configuration, keyed
+// by task name:
+//
+//	tasks {
+//	  go/test {
+//	    profile ci
+//	    args [
+//	      -run
+//	      TestFast
+//	    ]
+//	    env {
+//	      DATABASE_URL postgres://localhost/test
+//	    }
+//	  }
+//	}
+func ParseProject(path string) (map[string]TaskDefaults, error) {
+	doc, err := parseDocument(path)
+	if err != nil {
+		return nil, err
+	}
+
+	defaults := map[string]TaskDefaults{}
+	for _, node := range doc.Nodes {
+		if node.Key != "tasks" {
+			continue
+		}
+		tasks, ok := node.Value.(up.Block)
+		if !ok {
+			return nil, fmt.Errorf("tasks must be a block of task names")
+		}
+		for name, value := range tasks {
+			block, ok := value.(up.Block)
+			if !ok {
+				return nil, fmt.Errorf("task %s: defaults must be a block", name)
+			}
+			var d TaskDefaults
+			for key, v := range block {
+				switch key {
+				case "profile":
+					profile, ok := v.(string)
+					if !ok || profile == "" {
+						return nil, fmt.Errorf("task %s: profile must be a profile name", name)
+					}
+					d.Profile = profile
+				case "args":
+					d.Args = extractList(v)
+				case "env":
+					env := extractEnvironment(v)
+					sort.Strings(env)
+					for _, e := range env {
+						d.Env = append(d.Env, container.Environment(e))
+					}
+				default:
+					return nil, fmt.Errorf("task %s: unknown key %q", name, key)
+				}
+			}
+			defaults[name] = d
+		}
+	}
+	return defaults, nil
+}
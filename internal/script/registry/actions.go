@@ -0,0 +1,200 @@
+package registry
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/gloo-foo/vsl/internal/script"
+)
+
+// InstallResult holds the result of installing a script.
+type InstallResult struct {
+	Success  bool   `json:"success"`
+	Name     string `json:"name"`
+	Source   string `json:"source"`
+	SHA256   string `json:"sha256,omitempty"`
+	Replaced bool   `json:"replaced,omitempty"` // A script was installed under the name before
+	Message  string `json:"message"`
+}
+
+// MarshalJSON implements json.Marshaler
+func (r InstallResult) MarshalJSON() ([]byte, error) {
+	type Alias InstallResult
+	return json.Marshal((Alias)(r))
+}
+
+// ListResult holds the installed scripts.
+type ListResult struct {
+	Success  bool    `json:"success"`
+	Registry string  `json:"registry"`
+	Scripts  []Entry `json:"scripts"`
+	Message  string  `json:"message"`
+}
+
+// MarshalJSON implements json.Marshaler
+func (r ListResult) MarshalJSON() ([]byte, error) {
+	type Alias ListResult
+	return json.Marshal((Alias)(r))
+}
+
+// RemoveResult holds the result of removing installed scripts.
+type RemoveResult struct {
+	Success bool     `json:"success"`
+	Removed []string `json:"removed"`
+	Message string   `json:"message"`
+}
+
+// MarshalJSON implements json.Marshaler
+func (r RemoveResult) MarshalJSON() ([]byte, error) {
+	type Alias RemoveResult
+	return json.Marshal((Alias)(r))
+}
+
+// Install records a script in the registry under a name. Local scripts are
+// recorded by their absolute path and run from where they are; remote
+// scripts are fetched once to check that they parse and pinned to the
+// sha256 of what was fetched, or of the given sum, so they never change
+// without being installed again. Unpinned remote scripts are fetched again
+// as they run.
+func Install(ctx context.Context, logger *slog.Logger, cfg InstallConfig) (InstallResult, error) {
+	source, file := cfg.Source, cfg.Source
+	remote, isRemote := script.ParseRemote(source)
+	if _, err := os.Stat(source); err == nil {
+		isRemote = false
+	}
+	name := cfg.Name
+	sum := strings.TrimPrefix(cfg.SHA256, "sha256:")
+	if isRemote {
+		if cfg.Unpinned && sum != "" {
+			return InstallResult{}, errors.New("--sha256 pins the script; it cannot be combined with --unpinned")
+		}
+		logger.Info("Fetching script", "source", source)
+		var err error
+		if file, err = remote.Fetch(ctx, sum); err != nil {
+			return InstallResult{}, err
+		}
+		if sum == "" && !cfg.Unpinned {
+			if sum, err = fileSHA256(file); err != nil {
+				return InstallResult{}, err
+			}
+			logger.Info("Pinned script", "source", source, "sha256", sum)
+		}
+		if name == "" {
+			name = path.Base(strings.SplitN(remote.URL, "?", 2)[0])
+			if remote.URL == "" {
+				name = path.Base(remote.Path)
+			}
+		}
+	} else {
+		if cfg.SHA256 != "" || cfg.Unpinned {
+			return InstallResult{}, errors.New("--sha256 and --unpinned apply to remote scripts only")
+		}
+		abs, err := filepath.Abs(source)
+		if err != nil {
+			return InstallResult{}, err
+		}
+		source, file = abs, abs
+		if name == "" {
+			name = filepath.Base(abs)
+		}
+	}
+	if cfg.Name == "" {
+		name = strings.TrimSuffix(name, path.Ext(name))
+	}
+	if !validName.MatchString(name) {
+		return InstallResult{}, fmt.Errorf("invalid name %q: use letters, digits, - and _", name)
+	}
+	if slices.Contains(cfg.Reserved, name) {
+		return InstallResult{}, fmt.Errorf("%s is a vsl command; choose another name with --name", name)
+	}
+	if _, err := script.ParseFile(file); err != nil {
+		return InstallResult{}, fmt.Errorf("%s is not a runnable script: %w", cfg.Source, err)
+	}
+
+	registry, err := Load()
+	if err != nil {
+		return InstallResult{}, err
+	}
+	previous, replaced := registry.Lookup(name)
+	if replaced && !cfg.Force && previous.Source != source {
+		return InstallResult{}, fmt.Errorf("%s is already installed from %s; use --force to replace it", name, previous.Source)
+	}
+	registry.Scripts[name] = Entry{Name: name, Source: source, SHA256: sum}
+	if err := registry.Save(); err != nil {
+		return InstallResult{}, err
+	}
+
+	logger.Info("Installed script", "name", name, "source", source)
+	return InstallResult{
+		Success:  true,
+		Name:     name,
+		Source:   source,
+		SHA256:   registry.Scripts[name].SHA256,
+		Replaced: replaced,
+		Message:  fmt.Sprintf("Installed %s; run it with vsl %s", source, name),
+	}, nil
+}
+
+// fileSHA256 returns the hex sha256 of the file at path.
+func fileSHA256(path string) (string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// List returns the installed scripts.
+func List(_ context.Context, _ *slog.Logger, _ ListConfig) (ListResult, error) {
+	registry, err := Load()
+	if err != nil {
+		return ListResult{}, err
+	}
+	entries := registry.Entries()
+	return ListResult{
+		Success:  true,
+		Registry: registry.Path,
+		Scripts:  entries,
+		Message:  fmt.Sprintf("%d scripts installed", len(entries)),
+	}, nil
+}
+
+// Remove removes scripts from the registry. The scripts themselves are left
+// in place.
+func Remove(_ context.Context, logger *slog.Logger, cfg RemoveConfig) (RemoveResult, error) {
+	if len(cfg.Names) == 0 {
+		return RemoveResult{}, errors.New("the name of an installed script is required")
+	}
+	registry, err := Load()
+	if err != nil {
+		return RemoveResult{}, err
+	}
+	for _, name := range cfg.Names {
+		if _, ok := registry.Lookup(name); !ok {
+			return RemoveResult{}, fmt.Errorf("%w as %s", ErrUnknownScript, name)
+		}
+	}
+	for _, name := range cfg.Names {
+		logger.Info("Removing script", "name", name)
+		delete(registry.Scripts, name)
+	}
+	if err := registry.Save(); err != nil {
+		return RemoveResult{}, err
+	}
+	return RemoveResult{
+		Success: true,
+		Removed: cfg.Names,
+		Message: fmt.Sprintf("Removed %d scripts", len(cfg.Names)),
+	}, nil
+}
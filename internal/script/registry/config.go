@@ -0,0 +1,45 @@
+package registry
+
+import (
+	"github.com/gloo-foo/vsl/internal/app"
+	"github.com/gloo-foo/vsl/internal/app/log"
+)
+
+// InstallConfig holds configuration for installing a script.
+type InstallConfig struct {
+	Source   string   // Path, URL or repository path of the script
+	Name     string   // Name the script is run by; the script's file name without extension by default
+	SHA256   string   // sha256 a remote script must have; the sum of the script fetched by default
+	Unpinned bool     // Fetch a remote script again each time it runs instead of pinning it
+	Force    bool     // Replace a script installed under the same name
+	Reserved []string // Names of vsl's commands, which scripts cannot be installed under
+
+	// Output and logging
+	Output  app.FilePath
+	Logging log.Config
+}
+
+func (c InstallConfig) OutputFilePath() app.FilePath { return c.Output }
+func (c InstallConfig) LoggerConfig() log.Config     { return c.Logging }
+
+// ListConfig holds configuration for listing installed scripts.
+type ListConfig struct {
+	// Output and logging
+	Output  app.FilePath
+	Logging log.Config
+}
+
+func (c ListConfig) OutputFilePath() app.FilePath { return c.Output }
+func (c ListConfig) LoggerConfig() log.Config     { return c.Logging }
+
+// RemoveConfig holds configuration for removing installed scripts.
+type RemoveConfig struct {
+	Names []string // Names of the scripts removed
+
+	// Output and logging
+	Output  app.FilePath
+	Logging log.Config
+}
+
+func (c RemoveConfig) OutputFilePath() app.FilePath { return c.Output }
+func (c RemoveConfig) LoggerConfig() log.Config     { return c.Logging }
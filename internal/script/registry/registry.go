@@ -0,0 +1,102 @@
+// Package registry keeps the scripts a user installs under names of their
+// own, which vsl then runs like its built-in commands.
+package registry
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+
+	"github.com/gloo-foo/vsl/internal/state"
+)
+
+// FileName is the registry inside the vsl config directory.
+const FileName = "scripts.json"
+
+// ErrUnknownScript is returned when no script is installed under a name.
+var ErrUnknownScript = errors.New("no script installed")
+
+// validName matches the names scripts can be installed under.
+var validName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_-]*$`)
+
+// Entry is an installed script.
+type Entry struct {
+	Name   string `json:"name"`
+	Source string `json:"source"`           // Absolute path of a local script, or the URL or repository path of a remote one
+	SHA256 string `json:"sha256,omitempty"` // sha256 the remote script must have
+}
+
+// RunArgs returns the arguments of vsl running the script.
+func (e Entry) RunArgs() []string {
+	args := []string{"run"}
+	if e.SHA256 != "" {
+		args = append(args, "--sha256", e.SHA256)
+	}
+	return append(args, e.Source)
+}
+
+// Registry holds the installed scripts by name.
+type Registry struct {
+	Path    string
+	Scripts map[string]Entry
+}
+
+// registryFile is the on-disk form of the registry.
+type registryFile struct {
+	Scripts []Entry `json:"scripts"`
+}
+
+// Load reads the registry, which is empty until a script is installed.
+func Load() (*Registry, error) {
+	path, err := state.ConfigFile(FileName)
+	if err != nil {
+		return nil, err
+	}
+	r := &Registry{Path: path, Scripts: map[string]Entry{}}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return r, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read script registry: %w", err)
+	}
+	var file registryFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to decode script registry %s: %w", path, err)
+	}
+	for _, entry := range file.Scripts {
+		r.Scripts[entry.Name] = entry
+	}
+	return r, nil
+}
+
+// Lookup returns the script installed under name.
+func (r *Registry) Lookup(name string) (Entry, bool) {
+	entry, ok := r.Scripts[name]
+	return entry, ok
+}
+
+// Entries returns the installed scripts sorted by name.
+func (r *Registry) Entries() []Entry {
+	entries := make([]Entry, 0, len(r.Scripts))
+	for _, entry := range r.Scripts {
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name < entries[j].Name })
+	return entries
+}
+
+// Save writes the registry.
+func (r *Registry) Save() error {
+	if _, err := state.ConfigDir(); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(registryFile{Scripts: r.Entries()}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(r.Path, append(data, '\n'), 0o600)
+}
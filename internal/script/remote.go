@@ -0,0 +1,182 @@
+package script
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/gloo-foo/vsl/internal/git"
+	"github.com/gloo-foo/vsl/internal/state"
+)
+
+// remoteDir is the directory of the vsl cache holding fetched scripts.
+const remoteDir = "scripts"
+
+// ErrChecksumMismatch is returned when a fetched script does not have the
+// sha256 it was expected to.
+var ErrChecksumMismatch = errors.New("checksum mismatch")
+
+// unsafeNameChars are replaced in the names of cached scripts.
+var unsafeNameChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// Remote is a script fetched before it runs: the URL of the file, or a
+// path in a git repository given as host/org/repo//path@ref.
+type Remote struct {
+	URL  string // http(s) URL of the script, when fetched over HTTP
+	Repo string // URL of the repository holding the script
+	Path string // Path of the script in the repository
+	Ref  string // Branch, tag or commit of the repository, the remote HEAD when empty
+}
+
+// ParseRemote returns the remote script ref names, reporting whether it
+// names one rather than a local file.
+func ParseRemote(ref string) (Remote, bool) {
+	if strings.HasPrefix(ref, "https://") || strings.HasPrefix(ref, "http://") {
+		return Remote{URL: ref}, true
+	}
+	repo, file, ok := strings.Cut(ref, "//")
+	host, _, _ := strings.Cut(repo, "/")
+	if !ok || !strings.Contains(host, ".") || file == "" || strings.HasPrefix(ref, ".") {
+		return Remote{}, false
+	}
+	r := Remote{Repo: "https://" + strings.TrimSuffix(repo, "/"), Path: file}
+	if i := strings.LastIndex(file, "@"); i >= 0 {
+		r.Path, r.Ref = file[:i], file[i+1:]
+	}
+	return r, r.Path != ""
+}
+
+// String returns the reference the remote script was parsed from.
+func (r Remote) String() string {
+	if r.URL != "" {
+		return r.URL
+	}
+	s := strings.TrimPrefix(r.Repo, "https://") + "//" + r.Path
+	if r.Ref != "" {
+		s += "@" + r.Ref
+	}
+	return s
+}
+
+// Fetch fetches the remote script into the vsl cache and returns the path
+// of its copy. When sum, the hex sha256 of the script, is set the script
+// must match it, and a cached copy that does is used without fetching
+// again. Scripts in repositories are checked out with their repository, so
+// the fragments they include and the scripts they extend are found.
+func (r Remote) Fetch(ctx context.Context, sum string) (string, error) {
+	cache, err := state.CacheDir()
+	if err != nil {
+		return "", err
+	}
+	key := sha256.Sum256([]byte(r.String()))
+	dir := filepath.Join(cache, remoteDir, hex.EncodeToString(key[:])[:16])
+	sum = strings.ToLower(strings.TrimPrefix(sum, "sha256:"))
+
+	var file string
+	if r.URL != "" {
+		file, err = r.download(ctx, dir, sum)
+	} else {
+		file, err = r.checkout(ctx, dir)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch %s: %w", r, err)
+	}
+	if err := verify(file, sum); err != nil {
+		return "", fmt.Errorf("%s: %w", r, err)
+	}
+	return file, nil
+}
+
+// download fetches the script over HTTP into dir, unless a copy matching
+// sum is already there.
+func (r Remote) download(ctx context.Context, dir, sum string) (string, error) {
+	name := unsafeNameChars.ReplaceAllString(path.Base(strings.SplitN(r.URL, "?", 2)[0]), "_")
+	if name == "" || name == "." || name == "_" {
+		name = "script.up"
+	}
+	file := filepath.Join(dir, name)
+	if sum != "" && verify(file, sum) == nil {
+		return file, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, r.URL, nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return "", err
+	}
+	tmp, err := os.CreateTemp(dir, name+".*")
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+	if _, err := io.Copy(tmp, resp.Body); err != nil {
+		_ = tmp.Close()
+		return "", err
+	}
+	if err := tmp.Close(); err != nil {
+		return "", err
+	}
+	// A copy not matching sum never replaces the cached one
+	if err := verify(tmp.Name(), sum); err != nil {
+		return "", err
+	}
+	return file, os.Rename(tmp.Name(), file)
+}
+
+// checkout checks out the script's repository into dir.
+func (r Remote) checkout(ctx context.Context, dir string) (string, error) {
+	if !git.Available() {
+		return "", git.ErrNotInstalled
+	}
+	_, statErr := os.Stat(dir)
+	if err := git.ShallowCheckout(ctx, dir, r.Repo, r.Ref); err != nil {
+		if os.IsNotExist(statErr) {
+			_ = os.RemoveAll(dir)
+		}
+		return "", err
+	}
+	file := filepath.Join(dir, filepath.FromSlash(r.Path))
+	if rel, err := filepath.Rel(dir, file); err != nil || strings.HasPrefix(rel, "..") {
+		return "", fmt.Errorf("path %s is outside the repository", r.Path)
+	}
+	if _, err := os.Stat(file); err != nil {
+		return "", fmt.Errorf("no script %s in the repository", r.Path)
+	}
+	return file, nil
+}
+
+// verify checks that the file at path has the hex sha256 sum, when set.
+func verify(file, sum string) error {
+	if sum == "" {
+		return nil
+	}
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	actual := sha256.Sum256(content)
+	if got := hex.EncodeToString(actual[:]); got != sum {
+		return fmt.Errorf("%w: expected sha256 %s, got %s", ErrChecksumMismatch, sum, got)
+	}
+	return nil
+}
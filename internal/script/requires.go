@@ -0,0 +1,179 @@
+package script
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	up "github.com/uplang/go"
+)
+
+// requiresKey declares the vsl version and features a script needs, checked
+// before any of its other keys are read so older versions refuse it instead
+// of misreading it:
+//
+//	requires {
+//	  vsl >=0.5
+//	  features [gpu, secrets]
+//	}
+const requiresKey = "requires"
+
+// Version is the version of the running vsl, set at startup. Scripts
+// requiring a version are not checked against builds without a release
+// version.
+var Version string
+
+// ErrUnsupported is returned when a script requires a newer vsl or a
+// feature this one lacks.
+var ErrUnsupported = errors.New("unsupported script")
+
+// Features are the features scripts can require.
+var Features = []string{
+	"args",
+	"build",
+	"buildkit",
+	"caches",
+	"extends",
+	"gpu",
+	"healthcheck",
+	"hooks",
+	"include",
+	"inputs",
+	"kata",
+	"profiles",
+	"secrets",
+	"services",
+	"snapshot",
+	"steps",
+	"tests",
+	"when",
+}
+
+// checkRequires checks the requires block of a script against this vsl.
+func checkRequires(value up.Value) error {
+	block, ok := value.(up.Block)
+	if !ok {
+		return fmt.Errorf("requires must be a block of vsl and features")
+	}
+	for _, key := range sortedKeys(block) {
+		switch key {
+		case "vsl":
+			constraint, ok := block[key].(string)
+			if !ok {
+				return fmt.Errorf("requires vsl: expected a version constraint such as >=0.5")
+			}
+			if unquoted, err := strconv.Unquote(constraint); err == nil {
+				constraint = unquoted
+			}
+			if err := checkVersion(constraint, Version); err != nil {
+				return err
+			}
+		case "features":
+			var missing []string
+			for _, feature := range extractInlineList(block[key]) {
+				i := sort.SearchStrings(Features, feature)
+				if i == len(Features) || Features[i] != feature {
+					missing = append(missing, feature)
+				}
+			}
+			if len(missing) > 0 {
+				return fmt.Errorf("%w: requires features %s, which vsl %s does not support (supported: %s)",
+					ErrUnsupported, strings.Join(missing, ", "), versionName(), strings.Join(Features, ", "))
+			}
+		default:
+			return fmt.Errorf("requires %s: unknown key (expected vsl or features)", key)
+		}
+	}
+	return nil
+}
+
+// checkVersion checks that version meets constraint, a comma-separated list
+// of versions each prefixed by =, >, >=, < or <=; a bare version is a
+// minimum.
+func checkVersion(constraint, version string) error {
+	current, known := parseVersion(version)
+	for _, c := range strings.Split(constraint, ",") {
+		c = strings.TrimSpace(c)
+		rest := strings.TrimLeft(c, "<>=")
+		op := c[:len(c)-len(rest)]
+		want, ok := parseVersion(rest)
+		if !ok {
+			return fmt.Errorf("requires vsl: invalid version constraint %q (expected e.g. >=0.5)", c)
+		}
+		if !known {
+			continue
+		}
+		cmp := compareVersions(current, want)
+		var met bool
+		switch op {
+		case "", ">=":
+			met = cmp >= 0
+		case ">":
+			met = cmp > 0
+		case "<=":
+			met = cmp <= 0
+		case "<":
+			met = cmp < 0
+		case "=", "==":
+			met = cmp == 0
+		default:
+			return fmt.Errorf("requires vsl: invalid operator %q (expected =, >, >=, < or <=)", op)
+		}
+		if !met {
+			return fmt.Errorf("%w: requires vsl %s, this is vsl %s", ErrUnsupported, constraint, version)
+		}
+	}
+	return nil
+}
+
+// parseVersion reads the numbers of a version such as v1.2.3, ignoring any
+// pre-release or build suffix.
+func parseVersion(s string) ([]int, bool) {
+	s = strings.TrimPrefix(strings.TrimSpace(s), "v")
+	if i := strings.IndexAny(s, "-+"); i >= 0 {
+		s = s[:i]
+	}
+	if s == "" {
+		return nil, false
+	}
+	var parts []int
+	for _, field := range strings.Split(s, ".") {
+		n, err := strconv.Atoi(field)
+		if err != nil || n < 0 {
+			return nil, false
+		}
+		parts = append(parts, n)
+	}
+	return parts, true
+}
+
+// compareVersions compares two versions part by part, missing parts being
+// zero.
+func compareVersions(a, b []int) int {
+	for i := 0; i < max(len(a), len(b)); i++ {
+		var x, y int
+		if i < len(a) {
+			x = a[i]
+		}
+		if i < len(b) {
+			y = b[i]
+		}
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+// versionName returns the version of the running vsl for messages.
+func versionName() string {
+	if Version == "" {
+		return "(development build)"
+	}
+	return Version
+}
@@ -0,0 +1,20 @@
+package scaffold
+
+import (
+	"github.com/gloo-foo/vsl/internal/app"
+	"github.com/gloo-foo/vsl/internal/app/log"
+)
+
+// Config holds configuration for writing a starter UP script.
+type Config struct {
+	Template string // Built-in template to write; detected from the project files when empty
+	File     string // Script written, <template>.up in the current directory when empty
+	Force    bool   // Overwrite an existing script
+
+	// Output and logging
+	Output  app.FilePath
+	Logging log.Config
+}
+
+func (c Config) OutputFilePath() app.FilePath { return c.Output }
+func (c Config) LoggerConfig() log.Config     { return c.Logging }
@@ -0,0 +1,134 @@
+// Package scaffold writes starter UP scripts from built-in templates for
+// common stacks, detected from the files of the project.
+package scaffold
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/gloo-foo/vsl/internal/git"
+)
+
+// ErrNoTemplate is returned when no template is given and none is detected.
+var ErrNoTemplate = errors.New("no template detected")
+
+// Result holds the script written.
+type Result struct {
+	Success  bool   `json:"success"`
+	Template string `json:"template"`
+	Detected string `json:"detected,omitempty"` // Marker file the template was detected from
+	File     string `json:"file"`
+	Message  string `json:"message"`
+	Error    string `json:"error,omitempty"`
+}
+
+// MarshalJSON implements json.Marshaler
+func (r Result) MarshalJSON() ([]byte, error) {
+	type Alias Result
+	return json.Marshal((Alias)(r))
+}
+
+// Failed implements app.Failable
+func (r Result) Failed(err error) json.Marshaler {
+	r.Success = false
+	r.Error = err.Error()
+	return r
+}
+
+// Init writes a starter script into the current directory, from the named
+// template or the first whose marker file is found in the current directory
+// or at the root of its repository. Existing scripts are kept unless Force
+// is set.
+func Init(_ context.Context, logger *slog.Logger, cfg Config) (Result, error) {
+	result := Result{Template: cfg.Template}
+
+	var template *Template
+	if cfg.Template != "" {
+		if template = lookup(cfg.Template); template == nil {
+			return result, fmt.Errorf("unknown template %s (available: %s)", cfg.Template, strings.Join(names(), ", "))
+		}
+	} else {
+		pwd, err := os.Getwd()
+		if err != nil {
+			return result, err
+		}
+		template, result.Detected = detect(pwd)
+		if template == nil {
+			return result, fmt.Errorf("%w: name one of %s", ErrNoTemplate, strings.Join(names(), ", "))
+		}
+		logger.Info("Detected template", "template", template.Name, "marker", result.Detected)
+	}
+	result.Template = template.Name
+
+	result.File = cfg.File
+	if result.File == "" {
+		result.File = template.Name + ".up"
+	}
+	flags := os.O_WRONLY | os.O_CREATE | os.O_EXCL
+	if cfg.Force {
+		flags = os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	}
+	file, err := os.OpenFile(result.File, flags, 0o755)
+	if errors.Is(err, os.ErrExist) {
+		return result, fmt.Errorf("%s already exists, pass --force to overwrite it", result.File)
+	}
+	if err != nil {
+		return result, err
+	}
+	if _, err := file.WriteString(template.Script); err != nil {
+		_ = file.Close()
+		return result, err
+	}
+	if err := file.Close(); err != nil {
+		return result, err
+	}
+
+	result.Success = true
+	result.Message = fmt.Sprintf("Wrote %s from the %s template; run it with ./%s", result.File, template.Name, result.File)
+	return result, nil
+}
+
+// detect returns the first template whose marker is found in dir or at the
+// root of its repository, with the marker's path.
+func detect(dir string) (*Template, string) {
+	dirs := []string{dir}
+	if root, err := git.FindRoot(dir); err == nil && root != "" && string(root) != dir {
+		dirs = append(dirs, string(root))
+	}
+	for _, d := range dirs {
+		for i := range Templates {
+			for _, marker := range Templates[i].Markers {
+				path := filepath.Join(d, marker)
+				if _, err := os.Stat(path); err == nil {
+					return &Templates[i], path
+				}
+			}
+		}
+	}
+	return nil, ""
+}
+
+// lookup returns the template named name.
+func lookup(name string) *Template {
+	for i := range Templates {
+		if Templates[i].Name == name {
+			return &Templates[i]
+		}
+	}
+	return nil
+}
+
+// names returns the names of the templates.
+func names() []string {
+	result := make([]string, 0, len(Templates))
+	for _, t := range Templates {
+		result = append(result, t.Name)
+	}
+	return result
+}
@@ -0,0 +1,97 @@
+package scaffold
+
+// Template is a starter script for a stack, used when one of its marker
+// files is found in the current directory or at the repository root.
+type Template struct {
+	Name    string
+	Markers []string
+	Script  string
+}
+
+// Templates are the built-in templates, in detection order.
+var Templates = []Template{
+	{
+		Name:    "go",
+		Markers: []string{"go.mod", "go.work"},
+		Script: `#!/usr/bin/env vsl
+
+description Test the Go module
+image golang:1.22
+caches [
+  /root/.cache/go-build
+  /go/pkg/mod
+]
+env {
+  CGO_ENABLED 0
+}
+command [
+  go
+  test
+  ./...
+]
+`,
+	},
+	{
+		Name:    "node",
+		Markers: []string{"package.json"},
+		Script: `#!/usr/bin/env vsl
+
+description Install dependencies and test the Node.js project
+image node:20
+caches [
+  node_modules
+  /root/.npm
+]
+env {
+  NODE_ENV development
+}
+command [
+  sh
+  -c
+  npm ci && npm test
+]
+`,
+	},
+	{
+		Name:    "python",
+		Markers: []string{"pyproject.toml", "requirements.txt", "setup.py", "Pipfile"},
+		Script: `#!/usr/bin/env vsl
+
+description Install dependencies and test the Python project
+image python:3.12
+caches [
+  /root/.cache/pip
+]
+env {
+  PYTHONUNBUFFERED 1
+  PIP_DISABLE_PIP_VERSION_CHECK 1
+}
+command [
+  sh
+  -c
+  pip install -r requirements.txt && python -m pytest
+]
+`,
+	},
+	{
+		Name:    "rust",
+		Markers: []string{"Cargo.toml"},
+		Script: `#!/usr/bin/env vsl
+
+description Test the Rust crate
+image rust:1
+caches [
+  /usr/local/cargo/registry
+  /usr/local/cargo/git
+  target
+]
+env {
+  CARGO_TERM_COLOR always
+}
+command [
+  cargo
+  test
+]
+`,
+	},
+}
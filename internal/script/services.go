@@ -0,0 +1,115 @@
+package script
+
+import (
+	"fmt"
+	"sort"
+
+	up "github.com/uplang/go"
+
+	runpkg "github.com/gloo-foo/vsl/internal/container/run"
+)
+
+// servicesKey holds the containers a script starts together with vsl up,
+// keyed by service name. A service block takes any script key, overriding
+// the script's like a profile, and depends_on to list the services that
+// must be healthy before it starts:
+//
+//	services {
+//	  db {
+//	    image postgres:16
+//	    healthcheck pg_isready -U postgres
+//	  }
+//	  app {
+//	    image ghcr.io/example/app
+//	    depends_on [db]
+//	    ports [8080:8080]
+//	  }
+//	}
+const servicesKey = "services"
+
+// Service is a container of a script's services block.
+type Service struct {
+	Name      string
+	DependsOn []string
+	Config    runpkg.Config
+}
+
+// ParseServices parses the services declared in an UP script file, ordered
+// by name, with the selected Profile applied. Scripts without a services
+// block have no services.
+func ParseServices(path string) ([]Service, error) {
+	nodes, err := parseNodes(path)
+	if err != nil {
+		return nil, err
+	}
+	nodes, profiled, err := applyProfile(nodes, Profile)
+	if err != nil {
+		return nil, err
+	}
+	if nodes, err = applyWhen(nodes); err != nil {
+		return nil, err
+	}
+
+	// Services share the script's other keys, but not its steps
+	var base, declared []sourcedNode
+	for _, node := range nodes {
+		switch node.Key {
+		case servicesKey:
+			declared = append(declared, node)
+		case "steps":
+		default:
+			base = append(base, node)
+		}
+	}
+
+	var services []Service
+	for _, node := range declared {
+		block, ok := node.Value.(up.Block)
+		if !ok {
+			return nil, fmt.Errorf("services must be a block of service names")
+		}
+		for name, value := range block {
+			serviceBlock, ok := value.(up.Block)
+			if !ok {
+				return nil, fmt.Errorf("service %s must be a block", name)
+			}
+			service, err := parseService(name, serviceBlock, base, node.dir)
+			if err != nil {
+				return nil, fmt.Errorf("service %s: %w", name, err)
+			}
+			if profiled {
+				service.Config.Profile = Profile
+			}
+			services = append(services, service)
+		}
+	}
+
+	sort.Slice(services, func(i, j int) bool { return services[i].Name < services[j].Name })
+	return services, nil
+}
+
+// parseService converts a service block over the script's other nodes.
+func parseService(name string, block up.Block, base []sourcedNode, dir string) (Service, error) {
+	service := Service{Name: name}
+	overrides := up.Block{}
+	for key, value := range block {
+		if key == "depends_on" {
+			service.DependsOn = extractInlineList(value)
+			continue
+		}
+		overrides[key] = value
+	}
+
+	cfg, err := parseConfig(override(base, overrides, sourcedNode{dir: dir}))
+	if err != nil {
+		return service, err
+	}
+	if cfg.Image == "" && cfg.Build == nil {
+		return service, fmt.Errorf("must specify image or build")
+	}
+	if cfg.Host {
+		return service, fmt.Errorf("services run in containers and cannot set host")
+	}
+	service.Config = *cfg
+	return service, nil
+}
@@ -0,0 +1,119 @@
+package script
+
+import (
+	"fmt"
+
+	up "github.com/uplang/go"
+
+	"github.com/gloo-foo/vsl/internal/container"
+	runpkg "github.com/gloo-foo/vsl/internal/container/run"
+)
+
+// extractSteps converts a steps list, each step a block:
+//
+//	steps [
+//	  {
+//	    name lint
+//	    image golangci/golangci-lint
+//	    command [golangci-lint, run]
+//	  }
+//	  {
+//	    name test
+//	    command [go, test, ./...]
+//	    continue_on_error true
+//	  }
+//	  {
+//	    name notarize
+//	    host true
+//	    command [xcrun, notarytool, submit, dist/app.zip]
+//	    when {
+//	      os darwin
+//	    }
+//	  }
+//	]
+//
+// Steps whose when conditions do not hold on this host are left out.
+func extractSteps(value up.Value) ([]runpkg.Step, error) {
+	list, ok := value.(up.List)
+	if !ok {
+		return nil, fmt.Errorf("steps must be a list of step blocks")
+	}
+	steps := make([]runpkg.Step, 0, len(list))
+	for i, item := range list {
+		block, ok := item.(up.Block)
+		if !ok {
+			return nil, fmt.Errorf("step %d must be a block", i+1)
+		}
+		step, matched, err := parseStep(block)
+		if err != nil {
+			return nil, fmt.Errorf("step %d: %w", i+1, err)
+		}
+		if matched {
+			steps = append(steps, step)
+		}
+	}
+	return steps, nil
+}
+
+// parseStep converts a step block, reporting whether its when conditions
+// hold.
+func parseStep(block up.Block) (runpkg.Step, bool, error) {
+	var step runpkg.Step
+	matched := true
+	for key, value := range block {
+		scalar, _ := value.(string)
+		switch key {
+		case "name":
+			step.Name = scalar
+		case "image":
+			step.Image = container.Image(scalar)
+		case "command":
+			for _, c := range extractInlineList(value) {
+				step.Command = append(step.Command, container.Command(c))
+			}
+		case "entrypoint":
+			for _, e := range extractInlineList(value) {
+				step.Entrypoint = append(step.Entrypoint, container.Entrypoint(e))
+			}
+		case "env", "environment":
+			for _, e := range extractEnvironment(value) {
+				step.Environment = append(step.Environment, container.Environment(e))
+			}
+		case "workdir", "working_dir":
+			step.WorkingDir = container.WorkingDir(scalar)
+		case "host", "continue_on_error":
+			b, err := parseBool(value)
+			if err != nil {
+				return step, false, fmt.Errorf("%s: %w", key, err)
+			}
+			if key == "host" {
+				step.Host = b
+			} else {
+				step.ContinueOnError = b
+			}
+		case whenKey:
+			conditions, ok := value.(up.Block)
+			if !ok {
+				return step, false, fmt.Errorf("when: expected a block of conditions")
+			}
+			var err error
+			if matched, err = matches(conditions); err != nil {
+				return step, false, fmt.Errorf("when: %w", err)
+			}
+		default:
+			return step, false, fmt.Errorf("unknown key %q (expected name, image, command, entrypoint, env, workdir, host, continue_on_error or when)", key)
+		}
+	}
+	return step, matched, nil
+}
+
+// stepsComplete reports whether steps are declared and each names its image
+// or runs on the host, so the script needs no image of its own.
+func stepsComplete(steps []runpkg.Step) bool {
+	for _, step := range steps {
+		if step.Image == "" && !step.Host {
+			return false
+		}
+	}
+	return len(steps) > 0
+}
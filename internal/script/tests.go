@@ -0,0 +1,117 @@
+package script
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	up "github.com/uplang/go"
+)
+
+// Test is a test case declared in a script's tests block:
+//
+//	tests {
+//	  greets {
+//	    args [
+//	      world
+//	    ]
+//	    exit_code 0
+//	    stdout ^hello world
+//	    files [
+//	      out/greeting.txt
+//	    ]
+//	  }
+//	}
+type Test struct {
+	Name     string         // Case name, the key in the tests block
+	Args     []string       // Script arguments for the run
+	ExitCode int            // Expected exit code (default 0)
+	Stdout   *regexp.Regexp // Pattern the container's stdout must match
+	Stderr   *regexp.Regexp // Pattern the container's stderr must match
+	Files    []string       // Files the run must produce, relative to the current directory
+}
+
+// ParseTests parses the test cases declared in an UP script file, ordered by name.
+// Scripts without a tests block have no test cases.
+func ParseTests(path string) ([]Test, error) {
+	doc, err := parseDocument(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var tests []Test
+	for _, node := range doc.Nodes {
+		if node.Key != "tests" {
+			continue
+		}
+		block, ok := node.Value.(up.Block)
+		if !ok {
+			return nil, fmt.Errorf("tests must be a block of named test cases")
+		}
+		for name, value := range block {
+			caseBlock, ok := value.(up.Block)
+			if !ok {
+				return nil, fmt.Errorf("test %q must be a block", name)
+			}
+			test, err := parseTest(name, caseBlock)
+			if err != nil {
+				return nil, err
+			}
+			tests = append(tests, test)
+		}
+	}
+
+	sort.Slice(tests, func(i, j int) bool { return tests[i].Name < tests[j].Name })
+	return tests, nil
+}
+
+// parseTest converts a test case block, validating its assertions.
+func parseTest(name string, block up.Block) (Test, error) {
+	test := Test{
+		Name:  name,
+		Args:  extractInlineList(block["args"]),
+		Files: extractInlineList(block["files"]),
+	}
+
+	for key, value := range block {
+		scalar, _ := value.(string)
+		var err error
+		switch key {
+		case "args", "files":
+		case "exit_code":
+			test.ExitCode, err = parseInt(value)
+		case "stdout":
+			test.Stdout, err = regexp.Compile(scalar)
+		case "stderr":
+			test.Stderr, err = regexp.Compile(scalar)
+		default:
+			err = fmt.Errorf("unknown key (expected args, exit_code, stdout, stderr or files)")
+		}
+		if err != nil {
+			return test, fmt.Errorf("test %q: %s: %w", name, key, err)
+		}
+	}
+	return test, nil
+}
+
+// extractInlineList extracts a list that may also be written inline as
+// [a, b], which the UP parser keeps as a string inside blocks.
+func extractInlineList(value up.Value) []string {
+	scalar, ok := value.(string)
+	if !ok {
+		return extractList(value)
+	}
+	scalar = strings.TrimSpace(scalar)
+	if !strings.HasPrefix(scalar, "[") || !strings.HasSuffix(scalar, "]") {
+		return []string{scalar}
+	}
+
+	var result []string
+	for _, item := range strings.Split(scalar[1:len(scalar)-1], ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			result = append(result, item)
+		}
+	}
+	return result
+}
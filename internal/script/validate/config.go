@@ -0,0 +1,21 @@
+package validate
+
+import (
+	"github.com/gloo-foo/vsl/internal/app"
+	"github.com/gloo-foo/vsl/internal/app/log"
+	"github.com/gloo-foo/vsl/internal/container"
+)
+
+// Config holds configuration for validating UP scripts.
+type Config struct {
+	Scripts []container.ScriptPath // Scripts checked against the script schema
+	Quiet   bool                   // Only print the problems, not the JSON result
+
+	// Output and logging
+	Output  app.FilePath
+	Logging log.Config
+}
+
+func (c Config) OutputFilePath() app.FilePath { return c.Output }
+func (c Config) LoggerConfig() log.Config     { return c.Logging }
+func (c Config) QuietOutput() bool            { return c.Quiet }
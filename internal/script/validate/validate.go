@@ -0,0 +1,83 @@
+// Package validate checks UP scripts against the script schema without
+// running them, for pre-commit hooks and CI.
+package validate
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+
+	"github.com/gloo-foo/vsl/internal/container"
+	"github.com/gloo-foo/vsl/internal/script"
+)
+
+// ErrInvalid is returned when a script has problems or cannot be read.
+var ErrInvalid = errors.New("invalid scripts")
+
+// problems is where problems are printed, one per line like compiler errors.
+var problems io.Writer = os.Stderr
+
+// Result holds the outcome of validating scripts.
+type Result struct {
+	Success bool           `json:"success"`
+	Scripts []ScriptResult `json:"scripts"`
+	Message string         `json:"message,omitempty"`
+	Error   string         `json:"error,omitempty"`
+}
+
+// ScriptResult holds the problems found in a script.
+type ScriptResult struct {
+	Script   container.ScriptPath `json:"script"`
+	Valid    bool                 `json:"valid"`
+	Problems []script.Problem     `json:"problems,omitempty"`
+}
+
+// MarshalJSON implements json.Marshaler
+func (r Result) MarshalJSON() ([]byte, error) {
+	type Alias Result
+	return json.Marshal((Alias)(r))
+}
+
+// Failed implements app.Failable
+func (r Result) Failed(err error) json.Marshaler {
+	r.Success = false
+	r.Error = err.Error()
+	return r
+}
+
+// Validate checks each script and the fragments it includes, printing the
+// problems found as file:line: key: message. It fails if any script is
+// invalid.
+func Validate(_ context.Context, logger *slog.Logger, cfg Config) (Result, error) {
+	result := Result{Scripts: make([]ScriptResult, 0, len(cfg.Scripts))}
+
+	invalid := 0
+	for _, path := range cfg.Scripts {
+		sr := ScriptResult{Script: path}
+		found, err := script.Check(string(path))
+		if err != nil {
+			found = []script.Problem{{File: string(path), Message: err.Error()}}
+		}
+		for _, p := range found {
+			_, _ = fmt.Fprintln(problems, p)
+		}
+		sr.Problems = found
+		sr.Valid = len(found) == 0
+		if !sr.Valid {
+			invalid++
+			logger.Warn("Script is invalid", "script", path, "problems", len(found))
+		}
+		result.Scripts = append(result.Scripts, sr)
+	}
+
+	if invalid > 0 {
+		return result, fmt.Errorf("%w: %d of %d scripts have problems", ErrInvalid, invalid, len(cfg.Scripts))
+	}
+	result.Success = true
+	result.Message = fmt.Sprintf("%d scripts are valid", len(cfg.Scripts))
+	return result, nil
+}
@@ -0,0 +1,123 @@
+package script
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"slices"
+	"strings"
+
+	up "github.com/uplang/go"
+)
+
+// whenKey holds named blocks of keys overriding the script's, like a
+// profile, when the host matches the conditions the block sets. Blocks are
+// applied in name order, after the selected profile:
+//
+//	when {
+//	  arm {
+//	    arch arm64
+//	    image ghcr.io/example/tool:arm64
+//	  }
+//	  mac {
+//	    os darwin
+//	    exclude_with volume
+//	  }
+//	  ci {
+//	    has_env CI
+//	    cpus 2
+//	  }
+//	}
+const whenKey = "when"
+
+// conditionKeys are the keys of a when block that set its conditions rather
+// than override the script.
+var conditionKeys = map[string]bool{"os": true, "arch": true, "has_env": true}
+
+// conditionAliases maps other common names of operating systems and
+// architectures to those Go uses.
+var conditionAliases = map[string]string{
+	"macos":   "darwin",
+	"x86_64":  "amd64",
+	"aarch64": "arm64",
+}
+
+// applyWhen removes the when blocks from nodes and applies those whose
+// conditions hold on this host.
+func applyWhen(nodes []sourcedNode) ([]sourcedNode, error) {
+	var kept, blocks []sourcedNode
+	for _, node := range nodes {
+		if node.Key == whenKey {
+			blocks = append(blocks, node)
+		} else {
+			kept = append(kept, node)
+		}
+	}
+	for _, node := range blocks {
+		block, ok := node.Value.(up.Block)
+		if !ok {
+			return nil, fmt.Errorf("when must be a block of named conditions")
+		}
+		for _, name := range sortedKeys(block) {
+			entries, ok := block[name].(up.Block)
+			if !ok {
+				return nil, fmt.Errorf("when %s must be a block", name)
+			}
+			conditions, overrides := up.Block{}, up.Block{}
+			for key, value := range entries {
+				if conditionKeys[key] {
+					conditions[key] = value
+				} else {
+					overrides[key] = value
+				}
+			}
+			matched, err := matches(conditions)
+			if err != nil {
+				return nil, fmt.Errorf("when %s: %w", name, err)
+			}
+			if matched {
+				kept = override(kept, overrides, node)
+			}
+		}
+	}
+	return kept, nil
+}
+
+// matches reports whether the host meets every condition: os and arch name
+// the operating system and architecture, has_env an environment variable
+// that is set. A condition listing several values holds when any of them
+// does, and a value prefixed with ! holds when it does not match.
+func matches(conditions up.Block) (bool, error) {
+	for _, key := range sortedKeys(conditions) {
+		values := extractInlineList(conditions[key])
+		if len(values) == 0 || slices.Contains(values, "") {
+			return false, fmt.Errorf("%s: expected a value or a list", key)
+		}
+		held := false
+		for _, value := range values {
+			negated := strings.HasPrefix(value, "!")
+			value = strings.TrimPrefix(value, "!")
+			if alias, ok := conditionAliases[value]; ok {
+				value = alias
+			}
+			var ok bool
+			switch key {
+			case "os":
+				ok = value == runtime.GOOS
+			case "arch":
+				ok = value == runtime.GOARCH
+			case "has_env":
+				_, ok = os.LookupEnv(value)
+			default:
+				return false, fmt.Errorf("unknown condition %q (expected os, arch or has_env)", key)
+			}
+			if ok != negated {
+				held = true
+			}
+		}
+		if !held {
+			return false, nil
+		}
+	}
+	return true, nil
+}
@@ -0,0 +1,264 @@
+package secret
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/gloo-foo/vsl/internal/credentials"
+)
+
+// Backend types.
+const (
+	TypeVault     = "vault"     // HashiCorp Vault KV, through the vault CLI
+	TypeSOPS      = "sops"      // SOPS-encrypted files, through the sops CLI
+	Type1Password = "1password" // 1Password, through the op CLI
+	TypeAWS       = "aws"       // AWS Secrets Manager, through the aws CLI
+	TypeEnv       = "env"       // A variable of the host environment
+	TypeFile      = "file"      // The content of a host file
+	TypeCmd       = "cmd"       // The output of a host command line, subject to the host command policy
+	TypeStore     = "store"     // A secret stored encrypted with vsl config set-secret
+)
+
+// AllowFunc decides whether a command may run on the host, returning an
+// error when it may not.
+type AllowFunc func(argv []string) error
+
+// Backend fetches secret values.
+type Backend interface {
+	Resolve(ctx context.Context, ref Ref) (string, error)
+}
+
+// BackendConfig is a named backend of the global configuration, such as
+//
+//	vault {
+//	  type vault
+//	  address https://vault.example.com:8200
+//	}
+type BackendConfig struct {
+	Name    string            `json:"name"`
+	Type    string            `json:"type"`
+	Options map[string]string `json:"options,omitempty"`
+}
+
+// Types returns the backend types, sorted.
+func Types() []string {
+	types := []string{TypeVault, TypeSOPS, Type1Password, TypeAWS, TypeEnv, TypeFile, TypeCmd, TypeStore}
+	sort.Strings(types)
+	return types
+}
+
+// backend returns the backend of the configured type. Command backends run
+// their command when allow permits it.
+func (c BackendConfig) backend(allow AllowFunc) (Backend, error) {
+	switch c.Type {
+	case TypeVault:
+		return vault(c.Options), nil
+	case TypeSOPS:
+		return sops(c.Options), nil
+	case Type1Password, "op":
+		return onePassword(c.Options), nil
+	case TypeAWS:
+		return aws(c.Options), nil
+	case TypeEnv:
+		return hostEnv{}, nil
+	case TypeFile:
+		return file{}, nil
+	case TypeCmd:
+		return command{allow: allow}, nil
+	case TypeStore:
+		return store{}, nil
+	case "":
+		return nil, fmt.Errorf("backend %q is not configured", c.Name)
+	default:
+		return nil, fmt.Errorf("backend %q has unknown type %q, expected one of %s", c.Name, c.Type, strings.Join(Types(), ", "))
+	}
+}
+
+// vault reads a field of a KV secret, with the path given as path#field.
+// The address and namespace options set VAULT_ADDR and VAULT_NAMESPACE;
+// the token comes from the vault CLI's usual sources.
+type vault map[string]string
+
+func (v vault) Resolve(ctx context.Context, ref Ref) (string, error) {
+	path, field, ok := strings.Cut(ref.Path, "#")
+	if !ok || field == "" {
+		return "", fmt.Errorf("path %q must name a field as path#field", ref.Path)
+	}
+	var env []string
+	if v["address"] != "" {
+		env = append(env, "VAULT_ADDR="+v["address"])
+	}
+	if v["namespace"] != "" {
+		env = append(env, "VAULT_NAMESPACE="+v["namespace"])
+	}
+	return cli(ctx, env, "vault", "kv", "get", "-field="+field, path)
+}
+
+// sops decrypts a file, relative to the script, with the path given as
+// file or file#dotted.key to extract a single value. The age_key_file option
+// sets SOPS_AGE_KEY_FILE.
+type sops map[string]string
+
+func (s sops) Resolve(ctx context.Context, ref Ref) (string, error) {
+	file, key, _ := strings.Cut(ref.Path, "#")
+	if !filepath.IsAbs(file) && ref.Dir != "" {
+		file = filepath.Join(ref.Dir, file)
+	}
+	var env []string
+	if s["age_key_file"] != "" {
+		env = append(env, "SOPS_AGE_KEY_FILE="+s["age_key_file"])
+	}
+	args := []string{"--decrypt"}
+	if key != "" {
+		var extract strings.Builder
+		for _, part := range strings.Split(key, ".") {
+			extract.WriteString(fmt.Sprintf("[%q]", part))
+		}
+		args = append(args, "--extract", extract.String())
+	}
+	return cli(ctx, env, "sops", append(args, file)...)
+}
+
+// onePassword reads a field by its secret reference, op://vault/item/field,
+// with or without the scheme. The account option selects the account.
+type onePassword map[string]string
+
+func (o onePassword) Resolve(ctx context.Context, ref Ref) (string, error) {
+	reference := ref.Path
+	if !strings.HasPrefix(reference, "op://") {
+		reference = "op://" + reference
+	}
+	args := []string{"read", "--no-newline"}
+	if o["account"] != "" {
+		args = append(args, "--account", o["account"])
+	}
+	return cli(ctx, nil, "op", append(args, reference)...)
+}
+
+// aws reads a secret string, with the path given as name or name#key to
+// pick a key of a JSON secret. The region and profile options select where
+// it is read from.
+type aws map[string]string
+
+func (a aws) Resolve(ctx context.Context, ref Ref) (string, error) {
+	name, key, _ := strings.Cut(ref.Path, "#")
+	args := []string{"secretsmanager", "get-secret-value", "--secret-id", name, "--query", "SecretString", "--output", "text"}
+	if a["region"] != "" {
+		args = append(args, "--region", a["region"])
+	}
+	if a["profile"] != "" {
+		args = append(args, "--profile", a["profile"])
+	}
+	value, err := cli(ctx, nil, "aws", args...)
+	if err != nil || key == "" {
+		return value, err
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(value), &fields); err != nil {
+		return "", fmt.Errorf("secret %s is not a JSON object, cannot read key %q", name, key)
+	}
+	raw, ok := fields[key]
+	if !ok {
+		return "", fmt.Errorf("secret %s has no key %q", name, key)
+	}
+	var s string
+	if json.Unmarshal(raw, &s) == nil {
+		return s, nil
+	}
+	return string(raw), nil
+}
+
+// hostEnv reads the host environment variable the path names.
+type hostEnv struct{}
+
+func (hostEnv) Resolve(_ context.Context, ref Ref) (string, error) {
+	value, ok := os.LookupEnv(ref.Path)
+	if !ok {
+		return "", fmt.Errorf("variable %s is not set", ref.Path)
+	}
+	return value, nil
+}
+
+// file reads a host file, relative to the script or the home directory with
+// a leading ~/, without its trailing newline.
+type file struct{}
+
+func (file) Resolve(_ context.Context, ref Ref) (string, error) {
+	path := ref.Path
+	if rest, ok := strings.CutPrefix(path, "~/"); ok {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		path = filepath.Join(home, rest)
+	}
+	if !filepath.IsAbs(path) && ref.Dir != "" {
+		path = filepath.Join(ref.Dir, path)
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(content), "\r\n"), nil
+}
+
+// store reads a secret of the encrypted credential store.
+type store struct{}
+
+func (store) Resolve(_ context.Context, ref Ref) (string, error) {
+	s, err := credentials.Open()
+	if err != nil {
+		return "", err
+	}
+	value, ok := s.Get(ref.Path)
+	if !ok {
+		return "", fmt.Errorf("no secret %q in the store, add it with vsl config set-secret %s", ref.Path, ref.Path)
+	}
+	return value, nil
+}
+
+// command runs a host command line with the shell and reads its output, for
+// password managers without a backend, such as pass show app/token.
+type command struct {
+	allow AllowFunc
+}
+
+func (c command) Resolve(ctx context.Context, ref Ref) (string, error) {
+	argv := []string{"/bin/sh", "-c", ref.Path}
+	if c.allow != nil {
+		if err := c.allow(argv); err != nil {
+			return "", err
+		}
+	}
+	return cli(ctx, nil, argv[0], argv[1:]...)
+}
+
+// cli runs a backend's command line tool with env added to the environment
+// and returns its output without the trailing newline.
+func cli(ctx context.Context, env []string, name string, args ...string) (string, error) {
+	if _, err := exec.LookPath(name); err != nil {
+		return "", fmt.Errorf("%s not found in PATH", name)
+	}
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Env = append(os.Environ(), env...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && stderr.Len() > 0 {
+			return "", fmt.Errorf("%s failed: %s", name, strings.TrimSpace(stderr.String()))
+		}
+		return "", fmt.Errorf("%s failed: %w", name, err)
+	}
+	return strings.TrimRight(string(out), "\r\n"), nil
+}
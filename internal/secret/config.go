@@ -0,0 +1,91 @@
+package secret
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	up "github.com/uplang/go"
+
+	"github.com/gloo-foo/vsl/internal/state"
+)
+
+// ConfigFileName is the global configuration in the vsl config directory.
+const ConfigFileName = "config.up"
+
+// LoadBackends reads the secret backends of the global configuration, keyed
+// by name:
+//
+//	secret_backends {
+//	  vault {
+//	    type vault
+//	    address https://vault.example.com:8200
+//	  }
+//	  prod {
+//	    type aws
+//	    region eu-west-1
+//	    profile prod
+//	  }
+//	}
+//
+// Keys other than type are options of the backend. A missing configuration
+// file defines no backends.
+func LoadBackends() (map[string]BackendConfig, error) {
+	dir, err := state.ConfigDir()
+	if err != nil {
+		return nil, err
+	}
+	path := filepath.Join(dir, ConfigFileName)
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return map[string]BackendConfig{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	defer func(file *os.File) {
+		err := file.Close()
+		if err != nil {
+			panic(err)
+		}
+	}(file)
+
+	doc, err := up.NewParser().ParseDocument(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+
+	backends := map[string]BackendConfig{}
+	for _, node := range doc.Nodes {
+		if node.Key != "secret_backends" {
+			continue
+		}
+		block, ok := node.Value.(up.Block)
+		if !ok {
+			return nil, fmt.Errorf("%s: secret_backends must be a block of backend names", path)
+		}
+		for name, value := range block {
+			settings, ok := value.(up.Block)
+			if !ok {
+				return nil, fmt.Errorf("%s: secret backend %s must be a block", path, name)
+			}
+			cfg := BackendConfig{Name: name, Options: map[string]string{}}
+			for key, v := range settings {
+				scalar, ok := v.(string)
+				if !ok {
+					return nil, fmt.Errorf("%s: secret backend %s: %s must be a value", path, name, key)
+				}
+				if key == "type" {
+					cfg.Type = scalar
+				} else {
+					cfg.Options[key] = scalar
+				}
+			}
+			if cfg.Type == "" {
+				return nil, fmt.Errorf("%s: secret backend %s has no type", path, name)
+			}
+			backends[name] = cfg
+		}
+	}
+	return backends, nil
+}
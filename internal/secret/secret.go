@@ -0,0 +1,147 @@
+// Package secret resolves the secrets declared by scripts from named backends
+// such as Vault, SOPS, 1Password and AWS Secrets Manager when a run starts,
+// and keeps their values out of logs and results.
+package secret
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Mask replaces secret values in output shown to the user.
+const Mask = "********"
+
+// Ref is a secret declared by a script, such as
+// secret db_pass { backend vault, path kv/app#password }.
+type Ref struct {
+	Name    string `json:"name"`
+	Backend string `json:"backend"`
+	Path    string `json:"path"`
+	Env     string `json:"env,omitempty"` // Variable the value is injected as, the upper-cased name by default
+	Dir     string `json:"dir,omitempty"` // Directory relative file paths resolve against, the script's
+}
+
+// Variable returns the environment variable the secret is injected as.
+func (r Ref) Variable() string {
+	if r.Env != "" {
+		return r.Env
+	}
+	return strings.ToUpper(r.Name)
+}
+
+// Value is a resolved secret.
+type Value struct {
+	Ref
+	Value string
+}
+
+// Values are the secrets resolved for a run.
+type Values []Value
+
+// Resolve fetches refs from their backends, configured in the global
+// configuration or, for a backend name matching a backend type, used with
+// that type's defaults. Command secrets run their command when allow
+// permits it.
+func Resolve(ctx context.Context, refs []Ref, allow AllowFunc) (Values, error) {
+	if len(refs) == 0 {
+		return nil, nil
+	}
+	backends, err := LoadBackends()
+	if err != nil {
+		return nil, err
+	}
+
+	values := make(Values, 0, len(refs))
+	for _, ref := range refs {
+		cfg, ok := backends[ref.Backend]
+		if !ok {
+			cfg = BackendConfig{Name: ref.Backend}
+			if _, err := (BackendConfig{Type: ref.Backend}).backend(allow); err == nil {
+				cfg.Type = ref.Backend
+			}
+		}
+		backend, err := cfg.backend(allow)
+		if err != nil {
+			return nil, fmt.Errorf("secret %s: %w", ref.Name, err)
+		}
+		value, err := backend.Resolve(ctx, ref)
+		if err != nil {
+			return nil, fmt.Errorf("secret %s: %s backend: %w", ref.Name, cfg.Name, err)
+		}
+		values = append(values, Value{Ref: ref, Value: value})
+	}
+	return values, nil
+}
+
+// Env returns the secrets as NAME=value entries.
+func (v Values) Env() []string {
+	env := make([]string, len(v))
+	for i, s := range v {
+		env[i] = s.Variable() + "=" + s.Value
+	}
+	return env
+}
+
+// Names returns the names of the secrets.
+func (v Values) Names() []string {
+	names := make([]string, len(v))
+	for i, s := range v {
+		names[i] = s.Name
+	}
+	return names
+}
+
+// Redact replaces every secret value in s with Mask, longest values first so
+// a secret containing another is masked whole.
+func (v Values) Redact(s string) string {
+	values := make([]string, 0, len(v))
+	for _, secret := range v {
+		if secret.Value != "" {
+			values = append(values, secret.Value)
+		}
+	}
+	sort.Slice(values, func(i, j int) bool { return len(values[i]) > len(values[j]) })
+	for _, value := range values {
+		s = strings.ReplaceAll(s, value, Mask)
+	}
+	return s
+}
+
+// RedactEnv masks the values of the secret variables among NAME=value
+// entries, leaving other entries untouched.
+func (v Values) RedactEnv(env []string) []string {
+	return v.replaceEnv(env, func(string) string { return Mask })
+}
+
+// FingerprintEnv replaces the values of the secret variables among
+// NAME=value entries by a digest, so a changed secret can be told apart
+// without recording it.
+func (v Values) FingerprintEnv(env []string) []string {
+	return v.replaceEnv(env, func(value string) string {
+		sum := sha256.Sum256([]byte(value))
+		return "sha256:" + hex.EncodeToString(sum[:])[:12]
+	})
+}
+
+func (v Values) replaceEnv(env []string, replace func(string) string) []string {
+	if len(v) == 0 {
+		return env
+	}
+	secret := make(map[string]bool, len(v))
+	for _, s := range v {
+		secret[s.Variable()] = true
+	}
+	result := make([]string, len(env))
+	for i, e := range env {
+		name, value, _ := strings.Cut(e, "=")
+		if secret[name] {
+			e = name + "=" + replace(value)
+		}
+		result[i] = e
+	}
+	return result
+}
@@ -0,0 +1,20 @@
+package snapshot
+
+import (
+	"github.com/gloo-foo/vsl/internal/app"
+	"github.com/gloo-foo/vsl/internal/app/log"
+)
+
+// Config holds configuration for restoring snapshots.
+type Config struct {
+	ID    string // Snapshot to restore, the newest when empty
+	List  bool   // List the snapshots instead of restoring one
+	Force bool   // Delete the files created since without asking
+
+	// Output and logging
+	Output  app.FilePath
+	Logging log.Config
+}
+
+func (c Config) OutputFilePath() app.FilePath { return c.Output }
+func (c Config) LoggerConfig() log.Config     { return c.Logging }
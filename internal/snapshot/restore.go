@@ -0,0 +1,116 @@
+// Package snapshot restores the worktree snapshots vsl run --snapshot takes
+// before containers get write access to a repository.
+package snapshot
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/gloo-foo/vsl/internal/container"
+	"github.com/gloo-foo/vsl/internal/git"
+	"golang.org/x/term"
+)
+
+// Result holds the snapshots of a repository or the one restored.
+type Result struct {
+	Success   bool              `json:"success"`
+	Root      container.GitRoot `json:"root"`
+	Snapshots []git.Snapshot    `json:"snapshots,omitempty"` // Newest first, when listing
+	Restored  *git.Snapshot     `json:"restored,omitempty"`
+	Removed   []string          `json:"removed,omitempty"`    // Files and directories created since, deleted
+	HeadMoved bool              `json:"head_moved,omitempty"` // HEAD differs from when the snapshot was taken
+	Message   string            `json:"message"`
+	Error     string            `json:"error,omitempty"`
+}
+
+// MarshalJSON implements json.Marshaler
+func (r Result) MarshalJSON() ([]byte, error) {
+	type Alias Result
+	return json.Marshal((Alias)(r))
+}
+
+// Failed implements app.Failable
+func (r Result) Failed(err error) json.Marshaler {
+	r.Success = false
+	r.Error = err.Error()
+	return r
+}
+
+// Restore puts the worktree of the repository containing the current
+// directory back in the state of a snapshot, or lists its snapshots. Commits
+// made since and the index are kept; when HEAD moved, the result says so, as
+// the restored files then show up as changes against it.
+func Restore(ctx context.Context, logger *slog.Logger, cfg Config) (Result, error) {
+	pwd, err := os.Getwd()
+	if err != nil {
+		return Result{}, fmt.Errorf("failed to get current directory: %w", err)
+	}
+	root, err := git.FindRoot(pwd)
+	if err != nil {
+		return Result{}, fmt.Errorf("not in a git repository: %w", err)
+	}
+	result := Result{Root: root}
+
+	if cfg.List {
+		snapshots, err := git.Snapshots(ctx, root)
+		if err != nil {
+			return result, fmt.Errorf("failed to list snapshots: %w", err)
+		}
+		result.Success = true
+		result.Snapshots = snapshots
+		result.Message = fmt.Sprintf("%d snapshots", len(snapshots))
+		return result, nil
+	}
+
+	snapshot, err := git.FindSnapshot(ctx, root, cfg.ID)
+	if err != nil {
+		return result, err
+	}
+	removals, err := git.SnapshotRemovals(ctx, root, snapshot)
+	if err != nil {
+		return result, fmt.Errorf("failed to restore snapshot %s: %w", snapshot.ID, err)
+	}
+	if len(removals) > 0 && !cfg.Force {
+		if err := confirmRemovals(snapshot, removals); err != nil {
+			return result, err
+		}
+	}
+	logger.Info("Restoring snapshot", "root", root, "id", snapshot.ID, "message", snapshot.Message)
+	if err := git.RestoreSnapshot(ctx, root, snapshot); err != nil {
+		return result, fmt.Errorf("failed to restore snapshot %s: %w", snapshot.ID, err)
+	}
+	if head, err := git.Commit(ctx, root); err == nil && head != snapshot.Head {
+		logger.Warn("HEAD moved since the snapshot", "snapshot_head", snapshot.Head, "head", head)
+		result.HeadMoved = true
+	}
+
+	result.Success = true
+	result.Restored = &snapshot
+	result.Removed = removals
+	result.Message = "Snapshot " + snapshot.ID + " restored"
+	return result, nil
+}
+
+// confirmRemovals lists the files restoring the snapshot deletes and asks on
+// the terminal whether to go ahead.
+func confirmRemovals(snapshot git.Snapshot, removals []string) error {
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		return fmt.Errorf("restoring snapshot %s deletes %d files and directories created since, use --force to restore it without a terminal", snapshot.ID, len(removals))
+	}
+	_, _ = fmt.Fprintf(os.Stderr, "Restoring snapshot %s deletes the files created since:\n", snapshot.ID)
+	for _, path := range removals {
+		_, _ = fmt.Fprintf(os.Stderr, "  %s\n", path)
+	}
+	_, _ = fmt.Fprint(os.Stderr, "Restore it? [y/N] ")
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return nil
+	}
+	return fmt.Errorf("restoring snapshot %s declined", snapshot.ID)
+}
@@ -0,0 +1,46 @@
+// Package state locates the directories vsl uses to persist data between runs.
+package state
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+const dirName = "vsl"
+
+// CacheDir returns the vsl cache directory, creating it if necessary.
+func CacheDir() (string, error) {
+	base, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to locate cache directory: %w", err)
+	}
+	dir := filepath.Join(base, dirName)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return "", fmt.Errorf("failed to create cache directory: %w", err)
+	}
+	return dir, nil
+}
+
+// ConfigDir returns the vsl configuration directory, creating it if necessary.
+func ConfigDir() (string, error) {
+	base, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to locate config directory: %w", err)
+	}
+	dir := filepath.Join(base, dirName)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return "", fmt.Errorf("failed to create config directory: %w", err)
+	}
+	return dir, nil
+}
+
+// ConfigFile returns the path of the named file in the vsl configuration
+// directory, without creating the directory.
+func ConfigFile(name string) (string, error) {
+	base, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to locate config directory: %w", err)
+	}
+	return filepath.Join(base, dirName, name), nil
+}
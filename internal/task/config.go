@@ -0,0 +1,27 @@
+package task
+
+import (
+	"github.com/gloo-foo/vsl/internal/app"
+	"github.com/gloo-foo/vsl/internal/app/log"
+)
+
+// Config holds configuration for listing and picking tasks.
+type Config struct {
+	Query string   // Fuzzy filter on task names and descriptions
+	All   bool     // List every task without prompting
+	Args  []string // Arguments passed to the picked task
+
+	// Print the plan of the task's run and ask for confirmation before running it
+	Explain bool
+
+	// Output and logging
+	Output  app.FilePath
+	Logging log.Config
+}
+
+func (c Config) OutputFilePath() app.FilePath { return c.Output }
+func (c Config) LoggerConfig() log.Config     { return c.Logging }
+
+// QuietOutput keeps the JSON result off stdout when a task runs, as the
+// task's output is streamed there.
+func (c Config) QuietOutput() bool { return runsTask(c) }
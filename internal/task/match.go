@@ -0,0 +1,69 @@
+package task
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// nameBonus ranks any match in a task's name above matches in descriptions.
+const nameBonus = 1 << 30
+
+// Match returns the tasks fuzzily matching query, best matches first. A task
+// matches when the characters of query appear in order in its name or
+// description, ignoring case; contiguous runs, matches at word starts and
+// matches in the name rank higher. An empty query matches every task.
+func Match(tasks []Task, query string) []Task {
+	if query == "" {
+		return tasks
+	}
+
+	type scored struct {
+		task  Task
+		score int
+	}
+	var matches []scored
+	for _, t := range tasks {
+		score, ok := fuzzy(t.Name, query)
+		if ok {
+			score += nameBonus
+		} else if score, ok = fuzzy(t.Description, query); !ok {
+			continue
+		}
+		matches = append(matches, scored{task: t, score: score})
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].score > matches[j].score })
+	result := make([]Task, len(matches))
+	for i, m := range matches {
+		result[i] = m.task
+	}
+	return result
+}
+
+// fuzzy scores how well query matches s as a case-insensitive subsequence.
+func fuzzy(s, query string) (int, bool) {
+	text := []rune(strings.ToLower(s))
+	pattern := []rune(strings.ToLower(query))
+
+	score, ti, previous := 0, 0, -2
+	for _, r := range pattern {
+		for ti < len(text) && text[ti] != r {
+			ti++
+		}
+		if ti == len(text) {
+			return 0, false
+		}
+		score++
+		if ti == previous+1 {
+			score += 5
+		}
+		if ti == 0 || !unicode.IsLetter(text[ti-1]) && !unicode.IsDigit(text[ti-1]) {
+			score += 3
+		}
+		previous = ti
+		ti++
+	}
+	// Prefer shorter names among equal matches
+	return score*100 - len(text), true
+}
@@ -0,0 +1,145 @@
+// Package task discovers the UP scripts of a workspace as named tasks, so
+// scripts of large monorepos can be listed, searched and picked.
+package task
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/gloo-foo/vsl/internal/container"
+	"github.com/gloo-foo/vsl/internal/git"
+	"github.com/gloo-foo/vsl/internal/script"
+)
+
+// Extension is the file extension of UP scripts.
+const Extension = ".up"
+
+// Task is a runnable UP script of the workspace.
+type Task struct {
+	Name        string               `json:"name"` // Script path relative to the workspace root, without extension
+	Script      container.ScriptPath `json:"script"`
+	Description string               `json:"description,omitempty"`
+	Image       container.Image      `json:"image,omitempty"`
+	Build       bool                 `json:"build,omitempty"` // The image is built from a Dockerfile
+	Host        bool                 `json:"host,omitempty"`  // The script runs on the host
+	Error       string               `json:"error,omitempty"` // The script cannot be parsed
+
+	// Defaults are overlaid from the project configuration when the task runs
+	Defaults *script.TaskDefaults `json:"defaults,omitempty"`
+}
+
+// Root returns the workspace root for dir: its git root, or dir itself
+// outside repositories.
+func Root(dir string) string {
+	if root, err := git.FindRoot(dir); err == nil && root != "" {
+		return string(root)
+	}
+	return dir
+}
+
+// Discover indexes the UP scripts under root, sorted by name, with the
+// defaults the project configuration at root sets for them. In a git
+// worktree, files excluded by .gitignore and the other ignore files are
+// skipped; otherwise the tree is walked, skipping hidden directories.
+func Discover(ctx context.Context, root string) ([]Task, error) {
+	defaults, err := projectDefaults(root)
+	if err != nil {
+		return nil, err
+	}
+
+	files, err := git.ListFiles(ctx, container.GitRoot(root), "*"+Extension)
+	if err != nil {
+		if files, err = walk(root); err != nil {
+			return nil, err
+		}
+	}
+
+	tasks := make([]Task, 0, len(files))
+	for _, f := range files {
+		if f == script.ProjectFile {
+			continue
+		}
+		p := filepath.Join(root, filepath.FromSlash(f))
+		if info, err := os.Stat(p); err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+		name := filepath.ToSlash(strings.TrimSuffix(f, Extension))
+		d, ok := defaults[name]
+		t := describe(p, name, d.Profile)
+		if ok {
+			t.Defaults = &d
+		}
+		tasks = append(tasks, t)
+	}
+	sort.Slice(tasks, func(i, j int) bool { return tasks[i].Name < tasks[j].Name })
+	return tasks, nil
+}
+
+// projectDefaults reads the per-task defaults of the project configuration
+// at root, if there is one.
+func projectDefaults(root string) (map[string]script.TaskDefaults, error) {
+	path := filepath.Join(root, script.ProjectFile)
+	if _, err := os.Stat(path); errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	defaults, err := script.ParseProject(path)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return defaults, nil
+}
+
+// profile returns the profile tasks are parsed with: the one --profile
+// selects, or else the default of the project configuration.
+func profile(defaultProfile string) string {
+	if script.Profile != "" {
+		return script.Profile
+	}
+	return defaultProfile
+}
+
+// walk lists the UP scripts under root without git, relative to root.
+func walk(root string) ([]string, error) {
+	var files []string
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() && p != root && strings.HasPrefix(d.Name(), ".") {
+			return filepath.SkipDir
+		}
+		if !d.IsDir() && filepath.Ext(p) == Extension {
+			rel, err := filepath.Rel(root, p)
+			if err != nil {
+				return err
+			}
+			files = append(files, filepath.ToSlash(rel))
+		}
+		return nil
+	})
+	return files, err
+}
+
+// describe reads the image, build, host and description of a script, parsed
+// with the profile its project defaults set.
+func describe(path, name, defaultProfile string) Task {
+	t := Task{Name: name, Script: container.ScriptPath(path)}
+	cfg, err := script.ParseFileProfile(path, profile(defaultProfile))
+	if err != nil {
+		t.Error = err.Error()
+		return t
+	}
+	t.Image = cfg.Image
+	t.Build = cfg.Build != nil
+	t.Host = cfg.Host
+	if t.Description, err = script.ParseDescription(path); err != nil {
+		t.Error = err.Error()
+	}
+	return t
+}
@@ -0,0 +1,250 @@
+package task
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"golang.org/x/term"
+
+	"github.com/gloo-foo/vsl/internal/container"
+	"github.com/gloo-foo/vsl/internal/container/run"
+	"github.com/gloo-foo/vsl/internal/script"
+)
+
+// pickerSize is the number of matches the picker shows at once.
+const pickerSize = 20
+
+// ErrNoMatch is returned when no task matches the query.
+var ErrNoMatch = errors.New("no task matches")
+
+// errCancelled is returned when the picker is left without a selection.
+var errCancelled = errors.New("no task selected")
+
+// Result holds the tasks of a workspace and, after picking, the run of the
+// selected task.
+type Result struct {
+	Success  bool        `json:"success"`
+	Root     string      `json:"root"`
+	Query    string      `json:"query,omitempty"`
+	Tasks    []Task      `json:"tasks"`
+	Selected *Task       `json:"selected,omitempty"`
+	Run      *run.Result `json:"run,omitempty"`
+	Message  string      `json:"message"`
+	Error    string      `json:"error,omitempty"`
+}
+
+// MarshalJSON implements json.Marshaler
+func (r Result) MarshalJSON() ([]byte, error) {
+	type Alias Result
+	return json.Marshal((Alias)(r))
+}
+
+// Failed implements app.Failable
+func (r Result) Failed(err error) json.Marshaler {
+	r.Success = false
+	r.Error = err.Error()
+	return r
+}
+
+// Tasks indexes the UP scripts of the workspace containing the current
+// directory. A query naming a task exactly runs it with Args. Otherwise, with
+// All or without a terminal, the tasks matching the query are listed, and on
+// a terminal an interactive picker narrows them down and runs the selected
+// task, streaming its output.
+func Tasks(ctx context.Context, logger *slog.Logger, cfg Config) (Result, error) {
+	pwd, err := os.Getwd()
+	if err != nil {
+		return Result{}, fmt.Errorf("failed to get current directory: %w", err)
+	}
+
+	result := Result{Root: Root(pwd), Query: cfg.Query}
+	logger.Debug("Discovering tasks", "root", result.Root)
+	all, err := Discover(ctx, result.Root)
+	if err != nil {
+		return result, fmt.Errorf("failed to discover tasks: %w", err)
+	}
+	if cfg.All {
+		cfg.Query = ""
+		result.Query = ""
+	}
+	result.Tasks = Match(all, cfg.Query)
+
+	selected, named := byName(all, cfg.Query)
+	if !named {
+		if cfg.All || !interactive() {
+			result.Success = true
+			result.Message = fmt.Sprintf("Found %d tasks", len(result.Tasks))
+			return result, nil
+		}
+		if selected, err = pick(os.Stdin, os.Stderr, all, cfg.Query); err != nil {
+			return result, err
+		}
+	}
+	result.Selected = &selected
+	logger.Info("Running task", "task", selected.Name, "script", selected.Script)
+
+	scriptCfg, err := parseTask(logger, selected, cfg.Args)
+	if err != nil {
+		return result, fmt.Errorf("task %s: %w", selected.Name, err)
+	}
+	// Output is streamed like a local command unless the task owns the terminal
+	scriptCfg.Pipe = !scriptCfg.Interactive && !scriptCfg.Detach
+	scriptCfg.Explain = cfg.Explain
+
+	runResult, err := run.Run(ctx, logger, *scriptCfg)
+	result.Run = &runResult
+	if err != nil {
+		return result, err
+	}
+	result.Success = true
+	result.Message = fmt.Sprintf("Task %s completed", selected.Name)
+	return result, nil
+}
+
+// parseTask parses the script of a task run with args, overlaying the
+// defaults of the project configuration: its profile unless --profile
+// selects one, its arguments when args is empty, and its environment. The
+// defaults applied are recorded for the explain plan.
+func parseTask(logger *slog.Logger, t Task, args []string) (*run.Config, error) {
+	d := t.Defaults
+	if d == nil {
+		d = &script.TaskDefaults{}
+	}
+	scriptCfg, err := script.ParseFileProfile(string(t.Script), profile(d.Profile))
+	if err != nil {
+		return nil, err
+	}
+	scriptCfg.ScriptPath = t.Script
+	scriptCfg.ScriptArgs = args
+
+	var applied []string
+	if d.Profile != "" && script.Profile == "" && scriptCfg.Profile == d.Profile {
+		applied = append(applied, "profile "+d.Profile)
+	}
+	if len(args) == 0 && len(d.Args) > 0 {
+		scriptCfg.ScriptArgs = d.Args
+		applied = append(applied, "args "+strings.Join(d.Args, " "))
+	}
+	if len(d.Env) > 0 {
+		scriptCfg.Environment = overlayEnv(scriptCfg.Environment, d.Env)
+		names := make([]string, len(d.Env))
+		for i, e := range d.Env {
+			names[i] = envName(e)
+		}
+		applied = append(applied, "env "+strings.Join(names, ", "))
+	}
+	if len(applied) > 0 {
+		logger.Debug("Applying project defaults", "task", t.Name, "defaults", applied)
+		scriptCfg.TaskDefaults = fmt.Sprintf("%s of task %s: %s", script.ProjectFile, t.Name, strings.Join(applied, "; "))
+	}
+	return scriptCfg, nil
+}
+
+// interactive reports whether tasks can be picked on the terminal.
+func interactive() bool {
+	return term.IsTerminal(int(os.Stdin.Fd())) && term.IsTerminal(int(os.Stderr.Fd()))
+}
+
+// runsTask reports whether cfg runs a task rather than listing tasks: its
+// query names the script of a task, or one is picked on the terminal.
+func runsTask(cfg Config) bool {
+	if cfg.All {
+		return false
+	}
+	if interactive() {
+		return true
+	}
+	pwd, err := os.Getwd()
+	if err != nil || cfg.Query == "" {
+		return false
+	}
+	info, err := os.Stat(filepath.Join(Root(pwd), filepath.FromSlash(cfg.Query)+Extension))
+	return err == nil && info.Mode().IsRegular()
+}
+
+// overlayEnv returns env with the variables of overlay, which replace the
+// variables of the same name.
+func overlayEnv(env, overlay []container.Environment) []container.Environment {
+	replaced := map[string]bool{}
+	for _, e := range overlay {
+		replaced[envName(e)] = true
+	}
+	result := make([]container.Environment, 0, len(env)+len(overlay))
+	for _, e := range env {
+		if !replaced[envName(e)] {
+			result = append(result, e)
+		}
+	}
+	return append(result, overlay...)
+}
+
+// envName returns the name of an environment entry.
+func envName(e container.Environment) string {
+	name, _, _ := strings.Cut(string(e), "=")
+	return name
+}
+
+// byName returns the task named exactly name.
+func byName(tasks []Task, name string) (Task, bool) {
+	for _, t := range tasks {
+		if name != "" && t.Name == name {
+			return t, true
+		}
+	}
+	return Task{}, false
+}
+
+// pick shows the tasks matching query on out and reads a choice from in:
+// the number of a task, or text that replaces the query. A single match is
+// selected without asking.
+func pick(in io.Reader, out io.Writer, tasks []Task, query string) (Task, error) {
+	reader := bufio.NewReader(in)
+	for {
+		matches := Match(tasks, query)
+		switch len(matches) {
+		case 0:
+			return Task{}, fmt.Errorf("%w %q", ErrNoMatch, query)
+		case 1:
+			return matches[0], nil
+		}
+
+		shown := matches[:min(len(matches), pickerSize)]
+		for i, t := range shown {
+			_, _ = fmt.Fprintf(out, "%3d) %s", i+1, t.Name)
+			if t.Description != "" {
+				_, _ = fmt.Fprintf(out, " - %s", t.Description)
+			}
+			_, _ = fmt.Fprintln(out)
+		}
+		if len(matches) > len(shown) {
+			_, _ = fmt.Fprintf(out, "     ... %d more, type to filter\n", len(matches)-len(shown))
+		}
+		_, _ = fmt.Fprint(out, "Select a task (number, or text to filter): ")
+
+		line, err := reader.ReadString('\n')
+		answer := strings.TrimSpace(line)
+		if answer == "" {
+			return Task{}, errCancelled
+		}
+		if n, convErr := strconv.Atoi(answer); convErr == nil {
+			if n >= 1 && n <= len(shown) {
+				return shown[n-1], nil
+			}
+			_, _ = fmt.Fprintf(out, "No task %d\n", n)
+			continue
+		}
+		if err != nil {
+			return Task{}, errCancelled
+		}
+		query = answer
+	}
+}
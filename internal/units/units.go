@@ -0,0 +1,188 @@
+// Package units parses human-friendly sizes and durations for flags and
+// script keys, and renders them back in humanized form.
+//
+// Values marshal to JSON as raw numbers (bytes and milliseconds) so machine
+// consumers never parse units; String renders them for people.
+package units
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/docker/go-units"
+)
+
+// quantity matches one number with an optional unit. Both "." and "," are
+// accepted as the decimal separator, and the unit may follow a space.
+var quantity = regexp.MustCompile(`^\s*([0-9]+(?:[.,][0-9]+)?|[.,][0-9]+)\s*([a-zµμ]*)`)
+
+// parseNumber parses a decimal number written with either separator.
+func parseNumber(s string) (float64, error) {
+	return strconv.ParseFloat(strings.Replace(s, ",", ".", 1), 64)
+}
+
+// byteUnits maps size suffixes to multipliers. Like Docker's memory flags,
+// all multiples are binary (1k = 1024). Besides B the French octet (o, ko, Mo)
+// is accepted.
+var byteUnits = map[string]int64{
+	"": 1, "b": 1, "byte": 1, "bytes": 1, "o": 1, "octet": 1, "octets": 1,
+	"k": units.KiB, "kb": units.KiB, "kib": units.KiB, "ko": units.KiB, "kilobyte": units.KiB, "kilobytes": units.KiB,
+	"m": units.MiB, "mb": units.MiB, "mib": units.MiB, "mo": units.MiB, "megabyte": units.MiB, "megabytes": units.MiB,
+	"g": units.GiB, "gb": units.GiB, "gib": units.GiB, "go": units.GiB, "gigabyte": units.GiB, "gigabytes": units.GiB,
+	"t": units.TiB, "tb": units.TiB, "tib": units.TiB, "to": units.TiB, "terabyte": units.TiB, "terabytes": units.TiB,
+}
+
+// Bytes is a size in bytes. The zero value means unset.
+type Bytes int64
+
+// ParseBytes parses a size such as 512m, 2gb, 1.5 GiB or 1,5Go.
+// A number without a unit is a count of bytes.
+func ParseBytes(s string) (Bytes, error) {
+	lower := strings.ToLower(strings.TrimSpace(s))
+	m := quantity.FindStringSubmatch(lower)
+	if m == nil || len(m[0]) != len(lower) {
+		return 0, fmt.Errorf("invalid size %q (expected e.g. 512m or 2gb)", s)
+	}
+	n, err := parseNumber(m[1])
+	if err != nil {
+		return 0, fmt.Errorf("invalid size %q: %w", s, err)
+	}
+	multiplier, ok := byteUnits[m[2]]
+	if !ok {
+		return 0, fmt.Errorf("invalid size %q: unknown unit %q (expected b, k, m, g or t)", s, m[2])
+	}
+	size := n * float64(multiplier)
+	if size > math.MaxInt64 {
+		return 0, fmt.Errorf("invalid size %q: too large", s)
+	}
+	return Bytes(size), nil
+}
+
+// String renders the size with binary units, such as 512MiB.
+func (b Bytes) String() string {
+	return units.BytesSize(float64(b))
+}
+
+// Set implements flag.Value, so Bytes can back a cli.GenericFlag.
+func (b *Bytes) Set(s string) error {
+	v, err := ParseBytes(s)
+	if err != nil {
+		return err
+	}
+	*b = v
+	return nil
+}
+
+// MarshalJSON renders the size as a number of bytes.
+func (b Bytes) MarshalJSON() ([]byte, error) {
+	return json.Marshal(int64(b))
+}
+
+// UnmarshalJSON accepts a number of bytes or a human-friendly size.
+func (b *Bytes) UnmarshalJSON(data []byte) error {
+	var s string
+	if json.Unmarshal(data, &s) == nil {
+		return b.Set(s)
+	}
+	var n int64
+	if err := json.Unmarshal(data, &n); err != nil {
+		return err
+	}
+	*b = Bytes(n)
+	return nil
+}
+
+// durationUnits maps duration suffixes, including spelled-out forms, to their length.
+var durationUnits = map[string]time.Duration{
+	"ns": time.Nanosecond,
+	"us": time.Microsecond, "µs": time.Microsecond, "μs": time.Microsecond,
+	"ms": time.Millisecond,
+	"s":  time.Second, "sec": time.Second, "secs": time.Second, "second": time.Second, "seconds": time.Second,
+	"m": time.Minute, "min": time.Minute, "mins": time.Minute, "minute": time.Minute, "minutes": time.Minute,
+	"h": time.Hour, "hr": time.Hour, "hrs": time.Hour, "hour": time.Hour, "hours": time.Hour,
+	"d": 24 * time.Hour, "day": 24 * time.Hour, "days": 24 * time.Hour,
+}
+
+// Duration is a length of time. The zero value means unset.
+type Duration time.Duration
+
+// ParseDuration parses a duration such as 90s, 1h30m, 1.5h, 2 days or 1,5 hours.
+// A number without a unit is a count of seconds.
+func ParseDuration(s string) (Duration, error) {
+	rest := strings.ToLower(strings.TrimSpace(s))
+	if rest == "" {
+		return 0, fmt.Errorf("invalid duration %q (expected e.g. 90s or 1h30m)", s)
+	}
+
+	var total float64
+	for rest != "" {
+		m := quantity.FindStringSubmatch(rest)
+		if m == nil {
+			return 0, fmt.Errorf("invalid duration %q (expected e.g. 90s or 1h30m)", s)
+		}
+		n, err := parseNumber(m[1])
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration %q: %w", s, err)
+		}
+		unit, ok := durationUnits[m[2]]
+		switch {
+		case m[2] == "" && len(m[0]) == len(rest) && total == 0:
+			unit, ok = time.Second, true
+		case !ok:
+			return 0, fmt.Errorf("invalid duration %q: unknown unit %q (expected s, m, h or d)", s, m[2])
+		}
+		total += n * float64(unit)
+		rest = strings.TrimSpace(rest[len(m[0]):])
+	}
+
+	if total > math.MaxInt64 {
+		return 0, fmt.Errorf("invalid duration %q: too large", s)
+	}
+	return Duration(total), nil
+}
+
+// String renders the duration compactly, such as 1h30m or 90ms.
+func (d Duration) String() string {
+	s := time.Duration(d).String()
+	if strings.HasSuffix(s, "m0s") {
+		s = strings.TrimSuffix(s, "0s")
+	}
+	if strings.HasSuffix(s, "h0m") {
+		s = strings.TrimSuffix(s, "0m")
+	}
+	return s
+}
+
+// Set implements flag.Value, so Duration can back a cli.GenericFlag.
+func (d *Duration) Set(s string) error {
+	v, err := ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = v
+	return nil
+}
+
+// MarshalJSON renders the duration as a number of milliseconds.
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).Milliseconds())
+}
+
+// UnmarshalJSON accepts a number of milliseconds or a human-friendly duration.
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if json.Unmarshal(data, &s) == nil {
+		return d.Set(s)
+	}
+	var ms int64
+	if err := json.Unmarshal(data, &ms); err != nil {
+		return err
+	}
+	*d = Duration(time.Duration(ms) * time.Millisecond)
+	return nil
+}
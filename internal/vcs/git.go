@@ -0,0 +1,36 @@
+package vcs
+
+import (
+	"context"
+
+	"github.com/gloo-foo/vsl/internal/container"
+	"github.com/gloo-foo/vsl/internal/git"
+)
+
+// gitRemote is the remote whose URL is reported.
+const gitRemote = "origin"
+
+// Git adapts the git package, which works without the git binary too.
+type Git struct{}
+
+func (Git) Name() string { return NameGit }
+
+func (Git) FindRoot(startDir string, ceilings []string) (container.GitRoot, error) {
+	return git.FindRootBelow(startDir, ceilings)
+}
+
+func (Git) Branch(ctx context.Context, root container.GitRoot) (string, error) {
+	return git.Branch(ctx, root)
+}
+
+func (Git) Commit(ctx context.Context, root container.GitRoot) (string, error) {
+	return git.Commit(ctx, root)
+}
+
+func (Git) Dirty(ctx context.Context, root container.GitRoot) (bool, error) {
+	return git.Dirty(ctx, root)
+}
+
+func (Git) RemoteURL(ctx context.Context, root container.GitRoot) (string, error) {
+	return git.RemoteURL(ctx, root, gitRemote)
+}
@@ -0,0 +1,82 @@
+package vcs
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/gloo-foo/vsl/internal/container"
+)
+
+// Mercurial finds repositories by their .hg directory. Without the hg
+// binary, the branch and the working directory's parent revision are read
+// from the repository files.
+type Mercurial struct{}
+
+func (Mercurial) Name() string { return NameMercurial }
+
+func (Mercurial) FindRoot(startDir string, ceilings []string) (container.GitRoot, error) {
+	return findMarker(startDir, ".hg", ceilings)
+}
+
+// Branch returns the named branch of the working directory, "default"
+// unless another was set.
+func (Mercurial) Branch(ctx context.Context, root container.GitRoot) (string, error) {
+	if out, err := hg(ctx, root, "branch"); err == nil {
+		return out, nil
+	}
+	content, err := os.ReadFile(filepath.Join(string(root), ".hg", "branch"))
+	if os.IsNotExist(err) {
+		return "default", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(content)), nil
+}
+
+// Commit returns the node ID of the working directory's first parent, read
+// from the start of the dirstate without the binary.
+func (Mercurial) Commit(ctx context.Context, root container.GitRoot) (string, error) {
+	if out, err := hg(ctx, root, "log", "-r", ".", "-T", "{node}"); err == nil {
+		return out, nil
+	}
+	f, err := os.Open(filepath.Join(string(root), ".hg", "dirstate"))
+	if err != nil {
+		return "", err
+	}
+	defer func(f *os.File) {
+		err := f.Close()
+		if err != nil {
+			panic(err)
+		}
+	}(f)
+	node := make([]byte, 20)
+	if _, err := io.ReadFull(f, node); err != nil {
+		return "", fmt.Errorf("failed to read hg dirstate: %w", err)
+	}
+	return hex.EncodeToString(node), nil
+}
+
+// Dirty reports modified, added or removed files; it requires the binary.
+func (Mercurial) Dirty(ctx context.Context, root container.GitRoot) (bool, error) {
+	out, err := hg(ctx, root, "status", "--modified", "--added", "--removed", "--deleted")
+	if err != nil {
+		return false, err
+	}
+	return out != "", nil
+}
+
+// RemoteURL returns the default path; it requires the binary.
+func (Mercurial) RemoteURL(ctx context.Context, root container.GitRoot) (string, error) {
+	return hg(ctx, root, "paths", "default")
+}
+
+// hg runs Mercurial without user extensions or aliases changing its output.
+func hg(ctx context.Context, root container.GitRoot, args ...string) (string, error) {
+	return command(ctx, root, "hg", append([]string{"--config", "ui.interactive=false", "-y"}, args...)...)
+}
@@ -0,0 +1,64 @@
+package vcs
+
+import (
+	"context"
+	"strings"
+
+	"github.com/gloo-foo/vsl/internal/container"
+)
+
+// jjRemote is the git remote whose URL is reported.
+const jjRemote = "origin"
+
+// Jujutsu finds repositories by their .jj directory. Its metadata requires
+// the jj binary. The working copy is itself a commit, which is reported;
+// its bookmarks stand in for the branch.
+type Jujutsu struct{}
+
+func (Jujutsu) Name() string { return NameJujutsu }
+
+func (Jujutsu) FindRoot(startDir string, ceilings []string) (container.GitRoot, error) {
+	return findMarker(startDir, ".jj", ceilings)
+}
+
+// Branch returns the bookmarks pointing at the working-copy commit,
+// separated by commas, or "" when there are none.
+func (Jujutsu) Branch(ctx context.Context, root container.GitRoot) (string, error) {
+	return jj(ctx, root, "log", "-r", "@", "-T", `bookmarks.map(|b| b.name()).join(",")`)
+}
+
+func (Jujutsu) Commit(ctx context.Context, root container.GitRoot) (string, error) {
+	return jj(ctx, root, "log", "-r", "@", "-T", "commit_id")
+}
+
+// Dirty reports whether the working-copy commit has changes.
+func (Jujutsu) Dirty(ctx context.Context, root container.GitRoot) (bool, error) {
+	out, err := jj(ctx, root, "log", "-r", "@", "-T", "empty")
+	if err != nil {
+		return false, err
+	}
+	return out == "false", nil
+}
+
+// RemoteURL returns the URL of the origin git remote.
+func (Jujutsu) RemoteURL(ctx context.Context, root container.GitRoot) (string, error) {
+	out, err := jj(ctx, root, "git", "remote", "list")
+	if err != nil {
+		return "", err
+	}
+	for _, line := range strings.Split(out, "\n") {
+		if name, url, ok := strings.Cut(line, " "); ok && name == jjRemote {
+			return url, nil
+		}
+	}
+	return "", nil
+}
+
+// jj runs a Jujutsu command with plain output. Like every jj command, it
+// first snapshots the working copy, so the working-copy commit is current.
+func jj(ctx context.Context, root container.GitRoot, args ...string) (string, error) {
+	if args[0] == "log" {
+		args = append(args, "--no-graph")
+	}
+	return command(ctx, root, "jj", append([]string{"--no-pager", "--color=never"}, args...)...)
+}
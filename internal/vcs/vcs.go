@@ -0,0 +1,113 @@
+// Package vcs detects the version control system managing a directory, git,
+// Jujutsu or Mercurial, and reads the metadata vsl reports and injects from
+// its repository.
+package vcs
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/gloo-foo/vsl/internal/container"
+)
+
+// Names of the supported version control systems, as reported in results.
+const (
+	NameGit       = "git"
+	NameJujutsu   = "jj"
+	NameMercurial = "hg"
+)
+
+// ErrNoRepository is returned when no supported repository contains a directory.
+var ErrNoRepository = errors.New("no repository found")
+
+// VCS is a version control system vsl can find repositories of and describe.
+type VCS interface {
+	// Name identifies the system, such as "git".
+	Name() string
+	// FindRoot returns the root of the repository containing startDir,
+	// without entering the ceiling directories.
+	FindRoot(startDir string, ceilings []string) (container.GitRoot, error)
+	// Branch returns the current branch, or "" when there is none.
+	Branch(ctx context.Context, root container.GitRoot) (string, error)
+	// Commit returns the full identifier of the checked-out revision.
+	Commit(ctx context.Context, root container.GitRoot) (string, error)
+	// Dirty reports whether the working copy has uncommitted changes.
+	Dirty(ctx context.Context, root container.GitRoot) (bool, error)
+	// RemoteURL returns the URL of the default remote.
+	RemoteURL(ctx context.Context, root container.GitRoot) (string, error)
+}
+
+// Systems lists the supported systems. When several manage the same root,
+// as for Jujutsu repositories colocated with git, the first one wins.
+var Systems = []VCS{Jujutsu{}, Git{}, Mercurial{}}
+
+// Detect returns the innermost repository containing startDir among all
+// Systems, and the system managing it.
+func Detect(startDir string, ceilings []string) (VCS, container.GitRoot, error) {
+	var found VCS
+	var root container.GitRoot
+	for _, system := range Systems {
+		r, err := system.FindRoot(startDir, ceilings)
+		if err != nil || r == "" {
+			continue
+		}
+		if found == nil || len(r) > len(root) {
+			found, root = system, r
+		}
+	}
+	if found == nil {
+		return nil, "", ErrNoRepository
+	}
+	return found, root, nil
+}
+
+// ByName returns the system called name.
+func ByName(name string) (VCS, bool) {
+	for _, system := range Systems {
+		if system.Name() == name {
+			return system, true
+		}
+	}
+	return nil, false
+}
+
+// findMarker walks up from startDir to the first directory holding the
+// marker directory, such as .hg, without entering the ceilings.
+func findMarker(startDir, marker string, ceilings []string) (container.GitRoot, error) {
+	dir := startDir
+	for {
+		if info, err := os.Stat(filepath.Join(dir, marker)); err == nil && info.IsDir() {
+			return container.GitRoot(dir), nil
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir || slices.Contains(ceilings, parent) {
+			return "", ErrNoRepository
+		}
+		dir = parent
+	}
+}
+
+// command runs binary with args in root and returns its trimmed standard
+// output, or exec.ErrNotFound when the binary is not installed.
+func command(ctx context.Context, root container.GitRoot, binary string, args ...string) (string, error) {
+	path, err := exec.LookPath(binary)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", binary, exec.ErrNotFound)
+	}
+	cmd := exec.CommandContext(ctx, path, args...)
+	cmd.Dir = string(root)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("%s %s: %w: %s", binary, strings.Join(args, " "), err, strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}